@@ -0,0 +1,122 @@
+// This file contains the accessibility tree related code of the page.
+
+package rod
+
+import (
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// AXNode is a node of the accessibility tree, the ignored nodes are skipped,
+// their children are attached to the closest ancestor that is not ignored.
+type AXNode struct {
+	*proto.AccessibilityAXNode
+
+	// Role of the node, such as "button"
+	Role string
+
+	// Name is the accessible name of the node, such as the text of a button
+	Name string
+
+	Children []*AXNode
+
+	page *Page
+}
+
+// Virtual returns true if the node has no dom node, such as the text nodes that are generated by css
+func (n *AXNode) Virtual() bool {
+	return n.BackendDOMNodeID == 0
+}
+
+// ElementE resolves the dom node of the ax node, returns an ErrAXVirtualNode error if the node is virtual
+func (n *AXNode) ElementE() (*Element, error) {
+	if n.Virtual() {
+		return nil, &Error{nil, ErrAXVirtualNode, n.NodeID}
+	}
+	return n.page.resolveNodeE(n.BackendDOMNodeID)
+}
+
+// AccessibilitySnapshotE returns the root node of the accessibility tree of the page
+func (p *Page) AccessibilitySnapshotE() (*AXNode, error) {
+	res, err := proto.AccessibilityGetFullAXTree{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Nodes) == 0 {
+		return nil, &Error{nil, ErrAXNodeNotFound, nil}
+	}
+
+	dict := map[proto.AccessibilityAXNodeID]*proto.AccessibilityAXNode{}
+	for _, node := range res.Nodes {
+		dict[node.NodeID] = node
+	}
+
+	// the first node is the root
+	root := p.newAXNode(res.Nodes[0])
+	root.Children = p.axChildren(dict, res.Nodes[0])
+	return root, nil
+}
+
+// ElementByRoleE returns the first element whose accessibility node matches the role and the accessible name
+func (p *Page) ElementByRoleE(role, name string) (*Element, error) {
+	root, err := p.AccessibilitySnapshotE()
+	if err != nil {
+		return nil, err
+	}
+
+	node := root.find(role, name)
+	if node == nil {
+		return nil, &Error{nil, ErrAXNodeNotFound, map[string]string{"role": role, "name": name}}
+	}
+	return node.ElementE()
+}
+
+func (p *Page) newAXNode(node *proto.AccessibilityAXNode) *AXNode {
+	return &AXNode{
+		AccessibilityAXNode: node,
+		Role:                axValue(node.Role),
+		Name:                axValue(node.Name),
+		page:                p,
+	}
+}
+
+// axChildren returns the children of the node that are not ignored, the children of an ignored node are
+// lifted to the node
+func (p *Page) axChildren(dict map[proto.AccessibilityAXNodeID]*proto.AccessibilityAXNode, node *proto.AccessibilityAXNode) []*AXNode {
+	list := []*AXNode{}
+	for _, id := range node.ChildIds {
+		child, has := dict[id]
+		if !has {
+			continue
+		}
+
+		if child.Ignored {
+			list = append(list, p.axChildren(dict, child)...)
+			continue
+		}
+
+		n := p.newAXNode(child)
+		n.Children = p.axChildren(dict, child)
+		list = append(list, n)
+	}
+	return list
+}
+
+// find the first node that matches the role and name in depth-first order
+func (n *AXNode) find(role, name string) *AXNode {
+	if n.Role == role && n.Name == name {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.find(role, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func axValue(v *proto.AccessibilityAXValue) string {
+	if v == nil {
+		return ""
+	}
+	return v.Value.String()
+}
@@ -0,0 +1,179 @@
+// This file contains the detach and the reattach of the page session. The session can be detached externally,
+// such as the DevTools is opened on the tab, then the browser will reject every call of the old session,
+// so the calls must fail fast with a clear error, or be retried on a new session of the same target.
+
+package rod
+
+import (
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// attachState is shared by all the clones of a page session
+type attachState struct {
+	page *Page // the page that owns the session, it will be reattached by the auto reattach
+
+	detached chan kit.Nil // closed when the session is detached
+	gone     chan kit.Nil // closed when the target is destroyed
+	once     sync.Once
+	goneOnce sync.Once
+
+	reattachOnce sync.Once
+	next         proto.TargetSessionID // the session that replaces this one
+	nextErr      error
+}
+
+// sessionLock is shared by all the clones of a page target
+type sessionLock struct {
+	sync.RWMutex
+
+	reattach sync.Mutex // only one ReattachE runs at a time
+}
+
+// sessionClient returns the client and the id of the current session of the page
+func (p *Page) sessionClient() (proto.Client, string) {
+	p.sessionLock.RLock()
+	defer p.sessionLock.RUnlock()
+	return &callClient{p.browser.cdpClient(), p.TargetID, p.crash, p.attach}, string(p.SessionID)
+}
+
+func (p *Page) attachState() *attachState {
+	p.sessionLock.RLock()
+	defer p.sessionLock.RUnlock()
+	return p.attach
+}
+
+func newAttachState(p *Page) *attachState {
+	return &attachState{
+		page:     p,
+		detached: make(chan kit.Nil),
+		gone:     make(chan kit.Nil),
+	}
+}
+
+// watch records the detach of the session until the events are closed, the events are the browser level ones,
+// because the detach isn't reported on the session itself
+func (s *attachState) watch(events chan goob.Event, targetID proto.TargetTargetID, sessionID func() proto.TargetSessionID) {
+	goob.Each(events, func(msg *cdp.Event) {
+		detached := &proto.TargetDetachedFromTarget{}
+		destroyed := &proto.TargetTargetDestroyed{}
+
+		switch {
+		case Event(msg, detached) && detached.SessionID == sessionID():
+			s.once.Do(func() { close(s.detached) })
+		case Event(msg, destroyed) && destroyed.TargetID == targetID:
+			s.goneOnce.Do(func() { close(s.gone) })
+			s.once.Do(func() { close(s.detached) })
+			s.page.browser.pages.remove(targetID)
+		}
+	})
+}
+
+// err returns an ErrTargetGone error if the target is destroyed, or an ErrPageDetached error if only the session
+// is detached
+func (s *attachState) err() error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case <-s.gone:
+		return &Error{nil, ErrTargetGone, s.page.TargetID}
+	default:
+	}
+
+	select {
+	case <-s.detached:
+		return &Error{nil, ErrPageDetached, s.page.TargetID}
+	default:
+		return nil
+	}
+}
+
+// reattach returns the new session that replaces the detached one, the page will only be reattached once no matter
+// how many calls of the session fail. If the auto reattach of the browser is disabled the error of the state
+// will be returned.
+func (s *attachState) reattach() (proto.TargetSessionID, error) {
+	s.reattachOnce.Do(func() {
+		s.nextErr = s.err()
+		if IsError(s.nextErr, ErrTargetGone) || !s.page.browser.autoReattach {
+			return
+		}
+
+		s.nextErr = s.page.ReattachE()
+		s.next = s.page.sessionID()
+	})
+	return s.next, s.nextErr
+}
+
+// ReattachE attaches a new session to the target of the page, and restores the emulations that are set via the page,
+// such as the viewport, the user agent, the extra headers, the emulated media, and the network conditions. Use it after the session is
+// detached externally, such as the DevTools is opened on the tab. The hijacking and the exposed functions are not
+// restored. If the target is destroyed an ErrTargetGone error will be returned. For an iframe, reattach the root page
+// and get the frame again. The clones of the page created before the call still use the old session, unless the
+// auto reattach of the browser is enabled.
+func (p *Page) ReattachE() error {
+	p.sessionLock.reattach.Lock()
+	defer p.sessionLock.reattach.Unlock()
+
+	if err := p.attachState().err(); IsError(err, ErrTargetGone) {
+		return err
+	}
+
+	_, err := proto.TargetGetTargetInfo{TargetID: p.TargetID}.Call(p.browser)
+	if err != nil {
+		return &Error{err, ErrTargetGone, p.TargetID}
+	}
+
+	// the new session is set up via a copy, so that the other goroutines keep using the old fields until it's done,
+	// the calls of the old session will be rejected, attach via the browser
+	p.sessionLock.RLock()
+	next := *p
+	p.sessionLock.RUnlock()
+	next.SessionID = ""
+	next.lazy = nil
+	next.attach = nil
+	next.windowObjectID = ""
+
+	// the viewport is restored by the initSession
+	err = next.initSession(p)
+	if err != nil {
+		return err
+	}
+
+	p.sessionLock.Lock()
+	p.SessionID = next.SessionID
+	p.FrameID = next.FrameID
+	p.attach = next.attach
+	p.windowObjectID = ""
+	p.sessionLock.Unlock()
+
+	if p.userAgent != nil {
+		err = p.userAgent.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.headers != nil {
+		err = p.headers.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.media != nil {
+		return p.media.Call(p)
+	}
+	return nil
+}
+
+func (p *Page) sessionID() proto.TargetSessionID {
+	p.sessionLock.RLock()
+	defer p.sessionLock.RUnlock()
+	return p.SessionID
+}
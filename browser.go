@@ -1,5 +1,6 @@
 //go:generate go run ./lib/proto/generate
 //go:generate go run ./lib/assets/generate
+//go:generate go run ./lib/sugar/generate
 
 package rod
 
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/tidwall/gjson"
 	"github.com/ysmood/goob"
 	"github.com/ysmood/kit"
 	"github.com/ysmood/rod/lib/cdp"
@@ -29,14 +31,37 @@ type Browser struct {
 	ctx           context.Context
 	ctxCancel     func()
 	timeoutCancel func()
+	timeoutParent *Browser // the object that the Timeout is called on
 
 	// BrowserContextID is the id for incognito window
 	BrowserContextID proto.BrowserBrowserContextID
 
 	slowmotion time.Duration // slowdown user inputs
 	trace      bool          // enable show auto tracing of user inputs
+	traceLog   *traceLog     // nil means the trace records won't be written
+	cdpLog     *cdpLogState  // the log of the cdp traffic
+	stable     time.Duration // wait for the element to be stable before clicking it
+	bypassCSP  bool          // bypass the CSP of the page if it blocks the rod helper
+
+	autoReattach bool // reattach the detached page sessions on the next call
+
+	backoff *backoff // nil means the default backoff of the Page.Sleeper
+
+	defaultViewport *proto.EmulationSetDeviceMetricsOverride // nil means the new pages keep the window size
+
+	eventBuffer   int           // the size of the event queue of each page session
+	eventOverflow EventOverflow // the policy when the event queue of a page session is full
+
+	pages *pageRegistry
+
+	stealth map[StealthEvasion]bool // the evasions to install into the new pages
+
+	version *versionState
+
+	downloads *downloadState
 
 	monitorServer *kit.ServerContext
+	monitor       *monitorState
 
 	client *cdp.Client
 	event  *goob.Observable // all the browser events from cdp client
@@ -47,6 +72,14 @@ func New() *Browser {
 	b := &Browser{
 		trace:      defaults.Trace,
 		slowmotion: defaults.Slow,
+		stable:     100 * time.Millisecond,
+		version:    &versionState{},
+		pages:      &pageRegistry{list: map[proto.TargetTargetID]*Page{}},
+		cdpLog:     &cdpLogState{},
+		monitor:    &monitorState{},
+		downloads:  &downloadState{list: map[proto.BrowserBrowserContextID]*downloadBehavior{}},
+
+		eventBuffer: defaultEventBuffer,
 	}
 
 	return b.Context(context.Background())
@@ -70,6 +103,43 @@ func (b *Browser) Trace(enable bool) *Browser {
 	return b
 }
 
+// WaitStableBeforeClick sets the duration that the element must keep its position and size before it gets clicked,
+// it helps to avoid clicking the wrong place when the element is animating. Set it to 0 to disable the waiting.
+// The default is 100ms.
+func (b *Browser) WaitStableBeforeClick(d time.Duration) *Browser {
+	b.stable = d
+	return b
+}
+
+// BypassCSP enables/disables bypassing the Content-Security-Policy of a page when it blocks the injection of
+// the rod helper, the bypass takes effect after the next navigation or reload of the page.
+func (b *Browser) BypassCSP(enable bool) *Browser {
+	b.bypassCSP = enable
+	return b
+}
+
+// AutoReattach enables/disables reattaching the page automatically when its session is detached externally,
+// such as the DevTools is opened on the tab, the call that fails because of the detach will be retried once on the
+// new session. See Page.ReattachE for what will be restored.
+func (b *Browser) AutoReattach(enable bool) *Browser {
+	b.autoReattach = enable
+	return b
+}
+
+// DefaultViewport sets the viewport that is emulated for every page of the browser when it's attached, before the
+// page loads its document, so the first load of a new page already sees the metrics. The ViewportE of a page
+// overrides it for the page. The iframes are not affected.
+func (b *Browser) DefaultViewport(v *proto.EmulationSetDeviceMetricsOverride) *Browser {
+	b.defaultViewport = v
+	return b
+}
+
+// NoDefaultViewport disables the DefaultViewport, the new pages will keep the window size that the browser chooses
+func (b *Browser) NoDefaultViewport() *Browser {
+	b.defaultViewport = nil
+	return b
+}
+
 // Client set the cdp client
 func (b *Browser) Client(c *cdp.Client) *Browser {
 	b.client = c
@@ -119,17 +189,44 @@ func (b *Browser) CloseE() error {
 	return nil
 }
 
-// IncognitoE creates a new incognito browser
+// IncognitoE creates a new incognito browser, it's the same as NewContextE
 func (b *Browser) IncognitoE() (*Browser, error) {
+	return b.NewContextE()
+}
+
+// NewContextE creates a new browser context, it's like an incognito window, the cookies and storages
+// are isolated from the other contexts. The returned clone will create the pages in the new context,
+// and its events of the targets from the other contexts will be filtered out.
+func (b *Browser) NewContextE() (*Browser, error) {
 	res, err := proto.TargetCreateBrowserContext{}.Call(b)
 	if err != nil {
 		return nil, err
 	}
 
-	incognito := *b
-	incognito.BrowserContextID = res.BrowserContextID
+	ctx := b.Context(b.ctx)
+	ctx.BrowserContextID = res.BrowserContextID
+	ctx.event = b.event.Filter(ctx.ctx, func(e *cdp.Event) bool {
+		id := gjson.GetBytes(e.Params, "targetInfo.browserContextId")
+		return !id.Exists() || id.String() == string(res.BrowserContextID)
+	})
 
-	return &incognito, nil
+	return ctx, nil
+}
+
+// CloseContextE disposes the browser context and closes all the pages of it
+func (b *Browser) CloseContextE() error {
+	if b.BrowserContextID == "" {
+		return &Error{nil, ErrDefaultBrowserContext, nil}
+	}
+
+	err := proto.TargetDisposeBrowserContext{BrowserContextID: b.BrowserContextID}.Call(b)
+	if err != nil {
+		return err
+	}
+
+	b.ctxCancel()
+
+	return nil
 }
 
 // PageE doc is similar to the method Page
@@ -138,9 +235,16 @@ func (b *Browser) PageE(url string) (*Page, error) {
 		url = "about:blank"
 	}
 
+	// the evasions and the default viewport must be applied before the page loads its document,
+	// so the page is created blank first
+	blank := (len(b.stealth) > 0 || b.defaultViewport != nil) && url != "about:blank"
+
 	req := proto.TargetCreateTarget{
 		URL: url,
 	}
+	if blank {
+		req.URL = "about:blank"
+	}
 
 	if b.BrowserContextID != "" {
 		req.BrowserContextID = b.BrowserContextID
@@ -151,30 +255,154 @@ func (b *Browser) PageE(url string) (*Page, error) {
 		return nil, err
 	}
 
-	return b.PageFromTargetIDE(target.TargetID)
+	page, err := b.PageFromTargetIDE(target.TargetID)
+	if err != nil || !blank {
+		return page, err
+	}
+
+	return page, page.NavigateE(url)
 }
 
-// PagesE doc is similar to the method Pages
+// PagesE returns the pages of the browser, the devtools pages and the background pages of the extensions are
+// excluded, use the AllPagesE to include them. The pages that are not attached by the browser yet will be attached
+// lazily on their first calls, the attached ones are the same Page instances that the browser already has.
 func (b *Browser) PagesE() (Pages, error) {
+	return b.listPages(false)
+}
+
+// CloseAllPagesE closes the pages of the browser one by one, each page is waited until its target is destroyed.
+// If the browser is a browser context, only the pages of the context will be closed.
+func (b *Browser) CloseAllPagesE() error {
 	list, err := proto.TargetGetTargets{}.Call(b)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	pageList := Pages{}
 	for _, target := range list.TargetInfos {
 		if target.Type != "page" {
 			continue
 		}
 
-		page, err := b.PageFromTargetIDE(target.TargetID)
+		if b.BrowserContextID != "" && target.BrowserContextID != b.BrowserContextID {
+			continue
+		}
+
+		wait := b.waitDestroyed(b.ctx, target.TargetID)
+
+		_, err := proto.TargetCloseTarget{TargetID: target.TargetID}.Call(b)
+		if err != nil {
+			wait.cancel()
+			return err
+		}
+
+		err = wait.wait()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		pageList = append(pageList, page)
 	}
 
-	return pageList, nil
+	return nil
+}
+
+// targetWaiter waits for the Target.targetDestroyed event of a target
+type targetWaiter struct {
+	ctx    context.Context
+	cancel func()
+	s      chan goob.Event
+	id     proto.TargetTargetID
+}
+
+// waitDestroyed subscribes to the browser events before the target gets closed
+func (b *Browser) waitDestroyed(ctx context.Context, id proto.TargetTargetID) *targetWaiter {
+	ctx, cancel := context.WithCancel(ctx)
+	return &targetWaiter{ctx, cancel, b.event.Subscribe(ctx), id}
+}
+
+func (w *targetWaiter) wait() error {
+	defer w.cancel()
+
+	destroyed := false
+	goob.Each(w.s, func(msg *cdp.Event) bool {
+		e := &proto.TargetTargetDestroyed{}
+		destroyed = Event(msg, e) && e.TargetID == w.id
+		return destroyed
+	})
+
+	if !destroyed {
+		return w.ctx.Err()
+	}
+	return nil
+}
+
+// EachPageE calls the handler with every page of the browser, both the existing ones and the ones created later,
+// such as the popups opened by window.open. Each page is attached and initialized before the handler is called,
+// the pages that are destroyed before that will be skipped. The handler may be called concurrently,
+// it will stop receiving pages when cancel is called or the context is done.
+func (b *Browser) EachPageE(handler func(*Page)) (cancel func(), err error) {
+	// subscribe before listing the targets, so that we won't miss any page
+	ctx, cancel := context.WithCancel(b.ctx)
+	s := b.event.Subscribe(ctx)
+
+	list, err := proto.TargetGetTargets{}.Call(b)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	var lock sync.Mutex
+	seen := map[proto.TargetTargetID]bool{}
+	destroyed := map[proto.TargetTargetID]bool{}
+
+	attach := func(info *proto.TargetTargetInfo) {
+		if info.Type != proto.TargetTargetInfoTypePage ||
+			(b.BrowserContextID != "" && info.BrowserContextID != b.BrowserContextID) {
+			return
+		}
+
+		lock.Lock()
+		if seen[info.TargetID] {
+			lock.Unlock()
+			return
+		}
+		seen[info.TargetID] = true
+		lock.Unlock()
+
+		page, err := b.PageFromTargetIDE(info.TargetID)
+
+		lock.Lock()
+		gone := destroyed[info.TargetID]
+		delete(destroyed, info.TargetID)
+		lock.Unlock()
+
+		// the page is the shared one of the registry, it's not canceled here
+		if err != nil || gone || ctx.Err() != nil {
+			return
+		}
+
+		handler(page)
+	}
+
+	for _, info := range list.TargetInfos {
+		go attach(info)
+	}
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		created := &proto.TargetTargetCreated{}
+		gone := &proto.TargetTargetDestroyed{}
+
+		switch {
+		case Event(msg, created):
+			go attach(created.TargetInfo)
+		case Event(msg, gone):
+			lock.Lock()
+			if seen[gone.TargetID] {
+				destroyed[gone.TargetID] = true
+			}
+			lock.Unlock()
+		}
+	})
+
+	return cancel, nil
 }
 
 // WaitEvent waits for the next event for one time. It will also load the data into the event object.
@@ -191,6 +419,16 @@ func (b *Browser) WaitEvent() (wait func(proto.Event)) {
 	}
 }
 
+// OnEvent calls the handler for each occurrence of the event type of e, the payload is decoded into e before
+// each call. Each subscriber gets all the events independently, the handler is called in order in its own goroutine.
+// The subscription stops when cancel is called or the context is done.
+func (b *Browser) OnEvent(e proto.Event, handler func()) (cancel func()) {
+	ctx, cancel := context.WithCancel(b.ctx)
+	s := b.event.Subscribe(ctx)
+	go onEvent(s, e, handler)
+	return cancel
+}
+
 // Event returns the observable for browser events
 func (b *Browser) Event() *goob.Observable {
 	return b.event
@@ -243,24 +481,49 @@ func (b *Browser) HandleAuthE(username, password string) (func() error, error) {
 	}, nil
 }
 
+// GrantPermissionsE grants the permissions to the origin for the browser context, such as "https://example.com".
+// If origin is empty, the permissions will be granted to all the origins.
+func (b *Browser) GrantPermissionsE(origin string, permissions []proto.BrowserPermissionType) error {
+	return proto.BrowserGrantPermissions{
+		Origin:           origin,
+		Permissions:      permissions,
+		BrowserContextID: b.BrowserContextID,
+	}.Call(b)
+}
+
 // CallContext parameters for proto
 func (b *Browser) CallContext() (context.Context, proto.Client, string) {
-	return b.ctx, b.client, ""
+	return b.ctx, &callClient{b.cdpClient(), "", nil, nil}, ""
 }
 
-// PageFromTargetIDE creates a Page instance from a targetID
+// PageFromTargetIDE creates a Page instance from a targetID. If the target is already attached by the browser,
+// the same Page instance will be returned.
 func (b *Browser) PageFromTargetIDE(targetID proto.TargetTargetID) (*Page, error) {
+	// the concurrent calls for the same target get the same page, and wait for the same attach of it
+	page := b.lazyPage(targetID)
+
+	err := page.ensureSession()
+	if err != nil {
+		b.pages.removePage(page)
+		return nil, err
+	}
+	return page, nil
+}
+
+// newPage creates the page without attaching it
+func (b *Browser) newPage(targetID proto.TargetTargetID) *Page {
 	page := (&Page{
 		browser:             b,
 		TargetID:            targetID,
 		getDownloadFileLock: &sync.Mutex{},
-		viewport:            &proto.EmulationSetDeviceMetricsOverride{},
 	}).Context(b.ctx)
+	page.initTargetStates()
 
 	page.Mouse = &Mouse{page: page, id: kit.RandString(8)}
 	page.Keyboard = &Keyboard{page: page}
+	page.Touch = &Touch{page: page}
 
-	return page, page.initSession()
+	return page
 }
 
 func (b *Browser) initEvents() error {
@@ -272,6 +535,9 @@ func (b *Browser) initEvents() error {
 			case <-b.ctx.Done():
 				return
 			case msg := <-b.client.Event():
+				if l := b.cdpLog.get(); l != nil {
+					l.event(msg.SessionID, msg.Method, msg.Params)
+				}
 				b.event.Publish(msg)
 			}
 		}
@@ -1,13 +1,21 @@
 package rod_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ysmood/goob"
 	"github.com/ysmood/kit"
 	"github.com/ysmood/rod"
+	"github.com/ysmood/rod/lib/cdp"
 	"github.com/ysmood/rod/lib/launcher"
 	"github.com/ysmood/rod/lib/proto"
 )
@@ -21,6 +29,172 @@ func (s *S) TestBrowserPages() {
 	s.Len(pages, 3)
 }
 
+func (s *S) TestBrowserPagesRegistry() {
+	page := s.browser.Page(srcFile("fixtures/click.html"))
+	defer page.Close()
+
+	var found *rod.Page
+	for _, p := range s.browser.Pages() {
+		if p.TargetID == page.TargetID {
+			found = p
+		}
+	}
+	s.True(found == page)
+	s.True(s.browser.PageFromTargetID(page.TargetID) == page)
+	s.True(s.browser.FindPage(`click\.html$`) == page)
+
+	_, err := s.browser.FindPageE(`not-exists`)
+	s.True(errors.Is(err, rod.ErrPageNotFound))
+
+	s.GreaterOrEqual(len(s.browser.AllPages()), len(s.browser.Pages()))
+}
+
+func (s *S) TestBrowserPagesLazy() {
+	res, err := proto.TargetCreateTarget{URL: srcFile("fixtures/click.html")}.Call(s.browser)
+	kit.E(err)
+	defer func() { kit.E(proto.TargetCloseTarget{TargetID: res.TargetID}.Call(s.browser)) }()
+
+	// the page is listed without being attached
+	p := s.browser.FindPage(`click\.html$`)
+	s.Equal(res.TargetID, p.TargetID)
+	s.Empty(p.SessionID)
+
+	// the clone made before the attach shares the session
+	clone := p.Timeout(time.Minute)
+	s.Equal("Title", clone.Element("h4").Text())
+	s.NotEmpty(clone.SessionID)
+	s.Equal("Title", p.Element("h4").Text())
+	s.Equal(clone.SessionID, p.SessionID)
+}
+
+func (s *S) TestBrowserPagesConcurrentAttach() {
+	res, err := proto.TargetCreateTarget{URL: srcFile("fixtures/click.html")}.Call(s.browser)
+	kit.E(err)
+	defer func() { kit.E(proto.TargetCloseTarget{TargetID: res.TargetID}.Call(s.browser)) }()
+
+	// the concurrent calls shouldn't attach two sessions to the same target
+	list := make(chan *rod.Page, 5)
+	for i := 0; i < cap(list); i++ {
+		go func() { list <- s.browser.PageFromTargetID(res.TargetID) }()
+	}
+
+	p := <-list
+	for i := 1; i < cap(list); i++ {
+		s.True(<-list == p)
+	}
+	s.NotEmpty(p.SessionID)
+	s.Equal("Title", p.Element("h4").Text())
+}
+
+func (s *S) TestBrowserCDPLog() {
+	p := s.browser.Page("")
+	defer p.Close()
+
+	buf := bytes.NewBuffer(nil)
+	s.browser.CDPLog(buf, &rod.CDPLogOptions{
+		Methods:  []string{"Network.setExtraHTTPHeaders", "Runtime.callFunctionOn"},
+		Sessions: []proto.TargetSessionID{p.SessionID},
+	})
+	p.SetExtraHeaders("Authorization", "secret")
+	p.Eval(`() => 1`)
+	s.browser.CDPLog(nil, nil)
+
+	records := []*rod.CDPLogRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		r := &rod.CDPLogRecord{}
+		kit.E(json.Unmarshal([]byte(line), r))
+		s.Equal(string(p.SessionID), r.SessionID)
+		records = append(records, r)
+	}
+
+	s.Len(records, 4)
+	s.Equal("call", records[0].Type)
+	s.Equal("response", records[1].Type)
+	s.Equal(records[0].ID, records[1].ID)
+	s.Equal("Runtime.callFunctionOn", records[2].Method)
+	s.NotContains(buf.String(), "secret")
+	s.Contains(string(records[0].Params), "[redacted]")
+}
+
+func (s *S) TestBrowserDefaultViewport() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<html><body style="height: 3000px">
+		<script>window.initialWidth = window.innerWidth</script>
+	</body></html>`))
+
+	s.browser.DefaultViewport(&proto.EmulationSetDeviceMetricsOverride{Width: 375, Height: 500, DeviceScaleFactor: 1})
+	defer s.browser.NoDefaultViewport()
+
+	p := s.browser.Page(url)
+	defer p.Close()
+
+	// the first load sees the default viewport
+	s.EqualValues(375, p.Eval(`() => window.initialWidth`).Int())
+
+	// the viewport of the page wins, and the fullpage screenshot restores it
+	p.Viewport(320, 400, 1, false)
+	p.ScreenshotFullPage()
+	s.EqualValues(320, p.Eval(`() => window.innerWidth`).Int())
+	s.EqualValues(400, p.Eval(`() => window.innerHeight`).Int())
+
+	s.browser.NoDefaultViewport()
+	blank := s.browser.Page("")
+	defer blank.Close()
+	s.NotEqual(int64(375), blank.Eval(`() => window.innerWidth`).Int())
+}
+
+type fakeT struct {
+	msg string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Fatal(args ...interface{}) {
+	t.msg = fmt.Sprint(args...)
+}
+
+func (s *S) TestRecover() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t := &fakeT{}
+	func() {
+		defer rod.Recover(t)
+		s.page.Context(ctx).ReloadAndWaitLoad()
+	}()
+	s.Contains(t.msg, "Page.ReloadAndWaitLoadE(): ")
+	s.Contains(t.msg, "browser_test.go")
+	s.NotContains(t.msg, "sugar_generated.go")
+
+	var err error
+	func() {
+		defer func() { err, _ = recover().(error) }()
+		s.page.Context(ctx).ReloadAndWaitLoad()
+	}()
+	s.True(errors.Is(err, rod.ErrCallFailed))
+	s.True(errors.Is(err, context.Canceled))
+
+	// the codes of the wrapped errors still match
+	inner := &rod.Error{Code: rod.ErrElementNotFound}
+	s.True(rod.IsError(&rod.Error{Err: inner, Code: rod.ErrCallFailed}, rod.ErrElementNotFound))
+
+	// the credentials are redacted, the long values are truncated
+	d := &rod.CallDetails{Method: "Page.SetExtraHeadersE", Args: rod.Array{[]string{"Authorization", "token", "X-A", "b"}}}
+	s.NotContains(d.String(), "token")
+	s.Contains(d.String(), `"X-A", "b"`)
+	d = &rod.CallDetails{Method: "Page.HandleAuthE", Args: rod.Array{"user", "secret"}}
+	s.NotContains(d.String(), "secret")
+	d = &rod.CallDetails{Method: "Page.EvalE", Args: rod.Array{strings.Repeat("a", 1000)}}
+	s.Less(len(d.String()), 500)
+
+	s.Panics(func() {
+		defer rod.Recover(t)
+		panic("not an error")
+	})
+}
+
 func (s *S) TestBrowserContext() {
 	s.browser.Timeout(time.Minute).CancelTimeout()
 }
@@ -37,6 +211,56 @@ func (s *S) TestIncognito() {
 	s.EqualValues(1, page.Eval(`k => localStorage[k]`, k).Int())
 }
 
+func (s *S) TestBrowserNewContext() {
+	file := srcFile("fixtures/click.html")
+	k := kit.RandString(8)
+
+	a := s.browser.NewContext()
+	b := s.browser.NewContext()
+
+	pa := a.Page(file)
+	pa.Eval(`k => localStorage[k] = 1`, k)
+	pb := b.Page(file)
+	s.Nil(pb.Eval(`k => localStorage[k]`, k).Value())
+
+	s.Len(a.Pages(), 1)
+	s.Equal(pa.TargetID, a.Pages()[0].TargetID)
+
+	// the target events of the other context should be filtered out
+	wait := a.WaitEvent()
+	b.Page("")
+	pa2 := a.Page("")
+	e := &proto.TargetTargetCreated{}
+	wait(e)
+	s.Equal(pa2.TargetID, e.TargetInfo.TargetID)
+
+	a.CloseContext()
+	s.Len(b.Pages(), 2)
+	b.CloseContext()
+
+	s.True(rod.IsError(s.browser.CloseContextE(), rod.ErrDefaultBrowserContext))
+}
+
+func (s *S) TestBrowserEachPage() {
+	b := s.browser.Incognito().Timeout(10 * time.Second)
+	defer b.CancelTimeout()
+
+	page := b.Page(srcFile("fixtures/open-page.html"))
+	defer page.Close()
+
+	pages := make(chan *rod.Page, 10)
+	cancel := b.EachPage(func(p *rod.Page) { pages <- p })
+	defer cancel()
+
+	s.Equal(page.TargetID, (<-pages).TargetID)
+
+	page.Element("a").Click()
+
+	popup := <-pages
+	defer popup.Close()
+	s.Equal("click me", popup.Element("button").Text())
+}
+
 func (s *S) TestBrowserWaitEvent() {
 	wait := s.browser.WaitEvent()
 	s.page.Navigate(srcFile("fixtures/click.html"))
@@ -83,6 +307,33 @@ func (s *S) TestMonitor() {
 	s.Greater(len(kit.Req("http://"+host+"/screenshot/"+string(p.TargetID)).MustBytes()), 1000)
 }
 
+func (s *S) TestMonitorJSON() {
+	b := rod.New().Connect()
+	defer b.Close()
+	p := b.Page(srcFile("fixtures/click.html")).WaitLoad()
+	url, err := b.ServeMonitorE("127.0.0.1:0")
+	kit.E(err)
+
+	pages := []*proto.TargetTargetInfo{}
+	kit.E(json.Unmarshal(kit.Req(url+"/pages").MustBytes(), &pages))
+	found := false
+	for _, info := range pages {
+		found = found || info.TargetID == p.TargetID
+	}
+	s.True(found)
+
+	events := url + "/page/" + string(p.TargetID) + "/events"
+	s.Equal("[]", kit.Req(events).MustString())
+
+	p.Eval(`() => console.log("hello")`)
+	s.Greater(len(kit.Req(url+"/page/"+string(p.TargetID)+"/screenshot").MustBytes()), 1000)
+
+	list := []*rod.MonitorEvent{}
+	kit.E(json.Unmarshal(kit.Req(events).MustBytes(), &list))
+	s.Equal(rod.MonitorEventConsole, list[len(list)-1].Type)
+	s.Equal("hello", list[len(list)-1].Msg)
+}
+
 func (s *S) TestRemoteLaunch() {
 	srv := kit.MustServer("127.0.0.1:0")
 	defer func() { _ = srv.Listener.Close() }()
@@ -210,8 +461,11 @@ func (s *S) TestResolveBlocking() {
 // It's obvious that, the v8 will take more time to parse long function.
 // For BenchmarkCache and BenchmarkNoCache, the difference is nearly 12% which is too much to ignore.
 func BenchmarkCacheOff(b *testing.B) {
-	p := rod.New().Connect().Page(srcFile("fixtures/click.html"))
+	browser := rod.New().Connect()
+	defer browser.Close()
+	p := browser.Page(srcFile("fixtures/click.html"))
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
@@ -248,8 +502,11 @@ func BenchmarkCacheOff(b *testing.B) {
 }
 
 func BenchmarkCache(b *testing.B) {
-	p := rod.New().Connect().Page(srcFile("fixtures/click.html"))
+	browser := rod.New().Connect()
+	defer browser.Close()
+	p := browser.Page(srcFile("fixtures/click.html"))
 
+	b.ReportAllocs()
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
@@ -258,3 +515,244 @@ func BenchmarkCache(b *testing.B) {
 		}`, time.Now().UnixNano())
 	}
 }
+
+func (s *S) TestBrowserVersion() {
+	v := s.browser.Version()
+
+	s.Regexp(`Chrome/\d+\.`, v.Product)
+	s.Contains(v.UserAgent, "AppleWebKit/")
+	s.NotEmpty(v.JsVersion)
+	s.NotEmpty(v.WebKitVersion())
+	s.Greater(v.Major(), 0)
+
+	// the result is cached
+	s.Same(v, s.browser.Version())
+
+	s.Equal(v.Major() >= 81, s.browser.Supports(rod.CapScrollIntoViewIfNeeded))
+	s.False(s.browser.Supports(rod.Capability("unknown")))
+
+	list, err := s.browser.CapabilitiesE()
+	s.Nil(err)
+	s.Len(list, 2)
+}
+
+func (s *S) TestBrowserRecordEvents() {
+	buf := &bytes.Buffer{}
+	stop := s.browser.RecordEvents(buf, 50)
+
+	p := s.browser.Page("")
+	p.Navigate("data:text/html," + strings.Repeat("a", 100)).WaitLoad()
+	p.Close()
+	stop()
+
+	ob := goob.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := ob.Subscribe(ctx)
+
+	go func() {
+		kit.E(rod.ReplayEvents(buf, ob))
+		ob.Publish(&cdp.Event{Method: "done"})
+	}()
+
+	created := false
+	truncated := false
+	for msg := range events {
+		e := msg.(*cdp.Event)
+		if e.Method == "done" {
+			break
+		}
+
+		info := &proto.TargetTargetCreated{}
+		if rod.Event(e, info) && info.TargetInfo.TargetID == p.TargetID {
+			created = true
+		}
+
+		changed := &proto.TargetTargetInfoChanged{}
+		if rod.Event(e, changed) && strings.HasPrefix(changed.TargetInfo.URL, "data:") {
+			s.Equal("data:text/html,"+strings.Repeat("a", 35)+"...(truncated)", changed.TargetInfo.URL)
+			truncated = true
+		}
+	}
+	s.True(created)
+	s.True(truncated)
+}
+
+func (s *S) TestReplayEventsWaitRequestIdle() {
+	url, engine, close := serve()
+	defer close()
+	engine.GET("/", ginHTML(`<html>ok</html>`))
+
+	buf := &bytes.Buffer{}
+	stop := s.browser.RecordEvents(buf, 0)
+	p := s.browser.Page(url).WaitLoad()
+	stop()
+	p.Close()
+
+	// split the requests from the rest of the recorded events
+	sent, rest := &bytes.Buffer{}, &bytes.Buffer{}
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		var record rod.EventRecord
+		if json.Unmarshal([]byte(line), &record) == nil && record.Method == "Network.requestWillBeSent" {
+			sent.WriteString(line)
+		} else {
+			rest.WriteString(line)
+		}
+	}
+	s.NotZero(sent.Len())
+
+	// the wait function of a page that has no traffic of its own is driven by the replayed events
+	replay := s.browser.Page("")
+	defer replay.Close()
+	wait := replay.WaitRequestIdleE(300*time.Millisecond, nil, nil)
+	done := make(chan error, 1)
+	go func() { done <- wait() }()
+
+	kit.E(rod.ReplayEvents(sent, replay.Event()))
+	select {
+	case <-done:
+		s.FailNow("the replayed requests should be pending")
+	case <-time.After(time.Second):
+	}
+
+	kit.E(rod.ReplayEvents(rest, replay.Event()))
+	s.Nil(<-done)
+}
+
+func (s *S) TestBrowserCloseAllPages() {
+	b := s.browser.NewContext()
+	defer b.CloseContext()
+
+	b.Page(srcFile("fixtures/click.html"))
+	b.Page("")
+	s.Len(b.Pages(), 2)
+
+	b.CloseAllPages()
+	s.Len(b.Pages(), 0)
+}
+
+func (s *S) TestPageWaitClose() {
+	p := s.browser.Page("")
+
+	wait := make(chan kit.Nil)
+	go func() {
+		p.WaitClose()
+		close(wait)
+	}()
+
+	p.Eval(`() => setTimeout(() => window.close(), 100)`)
+	<-wait
+
+	// the target is already gone
+	p.WaitClose()
+}
+
+func (s *S) TestPageCloseLeak() {
+	p := s.browser.Page("")
+	p.Close()
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		p := s.browser.Page("")
+		p.Close()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	s.LessOrEqual(runtime.NumGoroutine(), before+5)
+}
+
+func (s *S) TestPagePool() {
+	b := s.browser.Incognito()
+	defer b.CloseContext()
+
+	url, engine, close := serve()
+	defer close()
+	engine.GET("/", ginHTML(`<html><body>ok</body></html>`))
+
+	pool := b.PagePool(2)
+	defer pool.Close()
+
+	p := pool.Get(context.Background())
+	id := p.TargetID
+	p.Navigate(url).SetExtraHeaders("a", "b").Viewport(100, 100, 1, false)
+	p.SetCookies(&proto.NetworkCookieParam{Name: "k", Value: "v", URL: url})
+	p.SetUserAgent(nil)
+	pool.Put(p)
+
+	p = pool.Get(context.Background())
+	s.Equal(id, p.TargetID)
+	s.Equal("about:blank", p.Eval(`() => location.href`).String())
+	s.NotEqual("MacIntel", p.Eval(`() => navigator.platform`).String())
+	s.Len(p.Navigate(url).Cookies(), 0)
+
+	other := pool.Get(context.Background())
+	s.NotEqual(id, other.TargetID)
+
+	// the pool is full
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := pool.GetE(ctx)
+	s.Equal(context.DeadlineExceeded, err)
+
+	// the crashed page will be replaced
+	wait := p.WaitEvent()
+	_ = proto.PageCrash{}.Call(p)
+	wait(&proto.InspectorTargetCrashed{})
+	pool.Put(p)
+	pool.Put(other)
+
+	otherID := other.TargetID
+	p = pool.Get(context.Background())
+	other = pool.Get(context.Background())
+	ids := map[proto.TargetTargetID]bool{p.TargetID: true, other.TargetID: true}
+	s.False(ids[id])
+	s.True(ids[otherID])
+
+	// the page put back after the close will be discarded
+	pool.Close()
+	pool.Put(p)
+	for _, page := range b.Pages() {
+		s.NotEqual(p.TargetID, page.TargetID)
+	}
+
+	_, err = pool.GetE(context.Background())
+	s.True(rod.IsError(err, rod.ErrPoolClosed))
+	pool.Put(other)
+}
+
+func (s *S) TestBrowserStealth() {
+	b := s.browser.Context(context.Background()).Stealth(true)
+
+	url, engine, close := serve()
+	defer close()
+	engine.GET("/", func(ctx kit.GinContext) {
+		ctx.File(file("fixtures/fingerprint.html"))
+	})
+
+	p := b.Page(url).WaitLoad()
+	defer p.Close()
+
+	p.Wait(`() => window.fingerprint.permission !== undefined`)
+	fp := p.Eval(`() => window.fingerprint`)
+
+	s.False(fp.Get("webdriver").Bool())
+	s.Greater(fp.Get("plugins").Int(), int64(0))
+	s.Greater(fp.Get("mimeTypes").Int(), int64(0))
+	s.Equal("en-US,en", fp.Get("languages").String())
+	s.NotContains(fp.Get("userAgent").String(), "Headless")
+	s.Equal("Linux x86_64", fp.Get("platform").String())
+	s.True(fp.Get("runtime").Bool())
+	s.Equal("Intel Inc.", fp.Get("vendor").String())
+	s.True(fp.Get("permission").Bool())
+	s.Equal(false, p.Eval(`() => window.iframeWebdriver`).Value())
+
+	p.SetUserAgent(&proto.NetworkSetUserAgentOverride{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"})
+	s.Equal("Win32", p.Eval(`() => navigator.platform`).String())
+
+	// disable a broken evasion
+	b.StealthWith(rod.StealthWebdriver)
+	p2 := b.Page(url).WaitLoad()
+	defer p2.Close()
+	s.False(p2.Eval(`() => window.fingerprint.webdriver`).Bool())
+	s.Contains(p2.Eval(`() => window.fingerprint.userAgent`).String(), "Headless")
+}
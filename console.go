@@ -0,0 +1,146 @@
+// This file contains the console related code of the page.
+
+package rod
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// EachConsoleE calls the handler for every console api call of the page, such as console.log, in order.
+// The arguments that can be serialized will be resolved by value, the others keep their description and preview.
+// If exceptions is true, the uncaught exceptions of the page will be passed to the handler as the
+// "error" type console calls. The handler will stop receiving events when stop is called or the page context is done.
+func (p *Page) EachConsoleE(handler func(*proto.RuntimeConsoleAPICalled), exceptions bool) (stop func(), err error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err = proto.RuntimeEnable{}.Call(p)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		e := p.consoleEvent(msg, exceptions)
+		if e != nil {
+			handler(e)
+		}
+	})
+
+	return cancel, nil
+}
+
+// WaitConsoleE returns a wait function that waits until a console message contains the substr,
+// the wait function returns the text of the message.
+func (p *Page) WaitConsoleE(substr string) func() (string, error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err := proto.RuntimeEnable{}.Call(p)
+
+	return func() (string, error) {
+		defer cancel()
+
+		if err != nil {
+			return "", err
+		}
+
+		text := ""
+		goob.Each(s, func(msg *cdp.Event) bool {
+			e := p.consoleEvent(msg, false)
+			if e == nil {
+				return false
+			}
+
+			t := ConsoleText(e)
+			if strings.Contains(t, substr) {
+				text = t
+				return true
+			}
+			return false
+		})
+
+		if text == "" {
+			return "", p.ctx.Err()
+		}
+		return text, nil
+	}
+}
+
+// ConsoleText formats the arguments of the console call into a string, separated by space,
+// just like what the devtools prints
+func ConsoleText(e *proto.RuntimeConsoleAPICalled) string {
+	list := []string{}
+	for _, arg := range e.Args {
+		switch {
+		case arg.Type == proto.RuntimeRemoteObjectTypeString:
+			list = append(list, arg.Value.String())
+		case arg.Value.Raw != "":
+			list = append(list, arg.Value.Raw)
+		case arg.UnserializableValue != "":
+			list = append(list, string(arg.UnserializableValue))
+		default:
+			list = append(list, arg.Description)
+		}
+	}
+	return strings.Join(list, " ")
+}
+
+// consoleEvent converts the msg to a console call, returns nil if the msg is not a console call
+func (p *Page) consoleEvent(msg *cdp.Event, exceptions bool) *proto.RuntimeConsoleAPICalled {
+	called := &proto.RuntimeConsoleAPICalled{}
+	thrown := &proto.RuntimeExceptionThrown{}
+
+	if Event(msg, called) {
+		for _, arg := range called.Args {
+			p.resolveConsoleArg(arg)
+		}
+		return called
+	}
+
+	if exceptions && Event(msg, thrown) {
+		details := thrown.ExceptionDetails
+		arg := details.Exception
+		if arg == nil {
+			arg = &proto.RuntimeRemoteObject{
+				Type:  proto.RuntimeRemoteObjectTypeString,
+				Value: proto.NewJSON(details.Text),
+			}
+		}
+
+		return &proto.RuntimeConsoleAPICalled{
+			Type:               proto.RuntimeConsoleAPICalledTypeError,
+			Args:               []*proto.RuntimeRemoteObject{arg},
+			ExecutionContextID: details.ExecutionContextID,
+			Timestamp:          thrown.Timestamp,
+			StackTrace:         details.StackTrace,
+		}
+	}
+
+	return nil
+}
+
+// resolveConsoleArg fills the value of the object if it can be serialized
+func (p *Page) resolveConsoleArg(arg *proto.RuntimeRemoteObject) {
+	// such as the nodes and the errors will become empty objects if they are returned by value
+	if arg.ObjectID == "" || arg.Type != proto.RuntimeRemoteObjectTypeObject ||
+		(arg.Subtype != "" && arg.Subtype != proto.RuntimeRemoteObjectSubtypeArray) {
+		return
+	}
+
+	res, err := proto.RuntimeCallFunctionOn{
+		ObjectID:            arg.ObjectID,
+		FunctionDeclaration: `function() { return this }`,
+		ReturnByValue:       true,
+	}.Call(p)
+	if err != nil || res.ExceptionDetails != nil {
+		return
+	}
+
+	arg.Value = res.Result.Value
+}
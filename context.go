@@ -10,9 +10,13 @@ func (b *Browser) Context(ctx context.Context) *Browser {
 	ctx, cancel := context.WithCancel(ctx)
 
 	if b.ctx != nil {
+		parent := b.ctx
 		go func() {
-			<-b.ctx.Done()
-			cancel()
+			select {
+			case <-parent.Done():
+				cancel()
+			case <-ctx.Done():
+			}
 		}()
 	}
 
@@ -27,17 +31,24 @@ func (b *Browser) GetContext() context.Context {
 	return b.ctx
 }
 
-// Timeout for chained sub-operations
+// Timeout returns a clone whose context will be done after the duration d, it won't affect the original one.
+// Each clone owns its own timer, use CancelTimeout to release it.
 func (b *Browser) Timeout(d time.Duration) *Browser {
 	ctx, cancel := context.WithTimeout(b.ctx, d)
-	b.timeoutCancel = cancel
-	return b.Context(ctx)
+	newObj := b.Context(ctx)
+	newObj.timeoutCancel = cancel
+	newObj.timeoutParent = b
+	return newObj
 }
 
-// CancelTimeout context
+// CancelTimeout releases the timer of the clone created by Timeout, and returns the object that the Timeout is called on.
+// If the object isn't created by Timeout, it returns itself.
 func (b *Browser) CancelTimeout() *Browser {
+	if b.timeoutCancel == nil {
+		return b
+	}
 	b.timeoutCancel()
-	return b
+	return b.timeoutParent
 }
 
 // Context creates a clone with a context that inherits the previous one
@@ -45,9 +56,13 @@ func (p *Page) Context(ctx context.Context) *Page {
 	ctx, cancel := context.WithCancel(ctx)
 
 	if p.ctx != nil {
+		parent := p.ctx
 		go func() {
-			<-p.ctx.Done()
-			cancel()
+			select {
+			case <-parent.Done():
+				cancel()
+			case <-ctx.Done():
+			}
 		}()
 	}
 
@@ -62,17 +77,24 @@ func (p *Page) GetContext() context.Context {
 	return p.ctx
 }
 
-// Timeout for chained sub-operations
+// Timeout returns a clone whose context will be done after the duration d, it won't affect the original one.
+// Each clone owns its own timer, use CancelTimeout to release it.
 func (p *Page) Timeout(d time.Duration) *Page {
 	ctx, cancel := context.WithTimeout(p.ctx, d)
-	p.timeoutCancel = cancel
-	return p.Context(ctx)
+	newObj := p.Context(ctx)
+	newObj.timeoutCancel = cancel
+	newObj.timeoutParent = p
+	return newObj
 }
 
-// CancelTimeout context
+// CancelTimeout releases the timer of the clone created by Timeout, and returns the object that the Timeout is called on.
+// If the object isn't created by Timeout, it returns itself.
 func (p *Page) CancelTimeout() *Page {
+	if p.timeoutCancel == nil {
+		return p
+	}
 	p.timeoutCancel()
-	return p
+	return p.timeoutParent
 }
 
 // Context creates a clone with a context that inherits the previous one
@@ -80,9 +102,13 @@ func (el *Element) Context(ctx context.Context) *Element {
 	ctx, cancel := context.WithCancel(ctx)
 
 	if el.ctx != nil {
+		parent := el.ctx
 		go func() {
-			<-el.ctx.Done()
-			cancel()
+			select {
+			case <-parent.Done():
+				cancel()
+			case <-ctx.Done():
+			}
 		}()
 	}
 
@@ -97,15 +123,22 @@ func (el *Element) GetContext() context.Context {
 	return el.ctx
 }
 
-// Timeout for chained sub-operations
+// Timeout returns a clone whose context will be done after the duration d, it won't affect the original one.
+// Each clone owns its own timer, use CancelTimeout to release it.
 func (el *Element) Timeout(d time.Duration) *Element {
 	ctx, cancel := context.WithTimeout(el.ctx, d)
-	el.timeoutCancel = cancel
-	return el.Context(ctx)
+	newObj := el.Context(ctx)
+	newObj.timeoutCancel = cancel
+	newObj.timeoutParent = el
+	return newObj
 }
 
-// CancelTimeout context
+// CancelTimeout releases the timer of the clone created by Timeout, and returns the object that the Timeout is called on.
+// If the object isn't created by Timeout, it returns itself.
 func (el *Element) CancelTimeout() *Element {
+	if el.timeoutCancel == nil {
+		return el
+	}
 	el.timeoutCancel()
-	return el
+	return el.timeoutParent
 }
@@ -0,0 +1,327 @@
+// This file contains the code coverage related code of the page.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the sourceURL of the rod helper, its coverage is always skipped
+const helperScriptURL = "__rod_helper__"
+
+// coverageState is shared by all the clones of a page
+type coverageState struct {
+	lock sync.Mutex
+
+	// not nil when the coverage is started
+	js  *coverageSources
+	css *coverageSources
+}
+
+// coverageSources collects the sources of the scripts or style sheets, the sources are fetched when they are parsed,
+// because they may be gone after a navigation
+type coverageSources struct {
+	lock              sync.Mutex
+	cancel            func()
+	resetOnNavigation bool
+	urls              map[string]string
+	list              map[string]string
+}
+
+func newCoverageSources(cancel func(), resetOnNavigation bool) *coverageSources {
+	return &coverageSources{
+		cancel:            cancel,
+		resetOnNavigation: resetOnNavigation,
+		urls:              map[string]string{},
+		list:              map[string]string{},
+	}
+}
+
+func (c *coverageSources) add(id, url, source string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.urls[id] = url
+	c.list[id] = source
+}
+
+func (c *coverageSources) get(id string) (url, source string, has bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	url, has = c.urls[id]
+	return url, c.list[id], has
+}
+
+func (c *coverageSources) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.urls = map[string]string{}
+	c.list = map[string]string{}
+}
+
+// JSCoverageOptions of the StartJSCoverageE
+type JSCoverageOptions struct {
+	// CallCount collects the call counts beyond simple covered or not covered
+	CallCount bool
+
+	// Detailed collects the block-based coverage, not only the function-based one
+	Detailed bool
+
+	// ResetOnNavigation clears the coverage of the previous documents on each navigation,
+	// by default the coverage accumulates across the navigations
+	ResetOnNavigation bool
+}
+
+// JSCoverage is the coverage of a script with its source, the ranges of the Functions are the offsets of the Source
+type JSCoverage struct {
+	*proto.ProfilerScriptCoverage
+
+	Source string
+}
+
+// CSSCoverage is the coverage of a style sheet with its source
+type CSSCoverage struct {
+	URL    string
+	Source string
+
+	// Rules of the style sheet, the offsets are the offsets of the Source
+	Rules []*proto.CSSRuleUsage
+}
+
+// StartJSCoverageE starts to collect the coverage of the scripts, if opts is nil the default options will be used.
+// The anonymous scripts, such as the ones evaluated by EvalE, are not collected.
+func (p *Page) StartJSCoverageE(opts *JSCoverageOptions) error {
+	if opts == nil {
+		opts = &JSCoverageOptions{}
+	}
+
+	c := p.coverage
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.js != nil {
+		return &Error{nil, ErrCoverageStarted, "js"}
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+	sources := newCoverageSources(cancel, opts.ResetOnNavigation)
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		parsed := &proto.DebuggerScriptParsed{}
+		switch {
+		case Event(msg, parsed):
+			if parsed.URL == "" || parsed.URL == helperScriptURL {
+				return
+			}
+			res, err := proto.DebuggerGetScriptSource{ScriptID: parsed.ScriptID}.Call(p)
+			if err == nil {
+				sources.add(string(parsed.ScriptID), parsed.URL, res.ScriptSource)
+			}
+		case opts.ResetOnNavigation && Event(msg, &proto.RuntimeExecutionContextsCleared{}):
+			sources.reset()
+		}
+	})
+
+	err := p.startJSCoverage(opts)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.js = sources
+	return nil
+}
+
+func (p *Page) startJSCoverage(opts *JSCoverageOptions) error {
+	err := proto.RuntimeEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	// the Debugger.scriptParsed events will be emitted for the existing scripts
+	_, err = proto.DebuggerEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	// the debugger is only enabled for the sources, a "debugger" statement shouldn't freeze the page
+	err = proto.DebuggerSetSkipAllPauses{Skip: true}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	err = proto.ProfilerEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = proto.ProfilerStartPreciseCoverage{CallCount: opts.CallCount, Detailed: opts.Detailed}.Call(p)
+	return err
+}
+
+// StopJSCoverageE stops the js coverage and returns the coverage of the scripts
+func (p *Page) StopJSCoverageE() (list []*JSCoverage, err error) {
+	c := p.coverage
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sources := c.js
+	if sources == nil {
+		return nil, &Error{nil, ErrCoverageNotStarted, "js"}
+	}
+
+	defer func() {
+		sources.cancel()
+		c.js = nil
+
+		// the sources are fetched via the debugger, disable it after them
+		e := proto.DebuggerDisable{}.Call(p)
+		if err == nil && e != nil {
+			list, err = nil, e
+		}
+	}()
+
+	res, err := proto.ProfilerTakePreciseCoverage{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	err = proto.ProfilerStopPreciseCoverage{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	err = proto.ProfilerDisable{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	list = []*JSCoverage{}
+	for _, script := range res.Result {
+		_, source, has := sources.get(string(script.ScriptID))
+
+		// the script may be parsed right before the stop, its event is not handled yet
+		if !has && !sources.resetOnNavigation && script.URL != "" && script.URL != helperScriptURL {
+			src, err := proto.DebuggerGetScriptSource{ScriptID: script.ScriptID}.Call(p)
+			if err != nil {
+				return nil, err
+			}
+			source, has = src.ScriptSource, true
+		}
+
+		if !has {
+			continue
+		}
+		list = append(list, &JSCoverage{ProfilerScriptCoverage: script, Source: source})
+	}
+	return list, nil
+}
+
+// StartCSSCoverageE starts to collect the usage of the css rules. If resetOnNavigation is true the coverage of the
+// previous documents will be cleared on each navigation, otherwise it accumulates across the navigations.
+func (p *Page) StartCSSCoverageE(resetOnNavigation bool) error {
+	c := p.coverage
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.css != nil {
+		return &Error{nil, ErrCoverageStarted, "css"}
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+	sources := newCoverageSources(cancel, resetOnNavigation)
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		added := &proto.CSSStyleSheetAdded{}
+		switch {
+		case Event(msg, added):
+			// skip the inline style sheets
+			if added.Header.SourceURL == "" {
+				return
+			}
+			res, err := proto.CSSGetStyleSheetText{StyleSheetID: added.Header.StyleSheetID}.Call(p)
+			if err == nil {
+				sources.add(string(added.Header.StyleSheetID), added.Header.SourceURL, res.Text)
+			}
+		case resetOnNavigation && Event(msg, &proto.RuntimeExecutionContextsCleared{}):
+			sources.reset()
+		}
+	})
+
+	err := p.startCSSCoverage()
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.css = sources
+	return nil
+}
+
+func (p *Page) startCSSCoverage() error {
+	// the Runtime events are used to know the navigations
+	err := proto.RuntimeEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	err = proto.DOMEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	// the CSS.styleSheetAdded events will be emitted for the existing style sheets
+	err = proto.CSSEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	return proto.CSSStartRuleUsageTracking{}.Call(p)
+}
+
+// StopCSSCoverageE stops the css coverage and returns the coverage of the style sheets
+func (p *Page) StopCSSCoverageE() ([]*CSSCoverage, error) {
+	c := p.coverage
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sources := c.css
+	if sources == nil {
+		return nil, &Error{nil, ErrCoverageNotStarted, "css"}
+	}
+
+	defer func() {
+		sources.cancel()
+		c.css = nil
+	}()
+
+	res, err := proto.CSSStopRuleUsageTracking{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := map[string]*CSSCoverage{}
+	list := []*CSSCoverage{}
+	for _, rule := range res.RuleUsage {
+		id := string(rule.StyleSheetID)
+		url, source, has := sources.get(id)
+		if !has {
+			continue
+		}
+
+		cov, has := dict[id]
+		if !has {
+			cov = &CSSCoverage{URL: url, Source: source}
+			dict[id] = cov
+			list = append(list, cov)
+		}
+		cov.Rules = append(cov.Rules, rule)
+	}
+	return list, nil
+}
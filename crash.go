@@ -0,0 +1,100 @@
+// This file contains the tracking of the uncaught exceptions and the crash of the page session.
+// After the renderer crashes the browser won't respond to the calls of the session, so every call
+// and wait of the session must abort once the crash is reported, or they will hang until the deadline.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the max number of the recent exceptions to keep for each page session, the older ones will be dropped
+var pageExceptions = 50
+
+// crashState is shared by all the clones of a page session
+type crashState struct {
+	lock       sync.Mutex
+	exceptions []*proto.RuntimeExceptionDetails
+
+	crashed chan kit.Nil // closed when the renderer crashes
+	once    sync.Once
+}
+
+func newCrashState() *crashState {
+	return &crashState{crashed: make(chan kit.Nil)}
+}
+
+// watch records the exceptions and the crash until the events are closed
+func (s *crashState) watch(events chan goob.Event) {
+	goob.Each(events, func(msg *cdp.Event) {
+		thrown := &proto.RuntimeExceptionThrown{}
+
+		switch {
+		case Event(msg, thrown):
+			s.lock.Lock()
+			list := append(s.exceptions, thrown.ExceptionDetails)
+			if len(list) > pageExceptions {
+				list = list[len(list)-pageExceptions:]
+			}
+			s.exceptions = list
+			s.lock.Unlock()
+		case Event(msg, &proto.InspectorTargetCrashed{}):
+			s.once.Do(func() { close(s.crashed) })
+		}
+	})
+}
+
+// err returns an ErrPageCrashed error if the renderer has crashed, the details are the texts of the recent exceptions
+func (s *crashState) err() error {
+	if s == nil {
+		return nil
+	}
+
+	select {
+	case <-s.crashed:
+		texts := []string{}
+		for _, e := range s.list() {
+			texts = append(texts, exceptionText(e))
+		}
+		return &Error{nil, ErrPageCrashed, texts}
+	default:
+		return nil
+	}
+}
+
+func (s *crashState) list() []*proto.RuntimeExceptionDetails {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*proto.RuntimeExceptionDetails{}, s.exceptions...)
+}
+
+// context returns a child of ctx that will be canceled when the renderer crashes
+func (s *crashState) context(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if s == nil {
+		return ctx, cancel
+	}
+
+	go func() {
+		select {
+		case <-s.crashed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// ExceptionsE returns the recent uncaught exceptions of the page session, the oldest first.
+// If the renderer has crashed, an ErrPageCrashed error will be returned with the exceptions.
+// When the stealth is on, the exceptions are only recorded after a method enables the runtime, such as the
+// EachConsoleE, because the page can detect the enabled runtime.
+func (p *Page) ExceptionsE() ([]*proto.RuntimeExceptionDetails, error) {
+	return p.crash.list(), p.crash.err()
+}
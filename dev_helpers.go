@@ -7,12 +7,12 @@ package rod
 import (
 	"fmt"
 	"html"
-	"net/http"
+	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ysmood/kit"
-	"github.com/ysmood/rod/lib/assets"
 	"github.com/ysmood/rod/lib/proto"
 )
 
@@ -26,6 +26,8 @@ func (b *Browser) trySlowmotion() {
 }
 
 func (el *Element) tryTrace(htmlMessage string) func() {
+	el.page.browser.writeTrace(el.page.TargetID, htmlMessage)
+
 	if !el.page.browser.trace {
 		return func() {}
 	}
@@ -33,59 +35,77 @@ func (el *Element) tryTrace(htmlMessage string) func() {
 	return el.Trace(htmlMessage)
 }
 
-// ServeMonitor starts the monitor server
-// The reason why not to use "chrome://inspect/#devices" is one target cannot be driven by multiple controllers.
-func (b *Browser) ServeMonitor(host string) *kit.ServerContext {
-	if host == "" {
-		return nil
+// tryTrace writes the trace record, and shows the msg with an overlay if the trace is enabled.
+// When both of them are disabled, it won't send any request to the browser.
+func (p *Page) tryTrace(left, top, width, height float64, msg string) func() {
+	p.browser.writeTrace(p.TargetID, msg)
+
+	if !p.browser.trace {
+		return func() {}
 	}
 
-	srv := kit.MustServer(host)
-	srv.Engine.GET("/", func(ctx kit.GinContext) {
-		res, err := proto.TargetGetTargets{}.Call(b)
-		kit.E(err)
+	return p.Overlay(left, top, width, height, msg)
+}
 
-		ginHTML(ctx, kit.S(assets.Monitor, "list", res.TargetInfos))
-	})
-	srv.Engine.GET("/page/:id", func(ctx kit.GinContext) {
-		ginHTML(ctx, kit.S(
-			assets.MonitorPage,
-			"id", ctx.Param("id"),
-		))
-	})
-	srv.Engine.GET("/api/page/:id", func(ctx kit.GinContext) {
-		info, err := proto.TargetGetTargetInfo{
-			TargetID: proto.TargetTargetID(ctx.Param("id")),
-		}.Call(b)
-		kit.E(err)
-		ctx.PureJSON(http.StatusOK, info.TargetInfo)
-	})
-	srv.Engine.GET("/screenshot/:id", func(ctx kit.GinContext) {
-		id := proto.TargetTargetID(ctx.Param("id"))
-		p := b.PageFromTargetID(id)
+// TraceRecord is the record of a traced action, such as a click or an eval,
+// the Msg is the same as the message of the overlay
+type TraceRecord struct {
+	Time     time.Time            `json:"time"`
+	TargetID proto.TargetTargetID `json:"targetId"`
+	Msg      string               `json:"msg"`
+}
 
-		ctx.Header("Content-Type", "image/png;")
-		_, _ = ctx.Writer.Write(p.Screenshot())
-	})
+// traceLog is shared by all the clones of a browser
+type traceLog struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// TraceLog sets the writer to receive the trace records as json lines, such as os.Stdout for the CI logs.
+// It works even if the Trace is disabled, use nil to stop it.
+func (b *Browser) TraceLog(w io.Writer) *Browser {
+	b.traceLog = nil
+	if w != nil {
+		b.traceLog = &traceLog{w: w}
+	}
+	return b
+}
 
-	go func() { _ = srv.Do() }()
-	go func() {
-		<-b.ctx.Done()
-		_ = srv.Listener.Close()
-	}()
+func (b *Browser) writeTrace(targetID proto.TargetTargetID, msg string) {
+	if m := b.monitor.get(); m != nil {
+		m.add(targetID, MonitorEventTrace, msg)
+	}
+
+	l := b.traceLog
+	if l == nil {
+		return
+	}
 
-	url := "http://" + srv.Listener.Addr().String()
-	kit.Log("[rod] monitor server on", url, "(open it with your browser)")
+	line := kit.MustToJSONBytes(TraceRecord{Time: time.Now(), TargetID: targetID, Msg: msg})
 
-	return srv
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, _ = l.w.Write(append(line, '\n'))
 }
 
 // Overlay a rectangle on the main frame with specified message
 func (p *Page) Overlay(left, top, width, height float64, msg string) (remove func()) {
+	remove, err := p.OverlayE(left, top, width, height, msg)
+	CancelPanic(err)
+	if remove == nil {
+		return func() {}
+	}
+	return
+}
+
+// OverlayE draws a rectangle with the msg on the root page, the position is relative to the viewport, the msg is html.
+// The overlay is part of the document, so the screenshots will include it. The remove can be called more than once,
+// it's safe even if the overlay is already gone, such as after a navigation.
+func (p *Page) OverlayE(left, top, width, height float64, msg string) (remove func(), err error) {
 	root := p.Root()
 	id := "rod-" + kit.RandString(8)
 
-	_, err := root.EvalE(true, "", root.jsFn("overlay"), Array{
+	_, err = root.EvalE(true, "", root.jsFn("overlay"), Array{
 		id,
 		left,
 		top,
@@ -93,13 +113,59 @@ func (p *Page) Overlay(left, top, width, height float64, msg string) (remove fun
 		height,
 		msg,
 	})
-	CancelPanic(err)
+	if err != nil {
+		return nil, err
+	}
 
-	remove = func() {
-		_, _ = root.EvalE(true, "", root.jsFn("removeOverlay"), Array{id})
+	return root.removeOverlay(id), nil
+}
+
+// removeOverlay returns the idempotent function to remove the overlay of the id
+func (p *Page) removeOverlay(id string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			_, _ = p.EvalE(true, "", p.jsFn("removeOverlay"), Array{id})
+		})
 	}
+}
 
-	return
+// the colors of the box model, they are the same as the ones of the DevTools
+var highlightConfig = &proto.OverlayHighlightConfig{
+	ShowInfo:     true,
+	ContentColor: &proto.DOMRGBA{R: 111, G: 168, B: 220, A: 0.66},
+	PaddingColor: &proto.DOMRGBA{R: 147, G: 196, B: 125, A: 0.55},
+	BorderColor:  &proto.DOMRGBA{R: 255, G: 229, B: 153, A: 0.66},
+	MarginColor:  &proto.DOMRGBA{R: 246, G: 178, B: 107, A: 0.66},
+}
+
+// HighlightE highlights the element with the msg until remove is called, the msg is html. The element is outlined
+// with an overlay that follows it like the Trace, so the screenshots will include it. If the Overlay domain is
+// available the box model of the element will be highlighted too, like the DevTools, only one element of the page
+// can have the box model highlight at a time. The remove can be called more than once.
+func (el *Element) HighlightE(msg string) (remove func(), err error) {
+	page := el.page.Context(el.ctx)
+	id := "rod-" + kit.RandString(8)
+
+	_, err = el.EvalE(true, page.jsFn("elementOverlay"), Array{id, msg})
+	if err != nil {
+		return nil, err
+	}
+	removeOverlay := page.removeOverlay(id)
+
+	// the Overlay domain is optional, such as some remote browsers disable it
+	highlighted := proto.OverlayEnable{}.Call(page) == nil &&
+		proto.OverlayHighlightNode{HighlightConfig: highlightConfig, ObjectID: el.ObjectID}.Call(page) == nil
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			removeOverlay()
+			if highlighted {
+				_ = proto.OverlayHideHighlight{}.Call(page)
+			}
+		})
+	}, nil
 }
 
 // Trace with an overlay on the element
@@ -123,5 +189,5 @@ func (p *Page) traceFn(js string, params Array) func() {
 	fnName := strings.Replace(js, p.jsFnPrefix(), "rod.", 1)
 	paramsStr := html.EscapeString(strings.Trim(kit.MustToJSON(params), "[]"))
 	msg := fmt.Sprintf("retry <code>%s(%s)</code>", fnName, paramsStr)
-	return p.Overlay(0, 0, 500, 0, msg)
+	return p.tryTrace(0, 0, 500, 0, msg)
 }
@@ -0,0 +1,188 @@
+// This file contains the javascript dialog related code of the page.
+// The browser blocks the page until its dialog is answered, so every helper that answers the dialogs
+// must register itself via the dialogState, so that the other helpers know the dialog will be handled.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// dialogState is shared by all the clones of a page session
+type dialogState struct {
+	lock sync.Mutex
+
+	// the dialog that is opening, nil means no dialog
+	pending *proto.PageJavascriptDialogOpening
+
+	// the number of the helpers that will answer the dialogs
+	handlers int
+}
+
+// update keeps the pending dialog up to date, the event queue calls it before it publishes the event,
+// so that the subscribers that receive the opening of a dialog will always see it pending
+func (s *dialogState) update(msg *cdp.Event) {
+	opening := &proto.PageJavascriptDialogOpening{}
+
+	switch {
+	case Event(msg, opening):
+		s.lock.Lock()
+		s.pending = opening
+		s.lock.Unlock()
+	case Event(msg, &proto.PageJavascriptDialogClosed{}):
+		s.lock.Lock()
+		s.pending = nil
+		s.lock.Unlock()
+	}
+}
+
+func (s *dialogState) addHandler(delta int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers += delta
+}
+
+func (s *dialogState) handled() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.handlers > 0
+}
+
+// blocked returns an ErrDialogPending error if a dialog is blocking the page and no helper will answer it
+func (s *dialogState) blocked() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pending != nil && s.handlers == 0 {
+		return &Error{nil, ErrDialogPending, s.pending}
+	}
+	return nil
+}
+
+// unlessDialog calls fn with a clone of the page, if a dialog that no helper will answer opens before fn returns,
+// the clone will be canceled and an ErrDialogPending error will be returned, such as the beforeunload dialog
+// opened by the navigation.
+func (p *Page) unlessDialog(fn func(*Page) error) error {
+	err := p.dialog.blocked()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	s := p.event.Subscribe(ctx)
+	opened := make(chan *proto.PageJavascriptDialogOpening, 1)
+
+	go goob.Each(s, func(msg *cdp.Event) bool {
+		e := &proto.PageJavascriptDialogOpening{}
+		if Event(msg, e) && !p.dialog.handled() {
+			opened <- e
+			cancel()
+			return true
+		}
+		return false
+	})
+
+	err = fn(p.Context(ctx))
+
+	select {
+	case e := <-opened:
+		return &Error{nil, ErrDialogPending, e}
+	default:
+		return err
+	}
+}
+
+// HandleDialogE doc is similar to the method HandleDialog.
+// The handler is counted from the call until the wait function returns or the context of p is done.
+func (p *Page) HandleDialogE(accept bool, promptText string) func() error {
+	wait := p.WaitEvent()
+	p.dialog.addHandler(1)
+
+	done := make(chan kit.Nil)
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			p.dialog.addHandler(-1)
+		})
+	}
+
+	// an armed handler that is never waited won't disable the detection of the pending dialogs forever
+	go func() {
+		select {
+		case <-p.ctx.Done():
+			release()
+		case <-done:
+		}
+	}()
+
+	return func() error {
+		defer release()
+
+		wait(&proto.PageJavascriptDialogOpening{})
+		return proto.PageHandleJavaScriptDialog{
+			Accept:     accept,
+			PromptText: promptText,
+		}.Call(p)
+	}
+}
+
+// EachDialogE answers every javascript dialog of the page with the result of the handler until stop is called,
+// text is the input for the prompt dialogs. The handler receives the type of the dialog, such as the
+// beforeunload dialog must be accepted to allow the navigation. The dialog that is already opening
+// will be answered immediately.
+func (p *Page) EachDialogE(handler func(*proto.PageJavascriptDialogOpening) (accept bool, text string)) (stop func(), err error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	answer := func(e *proto.PageJavascriptDialogOpening) error {
+		accept, text := handler(e)
+		return proto.PageHandleJavaScriptDialog{Accept: accept, PromptText: text}.Call(p)
+	}
+
+	p.dialog.lock.Lock()
+	pending := p.dialog.pending
+	p.dialog.handlers++
+	p.dialog.lock.Unlock()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			cancel()
+			p.dialog.addHandler(-1)
+		})
+	}
+
+	if pending != nil {
+		err = answer(pending)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+	}
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		e := &proto.PageJavascriptDialogOpening{}
+		if Event(msg, e) {
+			_ = answer(e)
+		}
+	})
+
+	return stop, nil
+}
+
+// AutoHandleDialogsE answers every javascript dialog of the page with accept and promptText until stop is called,
+// so that an unexpected dialog won't block the page
+func (p *Page) AutoHandleDialogsE(accept bool, promptText string) (stop func(), err error) {
+	return p.EachDialogE(func(*proto.PageJavascriptDialogOpening) (bool, string) {
+		return accept, promptText
+	})
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ysmood/goob"
 	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/input"
 	"github.com/ysmood/rod/lib/proto"
 )
 
@@ -18,6 +24,7 @@ type Element struct {
 	ctx           context.Context
 	ctxCancel     func()
 	timeoutCancel func()
+	timeoutParent *Element // the object that the Timeout is called on
 
 	page *Page
 
@@ -31,7 +38,7 @@ func (el *Element) FocusE() error {
 		return err
 	}
 
-	_, err = el.EvalE(true, `() => this.focus()`, nil)
+	_, err = el.evalE(true, `() => this.focus()`, nil)
 	return err
 }
 
@@ -40,10 +47,40 @@ func (el *Element) ScrollIntoViewE() error {
 	defer el.tryTrace("scroll into view")()
 	el.page.browser.trySlowmotion()
 
-	_, err := el.EvalE(true, el.page.jsFn("scrollIntoViewIfNeeded"), nil)
+	supported, err := el.page.browser.SupportsE(CapScrollIntoViewIfNeeded)
+	if err != nil {
+		return err
+	}
+
+	if supported {
+		err = proto.DOMScrollIntoViewIfNeeded{ObjectID: el.ObjectID}.Call(el)
+		if !isMethodNotFound(err) {
+			return err
+		}
+	}
+
+	// the older browsers don't have the DOM.scrollIntoViewIfNeeded
+	_, err = el.EvalE(true, el.page.jsFn("scrollIntoViewIfNeeded"), Array{false})
 	return err
 }
 
+// the duration that the element must keep its position after a smooth scrolling
+var smoothScrollStable = 100 * time.Millisecond
+
+// ScrollIntoViewSmoothE doc is similar to the method ScrollIntoViewSmooth
+func (el *Element) ScrollIntoViewSmoothE() error {
+	defer el.tryTrace("scroll into view smoothly")()
+	el.page.browser.trySlowmotion()
+
+	_, err := el.EvalE(true, el.page.jsFn("scrollIntoViewIfNeeded"), Array{true})
+	if err != nil {
+		return err
+	}
+
+	// the smooth scrolling is animated, wait for it to finish
+	return el.WaitStableE(smoothScrollStable)
+}
+
 // ClickE doc is similar to the method Click
 func (el *Element) ClickE(button proto.InputMouseButton) error {
 	err := el.WaitVisibleE()
@@ -56,6 +93,18 @@ func (el *Element) ClickE(button proto.InputMouseButton) error {
 		return err
 	}
 
+	if el.page.browser.stable > 0 {
+		err = el.WaitStableE(el.page.browser.stable)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = el.checkInteractableE(false)
+	if err != nil {
+		return err
+	}
+
 	box, err := el.BoxE()
 	if err != nil {
 		return err
@@ -74,6 +123,87 @@ func (el *Element) ClickE(button proto.InputMouseButton) error {
 	return el.page.Mouse.ClickE(button)
 }
 
+// TapE doc is similar to the method Tap
+func (el *Element) TapE() error {
+	err := el.WaitVisibleE()
+	if err != nil {
+		return err
+	}
+
+	err = el.ScrollIntoViewE()
+	if err != nil {
+		return err
+	}
+
+	if el.page.browser.stable > 0 {
+		err = el.WaitStableE(el.page.browser.stable)
+		if err != nil {
+			return err
+		}
+	}
+
+	box, err := el.BoxE()
+	if err != nil {
+		return err
+	}
+
+	x := box.Left + box.Width/2
+	y := box.Top + box.Height/2
+
+	defer el.tryTrace("tap")()
+
+	return el.page.Touch.TapE(x, y)
+}
+
+// DragToE doc is similar to the method DragTo
+func (el *Element) DragToE(target *Element, steps int) error {
+	err := el.WaitVisibleE()
+	if err != nil {
+		return err
+	}
+
+	err = el.ScrollIntoViewE()
+	if err != nil {
+		return err
+	}
+
+	err = target.ScrollIntoViewE()
+	if err != nil {
+		return err
+	}
+
+	// get the positions after both of them are scrolled
+	fromX, fromY, err := el.centerE()
+	if err != nil {
+		return err
+	}
+
+	toX, toY, err := target.centerE()
+	if err != nil {
+		return err
+	}
+
+	defer el.tryTrace("drag")()
+
+	return el.page.Mouse.DragE(fromX, fromY, toX, toY, steps)
+}
+
+// centerE returns the center of the border box, it's relative to the viewport of the root page
+func (el *Element) centerE() (x, y float64, err error) {
+	res, err := proto.DOMGetBoxModel{ObjectID: el.ObjectID}.Call(el)
+	if err != nil {
+		return
+	}
+
+	offsetX, offsetY, err := el.page.frameOffsetE()
+	if err != nil {
+		return
+	}
+
+	left, top, right, bottom := quadBounds(res.Model.Border)
+	return offsetX + (left+right)/2, offsetY + (top+bottom)/2, nil
+}
+
 // PressE doc is similar to the method Press
 func (el *Element) PressE(key rune) error {
 	err := el.WaitVisibleE()
@@ -119,8 +249,77 @@ func (el *Element) SelectAllTextE() error {
 	return err
 }
 
+// the max times to check the state of the checkbox after the click, the page sleeper is used between the checks
+var checkRetries = 10
+
+// CheckedE returns the checked property of the checkbox or radio element
+func (el *Element) CheckedE() (bool, error) {
+	checked, err := el.PropertyE("checked")
+	if err != nil {
+		return false, err
+	}
+	return checked.Bool(), nil
+}
+
+// CheckE doc is similar to the method Check
+func (el *Element) CheckE() error {
+	return el.setChecked(true)
+}
+
+// UncheckE doc is similar to the method Uncheck
+func (el *Element) UncheckE() error {
+	typ, err := el.PropertyE("type")
+	if err != nil {
+		return err
+	}
+	if typ.String() == "radio" {
+		return &Error{nil, ErrUncheckRadio, el.ObjectID}
+	}
+
+	return el.setChecked(false)
+}
+
+// setChecked clicks the element if its checked property isn't the checked, then waits for the property to change,
+// the listeners of the page may change the state asynchronously
+func (el *Element) setChecked(checked bool) error {
+	current, err := el.CheckedE()
+	if err != nil {
+		return err
+	}
+	if current == checked {
+		return nil
+	}
+
+	err = el.ClickE(proto.InputMouseButtonLeft)
+	if err != nil {
+		return err
+	}
+
+	sleeper := kit.MergeSleepers(el.page.Sleeper(), kit.CountSleeper(checkRetries))
+	err = kit.Retry(el.ctx, sleeper, func() (bool, error) {
+		current, err := el.CheckedE()
+		if err != nil {
+			return true, err
+		}
+		return current == checked, nil
+	})
+	if errors.Is(err, kit.ErrMaxSleepCount) {
+		return &Error{nil, ErrCheckFailed, el.ObjectID}
+	}
+	return err
+}
+
 // InputE doc is similar to the method Input
 func (el *Element) InputE(text string) error {
+	return el.input(text, true)
+}
+
+// AppendE doc is similar to the method Append
+func (el *Element) AppendE(text string) error {
+	return el.input(text, false)
+}
+
+func (el *Element) input(text string, clear bool) error {
 	err := el.WaitVisibleE()
 	if err != nil {
 		return err
@@ -131,8 +330,30 @@ func (el *Element) InputE(text string) error {
 		return err
 	}
 
+	// the focus scrolls the element into view, so it can be hit tested
+	err = el.checkInteractableE(true)
+	if err != nil {
+		return err
+	}
+
+	if clear {
+		err = el.page.Keyboard.PressCombinationE(input.CommandOrControl, 'a')
+		if err != nil {
+			return err
+		}
+		err = el.page.Keyboard.PressE(input.Delete)
+		if err != nil {
+			return err
+		}
+	}
+
 	defer el.tryTrace("input " + text)()
 
+	// the key events will trigger the input events of the element
+	if el.page.inputKeystrokes {
+		return el.page.Keyboard.TypeE(text)
+	}
+
 	err = el.page.Keyboard.InsertTextE(text)
 	if err != nil {
 		return err
@@ -142,42 +363,261 @@ func (el *Element) InputE(text string) error {
 	return err
 }
 
-// SelectE doc is similar to the method Select
-func (el *Element) SelectE(selectors []string) error {
+// InputTimeE sets the value of the input element, the type of it should be time or datetime-local.
+// The seconds are only set when they are not zero.
+func (el *Element) InputTimeE(t time.Time) error {
+	return el.inputTime(t, "time", "datetime-local")
+}
+
+// InputDateE sets the value of the input element, the type of it should be date, month, week, or datetime-local
+func (el *Element) InputDateE(t time.Time) error {
+	return el.inputTime(t, "date", "month", "week", "datetime-local")
+}
+
+// inputTime sets the value via the value property instead of the keystrokes, because the formats of the
+// keystrokes depend on the locale of the browser. The value out of the min and max will be rejected.
+func (el *Element) inputTime(t time.Time, types ...string) error {
 	err := el.WaitVisibleE()
 	if err != nil {
 		return err
 	}
 
-	defer el.tryTrace(fmt.Sprintf(
-		`<span style="color: #777;">select</span> <code>%s</code>`,
-		strings.Join(selectors, "; ")))()
+	res, err := el.evalE(true, `() => this.type`, nil)
+	if err != nil {
+		return err
+	}
+	typ := res.Value.String()
+
+	clock := "15:04"
+	if t.Second() != 0 {
+		clock = "15:04:05"
+	}
+
+	layouts := map[string]string{
+		"time":           clock,
+		"date":           "2006-01-02",
+		"month":          "2006-01",
+		"datetime-local": "2006-01-02T" + clock,
+	}
+
+	value := ""
+	for _, name := range types {
+		if name != typ {
+			continue
+		}
+		if typ == "week" {
+			y, w := t.ISOWeek()
+			value = fmt.Sprintf("%04d-W%02d", y, w)
+		} else {
+			value = t.Format(layouts[typ])
+		}
+	}
+	if value == "" {
+		return &Error{nil, ErrInputType, typ}
+	}
+
+	defer el.tryTrace("input " + value)()
 	el.page.browser.trySlowmotion()
 
-	_, err = el.EvalE(true, el.page.jsFn("select"), Array{selectors})
-	return err
+	res, err = el.EvalE(true, el.page.jsFn("inputValue"), Array{value})
+	if err != nil {
+		return err
+	}
+
+	if msg := res.Value.String(); msg != "" {
+		return &Error{nil, ErrInputInvalid, msg}
+	}
+	return nil
+}
+
+// TypeE doc is similar to the method Type
+func (el *Element) TypeE(text string) error {
+	err := el.WaitVisibleE()
+	if err != nil {
+		return err
+	}
+
+	err = el.FocusE()
+	if err != nil {
+		return err
+	}
+
+	defer el.tryTrace("type " + text)()
+
+	// the key events will trigger the input events of the element
+	return el.page.Keyboard.TypeE(text)
+}
+
+// SelectE selects the options of the select element that match the selectors, multiple selectors are for the multi-selects.
+// If byText is true the selectors match the text content of the options, otherwise they match the values or are css selectors.
+// The input and change events will be dispatched. If any selector matches nothing, no option will be changed and
+// an ErrOptionNotFound error with the available options will be returned.
+func (el *Element) SelectE(selectors []string, byText bool) error {
+	mode := "value"
+	if byText {
+		mode = "text"
+	}
+	return el.selectOptions(strings.Join(selectors, "; "), toArray(selectors), mode)
+}
+
+// SelectIndexE selects the options of the select element at the indexes, the index starts from 0.
+// It's the same as the SelectE, if an index is out of the range an ErrOptionNotFound error will be returned.
+func (el *Element) SelectIndexE(indexes []int) error {
+	list := Array{}
+	names := []string{}
+	for _, i := range indexes {
+		list = append(list, i)
+		names = append(names, strconv.Itoa(i))
+	}
+	return el.selectOptions("index "+strings.Join(names, ", "), list, "index")
+}
+
+// selectOptions selects the options via the mode of the select helper, the name is for the trace
+func (el *Element) selectOptions(name string, selectors Array, mode string) error {
+	err := el.WaitVisibleE()
+	if err != nil {
+		return err
+	}
+
+	defer el.tryTrace(fmt.Sprintf(`<span style="color: #777;">select</span> <code>%s</code>`, name))()
+	el.page.browser.trySlowmotion()
+
+	res, err := el.EvalE(true, el.page.jsFn("select"), Array{selectors, mode})
+	if err != nil {
+		return err
+	}
+
+	if res.Value.Get("notFound").Exists() {
+		return &Error{nil, ErrOptionNotFound, res.Value.Raw}
+	}
+	return nil
+}
+
+func toArray(list []string) Array {
+	arr := Array{}
+	for _, s := range list {
+		arr = append(arr, s)
+	}
+	return arr
+}
+
+// SelectedOptionsE returns the values of the selected options of the select element
+func (el *Element) SelectedOptionsE() ([]string, error) {
+	res, err := el.evalE(true, `() => Array.from(this.selectedOptions).map(el => el.value)`, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{}
+	for _, v := range res.Value.Array() {
+		list = append(list, v.String())
+	}
+	return list, nil
 }
 
 // SetFilesE doc is similar to the method SetFiles
 func (el *Element) SetFilesE(paths []string) error {
+	absPaths, err := absFilePaths(paths)
+	if err != nil {
+		return err
+	}
+
+	defer el.tryTrace(fmt.Sprintf("set files: %v", absPaths))()
+	el.page.browser.trySlowmotion()
+
+	node, err := el.DescribeE()
+	if err != nil {
+		return err
+	}
+
+	// the element is called via the session of its own page, so that it works for the iframes too
+	return proto.DOMSetFileInputFiles{
+		Files:         absPaths,
+		BackendNodeID: node.BackendNodeID,
+	}.Call(el)
+}
+
+// absFilePaths returns the absolute paths of the files, the files must exist
+func absFilePaths(paths []string) ([]string, error) {
 	absPaths := []string{}
 	for _, p := range paths {
 		absPath, err := filepath.Abs(p)
 		if err != nil {
-			return err
+			return nil, err
 		}
+
+		_, err = os.Stat(absPath)
+		if err != nil {
+			return nil, err
+		}
+
 		absPaths = append(absPaths, absPath)
 	}
+	return absPaths, nil
+}
 
-	defer el.tryTrace(fmt.Sprintf("set files: %v", absPaths))
-	el.page.browser.trySlowmotion()
+// EventOptions of the DispatchEventE
+type EventOptions struct {
+	// Type is the constructor of the event, such as "Event", "InputEvent", or "KeyboardEvent",
+	// empty means "CustomEvent"
+	Type string
 
-	err := proto.DOMSetFileInputFiles{
-		Files:    absPaths,
-		ObjectID: el.ObjectID,
-	}.Call(el)
+	Bubbles    bool
+	Cancelable bool
 
-	return err
+	// Composed lets the event propagate across the shadow root into the light dom
+	Composed bool
+
+	// Init is merged into the init dict of the constructor, such as {"key": "Enter"} for the KeyboardEvent
+	Init map[string]interface{}
+}
+
+// the detail is only in the init dict of the CustomEvent, the other types ignore it
+const dispatchEventJS = `function (name, type, init) {
+	const Ctor = window[type]
+	if (typeof Ctor !== 'function' || !(Ctor.prototype instanceof Event || Ctor === Event)) {
+		throw new TypeError(type + ' is not an event type')
+	}
+	return !this.dispatchEvent(new Ctor(name, init))
+}`
+
+func dispatchEventArgs(name string, detail interface{}, opts *EventOptions) Array {
+	if opts == nil {
+		opts = &EventOptions{}
+	}
+
+	typ := opts.Type
+	if typ == "" {
+		typ = "CustomEvent"
+	}
+
+	init := map[string]interface{}{}
+	for k, v := range opts.Init {
+		init[k] = v
+	}
+	init["bubbles"] = opts.Bubbles
+	init["cancelable"] = opts.Cancelable
+	init["composed"] = opts.Composed
+	if detail != nil {
+		init["detail"] = detail
+	}
+
+	return Array{name, typ, init}
+}
+
+// DispatchEventE dispatches a synthetic event on the element, such as the custom events of the component libraries.
+// The detail is serialized as json, it's the detail of the CustomEvent. If opts is nil a CustomEvent that doesn't
+// bubble will be dispatched, the same as the default of the constructor. It returns true if the event is canceled
+// by one of the listeners via the preventDefault, the event must be cancelable for it.
+// The event is dispatched on the element itself, so it works for the elements in the shadow roots and iframes.
+func (el *Element) DispatchEventE(name string, detail interface{}, opts *EventOptions) (canceled bool, err error) {
+	defer el.tryTrace("dispatch " + name)()
+
+	res, err := el.evalE(true, dispatchEventJS, dispatchEventArgs(name, detail, opts))
+	if err != nil {
+		return false, err
+	}
+	return res.Value.Bool(), nil
 }
 
 // DescribeE doc is similar to the method Describe
@@ -189,27 +629,25 @@ func (el *Element) DescribeE() (*proto.DOMNode, error) {
 	return val.Node, nil
 }
 
-// ShadowRootE returns the shadow root of this element
+// ShadowRootE returns the shadow root of this element, both the open and closed shadow roots are supported.
+// It returns ErrShadowRootNotFound if the element doesn't have one.
 func (el *Element) ShadowRootE() (*Element, error) {
-	node, err := el.DescribeE()
+	res, err := proto.DOMDescribeNode{ObjectID: el.ObjectID, Pierce: true}.Call(el)
 	if err != nil {
 		return nil, err
 	}
 
-	// though now it's an array, w3c changed the spec of it to be a single.
-	id := node.ShadowRoots[0].BackendNodeID
-
-	shadowNode, err := proto.DOMResolveNode{BackendNodeID: id}.Call(el)
-	if err != nil {
-		return nil, err
+	if len(res.Node.ShadowRoots) == 0 {
+		return nil, &Error{nil, ErrShadowRootNotFound, res.Node.LocalName}
 	}
 
-	return el.page.ElementFromObjectID(shadowNode.Object.ObjectID), nil
+	// though now it's an array, w3c changed the spec of it to be a single.
+	return el.page.resolveNodeE(res.Node.ShadowRoots[0].BackendNodeID)
 }
 
 // FrameE doc is similar to the method Frame
 func (el *Element) FrameE() (*Page, error) {
-	node, err := el.DescribeE()
+	node, err := el.frameNode()
 	if err != nil {
 		return nil, err
 	}
@@ -218,24 +656,150 @@ func (el *Element) FrameE() (*Page, error) {
 	newPage.FrameID = node.FrameID
 	newPage.element = el
 	newPage.windowObjectID = ""
+	newPage.jsContextID = 0
+
+	isTarget, err := el.page.isFrameTarget(node.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the out-of-process iframe, such as a cross-origin iframe, is a separate target with its own session
+	if isTarget {
+		newPage.TargetID = proto.TargetTargetID(node.FrameID)
+		newPage.SessionID = ""
+		newPage.lazy = nil
+		newPage.initTargetStates()
+		newPage.cache.set(el.page.cache.get()) // the iframe inherits the cache of its parent
+
+		err = newPage.initSession(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = newPage.trackFrame()
+	if err != nil {
+		return nil, err
+	}
 
 	return &newPage, nil
 }
 
+// frameNode describes the iframe element, if the content frame of it isn't attached yet, such as the iframe is
+// just inserted, it waits for the frame to attach
+func (el *Element) frameNode() (*proto.DOMNode, error) {
+	ctx, cancel := context.WithCancel(el.ctx)
+	defer cancel()
+
+	// subscribe before the describe, so that we won't miss the attached event
+	attached := make(chan kit.Nil, 1)
+	s := el.page.event.Subscribe(ctx)
+	go goob.Each(s, func(msg *cdp.Event) {
+		if Event(msg, &proto.PageFrameAttached{}) {
+			select {
+			case attached <- kit.Nil{}:
+			default:
+			}
+		}
+	})
+
+	for {
+		node, err := el.DescribeE()
+		if err != nil {
+			return nil, err
+		}
+
+		if node.NodeName != "IFRAME" && node.NodeName != "FRAME" {
+			return nil, &Error{nil, ErrNotFrame, node.LocalName}
+		}
+
+		if node.FrameID != "" {
+			return node, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-attached:
+		}
+	}
+}
+
 // TextE doc is similar to the method Text
 func (el *Element) TextE() (string, error) {
+	err := el.ensureAttachedE()
+	if err != nil {
+		return "", err
+	}
+
 	str, err := el.EvalE(true, el.page.jsFn("text"), nil)
-	return str.Value.String(), err
+	if err != nil {
+		return "", err
+	}
+	return str.Value.String(), nil
 }
 
 // HTMLE doc is similar to the method HTML
 func (el *Element) HTMLE() (string, error) {
-	str, err := el.EvalE(true, `() => this.outerHTML`, nil)
-	return str.Value.String(), err
+	node, err := el.DescribeE()
+	if err != nil {
+		return "", el.detachedErr(err)
+	}
+
+	res, err := proto.DOMGetOuterHTML{BackendNodeID: node.BackendNodeID}.Call(el)
+	if err != nil {
+		return "", el.detachedErr(err)
+	}
+	return res.OuterHTML, nil
+}
+
+// AttributeE doc is similar to the method Attribute
+func (el *Element) AttributeE(name string) (*string, error) {
+	err := el.ensureAttachedE()
+	if err != nil {
+		return nil, err
+	}
+
+	attr, err := el.evalE(true, `(n) => this.getAttribute(n)`, Array{name})
+	if err != nil {
+		return nil, err
+	}
+
+	// getAttribute returns either a string or null
+	if attr.Type != proto.RuntimeRemoteObjectTypeString {
+		return nil, nil
+	}
+
+	s := attr.Value.String()
+	return &s, nil
+}
+
+// PropertyE doc is similar to the method Property
+func (el *Element) PropertyE(name string) (proto.JSON, error) {
+	err := el.ensureAttachedE()
+	if err != nil {
+		return proto.JSON{}, err
+	}
+
+	prop, err := el.evalE(true, `(n) => this[n]`, Array{name})
+	if err != nil {
+		return proto.JSON{}, err
+	}
+	return prop.Value, nil
 }
 
-// VisibleE doc is similar to the method Visible
+// VisibleE doc is similar to the method Visible, the element with the opacity of 0 isn't visible
 func (el *Element) VisibleE() (bool, error) {
+	res, err := el.EvalE(true, el.page.jsFn("opaqueVisible"), nil)
+	if err != nil {
+		return false, err
+	}
+	return res.Value.Bool(), nil
+}
+
+// displayedE is the same as the VisibleE, but the transparent element is displayed, because it still takes the
+// inputs, such as the custom-styled checkboxes and file inputs
+func (el *Element) displayedE() (bool, error) {
 	res, err := el.EvalE(true, el.page.jsFn("visible"), nil)
 	if err != nil {
 		return false, err
@@ -243,53 +807,140 @@ func (el *Element) VisibleE() (bool, error) {
 	return res.Value.Bool(), nil
 }
 
-// WaitStableE not using requestAnimation here because it can trigger to many checks,
+// WaitStableE waits until the box model of the element hasn't changed for d.
+// Not using requestAnimation here because it can trigger to many checks,
 // or miss checks for jQuery css animation.
-func (el *Element) WaitStableE(interval time.Duration) error {
+func (el *Element) WaitStableE(d time.Duration) error {
 	err := el.WaitVisibleE()
 	if err != nil {
 		return err
 	}
 
-	box := el.Box()
+	quad, err := el.borderQuadE()
+	if err != nil {
+		return err
+	}
 
-	t := time.NewTicker(interval)
-	defer t.Stop()
+	sleeper := el.page.Sleeper()
+	since := time.Now()
 
-	for range t.C {
-		select {
-		case <-t.C:
-		case <-el.ctx.Done():
-			return el.ctx.Err()
+	for time.Since(since) < d {
+		err = sleeper(el.ctx)
+		if err != nil {
+			return err
 		}
-		current := el.Box()
-		if *box == *current {
-			break
+
+		current, err := el.borderQuadE()
+		if err != nil {
+			return err
+		}
+
+		if !quadEqual(quad, current) {
+			// restart the timing and the backoff
+			quad = current
+			since = time.Now()
+			sleeper = el.page.Sleeper()
 		}
-		box = current
 	}
 	return nil
 }
 
 // WaitE doc is similar to the method Wait
 func (el *Element) WaitE(js string, params Array) error {
+	return el.page.Context(el.ctx).WaitE(nil, el.ObjectID, js, params)
+}
+
+// WaitVisibleE doc is similar to the method WaitVisible
+func (el *Element) WaitVisibleE() error {
 	return kit.Retry(el.ctx, el.page.Sleeper(), func() (bool, error) {
-		res, err := el.EvalE(true, js, params)
+		visible, err := el.displayedE()
 		if err != nil {
 			return true, err
 		}
 
-		if res.Value.Bool() {
+		if visible {
 			return true, nil
 		}
 
+		// a removed element will never be visible again
+		attached, err := el.attachedE()
+		if err != nil {
+			return true, err
+		}
+		if !attached {
+			return true, &Error{nil, ErrElementDetached, nil}
+		}
+
 		return false, nil
 	})
 }
 
-// WaitVisibleE doc is similar to the method WaitVisible
-func (el *Element) WaitVisibleE() error {
-	return el.WaitE(el.page.jsFn("visible"), nil)
+// WaitTextOptions of the WaitTextE
+type WaitTextOptions struct {
+	// Regexp treats the text to wait for as a regular expression of js
+	Regexp bool
+
+	// NormalizeSpace collapses the whitespaces of the text into single spaces and trims it before matching,
+	// because the innerText collapses the whitespaces differently across the versions of the browsers
+	NormalizeSpace bool
+}
+
+// the max length of the current text in the timeout error of the WaitTextE
+const waitTextMax = 100
+
+func waitTextErr(err error, text string, detail string) error {
+	if r := []rune(text); len(r) > waitTextMax {
+		text = string(r[:waitTextMax]) + "..."
+	}
+	return &Error{err, ErrWaitTimeout, fmt.Sprintf("%s, the current text is %q", detail, text)}
+}
+
+// WaitTextE waits until the text of the element contains the substr, the text is the innerText, or the value
+// for the inputs and textareas. If opts is nil the defaults will be used. If the context is done before
+// the text matches, an ErrWaitTimeout error with the current text of the element will be returned.
+func (el *Element) WaitTextE(substr string, opts *WaitTextOptions) error {
+	if opts == nil {
+		opts = &WaitTextOptions{}
+	}
+
+	text := ""
+	sleeper, stats := countSleeper(el.page.Sleeper())
+	err := kit.Retry(el.ctx, sleeper, func() (bool, error) {
+		res, err := el.evalE(true, el.page.jsFn("textMatches"), Array{substr, opts.Regexp, opts.NormalizeSpace})
+		if err != nil {
+			return true, err
+		}
+
+		text = res.Value.Get("text").String()
+		return res.Value.Get("matched").Bool(), nil
+	})
+
+	if err != nil && el.ctx.Err() != nil {
+		return waitTextErr(el.ctx.Err(), text, fmt.Sprintf("wait for text %q, %s", substr, stats))
+	}
+	return err
+}
+
+// the element is disabled if itself or one of its ancestors is aria-disabled, the disabled fieldsets are
+// matched by the :disabled
+const elementDisabledJS = `function () {
+	return !!(this.disabled || (this.matches && this.matches(':disabled')) ||
+		(this.closest && this.closest('[aria-disabled="true"]')))
+}`
+
+// WaitEnabledE waits until the element is enabled, such as it has no disabled attribute or property,
+// it's not inside a disabled fieldset, and it's not aria-disabled
+func (el *Element) WaitEnabledE() error {
+	return kit.Retry(el.ctx, el.page.Sleeper(), func() (bool, error) {
+		res, err := el.evalE(true, elementDisabledJS, nil)
+		if err != nil {
+			return true, err
+		}
+		if !res.Value.Bool() {
+			return true, nil
+		}
+		return false, el.ensureAttachedE()
+	})
 }
 
 // WaitInvisibleE doc is similar to the method WaitInvisible
@@ -297,6 +948,46 @@ func (el *Element) WaitInvisibleE() error {
 	return el.WaitE(el.page.jsFn("invisible"), nil)
 }
 
+// attachedE returns false if the element is removed from the document
+func (el *Element) attachedE() (bool, error) {
+	res, err := el.evalE(true, `() => this.isConnected`, nil)
+	if err != nil {
+		return false, err
+	}
+	return res.Value.Bool(), nil
+}
+
+// ensureAttachedE returns ErrElementDetached if the element is removed from the document
+func (el *Element) ensureAttachedE() error {
+	attached, err := el.attachedE()
+	if err != nil {
+		return err
+	}
+	if !attached {
+		return &Error{nil, ErrElementDetached, nil}
+	}
+	return nil
+}
+
+// detachedErr converts the err to ErrElementDetached if the element is removed from the document
+func (el *Element) detachedErr(err error) error {
+	attached, e := el.attachedE()
+	if e == nil && !attached {
+		return &Error{nil, ErrElementDetached, nil}
+	}
+	return err
+}
+
+// borderQuadE returns the border quad of the element, returns ErrElementDetached if the element is removed
+func (el *Element) borderQuadE() (proto.DOMQuad, error) {
+	res, err := proto.DOMGetBoxModel{ObjectID: el.ObjectID}.Call(el)
+	if err == nil {
+		return res.Model.Border, nil
+	}
+
+	return nil, el.detachedErr(err)
+}
+
 // Box represents the element bounding rect
 type Box struct {
 	Top    float64 `json:"top"`
@@ -307,7 +998,14 @@ type Box struct {
 
 // BoxE doc is similar to the method Box
 func (el *Element) BoxE() (*Box, error) {
-	res, err := el.EvalE(true, el.page.jsFn("box"), nil)
+	return el.boxE(false)
+}
+
+// boxE returns the box relative to the viewport of the root page, if sameSession is true the box is relative
+// to the viewport of the closest frame that has its own session, such as an out-of-process iframe,
+// it's the coordinate space that the DOM commands of the session use.
+func (el *Element) boxE(sameSession bool) (*Box, error) {
+	res, err := el.evalE(true, el.page.jsFn("box"), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -315,8 +1013,9 @@ func (el *Element) BoxE() (*Box, error) {
 	var rect Box
 	kit.E(json.Unmarshal([]byte(res.Value.Raw), &rect))
 
-	if el.page.IsIframe() {
-		frameRect, err := el.page.element.BoxE() // recursively get the box
+	frame := el.page.element
+	if el.page.IsIframe() && (!sameSession || frame.page.SessionID == el.page.SessionID) {
+		frameRect, err := frame.boxE(sameSession) // recursively get the box of the ancestor iframes
 		if err != nil {
 			return nil, err
 		}
@@ -326,6 +1025,113 @@ func (el *Element) BoxE() (*Box, error) {
 	return &rect, nil
 }
 
+// ClickableE returns true if the element is visible and it's the top element at its center point,
+// such as it's not covered by a modal. The descendants of the element at the point are treated as the element.
+func (el *Element) ClickableE() (bool, error) {
+	visible, err := el.VisibleE()
+	if err != nil || !visible {
+		return false, err
+	}
+
+	hit, _, err := el.hitTestE()
+	return hit, err
+}
+
+// hitTestE returns true if the element is the top element at its center point, otherwise the selector path of the
+// element that covers it, the path is empty if the covering element belongs to another frame
+func (el *Element) hitTestE() (hit bool, covering string, err error) {
+	box, err := el.boxE(true)
+	if err != nil {
+		return false, "", err
+	}
+
+	loc, err := proto.DOMGetNodeForLocation{
+		X:                         int64(box.Left + box.Width/2),
+		Y:                         int64(box.Top + box.Height/2),
+		IncludeUserAgentShadowDOM: true,
+	}.Call(el)
+	if err != nil {
+		return false, "", err
+	}
+
+	node, err := proto.DOMResolveNode{BackendNodeID: loc.BackendNodeID, ExecutionContextID: el.page.jsContextID}.Call(el)
+	if err != nil {
+		// the node belongs to another frame
+		return false, "", nil
+	}
+	defer func() { _ = el.page.ReleaseE(node.Object.ObjectID) }()
+
+	res, err := proto.RuntimeCallFunctionOn{
+		ObjectID: el.ObjectID,
+		FunctionDeclaration: `function(hit) {
+			for (let n = hit; n; n = n.parentNode || n.host) {
+				if (n === this) return { hit: true }
+			}
+
+			const path = []
+			for (let n = hit.nodeType === Node.ELEMENT_NODE ? hit : hit.parentElement; n; n = n.parentElement) {
+				if (n.id) {
+					path.unshift(n.localName + '#' + CSS.escape(n.id))
+					break
+				}
+
+				let part = n.localName
+				const same = n.parentElement ? Array.from(n.parentElement.children).filter(c => c.localName === n.localName) : []
+				if (same.length > 1) part += ':nth-of-type(' + (same.indexOf(n) + 1) + ')'
+				path.unshift(part)
+			}
+			return { covering: path.join(' > ') }
+		}`,
+		Arguments:     []*proto.RuntimeCallArgument{{ObjectID: node.Object.ObjectID}},
+		ReturnByValue: true,
+	}.Call(el)
+	if err != nil {
+		return false, "", err
+	}
+	if res.Result.Value.Get("hit").Bool() {
+		return true, "", nil
+	}
+	return false, res.Result.Value.Get("covering").String(), nil
+}
+
+// checkInteractableE returns an ErrNotInteractable error if the element is disabled, or it's covered by another
+// element at its center point, if the input is true the readonly element is not interactable either.
+// The element should be visible and scrolled into view.
+func (el *Element) checkInteractableE(input bool) error {
+	if el.page.skipInteractable {
+		return nil
+	}
+
+	res, err := el.evalE(true, elementDisabledJS, nil)
+	if err != nil {
+		return err
+	}
+	if res.Value.Bool() {
+		return &Error{nil, ErrNotInteractable, "the element is disabled"}
+	}
+
+	if input {
+		res, err = el.evalE(true, `() => !!this.readOnly`, nil)
+		if err != nil {
+			return err
+		}
+		if res.Value.Bool() {
+			return &Error{nil, ErrNotInteractable, "the element is readonly"}
+		}
+	}
+
+	hit, covering, err := el.hitTestE()
+	if err != nil {
+		return err
+	}
+
+	// the element of another frame can't be diagnosed, let the input decide
+	if !hit && covering != "" {
+		return &Error{nil, ErrNotInteractable, "the element is covered by: " + covering}
+	}
+	return nil
+}
+
 // ResourceE doc is similar to the method Resource
 func (el *Element) ResourceE() ([]byte, error) {
 	src, err := el.EvalE(true, el.page.jsFn("resource"), nil)
@@ -356,7 +1162,9 @@ func (el *Element) ResourceE() ([]byte, error) {
 	return bin, nil
 }
 
-// ScreenshotE of the area of the element
+// ScreenshotE of the area of the element, the element will be scrolled into view first.
+// If the element is larger than the viewport, the viewport will be temporarily expanded.
+// Set quality to -1 to use the default quality of the format.
 func (el *Element) ScreenshotE(format proto.PageCaptureScreenshotFormat, quality int) ([]byte, error) {
 	err := el.WaitVisibleE()
 	if err != nil {
@@ -368,27 +1176,43 @@ func (el *Element) ScreenshotE(format proto.PageCaptureScreenshotFormat, quality
 		return nil, err
 	}
 
-	box, err := el.BoxE()
+	res, err := proto.DOMGetBoxModel{ObjectID: el.ObjectID}.Call(el)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetX, offsetY, err := el.page.frameOffsetE()
+	if err != nil {
+		return nil, err
+	}
+
+	// the quad is relative to the viewport of the main frame of the session
+	left, top, right, bottom := quadBounds(res.Model.Border)
+	left, right = left+offsetX, right+offsetX
+	top, bottom = top+offsetY, bottom+offsetY
+
+	root := el.page.Root()
+
+	metrics, err := proto.PageGetLayoutMetrics{}.Call(root)
 	if err != nil {
 		return nil, err
 	}
 
 	opts := &proto.PageCaptureScreenshot{
 		Format: format,
-		Clip: &proto.PageViewport{
-			X:      box.Left,
-			Y:      box.Top,
-			Width:  box.Width,
-			Height: box.Height,
-			Scale:  1,
-		},
 	}
 
 	if quality > -1 {
 		opts.Quality = int64(quality)
 	}
 
-	return el.page.Root().ScreenshotE(false, opts)
+	return root.ScreenshotAreaE(
+		left+float64(metrics.LayoutViewport.PageX),
+		top+float64(metrics.LayoutViewport.PageY),
+		right-left,
+		bottom-top,
+		opts,
+	)
 }
 
 // ReleaseE doc is similar to the method Release
@@ -404,10 +1228,15 @@ func (el *Element) ReleaseE() error {
 
 // CallContext parameters for proto
 func (el *Element) CallContext() (context.Context, proto.Client, string) {
-	return el.ctx, el.page.browser.client, string(el.page.SessionID)
+	client, id := el.page.sessionClient()
+	return el.ctx, client, id
 }
 
 // EvalE doc is similar to the method Eval
 func (el *Element) EvalE(byValue bool, js string, params Array) (*proto.RuntimeRemoteObject, error) {
 	return el.page.Context(el.ctx).EvalE(byValue, el.ObjectID, js, params)
 }
+
+func (el *Element) evalE(byValue bool, js string, params Array) (*proto.RuntimeRemoteObject, error) {
+	return el.page.Context(el.ctx).evalE(byValue, el.ObjectID, js, params)
+}
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"image/jpeg"
 	"image/png"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/ysmood/kit"
+	"github.com/ysmood/rod"
 	"github.com/ysmood/rod/lib/input"
+	"github.com/ysmood/rod/lib/launcher"
 	"github.com/ysmood/rod/lib/proto"
 )
 
@@ -33,12 +38,125 @@ func (s *S) TestIframes() {
 	s.True(frame.Has("[a=ok]"))
 }
 
+func (s *S) TestIframeWithoutSrc() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	p.Eval(`() => {
+		const srcdoc = document.createElement('iframe')
+		srcdoc.srcdoc = '<p>srcdoc</p>'
+		document.body.append(srcdoc)
+
+		setTimeout(() => {
+			const blank = document.createElement('iframe')
+			blank.id = 'blank'
+			document.body.append(blank)
+			blank.contentDocument.body.innerHTML = '<p>blank</p>'
+		}, 100)
+	}`)
+
+	frame := p.Element("iframe").Frame()
+	s.Equal(p.TargetID, frame.Root().TargetID)
+	s.Equal("srcdoc", frame.Element("p").Text())
+
+	frame = p.Element("#blank").Frame()
+	s.Equal("blank", frame.Element("p").Text())
+
+	_, err := p.Element("button").FrameE()
+	s.True(errors.Is(err, rod.ErrNotFrame))
+}
+
+func (s *S) TestElementClickable() {
+	p := s.page.Navigate(srcFile("fixtures/clickable.html"))
+
+	s.True(p.Element("#covered").Visible())
+	s.False(p.Element("#covered").Clickable())
+	s.True(p.Element("#open").Clickable())
+	s.False(p.Element("#transparent").Visible())
+	s.False(p.Element("#transparent").Clickable())
+
+	// the transparent element still takes the inputs, the waits of the actions shouldn't hang on it
+	p.Timeout(3 * time.Second).Element("#transparent").WaitVisible()
+
+	p = s.page.Navigate(srcFile("fixtures/click-iframes.html"))
+	frame := p.Element("iframe").Frame().Element("iframe").Frame()
+	btn := frame.Element("button")
+
+	// the margins and borders of the two iframes
+	box := btn.Box()
+	s.Greater(box.Left, float64(200))
+	s.Greater(box.Top, float64(200))
+	s.True(btn.Clickable())
+}
+
+func (s *S) TestIframeNavigated() {
+	p := s.page.Navigate(srcFile("fixtures/click-iframe.html"))
+	frame := p.Element("iframe").Frame()
+	frame.Element("button").Click()
+	s.True(frame.Has("[a=ok]"))
+
+	wait := frame.WaitFrameNavigated()
+	frame.Eval(`() => setTimeout(() => location.search = '?reloaded')`)
+	s.Contains(wait(), "click.html?reloaded")
+
+	// the js context of the frame is recreated
+	s.Equal("?reloaded", frame.Eval(`() => location.search`).String())
+	s.False(frame.Has("[a=ok]"))
+
+	p.Eval(`() => document.querySelector('iframe').remove()`)
+	_, err := frame.ElementE(nil, "", "button")
+	s.True(errors.Is(err, rod.ErrFrameDetached))
+}
+
+func (s *S) TestCrossOriginIframe() {
+	url, engine, close := serve()
+	defer close()
+
+	// the localhost and 127.0.0.1 are different sites, the iframe will be out-of-process
+	frameURL := strings.Replace(url, "127.0.0.1", "localhost", 1) + "/frame"
+
+	engine.GET("/frame", ginHTML(`<html><button onclick="this.setAttribute('a', 'ok')">click</button></html>`))
+	engine.GET("/", ginHTML(`<html><iframe style="margin: 100px" src="`+frameURL+`"></iframe></html>`))
+
+	p := s.browser.Page(url)
+	defer p.Close()
+
+	frame := p.Element("iframe").Frame()
+	s.Equal(p.TargetID, frame.Root().TargetID)
+
+	// the frame is controlled via the session of its own target
+	s.EqualValues(frame.FrameID, frame.TargetID)
+	s.NotEqual(p.SessionID, frame.SessionID)
+	info, err := proto.TargetGetTargetInfo{TargetID: frame.TargetID}.Call(p)
+	kit.E(err)
+	s.Equal(proto.TargetTargetInfoTypeIframe, info.TargetInfo.Type)
+
+	frame.Element("button").Click()
+	s.True(frame.Has("[a=ok]"))
+	s.Equal(frameURL, frame.Eval(`() => location.href`).String())
+}
+
 func (s *S) TestShadowDOM() {
 	p := s.page.Navigate(srcFile("fixtures/shadow-dom.html")).WaitLoad()
 	el := p.Element("#container").ShadowRoot()
 	s.Equal("inside", el.Element("p").Text())
 }
 
+func (s *S) TestElementDeep() {
+	p := s.page.Navigate(srcFile("fixtures/shadow-dom-deep.html")).WaitLoad()
+	body := p.Element("body")
+
+	s.Equal("deep", body.ElementDeep(".deep").Text())
+
+	_, err := body.ElementDeepE(".hidden")
+	s.True(rod.IsError(err, rod.ErrShadowRootClosed))
+
+	_, err = p.Element("#closed").ShadowRoot().ElementDeepE(".not-exists")
+	s.True(rod.IsError(err, rod.ErrElementNotFound))
+
+	_, err = p.Element("body").ShadowRootE()
+	s.True(rod.IsError(err, rod.ErrShadowRootNotFound))
+}
+
 func (s *S) TestPress() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	el := p.Element("[type=text]")
@@ -49,6 +167,208 @@ func (s *S) TestPress() {
 	s.Equal("A b", el.Text())
 }
 
+func (s *S) TestPressIntl() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("[type=text]")
+	el.Press('ä')
+	el.Press('Ö')
+	el.Press('雲')
+
+	s.Equal("äÖ雲", el.Text())
+
+	p = s.page.Navigate(srcFile("fixtures/keys.html"))
+	p.Element("body")
+	p.Keyboard.Down('ß')
+	s.True(p.Has("body[event=key-down-ß]"))
+}
+
+func (s *S) TestIMEInput() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("[type=text]")
+	el.Eval(`() => {
+		window.composition = []
+		for (const t of ['compositionstart', 'compositionupdate', 'compositionend']) {
+			this.addEventListener(t, e => window.composition.push(t.slice(11) + ':' + e.data))
+		}
+	}`)
+	el.Focus()
+
+	p.Keyboard.IMECompose([]string{"k", "か", "かn", "かん", "かんj", "かんじ", "漢字"}, "漢字")
+	s.Equal("漢字", el.Text())
+
+	events := []string{}
+	p.EvalInto(&events, `() => window.composition`)
+	s.Equal("start:", events[0])
+	s.Len(events, 9)
+	s.Equal("update:漢字", events[7])
+	s.Equal("end:漢字", events[8])
+
+	el.SelectAllText().Input("")
+	p.Keyboard.IMEInput("雲")
+	s.Equal("雲", el.Text())
+}
+
+func (s *S) TestCanvasAndMedia() {
+	p := s.page.Navigate(srcFile("fixtures/media.html"))
+
+	img, err := png.Decode(bytes.NewReader(p.Element("#chart").CanvasToImage("image/png", 1)))
+	kit.E(err)
+	r, g, b, _ := img.At(0, 0).RGBA()
+	s.Equal([]uint32{0xffff, 0, 0}, []uint32{r, g, b})
+	_, _, _, a := img.At(15, 5).RGBA()
+	s.EqualValues(0, a)
+
+	if p.Eval(`() => !!document.querySelector('#gl').getContext('webgl')`).Bool() {
+		img, err = png.Decode(bytes.NewReader(p.Element("#gl").CanvasToImage("image/png", 1)))
+		kit.E(err)
+		_, _, b, _ = img.At(0, 0).RGBA()
+		s.EqualValues(0xffff, b)
+
+		img, err = png.Decode(bytes.NewReader(p.Element("#gl").WebGLToImage("image/png", 1)))
+		kit.E(err)
+		_, _, b, _ = img.At(0, 0).RGBA()
+		s.EqualValues(0xffff, b)
+	}
+	_, err = p.Element("#chart").WebGLToImageE("image/png", 1)
+	s.True(errors.Is(err, rod.ErrNotWebGL))
+
+	// the reading shouldn't bind a context to the canvas that the page hasn't initialised yet
+	p.Element("#empty").CanvasToImage("image/png", 1)
+	_, err = p.Element("#empty").WebGLToImageE("image/png", 1)
+	s.True(errors.Is(err, rod.ErrNotWebGL))
+	s.True(p.Eval(`() => !!document.querySelector('#empty').getContext('2d')`).Bool())
+
+	audio := p.Element("#audio")
+	audio.Wait(`() => this.readyState >= 1`)
+
+	state := audio.MediaState()
+	s.InDelta(1, state.Duration, 0.1)
+	s.True(state.Paused)
+	s.Zero(state.VideoWidth)
+
+	s.True(audio.Pause().MediaState().Paused)
+
+	_, err = p.Element("#chart").MediaStateE()
+	s.True(errors.Is(err, rod.ErrNotMedia))
+	_, err = audio.CanvasToImageE("image/png", 1)
+	s.True(errors.Is(err, rod.ErrNotCanvas))
+}
+
+func (s *S) TestMediaAutoplayPolicy() {
+	play := func(policy string) error {
+		url := launcher.New().Set("autoplay-policy", policy).Launch()
+		b := rod.New().ControlURL(url).Connect()
+		defer b.Close()
+
+		audio := b.Page(srcFile("fixtures/media.html")).Element("#audio")
+		audio.Wait(`() => this.readyState >= 1`)
+		return audio.PlayE()
+	}
+
+	s.Nil(play("no-user-gesture-required"))
+
+	// the page hasn't been interacted by the user
+	s.True(errors.Is(play("document-user-activation-required"), rod.ErrMediaNotAllowed))
+}
+
+func (s *S) TestRange() {
+	p := s.page.Navigate(srcFile("fixtures/range.html"))
+
+	el := p.Element("#volume")
+	s.Equal(rod.SliderInfo{Min: 10, Max: 95, Step: 5, Value: 50}, *el.SliderInfo())
+
+	el.SetRange(33)
+	s.Equal("35", el.Eval(`() => this.value`).String())
+	s.Equal("input 35,change 35", p.Eval(`() => window.events.join()`).String())
+
+	// the max isn't on a step boundary
+	el.SetRange(1000)
+	s.Equal("90", el.Eval(`() => this.value`).String())
+
+	el.DragRange(20)
+	s.Equal("20", el.Eval(`() => this.value`).String())
+
+	vertical := p.Element("#vertical")
+	s.True(vertical.SliderInfo().Vertical)
+	vertical.DragRange(0.7)
+	s.Equal("0.7", vertical.Eval(`() => this.value`).String())
+
+	_, err := p.Element("#text").SliderInfoE()
+	s.True(errors.Is(err, rod.ErrInputType))
+}
+
+func (s *S) TestInteractable() {
+	p := s.page.Navigate(srcFile("fixtures/clickable.html"))
+
+	err := p.Element("#covered").ClickE(proto.InputMouseButtonLeft)
+	s.True(errors.Is(err, rod.ErrNotInteractable))
+	s.Contains(err.Error(), "covered by: html > body > div:nth-of-type(1)")
+
+	err = p.Element("#disabled").ClickE(proto.InputMouseButtonLeft)
+	s.True(errors.Is(err, rod.ErrNotInteractable))
+	s.Contains(err.Error(), "disabled")
+
+	err = p.Element("#readonly").InputE("test")
+	s.True(errors.Is(err, rod.ErrNotInteractable))
+	s.Contains(err.Error(), "readonly")
+
+	go func() {
+		kit.Sleep(0.3)
+		p.Eval(`() => document.querySelector('#disabled').disabled = false`)
+	}()
+	p.Element("#disabled").WaitEnabled().Click()
+	s.Equal("clicked", p.Element("#disabled").Text())
+
+	// the checks can be skipped
+	p.SkipInteractableCheck(true)
+	defer p.SkipInteractableCheck(false)
+	p.Element("#readonly").Input("test")
+	s.Equal("readonly", p.Element("#readonly").Eval(`() => this.value`).String())
+}
+
+func (s *S) TestDispatchEvent() {
+	p := s.page.Navigate(srcFile("fixtures/shadow-dom.html"))
+	p.Eval(`() => {
+		window.got = []
+		document.addEventListener('sl-change', e => window.got.push(e.type + ' ' + JSON.stringify(e.detail)))
+		document.addEventListener('keydown', e => {
+			window.got.push(e.key)
+			e.preventDefault()
+		})
+	}`)
+
+	el := p.Element("body")
+	s.False(el.DispatchEvent("sl-change", map[string]int{"value": 1}, &rod.EventOptions{Bubbles: true}))
+	s.True(el.DispatchEvent("keydown", nil, &rod.EventOptions{
+		Type:       "KeyboardEvent",
+		Bubbles:    true,
+		Cancelable: true,
+		Init:       map[string]interface{}{"key": "Enter"},
+	}))
+
+	// the event without the bubbles won't reach the document
+	el.DispatchEvent("sl-change", nil, nil)
+	s.Equal(`sl-change {"value":1},Enter`, p.Eval(`() => window.got.join()`).String())
+
+	// the composed event crosses the shadow root
+	inner := p.Element("#container").ShadowRoot().Element("p")
+	inner.DispatchEvent("sl-change", "shadow", &rod.EventOptions{Bubbles: true, Composed: true})
+	s.Equal(`sl-change {"value":1},Enter,sl-change "shadow"`, p.Eval(`() => window.got.join()`).String())
+
+	s.False(p.DispatchEvent("resize", nil, &rod.EventOptions{Type: "Event"}))
+
+	_, err := el.DispatchEventE("x", nil, &rod.EventOptions{Type: "Object"})
+	s.True(rod.IsError(err, rod.ErrEval))
+}
+
+func (s *S) TestType() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("textarea")
+	el.Type("Aä 1\n")
+
+	s.Equal("Aä 1\n", el.Text())
+}
+
 func (s *S) TestKeyDown() {
 	p := s.page.Navigate(srcFile("fixtures/keys.html"))
 	p.Element("body")
@@ -76,20 +396,144 @@ func (s *S) TestText() {
 	s.True(p.Has("[event=textarea-change]"))
 }
 
+func (s *S) TestWaitText() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	p.Eval(`() => setTimeout(() => {
+		document.querySelector('button').innerText = 'clicked  \n  ok'
+	}, 100)`)
+
+	el := p.WaitText("h4, button", "clicked")
+	s.Equal("BUTTON", el.Eval(`() => this.tagName`).String())
+
+	kit.E(el.WaitTextE("clicked ok", &rod.WaitTextOptions{NormalizeSpace: true}))
+	kit.E(el.WaitTextE(`^clicked\s+ok$`, &rod.WaitTextOptions{Regexp: true}))
+
+	err := p.Element("h4").Timeout(300*time.Millisecond).WaitTextE("none", nil)
+	s.True(errors.Is(err, rod.ErrWaitTimeout))
+	s.Contains(err.Error(), `"Title"`)
+
+	_, err = p.Timeout(300*time.Millisecond).WaitTextE("h4", "none", nil)
+	s.True(errors.Is(err, rod.ErrWaitTimeout))
+	s.Contains(err.Error(), `"Title"`)
+}
+
+func (s *S) TestInputTime() {
+	p := s.page.Navigate(srcFile("fixtures/input-time.html"))
+	t := time.Date(2020, 3, 4, 5, 6, 0, 0, time.UTC)
+
+	s.Equal("2020-03-04", *p.Element("#date").InputDate(t).Attribute("changed"))
+	s.Equal("05:06", *p.Element("#time").InputTime(t).Attribute("changed"))
+	s.Equal("05:06:07", *p.Element("#time").InputTime(t.Add(7 * time.Second)).Attribute("changed"))
+	s.Equal("2020-03-04T05:06", *p.Element("#datetime").InputDate(t).Attribute("changed"))
+	s.Equal("2020-03", *p.Element("#month").InputDate(t).Attribute("changed"))
+	s.Equal("2020-W10", *p.Element("#week").InputDate(t).Attribute("changed"))
+
+	err := p.Element("#date").InputDateE(t.AddDate(1, 0, 0))
+	s.True(errors.Is(err, rod.ErrInputInvalid))
+	s.Equal("2020-03-04", p.Element("#date").Eval(`() => this.value`).String())
+
+	err = p.Element("#time").InputDateE(t)
+	s.True(errors.Is(err, rod.ErrInputType))
+	err = p.Element("#text").InputTimeE(t)
+	s.True(errors.Is(err, rod.ErrInputType))
+}
+
 func (s *S) TestSelectText() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	el := p.Element("textarea")
 	el.Input("test")
 	el.SelectAllText()
-	el.Input("test")
+	el.Append("test")
 	s.Equal("test", el.Text())
 
 	el.SelectText(`es`)
-	el.Input("__")
+	el.Append("__")
 
 	s.Equal("t__t", el.Text())
 }
 
+func (s *S) TestInputClear() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("[type=text]")
+	el.Input("abc")
+	el.Input("test")
+	s.Equal("test", el.Text())
+
+	el.Append("ok")
+	s.Equal("testok", el.Text())
+
+	el.Input("")
+	s.Equal("", el.Text())
+}
+
+func (s *S) TestInputKeystrokes() {
+	p := s.browser.Page(srcFile("fixtures/input.html"))
+	defer p.Close()
+
+	el := p.InputKeystrokes(true).Element("[type=text]")
+	el.Eval(`() => this.addEventListener('keydown', e => window.keys = (window.keys || '') + e.key)`)
+	el.Input("abc")
+	s.Equal("abc", el.Text())
+	s.Equal("abc", p.Eval(`() => window.keys`).String())
+
+	el.Append("ä")
+	s.Equal("abcä", el.Text())
+}
+
+func (s *S) TestKeyboardPressCombination() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("textarea")
+	el.Input("test")
+
+	p.Keyboard.PressCombination(input.CommandOrControl, 'a')
+	p.Keyboard.Press(input.Backspace)
+	s.Equal("", el.Text())
+
+	el.Input("abc")
+	p.Keyboard.PressCombination(input.Shift, input.ArrowLeft)
+	s.Equal("c", el.Eval(`() => this.value.slice(this.selectionStart, this.selectionEnd)`).String())
+
+	mac := s.browser.Page("")
+	defer mac.Close()
+	mac.SetUserAgent(&proto.NetworkSetUserAgentOverride{
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_4) AppleWebKit/537.36 (KHTML, like Gecko)",
+		Platform:  "MacIntel",
+	}).Navigate(srcFile("fixtures/input.html"))
+	s.Equal("MacIntel", mac.Eval(`() => navigator.platform`).String())
+
+	el = mac.Element("textarea")
+	el.Input("test")
+	mac.Keyboard.PressCombination(input.Meta, 'a')
+	s.Equal("test", el.Eval(`() => this.value.slice(this.selectionStart, this.selectionEnd)`).String())
+
+	mac.Keyboard.PressCombination(input.CommandOrControl, 'a')
+	mac.Keyboard.Press(input.Backspace)
+	s.Equal("", el.Text())
+}
+
+func (s *S) TestElementCheck() {
+	p := s.page.Navigate(srcFile("fixtures/check.html"))
+
+	el := p.Element("#checkbox")
+	s.False(el.Checked())
+
+	el.Check().Check()
+	s.True(el.Checked())
+	s.True(p.Has("body[event=change-true]"))
+
+	el.Uncheck().Uncheck()
+	s.False(el.Checked())
+	s.True(p.Has("body[event=change-false]"))
+
+	a := p.Element("#radio-a").Check()
+	s.True(a.Checked())
+	p.Element("#radio-b").Check()
+	s.False(a.Checked())
+
+	s.True(errors.Is(a.UncheckE(), rod.ErrUncheckRadio))
+}
+
 func (s *S) TestSelect() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	el := p.Element("select")
@@ -98,6 +542,31 @@ func (s *S) TestSelect() {
 	s.EqualValues(2, el.Eval("() => this.selectedIndex").Int())
 }
 
+func (s *S) TestSelectByTextValueIndex() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("select")
+
+	el.SelectByText("C")
+	s.EqualValues(2, el.Eval("() => this.selectedIndex").Int())
+
+	kit.E(el.SelectE([]string{"b"}, false))
+	s.Equal([]string{"b"}, el.SelectedOptions())
+
+	el.SelectIndex(3)
+	s.EqualValues(3, el.Eval("() => this.selectedIndex").Int())
+	s.True(rod.IsError(el.SelectIndexE([]int{10}), rod.ErrOptionNotFound))
+}
+
+func (s *S) TestSelectMultiple() {
+	p := s.page.Navigate(`data:text/html,<select multiple onchange="this.dataset.changed = true">
+		<option value="a">A</option><option value="b">B</option><option value="c">C</option></select>`)
+	el := p.Element("select")
+
+	el.SelectByText("A", "C")
+	s.Equal([]string{"a", "c"}, el.SelectedOptions())
+	s.Equal("true", *el.Attribute("data-changed"))
+}
+
 func (s *S) TestSetFiles() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	el := p.Element(`[type=file]`)
@@ -111,6 +580,21 @@ func (s *S) TestSetFiles() {
 	s.Equal("alert.html", list[1].String())
 }
 
+func (s *S) TestSetFilesInIframe() {
+	p := s.page.Navigate(`data:text/html,<iframe srcdoc="<input type=file multiple
+		onchange='document.body.dataset.files = Array.from(this.files).map(f => f.name).join()'>"></iframe>`)
+
+	frame := p.Element("iframe").Frame()
+	frame.Element("input").SetFiles(
+		slash("fixtures/click.html"),
+		slash("fixtures/alert.html"),
+	)
+	s.Equal("click.html,alert.html", frame.Eval(`() => document.body.dataset.files`).String())
+
+	err := frame.Element("input").SetFilesE([]string{slash("fixtures/not-exists.html")})
+	s.True(os.IsNotExist(err))
+}
+
 func (s *S) TestSelectQuery() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	el := p.Element("select")
@@ -122,8 +606,10 @@ func (s *S) TestSelectQuery() {
 func (s *S) TestSelectQueryNum() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	el := p.Element("select")
-	el.Select("123")
+	err := el.SelectE([]string{"123"}, false)
 
+	s.True(rod.IsError(err, rod.ErrOptionNotFound))
+	s.Contains(err.Error(), `"value":"a"`)
 	s.EqualValues(0, el.Eval("() => this.selectedIndex").Int())
 }
 
@@ -168,6 +654,57 @@ func (s *S) TestWaitStable() {
 	p.Has("[event=click]")
 }
 
+func (s *S) TestWaitStableDetached() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	el := p.Element("button")
+
+	el.Eval(`() => this.remove()`)
+
+	err := el.WaitStableE(time.Second)
+	s.True(rod.IsError(err, rod.ErrElementDetached))
+
+	err = el.WaitVisibleE()
+	s.True(rod.IsError(err, rod.ErrElementDetached))
+
+	// a removed element is invisible
+	el.WaitInvisible()
+}
+
+func (s *S) TestClickWithoutWaitStable() {
+	s.browser.WaitStableBeforeClick(0)
+	defer s.browser.WaitStableBeforeClick(100 * time.Millisecond)
+
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	p.Element("button").Click()
+	s.True(p.Has("[a=ok]"))
+}
+
+func (s *S) TestElementAttributeProperty() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	el := p.Element("input")
+
+	s.Nil(el.Attribute("data-none"))
+
+	el.Eval(`() => this.setAttribute("data-a", "ok")`)
+	s.Equal("ok", *el.Attribute("data-a"))
+
+	el.Input("abc")
+	s.Equal("abc", el.Property("value").String())
+	s.Equal("INPUT", el.Property("tagName").String())
+
+	s.Contains(el.HTML(), `data-a="ok"`)
+	s.Contains(p.HTML(), "<html")
+
+	el.Eval(`() => this.remove()`)
+
+	_, err := el.AttributeE("data-a")
+	s.True(rod.IsError(err, rod.ErrElementDetached))
+	_, err = el.PropertyE("value")
+	s.True(rod.IsError(err, rod.ErrElementDetached))
+	_, err = el.TextE()
+	s.True(rod.IsError(err, rod.ErrElementDetached))
+}
+
 func (s *S) TestResource() {
 	p := s.page.Navigate(srcFile("fixtures/resource.html"))
 	s.Equal(15456, len(p.Element("img").Resource()))
@@ -185,6 +722,17 @@ func (s *S) TestElementScreenshot() {
 	s.FileExists(f)
 }
 
+func (s *S) TestElementScreenshotJPEG() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	data, err := p.Element("h4").ScreenshotE(proto.PageCaptureScreenshotFormatJpeg, 10)
+	kit.E(err)
+	img, err := jpeg.Decode(bytes.NewBuffer(data))
+	kit.E(err)
+	s.EqualValues(200, img.Bounds().Dx())
+	s.EqualValues(30, img.Bounds().Dy())
+}
+
 func (s *S) TestUseReleasedElement() {
 	p := s.page.Navigate(srcFile("fixtures/click.html"))
 	btn := p.Element("button")
@@ -242,7 +790,7 @@ func (s *S) TestElementErrors() {
 	err = el.Context(ctx).InputE("a")
 	s.Error(err)
 
-	err = el.Context(ctx).SelectE([]string{"a"})
+	err = el.Context(ctx).SelectE([]string{"a"}, false)
 	s.Error(err)
 
 	err = el.Context(ctx).WaitStableE(0)
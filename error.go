@@ -1,6 +1,14 @@
 package rod
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
 
 // ErrCode for errors
 type ErrCode string
@@ -12,14 +20,124 @@ const (
 	ErrExpectElements ErrCode = "expect js to return an array of elements"
 	// ErrElementNotFound error code
 	ErrElementNotFound ErrCode = "cannot find element"
+	// ErrElementDetached error code
+	ErrElementDetached ErrCode = "element is detached from the document"
+	// ErrNotInteractable error code
+	ErrNotInteractable ErrCode = "element is not interactable"
+	// ErrShadowRootNotFound error code
+	ErrShadowRootNotFound ErrCode = "element doesn't have a shadow root"
+	// ErrShadowRootClosed error code
+	ErrShadowRootClosed ErrCode = "cannot find element, some shadow roots are closed"
+	// ErrOptionNotFound error code
+	ErrOptionNotFound ErrCode = "cannot find the option to select"
+	// ErrCSPBlocked error code
+	ErrCSPBlocked ErrCode = "blocked by the Content-Security-Policy of the page"
+	// ErrFrameNotFound error code
+	ErrFrameNotFound ErrCode = "cannot find the iframe"
+	// ErrFrameDetached error code
+	ErrFrameDetached ErrCode = "the iframe is detached from the page"
+	// ErrAXNodeNotFound error code
+	ErrAXNodeNotFound ErrCode = "cannot find the accessibility node"
+	// ErrAXVirtualNode error code
+	ErrAXVirtualNode ErrCode = "the accessibility node has no dom node"
 	// ErrSrcNotFound error code
 	ErrSrcNotFound ErrCode = "element doesn't have src attribute"
 	// ErrEval error code
 	ErrEval ErrCode = "eval error"
+	// ErrWaitTimeout error code
+	ErrWaitTimeout ErrCode = "the js still returns a falsy value when the context is done"
+	// ErrWaitFunctionUsed error code
+	ErrWaitFunctionUsed ErrCode = "the wait function can only be used once"
 	// ErrNavigation error code
 	ErrNavigation ErrCode = "navigation failed"
+	// ErrNoHistoryEntry error code
+	ErrNoHistoryEntry ErrCode = "no history entry to navigate to"
+	// ErrFunctionExposed error code
+	ErrFunctionExposed ErrCode = "function is already exposed"
+	// ErrFunctionNotExposed error code
+	ErrFunctionNotExposed ErrCode = "function is not exposed"
+	// ErrRequestFailed error code
+	ErrRequestFailed ErrCode = "request failed"
+	// ErrAuthFailed error code
+	ErrAuthFailed ErrCode = "failed to pass the auth challenge"
+	// ErrDownloadCanceled error code
+	ErrDownloadCanceled ErrCode = "the download is canceled"
+	// ErrNotFocused error code
+	ErrNotFocused ErrCode = "the document is not focused"
+	// ErrDefaultBrowserContext error code
+	ErrDefaultBrowserContext ErrCode = "the default browser context can't be closed"
+	// ErrCoverageStarted error code
+	ErrCoverageStarted ErrCode = "coverage is already started"
+	// ErrCoverageNotStarted error code
+	ErrCoverageNotStarted ErrCode = "coverage is not started"
+	// ErrTracingStarted error code
+	ErrTracingStarted ErrCode = "tracing is already started"
+	// ErrTracingNotStarted error code
+	ErrTracingNotStarted ErrCode = "tracing is not started"
+	// ErrTracingTimeout error code
+	ErrTracingTimeout ErrCode = "timeout waiting for the tracing to complete"
+	// ErrUncheckRadio error code
+	ErrUncheckRadio ErrCode = "a radio button can't be unchecked by clicking it"
+	// ErrCheckFailed error code
+	ErrCheckFailed ErrCode = "the checked state of the element didn't change after the click"
+	// ErrInvalidPageRanges error code
+	ErrInvalidPageRanges ErrCode = "the page ranges should be like \"1-3,5\", and the pages start from 1"
+	// ErrDialogPending error code
+	ErrDialogPending ErrCode = "a javascript dialog is blocking the page, use the HandleDialog or EachDialog to answer it"
+	// ErrNotFrame error code
+	ErrNotFrame ErrCode = "the element is not an iframe"
+	// ErrInputType error code
+	ErrInputType ErrCode = "the type of the input element doesn't accept the value"
+	// ErrInputInvalid error code
+	ErrInputInvalid ErrCode = "the input element rejects the value"
+	// ErrPageCrashed error code
+	ErrPageCrashed ErrCode = "the renderer of the page has crashed"
+	// ErrNoParent error code
+	ErrNoParent ErrCode = "the element has no parent element, such as the root of the document"
+	// ErrFormValue error code
+	ErrFormValue ErrCode = "the type of the value doesn't match the form control"
+	// ErrStorageAccess error code
+	ErrStorageAccess ErrCode = "the page can't access the storage"
+	// ErrNavigationStatus error code
+	ErrNavigationStatus ErrCode = "the server responds the navigation with an error status"
+	// ErrEvalValue error code
+	ErrEvalValue ErrCode = "the result of the js can't be unmarshaled into the destination"
+	// ErrPageDetached error code
+	ErrPageDetached ErrCode = "the session of the page is detached, such as the DevTools is opened on it"
+	// ErrTargetGone error code
+	ErrTargetGone ErrCode = "the target of the page is destroyed"
+	// ErrPageNotFound error code
+	ErrPageNotFound ErrCode = "no page matches"
+	// ErrIMEUnsupported error code
+	ErrIMEUnsupported ErrCode = "the browser doesn't support the composition of the input method"
+	// ErrNotCanvas error code
+	ErrNotCanvas ErrCode = "the element is not a canvas"
+	// ErrCanvasTainted error code
+	ErrCanvasTainted ErrCode = "the canvas is tainted by the cross-origin data"
+	// ErrNotMedia error code
+	ErrNotMedia ErrCode = "the element is not a video or audio"
+	// ErrMediaNotAllowed error code
+	ErrMediaNotAllowed ErrCode = "the autoplay policy of the browser doesn't allow the media to play"
+	// ErrFileChooserMode error code
+	ErrFileChooserMode ErrCode = "the file chooser only accepts a single file"
+	// ErrScrollPosition error code
+	ErrScrollPosition ErrCode = "the page isn't scrolled to the position"
+	// ErrCallFailed error code
+	ErrCallFailed ErrCode = "the call of the method failed"
+	// ErrHeaderDict error code
+	ErrHeaderDict ErrCode = "the dict of the headers should be the pairs of the name and the value"
+	// ErrPoolClosed error code
+	ErrPoolClosed ErrCode = "the page pool is closed"
+	// ErrNotWebGL error code
+	ErrNotWebGL ErrCode = "the canvas has no webgl context"
 )
 
+// Error implements the error interface, so that the error codes can be used as the sentinel errors,
+// such as errors.Is(err, rod.ErrNavigation)
+func (c ErrCode) Error() string {
+	return string(c)
+}
+
 // Error ...
 type Error struct {
 	Err     error
@@ -37,16 +155,123 @@ func (e *Error) Unwrap() error {
 	return e.Err
 }
 
-// IsError type matches
-func IsError(err error, code ErrCode) bool {
-	if err == nil {
-		return false
+// Is makes errors.Is match the code of the error
+func (e *Error) Is(target error) bool {
+	code, ok := target.(ErrCode)
+	return ok && e.Code == code
+}
+
+// CallError is returned when the browser rejects a proto call or the call reaches the deadline of the context,
+// it wraps the *cdp.Error or the context.DeadlineExceeded
+type CallError struct {
+	// Method of the proto call, such as "Page.navigate"
+	Method string
+
+	// TargetID is empty for the browser level calls
+	TargetID  proto.TargetTargetID
+	SessionID proto.TargetSessionID
+
+	Err error
+}
+
+// Error ...
+func (e *CallError) Error() string {
+	if e.TargetID == "" {
+		return fmt.Sprintf("[rod] %s failed: %v", e.Method, e.Err)
+	}
+	return fmt.Sprintf("[rod] %s failed on target %s (session %s): %v", e.Method, e.TargetID, e.SessionID, e.Err)
+}
+
+// Unwrap ...
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// EvalBatchError is returned by the EvalBatchE when some of the calls fail
+type EvalBatchError struct {
+	// Errors of the calls in the same order as the calls, nil means the call succeeded
+	Errors []error
+}
+
+// Error ...
+func (e *EvalBatchError) Error() string {
+	list := []string{}
+	for i, err := range e.Errors {
+		if err != nil {
+			list = append(list, fmt.Sprintf("call %d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("[rod] %d of %d eval calls failed\n%s", len(list), len(e.Errors), strings.Join(list, "\n"))
+}
+
+// Unwrap returns the error of the first failed call
+func (e *EvalBatchError) Unwrap() error {
+	for _, err := range e.Errors {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsNilContextError returns true if the err is caused by a js execution context that doesn't exist anymore,
+// such as the context is destroyed by a navigation.
+func IsNilContextError(err error) bool {
+	var cdpErr *cdp.Error
+	return errors.As(err, &cdpErr) && cdpErr.Code == -32000
+}
+
+// callClient wraps the cdp errors with the context of the call
+type callClient struct {
+	client   proto.Client
+	targetID proto.TargetTargetID
+	crash    *crashState  // nil for the browser level calls
+	attach   *attachState // nil for the browser level calls
+}
+
+func (c *callClient) Call(ctx context.Context, sessionID, method string, params interface{}) ([]byte, error) {
+	// the crashed renderer won't respond, fail fast instead of waiting for the deadline
+	if err := c.crash.err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.crash.context(ctx)
+	defer cancel()
+
+	// the browser rejects the calls of a detached session, use the new session if it's reattached
+	if c.attach.err() != nil {
+		id, err := c.attach.reattach()
+		if err != nil {
+			return nil, err
+		}
+		sessionID = string(id)
+	}
+
+	res, err := c.client.Call(ctx, sessionID, method, params)
+	if e := c.crash.err(); err != nil && e != nil {
+		return nil, e
 	}
 
-	e, ok := err.(*Error)
-	if !ok {
-		return false
+	// the detach may be reported after the call is sent, retry once on the new session
+	if err != nil && c.attach.err() != nil {
+		id, e := c.attach.reattach()
+		if e != nil {
+			return nil, e
+		}
+		if string(id) != sessionID {
+			res, err = c.client.Call(ctx, string(id), method, params)
+		}
 	}
 
-	return e.Code == code
+	// the deadline errors are wrapped too, so that we know which call timed out
+	var cdpErr *cdp.Error
+	if errors.As(err, &cdpErr) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, &CallError{method, c.targetID, proto.TargetSessionID(sessionID), err}
+	}
+	return res, err
+}
+
+// IsError returns true if the err or one of the errors it wraps has the code, such as the error that a sugar method
+// panics with wraps the error of the *E method
+func IsError(err error, code ErrCode) bool {
+	return err != nil && errors.Is(err, code)
 }
@@ -0,0 +1,246 @@
+// This file contains the batching of the js calls, the typed results of them, and the tracker of the remote objects
+// that are handed out.
+
+package rod
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// EvalCall is a js call of the EvalBatchE, the fields are the same as the params of the EvalE
+type EvalCall struct {
+	ByValue bool
+	ThisID  proto.RuntimeRemoteObjectID
+	JS      string
+	Args    Array
+}
+
+// EvalBatchE sends all the calls without waiting for the response of each one, so the round trips of the calls
+// overlap. The results are in the same order as the calls. If some of the calls fail, the results of them will be nil
+// and an *EvalBatchError will be returned with the results of the others, so one failing call won't mask the others.
+func (p *Page) EvalBatchE(calls []*EvalCall) ([]*proto.RuntimeRemoteObject, error) {
+	defer p.tryTrace(0, 0, 300, 0, "eval batch of "+strconv.Itoa(len(calls))+" calls")()
+
+	list := make([]*proto.RuntimeRemoteObject, len(calls))
+	errs := make([]error, len(calls))
+
+	// the calls share the window object, create it before they are sent
+	if p.windowObjectID == "" {
+		_, err := p.evalE(true, "", `() => {}`, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	windowObjectID := p.windowObjectID
+
+	wg := &sync.WaitGroup{}
+	for i, c := range calls {
+		wg.Add(1)
+		go func(i int, c *EvalCall) {
+			defer wg.Done()
+
+			objectID := c.ThisID
+			if objectID == "" {
+				objectID = windowObjectID
+			}
+
+			res, err := callFunctionOn(objectID, c.ByValue, c.JS, c.Args).Call(p)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			list[i], errs[i] = evalResult(res)
+		}(i, c)
+	}
+	wg.Wait()
+
+	failed := false
+	for i, c := range calls {
+		// the js context is destroyed during the batch, such as a navigation, retry the call in the new one
+		if c.ThisID == "" && IsNilContextError(errs[i]) {
+			list[i], errs[i] = p.evalE(c.ByValue, "", c.JS, c.Args)
+		}
+
+		if errs[i] != nil {
+			failed = true
+			continue
+		}
+		p.objects.add(list[i], c.JS)
+	}
+
+	if failed {
+		return list, &EvalBatchError{errs}
+	}
+	return list, nil
+}
+
+// EvalIntoE evaluates the js like the EvalE with byValue, and unmarshals the result into dst with json.Unmarshal,
+// such as an array into a slice and an object into a struct or map. The promise returned by the js will be awaited.
+// If the result is null or undefined an ErrEvalValue error will be returned, unless dst is a pointer to pointer,
+// then the pointer will be set to nil.
+func (p *Page) EvalIntoE(dst interface{}, js string, args Array) error {
+	return p.evalInto(dst, "", js, args)
+}
+
+// EvalIntoE is the same as the Page.EvalIntoE, the element will be the this of the js
+func (el *Element) EvalIntoE(dst interface{}, js string, args Array) error {
+	return el.page.Context(el.ctx).evalInto(dst, el.ObjectID, js, args)
+}
+
+func (p *Page) evalInto(dst interface{}, thisID proto.RuntimeRemoteObjectID, js string, args Array) error {
+	t := reflect.TypeOf(dst)
+	if t == nil || t.Kind() != reflect.Ptr || reflect.ValueOf(dst).IsNil() {
+		return &Error{nil, ErrEvalValue, fmt.Sprintf("dst must be a non-nil pointer, got %T", dst)}
+	}
+
+	res, err := p.EvalE(true, thisID, js, args)
+	if err != nil {
+		return err
+	}
+
+	raw := res.Value.Raw
+	if res.Type == proto.RuntimeRemoteObjectTypeUndefined {
+		raw = "undefined"
+	}
+
+	if raw == "null" || raw == "undefined" || raw == "" {
+		if t.Elem().Kind() != reflect.Ptr {
+			return &Error{nil, ErrEvalValue, fmt.Sprintf("%s returns %s for %T", js, raw, dst)}
+		}
+		reflect.ValueOf(dst).Elem().Set(reflect.Zero(t.Elem()))
+		return nil
+	}
+
+	err = json.Unmarshal([]byte(raw), dst)
+	if err != nil {
+		return &Error{err, ErrEvalValue, fmt.Sprintf("%s returns %s for %T", js, raw, dst)}
+	}
+	return nil
+}
+
+// ObjectLeak is a remote object that isn't released before the page is closed
+type ObjectLeak struct {
+	ObjectID proto.RuntimeRemoteObjectID
+
+	// Description of the object, such as "div#id.class", it's empty for the ElementFromObjectID
+	Description string
+
+	// JS that created the object, it's empty for the ElementFromObjectID
+	JS string
+
+	seq int
+}
+
+// objectTracker is shared by all the clones of a page
+type objectTracker struct {
+	lock sync.Mutex
+
+	// nil means the tracker is disabled
+	report func([]*ObjectLeak)
+
+	list map[proto.RuntimeRemoteObjectID]*ObjectLeak
+	seq  int
+}
+
+func (t *objectTracker) add(obj *proto.RuntimeRemoteObject, js string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.report == nil || obj == nil || obj.ObjectID == "" {
+		return
+	}
+	if _, has := t.list[obj.ObjectID]; has {
+		return
+	}
+
+	t.seq++
+	t.list[obj.ObjectID] = &ObjectLeak{
+		ObjectID:    obj.ObjectID,
+		Description: obj.Description,
+		JS:          js,
+		seq:         t.seq,
+	}
+}
+
+func (t *objectTracker) remove(id proto.RuntimeRemoteObjectID) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.list, id)
+}
+
+// leaks returns the tracked objects in the order they are handed out
+func (t *objectTracker) leaks() []*ObjectLeak {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	list := []*ObjectLeak{}
+	for _, l := range t.list {
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].seq < list[j].seq
+	})
+	return list
+}
+
+func (t *objectTracker) reportLeaks() {
+	t.lock.Lock()
+	report := t.report
+	t.lock.Unlock()
+
+	if report == nil {
+		return
+	}
+
+	if list := t.leaks(); len(list) > 0 {
+		report(list)
+	}
+}
+
+// TrackObjects records the remote objects handed out by the EvalE, EvalBatchE, and ElementFromObjectID, such as the
+// elements returned by the ElementE, until they are released. When the page is closed, report will be called with
+// the ones that are not released. The objects invalidated by the navigations will be reported too, because the browser
+// doesn't tell which objects it drops. Use nil to disable the tracker, the recorded objects will be cleared.
+func (p *Page) TrackObjects(report func(leaks []*ObjectLeak)) *Page {
+	t := p.objects
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.report = report
+	t.list = map[proto.RuntimeRemoteObjectID]*ObjectLeak{}
+	return p
+}
+
+// TrackedObjects returns the objects recorded by the TrackObjects that are not released yet
+func (p *Page) TrackedObjects() []*ObjectLeak {
+	return p.objects.leaks()
+}
+
+// ReleaseAllE releases all the objects recorded by the TrackObjects, the elements of them can't be used after it.
+// It tries to release all of them and returns the first error, the objects already dropped by the browser are ignored.
+func (p *Page) ReleaseAllE() error {
+	var first error
+	for _, l := range p.objects.leaks() {
+		err := p.ReleaseE(l.ObjectID)
+
+		// such as the object is dropped by a navigation, it won't leak anymore
+		if IsNilContextError(err) {
+			p.objects.remove(l.ObjectID)
+			continue
+		}
+
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
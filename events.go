@@ -0,0 +1,123 @@
+// This file contains the code to record and replay the cdp events, it helps to debug the failures on CI.
+
+package rod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+)
+
+// EventRecord is a line of the json lines written by the RecordEventsE
+type EventRecord struct {
+	Time      time.Time       `json:"time"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params,omitempty"`
+}
+
+// RecordEventsE writes every cdp event the browser receives to w as json lines of EventRecord, in order.
+// If paramsLimit is greater than 0, the string values of the params that are longer than it will be truncated,
+// such as the screenshot data and the response bodies. The stop function stops the recording and returns
+// the first error of the writing.
+func (b *Browser) RecordEventsE(w io.Writer, paramsLimit int) (stop func() error) {
+	ctx, cancel := context.WithCancel(b.ctx)
+	s := b.event.Subscribe(ctx)
+
+	var writeErr error
+	done := make(chan kit.Nil)
+
+	// only this goroutine writes to w, so the events from different sessions won't interleave
+	go func() {
+		defer close(done)
+
+		goob.Each(s, func(msg *cdp.Event) {
+			if writeErr != nil {
+				return
+			}
+
+			line := kit.MustToJSONBytes(EventRecord{
+				Time:      time.Now(),
+				SessionID: msg.SessionID,
+				Method:    msg.Method,
+				Params:    truncateParams(msg.Params, paramsLimit),
+			})
+			_, writeErr = w.Write(append(line, '\n'))
+		})
+	}()
+
+	return func() error {
+		cancel()
+		<-done
+		return writeErr
+	}
+}
+
+// ReplayEvents publishes the events recorded by the RecordEventsE to ob in order, such as a goob.New(),
+// so that the code that waits for the events can be tested without a real browser.
+// It returns when r reaches the end.
+func ReplayEvents(r io.Reader, ob *goob.Observable) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record EventRecord
+		err := json.Unmarshal(line, &record)
+		if err != nil {
+			return err
+		}
+
+		ob.Publish(&cdp.Event{
+			SessionID: record.SessionID,
+			Method:    record.Method,
+			Params:    record.Params,
+		})
+	}
+	return scanner.Err()
+}
+
+// truncateParams truncates the string values of the params that are longer than the limit
+func truncateParams(params json.RawMessage, limit int) json.RawMessage {
+	if limit <= 0 || len(params) <= limit {
+		return params
+	}
+
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(params))
+	d.UseNumber()
+	if d.Decode(&v) != nil {
+		return params
+	}
+
+	return kit.MustToJSONBytes(truncateStrings(v, limit))
+}
+
+func truncateStrings(v interface{}, limit int) interface{} {
+	switch val := v.(type) {
+	case string:
+		if len(val) > limit {
+			return val[:limit] + "...(truncated)"
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = truncateStrings(item, limit)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = truncateStrings(item, limit)
+		}
+	}
+	return v
+}
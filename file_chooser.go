@@ -0,0 +1,116 @@
+// This file contains the interception of the native file chooser, such as the upload widgets that open it via
+// a hidden input. The interception is per session, so the helpers must register themselves via the fileChooserState,
+// otherwise one of them may disable the interception that another one still waits for.
+
+package rod
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// fileChooserState is shared by all the clones of a page
+type fileChooserState struct {
+	lock sync.Mutex
+
+	// the number of the HandleFileDialogE that are waiting, the interception is enabled if it's not 0
+	armed int
+}
+
+// fileChooserCaller uses the browser context, so that the interception can be disabled after the page context is done
+type fileChooserCaller struct {
+	page *Page
+}
+
+// CallContext interface
+func (c fileChooserCaller) CallContext() (context.Context, proto.Client, string) {
+	client, id := c.page.sessionClient()
+	return c.page.browser.ctx, client, id
+}
+
+// armFileChooser adds delta to the armed, and enables or disables the interception when it changes from or to 0
+func (p *Page) armFileChooser(delta int) error {
+	s := p.fileChooser
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prev := s.armed
+	s.armed += delta
+	if (prev == 0) == (s.armed == 0) {
+		return nil
+	}
+	return proto.PageSetInterceptFileChooserDialog{Enabled: s.armed > 0}.Call(fileChooserCaller{p})
+}
+
+// HandleFileDialogE returns a wait function that sets the files of the next file chooser the page opens, such as the
+// one opened by the click on an upload button. Call it before the action that opens the chooser, the same as the
+// HandleDialogE. The chooser won't show up while it's armed. If the chooser only accepts a single file and there are
+// several paths, an ErrFileChooserMode error will be returned. The interception is disabled after the wait function
+// returns, or after the context of the page is done even if the wait function is never called.
+func (p *Page) HandleFileDialogE(paths []string) func() error {
+	absPaths, err := absFilePaths(paths)
+	if err != nil {
+		return func() error { return err }
+	}
+
+	// subscribe before the interception, so that we won't miss the event
+	ctx, cancel := p.crash.context(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err = p.armFileChooser(1)
+	if err != nil {
+		cancel()
+		return func() error { return err }
+	}
+
+	var once sync.Once
+	var disarmErr error
+	disarm := func() error {
+		once.Do(func() {
+			cancel()
+			disarmErr = p.armFileChooser(-1)
+		})
+		return disarmErr
+	}
+	go func() {
+		<-ctx.Done()
+		_ = disarm()
+	}()
+
+	return func() error {
+		err := p.waitFileChooser(s, absPaths)
+		if e := disarm(); err == nil {
+			err = e
+		}
+		return err
+	}
+}
+
+func (p *Page) waitFileChooser(s chan goob.Event, paths []string) error {
+	for msg := range s {
+		e := &proto.PageFileChooserOpened{}
+		if !Event(msg.(*cdp.Event), e) {
+			continue
+		}
+
+		if e.Mode == proto.PageFileChooserOpenedModeSelectSingle && len(paths) > 1 {
+			return &Error{nil, ErrFileChooserMode, paths}
+		}
+
+		defer p.tryTrace(0, 0, 300, 0, fmt.Sprintf("file chooser: %v", paths))()
+		return proto.DOMSetFileInputFiles{
+			Files:         paths,
+			BackendNodeID: e.BackendNodeID,
+		}.Call(p)
+	}
+
+	if err := p.crash.err(); err != nil {
+		return err
+	}
+	return p.ctx.Err()
+}
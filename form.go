@@ -0,0 +1,196 @@
+// This file contains the helpers to fill the forms, each field is dispatched to the input method of its control type.
+
+package rod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// FormOptions of the FillFormE
+type FormOptions struct {
+	// Form is the css selector of the form, if it's set the keys of the fields will be matched with the name
+	// attributes of the controls in the form first, then with the css selectors inside the form
+	Form string
+
+	// Submit the form after all the fields are filled, the form.requestSubmit is used, so the validation and
+	// the submit event handlers of the page will run like clicking the submit button
+	Submit bool
+
+	// WaitNavigation is the lifecycle event of the navigation to wait for after the submit, such as "load",
+	// empty means not to wait
+	WaitNavigation proto.PageLifecycleEventName
+}
+
+// FormError is returned by the FillFormE when some of the fields fail
+type FormError struct {
+	// Errors of the fields, the keys are the same as the ones of the fields
+	Errors map[string]error
+}
+
+// Error ...
+func (e *FormError) Error() string {
+	keys := []string{}
+	for k := range e.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := []string{}
+	for _, k := range keys {
+		list = append(list, fmt.Sprintf("%s: %v", k, e.Errors[k]))
+	}
+	return fmt.Sprintf("[rod] %d form fields failed\n%s", len(list), strings.Join(list, "\n"))
+}
+
+// FillFormE fills the fields, the keys are the css selectors of the controls, the values can be:
+//
+//	string       for the text inputs, textareas, selects, file inputs, and the radio of the group with the value
+//	[]string     for the multiple selects and the file inputs
+//	bool         for the checkboxes and radios
+//	time.Time    for the date, time, month, week, and datetime-local inputs
+//
+// The controls are not waited, they should already exist. All the fields will be tried, if some of them fail
+// a *FormError will be returned, and the form won't be submitted. If opts is nil the defaults will be used.
+func (p *Page) FillFormE(fields map[string]interface{}, opts *FormOptions) error {
+	if opts == nil {
+		opts = &FormOptions{}
+	}
+
+	var form *Element
+	if opts.Form != "" {
+		var err error
+		form, err = p.ElementE(nil, "", opts.Form)
+		if err != nil {
+			return err
+		}
+	}
+
+	// fill the fields in a stable order, because the page may react to the changes of the fields
+	keys := []string{}
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	errs := map[string]error{}
+	var last *Element
+	for _, key := range keys {
+		el, err := p.formControl(form, key)
+		if err == nil {
+			err = el.fill(fields[key])
+			last = el
+		}
+		if err != nil {
+			errs[key] = err
+		}
+	}
+
+	if len(errs) > 0 {
+		return &FormError{errs}
+	}
+
+	if !opts.Submit {
+		return nil
+	}
+
+	if form == nil && last != nil {
+		var err error
+		form, err = last.ElementByJSE(`() => this.form`, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if form == nil {
+		return &Error{nil, ErrElementNotFound, "form"}
+	}
+
+	var wait func() error
+	if opts.WaitNavigation != "" {
+		wait = p.WaitNavigationE(opts.WaitNavigation)
+	}
+
+	_, err := form.EvalE(true, `() => this.requestSubmit ? this.requestSubmit() : this.submit()`, nil)
+	if err != nil || wait == nil {
+		return err
+	}
+	return wait()
+}
+
+// formControl finds the control, the name attribute is preferred when the form is specified
+func (p *Page) formControl(form *Element, key string) (*Element, error) {
+	if form == nil {
+		return p.ElementE(nil, "", key)
+	}
+
+	el, err := form.ElementByJSE(`name => Array.from(this.elements).find(el => el.name === name) || null`, Array{key})
+	if IsError(err, ErrElementNotFound) {
+		return form.ElementE(key)
+	}
+	return el, err
+}
+
+// fill dispatches the value to the input method of the control type
+func (el *Element) fill(value interface{}) error {
+	res, err := el.evalE(true, `() => this.tagName === 'INPUT' ? this.type : this.tagName.toLowerCase()`, nil)
+	if err != nil {
+		return err
+	}
+	typ := res.Value.String()
+
+	mismatch := &Error{nil, ErrFormValue, fmt.Sprintf("%T for %s", value, typ)}
+
+	switch v := value.(type) {
+	case string:
+		switch typ {
+		case "select":
+			return el.SelectE([]string{v}, false)
+		case "file":
+			return el.SetFilesE([]string{v})
+		case "radio":
+			radio, err := el.ElementByJSE(`v => {
+				const scope = this.form || document
+				return Array.from(scope.querySelectorAll('input[type=radio]'))
+					.find(r => r.name === this.name && r.value === v) || null
+			}`, Array{v})
+			if err != nil {
+				return err
+			}
+			return radio.CheckE()
+		case "checkbox", "button", "submit", "reset", "image":
+			return mismatch
+		}
+		return el.InputE(v)
+
+	case []string:
+		switch typ {
+		case "select":
+			return el.SelectE(v, false)
+		case "file":
+			return el.SetFilesE(v)
+		}
+		return mismatch
+
+	case bool:
+		switch typ {
+		case "checkbox", "radio":
+			if v {
+				return el.CheckE()
+			}
+			return el.UncheckE()
+		}
+		return mismatch
+
+	case time.Time:
+		if typ == "time" {
+			return el.InputTimeE(v)
+		}
+		return el.InputDateE(v)
+	}
+
+	return mismatch
+}
@@ -0,0 +1,182 @@
+// This file contains the lifecycle tracking of the iframes.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// frameStates is shared by all the clones of a page, there's only one tracker for each iframe of the session
+type frameStates struct {
+	lock sync.Mutex
+	list map[proto.PageFrameID]*frameState
+}
+
+// frameState tracks the lifecycle of an iframe
+type frameState struct {
+	lock sync.Mutex
+
+	// it increases each time the js contexts of the frame are destroyed, such as when the frame navigates
+	gen      int
+	detached bool
+
+	// the js contexts created for the frame
+	contexts map[proto.RuntimeExecutionContextID]bool
+}
+
+// trackFrame starts to track the lifecycle of the frame of the iframe page
+func (p *Page) trackFrame() error {
+	p.frames.lock.Lock()
+	defer p.frames.lock.Unlock()
+
+	if f, has := p.frames.list[p.FrameID]; has {
+		p.frameState = f
+		return nil
+	}
+
+	err := proto.RuntimeEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	f := &frameState{contexts: map[proto.RuntimeExecutionContextID]bool{}}
+	p.frames.list[p.FrameID] = f
+	p.frameState = f
+
+	// the tracker is shared by all the clones, it shouldn't end with the context of the one that starts it,
+	// such as a Timeout clone, it ends when the frame is detached or the target is destroyed
+	ctx, cancel := context.WithCancel(p.browser.ctx)
+	s := p.event.Subscribe(ctx)
+	frameID := p.FrameID
+
+	gone := p.attachState().gone
+	go func() {
+		select {
+		case <-gone:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	track := func(msg *cdp.Event) {
+		navigated := &proto.PageFrameNavigated{}
+		detached := &proto.PageFrameDetached{}
+		destroyed := &proto.RuntimeExecutionContextDestroyed{}
+
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		switch {
+		case Event(msg, navigated) && navigated.Frame.ID == frameID:
+			f.gen++
+		case Event(msg, destroyed) && f.contexts[destroyed.ExecutionContextID]:
+			delete(f.contexts, destroyed.ExecutionContextID)
+			f.gen++
+		case Event(msg, detached) && detached.FrameID == frameID:
+			f.detached = true
+			cancel()
+		}
+	}
+
+	go func() {
+		goob.Each(s, track)
+
+		// the later callers shouldn't read the state that is no longer updated
+		p.frames.lock.Lock()
+		if p.frames.list[frameID] == f {
+			delete(p.frames.list, frameID)
+		}
+		p.frames.lock.Unlock()
+	}()
+
+	return nil
+}
+
+// checkFrame returns an ErrFrameDetached error if the frame is detached,
+// and clears the js context of the page if it's destroyed
+func (p *Page) checkFrame() error {
+	f := p.frameState
+	if f == nil {
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.detached {
+		return &Error{nil, ErrFrameDetached, p.FrameID}
+	}
+
+	if p.windowObjectID != "" && p.frameGen != f.gen {
+		p.windowObjectID = ""
+		p.jsContextID = 0
+	}
+	return nil
+}
+
+// frameContextCreated records the js context that is created for the frame
+func (p *Page) frameContextCreated(id proto.RuntimeExecutionContextID, gen int) {
+	f := p.frameState
+	if f == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.contexts[id] = true
+	p.frameGen = gen
+}
+
+func (p *Page) frameGeneration() int {
+	f := p.frameState
+	if f == nil {
+		return 0
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.gen
+}
+
+// WaitFrameNavigatedE returns a wait function that waits until the frame of the page navigates,
+// such as an iframe that reloads itself. The wait function returns the new url of the frame.
+// If the frame is detached before that, an ErrFrameDetached error will be returned.
+func (p *Page) WaitFrameNavigatedE() func() (string, error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	return func() (string, error) {
+		defer cancel()
+
+		var url *string
+		var err error
+		goob.Each(s, func(msg *cdp.Event) bool {
+			navigated := &proto.PageFrameNavigated{}
+			detached := &proto.PageFrameDetached{}
+
+			switch {
+			case Event(msg, navigated) && navigated.Frame.ID == p.FrameID:
+				url = &navigated.Frame.URL
+				return true
+			case Event(msg, detached) && detached.FrameID == p.FrameID:
+				err = &Error{nil, ErrFrameDetached, p.FrameID}
+				return true
+			}
+			return false
+		})
+
+		if err != nil {
+			return "", err
+		}
+		if url == nil {
+			return "", p.ctx.Err()
+		}
+		return *url, nil
+	}
+}
@@ -0,0 +1,395 @@
+// This file contains the HAR (HTTP Archive) recording of the page.
+// Spec: http://www.softwareishard.com/blog/har-12-spec/
+
+package rod
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/defaults"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// HAR is the root of a HAR 1.2 file, use json.Marshal to encode it
+type HAR struct {
+	Log *HARLog `json:"log"`
+}
+
+// HARLog ...
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator *HARCreator `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator ...
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry of a single request, each request of a redirect chain has its own entry
+type HAREntry struct {
+	StartedDateTime time.Time    `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         *HARRequest  `json:"request"`
+	Response        *HARResponse `json:"response"`
+	Cache           struct{}     `json:"cache"`
+	Timings         *HARTimings  `json:"timings"`
+	ServerIPAddress string       `json:"serverIPAddress,omitempty"`
+	Comment         string       `json:"comment,omitempty"`
+}
+
+// HARRequest ...
+type HARRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*HARNameValue `json:"cookies"`
+	Headers     []*HARNameValue `json:"headers"`
+	QueryString []*HARNameValue `json:"queryString"`
+	PostData    *HARPostData    `json:"postData,omitempty"`
+	HeadersSize int64           `json:"headersSize"`
+	BodySize    int64           `json:"bodySize"`
+}
+
+// HARResponse ...
+type HARResponse struct {
+	Status      int64           `json:"status"`
+	StatusText  string          `json:"statusText"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []*HARNameValue `json:"cookies"`
+	Headers     []*HARNameValue `json:"headers"`
+	Content     *HARContent     `json:"content"`
+	RedirectURL string          `json:"redirectURL"`
+	HeadersSize int64           `json:"headersSize"`
+	BodySize    int64           `json:"bodySize"`
+}
+
+// HARNameValue is used by the headers, cookies, and query strings
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData ...
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent of the response body, the Encoding will be "base64" if the Text is base64 encoded
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings in milliseconds, -1 means the timing doesn't apply to the request
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// StartHARE starts to record the network activities of the page, the stop function returns the recorded HAR.
+// Only the finished or failed requests will be included in the HAR.
+func (p *Page) StartHARE() (stop func() (*HAR, error), err error) {
+	return p.StartHARLimitE(0)
+}
+
+// StartHARLimitE is similar to StartHARE, the response bodies larger than maxBodySize bytes will be truncated.
+// If maxBodySize is 0, the bodies won't be truncated, if it's negative, the bodies won't be recorded.
+func (p *Page) StartHARLimitE(maxBodySize int) (stop func() (*HAR, error), err error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err = proto.NetworkEnable{}.Call(p)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	r := &harRecorder{
+		page:    p,
+		limit:   maxBodySize,
+		pending: map[proto.NetworkRequestID]*harPending{},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		goob.Each(s, r.handle)
+		close(done)
+	}()
+
+	return func() (*HAR, error) {
+		cancel()
+		<-done
+		r.wg.Wait()
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		entries := []*HAREntry{}
+		for _, e := range r.entries {
+			if e.finished {
+				entries = append(entries, e.entry)
+			}
+		}
+
+		return &HAR{Log: &HARLog{
+			Version: "1.2",
+			Creator: &HARCreator{Name: "rod", Version: defaults.Version},
+			Entries: entries,
+		}}, nil
+	}, nil
+}
+
+type harRecorder struct {
+	page  *Page
+	limit int
+	wg    sync.WaitGroup
+
+	lock    sync.Mutex
+	entries []*harPending
+	pending map[proto.NetworkRequestID]*harPending
+}
+
+type harPending struct {
+	entry    *HAREntry
+	start    time.Duration // the monotonic time when the request is sent
+	timing   *proto.NetworkResourceTiming
+	finished bool
+}
+
+func (r *harRecorder) handle(msg *cdp.Event) {
+	sent := &proto.NetworkRequestWillBeSent{}
+	received := &proto.NetworkResponseReceived{}
+	finished := &proto.NetworkLoadingFinished{}
+	failed := &proto.NetworkLoadingFailed{}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch {
+	case Event(msg, sent):
+		// the requests of a redirect chain share the same id, finish the previous one with the redirect response
+		if prev, has := r.pending[sent.RequestID]; has && sent.RedirectResponse != nil {
+			prev.entry.Response = harResponse(sent.RedirectResponse)
+			prev.entry.Response.RedirectURL = sent.Request.URL
+			prev.timing = sent.RedirectResponse.Timing
+			prev.finish(sent.Timestamp.Duration)
+		}
+
+		pending := &harPending{
+			entry: &HAREntry{
+				StartedDateTime: time.Now(),
+				Request:         harRequest(sent.Request),
+			},
+			start: sent.Timestamp.Duration,
+		}
+		if sent.WallTime != nil {
+			pending.entry.StartedDateTime = sent.WallTime.Time
+		}
+		r.pending[sent.RequestID] = pending
+		r.entries = append(r.entries, pending)
+
+	case Event(msg, received):
+		if pending, has := r.pending[received.RequestID]; has {
+			pending.entry.Response = harResponse(received.Response)
+			pending.entry.ServerIPAddress = received.Response.RemoteIPAddress
+			pending.timing = received.Response.Timing
+		}
+
+	case Event(msg, finished):
+		pending, has := r.pending[finished.RequestID]
+		if !has || pending.entry.Response == nil {
+			return
+		}
+		delete(r.pending, finished.RequestID)
+
+		pending.entry.Response.BodySize = int64(finished.EncodedDataLength)
+		pending.finish(finished.Timestamp.Duration)
+
+		if r.limit >= 0 {
+			// fetch the body eagerly, the browser may evict it
+			r.wg.Add(1)
+			go r.body(finished.RequestID, pending.entry.Response.Content)
+		}
+
+	case Event(msg, failed):
+		pending, has := r.pending[failed.RequestID]
+		if !has {
+			return
+		}
+		delete(r.pending, failed.RequestID)
+
+		if pending.entry.Response == nil {
+			pending.entry.Response = harResponse(&proto.NetworkResponse{})
+		}
+		pending.entry.Comment = failed.ErrorText
+		pending.finish(failed.Timestamp.Duration)
+	}
+}
+
+func (r *harRecorder) body(id proto.NetworkRequestID, content *HARContent) {
+	defer r.wg.Done()
+
+	res, err := proto.NetworkGetResponseBody{RequestID: id}.Call(r.page)
+	if err != nil {
+		r.lock.Lock()
+		content.Comment = err.Error()
+		r.lock.Unlock()
+		return
+	}
+
+	text, size, truncated := res.Body, int64(len(res.Body)), false
+	if res.Base64Encoded {
+		data, err := base64.StdEncoding.DecodeString(res.Body)
+		if err == nil {
+			size = int64(len(data))
+			if r.limit > 0 && len(data) > r.limit {
+				text, truncated = base64.StdEncoding.EncodeToString(data[:r.limit]), true
+			}
+		}
+	} else if r.limit > 0 && len(text) > r.limit {
+		text, truncated = text[:r.limit], true
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	content.Size = size
+	content.Text = text
+	if res.Base64Encoded {
+		content.Encoding = "base64"
+	}
+	if truncated {
+		content.Comment = "truncated"
+	}
+}
+
+// finish calculates the timings, end is the monotonic time when the request is finished
+func (p *harPending) finish(end time.Duration) {
+	p.finished = true
+
+	total := ms(end - p.start)
+	t := &HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Receive: total}
+
+	if p.timing != nil {
+		tm := p.timing
+
+		// the time between the request is sent by the page and the browser starts to handle it
+		queued := tm.RequestTime*1000 - ms(p.start)
+
+		for _, start := range []float64{tm.DNSStart, tm.ConnectStart, tm.SendStart} {
+			if start >= 0 {
+				t.Blocked = positive(queued + start)
+				break
+			}
+		}
+		if tm.DNSStart >= 0 {
+			t.DNS = tm.DNSEnd - tm.DNSStart
+		}
+		if tm.ConnectStart >= 0 {
+			t.Connect = tm.ConnectEnd - tm.ConnectStart
+		}
+		if tm.SslStart >= 0 {
+			t.SSL = tm.SslEnd - tm.SslStart
+		}
+		t.Send = positive(tm.SendEnd - tm.SendStart)
+		t.Wait = positive(tm.ReceiveHeadersEnd - tm.SendEnd)
+		t.Receive = positive(total - positive(t.Blocked) - positive(t.DNS) - positive(t.Connect) - t.Send - t.Wait)
+	}
+
+	p.entry.Timings = t
+	// the ssl is included in the connect
+	p.entry.Time = positive(t.Blocked) + positive(t.DNS) + positive(t.Connect) + t.Send + t.Wait + t.Receive
+}
+
+func harRequest(req *proto.NetworkRequest) *HARRequest {
+	r := &HARRequest{
+		Method:      req.Method,
+		URL:         req.URL + req.URLFragment,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []*HARNameValue{},
+		Headers:     harHeaders(req.Headers),
+		QueryString: []*HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(req.PostData)),
+	}
+
+	if u, err := url.Parse(req.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, v := range values {
+				r.QueryString = append(r.QueryString, &HARNameValue{Name: name, Value: v})
+			}
+		}
+		sort.SliceStable(r.QueryString, func(i, j int) bool { return r.QueryString[i].Name < r.QueryString[j].Name })
+	}
+
+	if req.HasPostData {
+		r.PostData = &HARPostData{Text: req.PostData}
+		for _, h := range r.Headers {
+			if strings.EqualFold(h.Name, "Content-Type") {
+				r.PostData.MimeType = h.Value
+			}
+		}
+	}
+
+	return r
+}
+
+func harResponse(res *proto.NetworkResponse) *HARResponse {
+	return &HARResponse{
+		Status:      res.Status,
+		StatusText:  res.StatusText,
+		HTTPVersion: harHTTPVersion(res.Protocol),
+		Cookies:     []*HARNameValue{},
+		Headers:     harHeaders(res.Headers),
+		Content:     &HARContent{MimeType: res.MIMEType},
+		HeadersSize: -1,
+	}
+}
+
+func harHeaders(headers proto.NetworkHeaders) []*HARNameValue {
+	list := []*HARNameValue{}
+	for k, v := range headers {
+		list = append(list, &HARNameValue{Name: k, Value: v.String()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// the protocol is something like "http/1.1" or "h2"
+func harHTTPVersion(protocol string) string {
+	if strings.HasPrefix(protocol, "http/") {
+		return strings.ToUpper(protocol)
+	}
+	return protocol
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func positive(n float64) float64 {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,192 @@
+// This file contains the extra headers that are only sent to some hosts, such as an auth token that shouldn't leak
+// to the third-party domains that the page loads. The headers are injected via the request interception, because
+// the Network.setExtraHTTPHeaders applies to all the requests.
+
+package rod
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// HostHeaders is a group of the extra headers of the SetExtraHeadersForHostsE
+type HostHeaders struct {
+	// Hosts are compared without the ports, a host such as "*.example.com" matches the subdomains of example.com
+	Hosts []string
+
+	Headers proto.NetworkHeaders
+}
+
+// match returns true if the host of the url is one of the hosts
+func (g *HostHeaders) match(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	for _, h := range g.Hosts {
+		h = strings.ToLower(h)
+		if host == h || (strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHosts returns true if the group is for the same hosts, the order of them doesn't matter
+func (g *HostHeaders) sameHosts(hosts []string) bool {
+	if len(g.Hosts) != len(hosts) {
+		return false
+	}
+	for _, a := range hosts {
+		has := false
+		for _, b := range g.Hosts {
+			has = has || strings.EqualFold(a, b)
+		}
+		if !has {
+			return false
+		}
+	}
+	return true
+}
+
+// hostHeadersState is shared by all the clones of a page
+type hostHeadersState struct {
+	lock   sync.Mutex
+	groups []*HostHeaders
+
+	// stops the interception, nil means there's no group
+	stop func() error
+}
+
+// SetExtraHeadersForHostsE sends the extra headers only with the requests whose hosts are one of the hosts,
+// the other requests are continued untouched. The dict is the same as the SetExtraHeadersE. Each call adds a
+// group of the hosts, if the group of the same hosts exists, its headers will be replaced. For the request
+// that matches several groups, the group added later overrides the earlier ones. The headers override the ones
+// of the SetExtraHeadersE with the same names for the matched requests.
+// It works with the other request interception helpers of the page no matter the order they are called, but the
+// requests they own, such as the ones of the HijackRequestsE or the SetProxyE, won't get the headers.
+// If the dict has an odd length, an ErrHeaderDict error will be returned.
+func (p *Page) SetExtraHeadersForHostsE(hosts []string, dict []string) error {
+	if len(dict)%2 != 0 {
+		return &Error{nil, ErrHeaderDict, dict}
+	}
+
+	headers := proto.NetworkHeaders{}
+	for i := 0; i < len(dict); i += 2 {
+		headers[dict[i]] = proto.NewJSON(dict[i+1])
+	}
+
+	s := p.hostHeaders
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, g := range s.groups {
+		if g.sameHosts(hosts) {
+			g.Headers = headers
+			return nil
+		}
+	}
+
+	if s.stop == nil {
+		fp := newFetchPattern("*")
+		fp.priority = fetchPriorityHeaders
+
+		stop, err := p.hijackRequests(fp, p.injectHostHeaders)
+		if err != nil {
+			return err
+		}
+		s.stop = stop
+	}
+
+	s.groups = append(s.groups, &HostHeaders{Hosts: hosts, Headers: headers})
+	return nil
+}
+
+// RemoveExtraHeadersForHostsE removes the group of the SetExtraHeadersForHostsE that has the same hosts,
+// the interception stops when no group is left
+func (p *Page) RemoveExtraHeadersForHostsE(hosts []string) error {
+	s := p.hostHeaders
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list := []*HostHeaders{}
+	for _, g := range s.groups {
+		if !g.sameHosts(hosts) {
+			list = append(list, g)
+		}
+	}
+	s.groups = list
+
+	if len(list) > 0 || s.stop == nil {
+		return nil
+	}
+
+	err := s.stop()
+	s.stop = nil
+	return err
+}
+
+// ExtraHeadersForHosts returns the copies of the groups of the SetExtraHeadersForHostsE in the order they are added
+func (p *Page) ExtraHeadersForHosts() []*HostHeaders {
+	s := p.hostHeaders
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list := []*HostHeaders{}
+	for _, g := range s.groups {
+		headers := proto.NetworkHeaders{}
+		for k, v := range g.Headers {
+			headers[k] = v
+		}
+		list = append(list, &HostHeaders{Hosts: append([]string{}, g.Hosts...), Headers: headers})
+	}
+	return list
+}
+
+func (p *Page) injectHostHeaders(h *HijackContext) error {
+	s := p.hostHeaders
+	s.lock.Lock()
+	var matched []*HostHeaders
+	for _, g := range s.groups {
+		if g.match(h.URL()) {
+			matched = append(matched, g)
+		}
+	}
+	s.lock.Unlock()
+
+	if len(matched) == 0 {
+		return h.ContinueRequest(nil)
+	}
+
+	// the header names are case-insensitive, the later ones override the earlier ones
+	names := []string{}
+	values := map[string]*proto.FetchHeaderEntry{}
+	set := func(headers proto.NetworkHeaders) {
+		for k, v := range headers {
+			key := strings.ToLower(k)
+			if _, has := values[key]; !has {
+				names = append(names, key)
+			}
+			values[key] = &proto.FetchHeaderEntry{Name: k, Value: v.String()}
+		}
+	}
+
+	set(h.Headers())
+	if p.headers != nil {
+		set(p.headers.Headers)
+	}
+	for _, g := range matched {
+		set(g.Headers)
+	}
+
+	entries := []*proto.FetchHeaderEntry{}
+	for _, name := range names {
+		entries = append(entries, values[name])
+	}
+	return h.ContinueRequest(&proto.FetchContinueRequest{Headers: entries})
+}
@@ -0,0 +1,511 @@
+// This file contains the request interception related code.
+// Fetch.enable replaces the previous patterns of the session,
+// so every helper that intercepts requests must register its patterns via the fetchState.
+
+package rod
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the priorities of the patterns, a paused request is owned by the matched pattern of the highest priority
+const (
+	// the SetExtraHeadersForHostsE only adds the headers, any other helper can own the request
+	fetchPriorityHeaders = iota
+
+	// the SetProxyE, the helpers that intercept the requests take them from the proxy
+	fetchPriorityProxy
+
+	fetchPriorityNormal
+)
+
+// fetchPattern is a pattern registered by an interception helper
+type fetchPattern struct {
+	pattern *proto.FetchRequestPattern
+	match   func(url string) bool
+
+	priority int
+
+	// the number of the characters of the pattern that aren't wildcards, the more the more specific
+	literals int
+
+	// handle is called in its own goroutine for each paused request the pattern owns,
+	// the pattern without it never owns any request
+	handle func(e *proto.FetchRequestPaused)
+
+	// if true the Fetch.authRequired events will be enabled
+	auth bool
+}
+
+// fetchState is shared by all the clones of a page
+type fetchState struct {
+	lock     sync.Mutex
+	patterns []*fetchPattern
+
+	// the context of the dispatching of the paused requests, nil means there's no pattern
+	ctx    context.Context
+	cancel func()
+}
+
+func newFetchPattern(pattern string) *fetchPattern {
+	reg := wildcardToRegexp(pattern)
+	return &fetchPattern{
+		pattern:  &proto.FetchRequestPattern{URLPattern: pattern},
+		match:    reg.MatchString,
+		priority: fetchPriorityNormal,
+		literals: len(strings.NewReplacer("\\", "", "*", "", "?", "").Replace(pattern)),
+	}
+}
+
+// moreSpecific returns true if the fp should own the requests that both of the patterns match
+func (fp *fetchPattern) moreSpecific(other *fetchPattern) bool {
+	if fp.priority != other.priority {
+		return fp.priority > other.priority
+	}
+	if typed := fp.pattern.ResourceType != ""; typed != (other.pattern.ResourceType != "") {
+		return typed
+	}
+	return fp.literals > other.literals
+}
+
+// add the pattern and update the Fetch domain of the page
+func (s *fetchState) add(p *Page, fp *fetchPattern) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// subscribe before the Fetch.enable, so that we won't miss any event
+	if s.ctx == nil || s.ctx.Err() != nil {
+		s.dispatch(p)
+	}
+
+	s.patterns = append(s.patterns, fp)
+
+	err := s.update(p)
+	if err != nil {
+		s.patterns = s.patterns[:len(s.patterns)-1]
+		if len(s.patterns) == 0 {
+			s.stop()
+		}
+	}
+	return err
+}
+
+// remove the pattern and update the Fetch domain of the page
+func (s *fetchState) remove(p *Page, fp *fetchPattern) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list := []*fetchPattern{}
+	for _, item := range s.patterns {
+		if item != fp {
+			list = append(list, item)
+		}
+	}
+	s.patterns = list
+
+	// the browser continues the paused requests itself after the Fetch.disable
+	err := s.update(p)
+	if len(list) == 0 {
+		s.stop()
+	}
+	return err
+}
+
+func (s *fetchState) stop() {
+	if s.ctx != nil {
+		s.cancel()
+		s.ctx = nil
+	}
+}
+
+// dispatch passes each paused request to the pattern that owns it, the ones that no pattern owns are continued,
+// such as the request of a pattern that has just been removed, or the one that is only paused for the auth
+func (s *fetchState) dispatch(p *Page) {
+	// the dispatching is shared by all the clones, it shouldn't end with the context of the one that starts it,
+	// such as a Timeout clone, it ends when the last pattern is removed or the target is destroyed
+	ctx, cancel := context.WithCancel(p.browser.ctx)
+	s.ctx, s.cancel = ctx, cancel
+	events := p.event.Subscribe(ctx)
+
+	gone := p.attachState().gone
+	go func() {
+		select {
+		case <-gone:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	caller := *p
+	caller.ctx = ctx
+
+	go goob.Each(events, func(msg *cdp.Event) {
+		e := &proto.FetchRequestPaused{}
+		if !Event(msg, e) {
+			return
+		}
+
+		if fp := s.owner(e); fp != nil {
+			go fp.handle(e)
+			return
+		}
+		go func() { _ = proto.FetchContinueRequest{RequestID: e.RequestID}.Call(&caller) }()
+	})
+}
+
+// owner returns the pattern that owns the paused request, it's the matched one that is more specific than the others,
+// if several are the same, the one registered first wins
+func (s *fetchState) owner(e *proto.FetchRequestPaused) *fetchPattern {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var owner *fetchPattern
+	for _, fp := range s.patterns {
+		if fp.handle == nil {
+			continue
+		}
+		if fp.pattern.ResourceType != "" && fp.pattern.ResourceType != e.ResourceType {
+			continue
+		}
+		if fp.match(e.Request.URL) && (owner == nil || fp.moreSpecific(owner)) {
+			owner = fp
+		}
+	}
+	return owner
+}
+
+func (s *fetchState) update(p *Page) error {
+	if len(s.patterns) == 0 {
+		return proto.FetchDisable{}.Call(p)
+	}
+
+	patterns := []*proto.FetchRequestPattern{}
+	auth := false
+	for _, fp := range s.patterns {
+		patterns = append(patterns, fp.pattern)
+		auth = auth || fp.auth
+	}
+
+	return proto.FetchEnable{Patterns: patterns, HandleAuthRequests: auth}.Call(p)
+}
+
+// HijackContext is the context of a paused request
+type HijackContext struct {
+	page    *Page
+	handled bool
+
+	// Event is the raw event of the paused request
+	Event *proto.FetchRequestPaused
+}
+
+// URL of the request
+func (h *HijackContext) URL() string {
+	return h.Event.Request.URL
+}
+
+// Method of the request
+func (h *HijackContext) Method() string {
+	return h.Event.Request.Method
+}
+
+// Headers of the request
+func (h *HijackContext) Headers() proto.NetworkHeaders {
+	return h.Event.Request.Headers
+}
+
+// PostData of the request
+func (h *HijackContext) PostData() string {
+	return h.Event.Request.PostData
+}
+
+// ContinueRequest sends the request to the server, the fields of req will override the original ones.
+// If req is nil, the request will be sent as it is.
+func (h *HijackContext) ContinueRequest(req *proto.FetchContinueRequest) error {
+	if req == nil {
+		req = &proto.FetchContinueRequest{}
+	}
+	req.RequestID = h.Event.RequestID
+	h.handled = true
+	return req.Call(h.page)
+}
+
+// FulfillRequest responds to the request with the res, the request will not be sent to the server
+func (h *HijackContext) FulfillRequest(res *proto.FetchFulfillRequest) error {
+	res.RequestID = h.Event.RequestID
+	if res.ResponseCode == 0 {
+		res.ResponseCode = 200
+	}
+	h.handled = true
+	return res.Call(h.page)
+}
+
+// FailRequest makes the request fail with the reason, such as blocking the request
+func (h *HijackContext) FailRequest(reason proto.NetworkErrorReason) error {
+	h.handled = true
+	return proto.FetchFailRequest{
+		RequestID:   h.Event.RequestID,
+		ErrorReason: reason,
+	}.Call(h.page)
+}
+
+// HijackRequestsE intercepts the requests whose url matches the pattern, such as the requests from the iframes.
+// Wildcards ('*' -> zero or more, '?' -> exactly one) are allowed. Escape character is backslash.
+// Each request will be handled in its own goroutine, if the handler doesn't respond to the request
+// the request will be continued, if the handler returns an error the request will fail.
+// If several patterns of the page match a request, only the most specific one gets it, such as "*/api" over "*",
+// the one registered first wins the tie.
+// The stop function disables the interception and returns the first error the handler returned.
+func (p *Page) HijackRequestsE(pattern string, handler func(*HijackContext) error) (stop func() error, err error) {
+	return p.hijackRequests(newFetchPattern(pattern), handler)
+}
+
+func (p *Page) hijackRequests(fp *fetchPattern, handler func(*HijackContext) error) (stop func() error, err error) {
+	var lock sync.Mutex
+	var handlerErr error
+
+	fp.handle = func(e *proto.FetchRequestPaused) {
+		h := &HijackContext{page: p, Event: e}
+
+		err := handler(h)
+		if err != nil {
+			lock.Lock()
+			if handlerErr == nil {
+				handlerErr = err
+			}
+			lock.Unlock()
+		}
+
+		if h.handled {
+			return
+		}
+
+		if err != nil {
+			_ = h.FailRequest(proto.NetworkErrorReasonFailed)
+		} else {
+			_ = h.ContinueRequest(nil)
+		}
+	}
+
+	err = p.fetch.add(p, fp)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		err := p.fetch.remove(p, fp)
+
+		lock.Lock()
+		defer lock.Unlock()
+		if handlerErr != nil {
+			return handlerErr
+		}
+		return err
+	}, nil
+}
+
+// the max times to answer the auth challenges of the same request before giving up
+var authMaxAttempts = 3
+
+// HandleAuthE returns a wait function that answers the auth challenges of the next request that requires them,
+// both the server and the proxy auth challenges are handled. The challenges are answered from the moment it's called,
+// so the navigation that requires them can be called before the wait function. The wait function returns after
+// the request gets its response, if the challenge repeats more than authMaxAttempts times, such as the credentials
+// are wrong, the wait function cancels the auth and returns an ErrAuthFailed error.
+// The requests are only paused for the auth, the other request interception helpers of the page still get them.
+func (p *Page) HandleAuthE(username, password string) func() error {
+	// the pattern has no handle, so it never owns the paused requests, the ones no other helper owns are continued
+	fp := newFetchPattern("*")
+	fp.auth = true
+
+	// subscribe before the Fetch.enable, so that we won't miss any event
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	// the Network events are used to know if the auth succeeded
+	err := proto.NetworkEnable{}.Call(p)
+	if err == nil {
+		err = p.fetch.add(p, fp)
+	}
+	if err != nil {
+		cancel()
+		return func() error { return err }
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+
+		err := p.answerAuth(s, username, password)
+		if e := p.fetch.remove(p, fp); err == nil {
+			err = e
+		}
+		done <- err
+	}()
+
+	return func() error {
+		return <-done
+	}
+}
+
+// answerAuth answers the auth challenges until the request that requires them gets its response
+func (p *Page) answerAuth(s chan goob.Event, username, password string) error {
+	requests := map[proto.NetworkRequestID]proto.FetchRequestID{}
+	attempts := map[proto.FetchRequestID]int{}
+	done := false
+
+	var authErr error
+	goob.Each(s, func(msg *cdp.Event) bool {
+		paused := &proto.FetchRequestPaused{}
+		auth := &proto.FetchAuthRequired{}
+		res := &proto.NetworkResponseReceived{}
+
+		switch {
+		case Event(msg, paused):
+			requests[proto.NetworkRequestID(paused.NetworkID)] = paused.RequestID
+
+		case Event(msg, auth):
+			attempts[auth.RequestID]++
+
+			if attempts[auth.RequestID] > authMaxAttempts {
+				_ = proto.FetchContinueWithAuth{
+					RequestID: auth.RequestID,
+					AuthChallengeResponse: &proto.FetchAuthChallengeResponse{
+						Response: proto.FetchAuthChallengeResponseResponseCancelAuth,
+					},
+				}.Call(p)
+				authErr = &Error{nil, ErrAuthFailed, auth.AuthChallenge}
+				done = true
+				return true
+			}
+
+			authErr = proto.FetchContinueWithAuth{
+				RequestID: auth.RequestID,
+				AuthChallengeResponse: &proto.FetchAuthChallengeResponse{
+					Response: proto.FetchAuthChallengeResponseResponseProvideCredentials,
+					Username: username,
+					Password: password,
+				},
+			}.Call(p)
+			if authErr != nil {
+				done = true
+				return true
+			}
+
+		case Event(msg, res):
+			if id, has := requests[res.RequestID]; has && attempts[id] > 0 {
+				done = true
+				return true
+			}
+		}
+		return false
+	})
+
+	if !done {
+		return p.ctx.Err()
+	}
+	return authErr
+}
+
+// blockingState is shared by all the clones of a page
+type blockingState struct {
+	lock sync.Mutex
+
+	urls  []*regexp.Regexp
+	types map[proto.NetworkResourceType]bool
+
+	// the patterns of the BlockResourceTypesE
+	patterns []*fetchPattern
+}
+
+// blocked returns true if the request will be blocked by the BlockRequestsE or BlockResourceTypesE
+func (s *blockingState) blocked(url string, typ proto.NetworkResourceType) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.types[typ] {
+		return true
+	}
+	for _, reg := range s.urls {
+		if reg.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockRequestsE blocks the requests whose url matches one of the patterns, wildcards ('*') are allowed.
+// Each call replaces the patterns of the previous one, call it with no patterns to stop the blocking.
+func (p *Page) BlockRequestsE(patterns []string) error {
+	if patterns == nil {
+		patterns = []string{}
+	}
+
+	err := proto.NetworkSetBlockedURLs{Urls: patterns}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	list := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		list = append(list, wildcardToRegexp(pattern))
+	}
+
+	p.blocking.lock.Lock()
+	p.blocking.urls = list
+	p.blocking.lock.Unlock()
+
+	return nil
+}
+
+// BlockResourceTypesE fails the requests of the resource types, such as proto.NetworkResourceTypeImage,
+// with the reason proto.NetworkErrorReasonBlockedByClient. It works with the other request interception helpers.
+// Each call replaces the types of the previous one, call it with no types to stop the blocking.
+func (p *Page) BlockResourceTypesE(types ...proto.NetworkResourceType) error {
+	b := p.blocking
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for _, fp := range b.patterns {
+		err := p.fetch.remove(p, fp)
+		if err != nil {
+			return err
+		}
+	}
+	b.patterns = nil
+	b.types = map[proto.NetworkResourceType]bool{}
+
+	if len(types) == 0 {
+		return nil
+	}
+
+	block := func(e *proto.FetchRequestPaused) {
+		_ = proto.FetchFailRequest{
+			RequestID:   e.RequestID,
+			ErrorReason: proto.NetworkErrorReasonBlockedByClient,
+		}.Call(p)
+	}
+
+	for _, t := range types {
+		fp := newFetchPattern("*")
+		fp.pattern.ResourceType = t
+		fp.handle = block
+
+		err := p.fetch.add(p, fp)
+		if err != nil {
+			return err
+		}
+
+		b.patterns = append(b.patterns, fp)
+		b.types[t] = true
+	}
+
+	return nil
+}
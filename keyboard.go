@@ -1,7 +1,10 @@
 package rod
 
 import (
+	"strings"
 	"sync"
+	"unicode"
+	"unicode/utf16"
 
 	"github.com/ysmood/rod/lib/input"
 	"github.com/ysmood/rod/lib/proto"
@@ -16,6 +19,13 @@ type Keyboard struct {
 	modifiers int64
 }
 
+// getModifiers returns the modifiers that are being pressed, the mouse and touch events carry them
+func (k *Keyboard) getModifiers() int64 {
+	k.Lock()
+	defer k.Unlock()
+	return k.modifiers
+}
+
 // DownE doc is similar to the method Down
 func (k *Keyboard) DownE(key rune) error {
 	actions := input.Encode(key)
@@ -23,11 +33,14 @@ func (k *Keyboard) DownE(key rune) error {
 	k.Lock()
 	defer k.Unlock()
 
+	modifiers := k.modifiers | input.Modifier(key)
+	actions[0].Modifiers |= modifiers
+
 	err := actions[0].Call(k.page)
 	if err != nil {
 		return err
 	}
-	k.modifiers = actions[0].Modifiers
+	k.modifiers = modifiers
 	return nil
 }
 
@@ -38,30 +51,54 @@ func (k *Keyboard) UpE(key rune) error {
 	k.Lock()
 	defer k.Unlock()
 
+	modifiers := k.modifiers &^ input.Modifier(key)
+	actions[len(actions)-1].Modifiers |= modifiers
+
 	err := actions[len(actions)-1].Call(k.page)
 	if err != nil {
 		return err
 	}
-	k.modifiers = 0
+	k.modifiers = modifiers
 	return nil
 }
 
 // PressE doc is similar to the method Press
 func (k *Keyboard) PressE(key rune) error {
-	if k.page.browser.trace {
-		defer k.page.Overlay(0, 0, 200, 0, "press "+input.Keys[key].Key)()
-	}
+	defer k.page.tryTrace(0, 0, 200, 0, "press "+input.Lookup(key).Key)()
 	k.page.browser.trySlowmotion()
 
-	actions := input.Encode(key)
+	k.Lock()
+	defer k.Unlock()
+
+	return k.press(key)
+}
+
+// TypeE doc is similar to the method Type
+func (k *Keyboard) TypeE(text string) error {
+	defer k.page.tryTrace(0, 0, 200, 0, "type "+text)()
+	k.page.browser.trySlowmotion()
 
 	k.Lock()
 	defer k.Unlock()
 
-	k.modifiers = actions[0].Modifiers
-	defer func() { k.modifiers = 0 }()
+	for _, key := range text {
+		err := k.press(key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *Keyboard) press(key rune) error {
+	actions := input.Encode(key)
+
+	prev := k.modifiers
+	k.modifiers |= actions[0].Modifiers
+	defer func() { k.modifiers = prev }()
 
 	for _, action := range actions {
+		action.Modifiers |= k.modifiers
 		err := action.Call(k.page)
 		if err != nil {
 			return err
@@ -70,11 +107,173 @@ func (k *Keyboard) PressE(key rune) error {
 	return nil
 }
 
+// the editing commands to trigger for the shortcuts on mac, the key events of the protocol won't trigger them
+var macCommands = map[rune]string{
+	'a': "selectAll",
+	'c': "copy",
+	'x': "cut",
+	'v': "paste",
+	'z': "undo",
+}
+
+// PressCombinationE doc is similar to the method PressCombination
+func (k *Keyboard) PressCombinationE(keys ...rune) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	mac, err := k.isMac()
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	list := []rune{}
+	for _, key := range keys {
+		if key == input.CommandOrControl {
+			key = input.Control
+			if mac {
+				key = input.Meta
+			}
+		}
+		list = append(list, key)
+		names = append(names, input.Lookup(key).Key)
+	}
+
+	defer k.page.tryTrace(0, 0, 200, 0, "press "+strings.Join(names, "+"))()
+	k.page.browser.trySlowmotion()
+
+	k.Lock()
+	defer k.Unlock()
+
+	prev := k.modifiers
+	defer func() { k.modifiers = prev }()
+
+	modifiers, last := list[:len(list)-1], list[len(list)-1]
+
+	pressed := 0
+	for _, key := range modifiers {
+		k.modifiers |= input.Modifier(key)
+
+		down := input.Encode(key)[0]
+		down.Modifiers |= k.modifiers
+		err = down.Call(k.page)
+		if err != nil {
+			break
+		}
+		pressed++
+	}
+
+	if err == nil {
+		err = k.tap(last, mac)
+	}
+
+	// release the pressed modifiers in reverse order, even if the tap failed
+	for i := pressed - 1; i >= 0; i-- {
+		key := modifiers[i]
+		k.modifiers &^= input.Modifier(key)
+
+		actions := input.Encode(key)
+		up := actions[len(actions)-1]
+		up.Modifiers |= k.modifiers
+		e := up.Call(k.page)
+		if err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// tap the key with the current modifiers, the char event will be omitted when a modifier other than the Shift
+// is pressed, so that the shortcut won't input the text
+func (k *Keyboard) tap(key rune, mac bool) error {
+	for _, action := range input.Encode(key) {
+		action.Modifiers |= k.modifiers
+
+		if action.Type == proto.InputDispatchKeyEventTypeChar && k.modifiers&^input.Modifier(input.Shift) != 0 {
+			continue
+		}
+
+		cmd, has := macCommands[unicode.ToLower(key)]
+		if mac && has && action.Type == proto.InputDispatchKeyEventTypeKeyDown &&
+			k.modifiers&input.Modifier(input.Meta) != 0 {
+			if cmd == "undo" && k.modifiers&input.Modifier(input.Shift) != 0 {
+				cmd = "redo"
+			}
+
+			err := proto.InputDispatchKeyEventWithCommands{
+				InputDispatchKeyEvent: *action,
+				Commands:              []string{cmd},
+			}.Call(k.page)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := action.Call(k.page)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isMac returns true if the platform of the page is mac, the platform override of the page is respected
+func (k *Keyboard) isMac() (bool, error) {
+	res, err := k.page.evalE(true, "", `() => navigator.platform`, nil)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(res.Value.String(), "Mac"), nil
+}
+
+// IMEInputE enters the text like an input method, the composition grows by one character of the text at a time,
+// then it's committed, so the page will receive the compositionstart, compositionupdate, and compositionend events.
+func (k *Keyboard) IMEInputE(text string) error {
+	steps := []string{}
+	runes := []rune(text)
+	for i := range runes {
+		steps = append(steps, string(runes[:i+1]))
+	}
+	return k.IMEComposeE(steps, text)
+}
+
+// IMEComposeE sets each of the steps as the composition text in order, then commits the text, such as the steps
+// {"k", "か", "かn", "かん", "かんj", "かんじ", "漢字"} and the text "漢字" for the romaji that converts after the
+// keystrokes. If the browser doesn't support the composition an ErrIMEUnsupported error will be returned, there's no
+// fallback, because the InsertTextE won't trigger the composition events, use it explicitly if the events don't matter.
+func (k *Keyboard) IMEComposeE(steps []string, text string) error {
+	defer k.page.tryTrace(0, 0, 200, 0, "ime input "+text)()
+	k.page.browser.trySlowmotion()
+
+	k.Lock()
+	defer k.Unlock()
+
+	for _, step := range steps {
+		caret := int64(len(utf16.Encode([]rune(step))))
+
+		err := proto.InputImeSetComposition{
+			Text:           step,
+			SelectionStart: caret,
+			SelectionEnd:   caret,
+		}.Call(k.page)
+		if isMethodNotFound(err) {
+			return &Error{err, ErrIMEUnsupported, nil}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// the insert text commits the active composition
+	return proto.InputInsertText{Text: text}.Call(k.page)
+}
+
 // InsertTextE doc is similar to the method InsertText
 func (k *Keyboard) InsertTextE(text string) error {
-	if k.page.browser.trace {
-		defer k.page.Overlay(0, 0, 200, 0, "insert text "+text)()
-	}
+	defer k.page.tryTrace(0, 0, 200, 0, "insert text "+text)()
 	k.page.browser.trySlowmotion()
 
 	err := proto.InputInsertText{Text: text}.Call(k.page)
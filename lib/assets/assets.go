@@ -35,6 +35,21 @@ var Helper = `
       return el || null
     },
 
+    textMatches (pattern, isRegexp, normalize) {
+      let text = rod.text.call(this)
+      if (normalize) text = text.replace(/\s+/g, ' ').trim()
+      const matched = isRegexp ? new RegExp(pattern).test(text) : text.includes(pattern)
+      return { matched, text }
+    },
+
+    // returns the first element whose text matches, or the text of the first element if none matches
+    elementText (selector, pattern, isRegexp, normalize) {
+      const list = Array.from((this.document || this).querySelectorAll(selector))
+      if (list.length === 0) return null
+      const el = list.find(el => rod.textMatches.call(el, pattern, isRegexp, normalize).matched)
+      return el || rod.textMatches.call(list[0], pattern, isRegexp, normalize).text
+    },
+
     parents (selector) {
       let p = this.parentElement
       const list = []
@@ -146,6 +161,31 @@ var Helper = `
       })
     },
 
+    // resolves when no mutation except the ones inside the ignored selectors happens for d milliseconds
+    waitDOMStable (d, ignore) {
+      return new Promise((resolve) => {
+        const ignored = (node) => {
+          const el = node.nodeType === Node.ELEMENT_NODE ? node : node.parentElement
+          return !!el && ignore.some(s => el.closest(s))
+        }
+
+        let timer
+        const observer = new MutationObserver((list) => {
+          if (list.every(m => ignored(m.target))) return
+          clearTimeout(timer)
+          timer = setTimeout(done, d)
+        })
+        const done = () => {
+          observer.disconnect()
+          resolve()
+        }
+
+        // observe the document itself, so that it works before the documentElement is created
+        observer.observe(document, { childList: true, subtree: true, attributes: true, characterData: true })
+        timer = setTimeout(done, d)
+      })
+    },
+
     waitLoad () {
       return new Promise((resolve) => {
         if (document.readyState === 'complete') return resolve()
@@ -153,7 +193,27 @@ var Helper = `
       })
     },
 
-    async scrollIntoViewIfNeeded () {
+    // the values of the dom are in css pixels no matter what the device scale factor is
+    layout () {
+      const root = document.scrollingElement || document.documentElement
+      const vv = window.visualViewport
+      return {
+        contentWidth: Math.max(root.scrollWidth, root.clientWidth),
+        contentHeight: Math.max(root.scrollHeight, root.clientHeight),
+        viewportWidth: vv ? vv.width : root.clientWidth,
+        viewportHeight: vv ? vv.height : root.clientHeight,
+        scrollX: window.scrollX,
+        scrollY: window.scrollY,
+        devicePixelRatio: window.devicePixelRatio
+      }
+    },
+
+    // ignores the "scroll-behavior: smooth" of the page, so that the layout can be read right after it
+    scrollTo (x, y) {
+      window.scrollTo({ left: x, top: y, behavior: 'instant' })
+    },
+
+    async scrollIntoViewIfNeeded (smooth) {
       if (!this.isConnected) { throw new Error('Node is detached from document') }
       if (this.nodeType !== Node.ELEMENT_NODE) { throw new Error('Node is not of type HTMLElement') }
 
@@ -164,7 +224,9 @@ var Helper = `
         })
         observer.observe(this)
       })
-      if (visibleRatio !== 1.0) { this.scrollIntoView({ block: 'center', inline: 'center', behavior: 'instant' }) }
+      if (visibleRatio !== 1.0) {
+        this.scrollIntoView({ block: 'center', inline: 'center', behavior: smooth ? 'smooth' : 'instant' })
+      }
     },
 
     inputEvent () {
@@ -172,6 +234,21 @@ var Helper = `
       this.dispatchEvent(new Event('change', { bubbles: true }))
     },
 
+    inputValue (value) {
+      const prev = this.value
+      this.value = value
+
+      // the browser doesn't clamp the value, it only marks the value as invalid
+      if (this.validity.rangeUnderflow || this.validity.rangeOverflow || this.value !== value) {
+        const msg = this.validationMessage || 'invalid value'
+        this.value = prev
+        return msg
+      }
+
+      rod.inputEvent.call(this)
+      return null
+    },
+
     selectText (pattern) {
       const m = this.value.match(new RegExp(pattern))
       if (m) {
@@ -183,19 +260,26 @@ var Helper = `
       this.select()
     },
 
-    select (selectors) {
-      selectors.forEach(s => {
-        Array.from(this.options).find(el => {
-          try {
-            if (el.innerText.includes(s) || el.matches(s)) {
-              el.selected = true
-              return true
-            }
-          } catch (e) { }
-        })
-      })
+    select (selectors, mode) {
+      const options = Array.from(this.options)
+      const matches = selectors.map(s => mode === 'index' ? options[s] : options.find(el => {
+        if (mode === 'text') return el.innerText.includes(s)
+        if (mode === 'value' && el.value === s) return true
+        if (mode === 'textOrCSS' && el.innerText.includes(s)) return true
+        try { return el.matches(s) } catch (e) { return false }
+      }))
+
+      const notFound = selectors.filter((_, i) => !matches[i])
+      if (notFound.length) {
+        return { notFound, options: options.map(el => ({ text: el.innerText, value: el.value })) }
+      }
+
+      if (this.multiple) options.forEach(el => { el.selected = false })
+      matches.forEach(el => { el.selected = true })
+
       this.dispatchEvent(new Event('input', { bubbles: true }))
       this.dispatchEvent(new Event('change', { bubbles: true }))
+      return null
     },
 
     visible () {
@@ -206,6 +290,11 @@ var Helper = `
         !!(box.top || box.bottom || box.width || box.height)
     },
 
+    // the transparent elements can't be seen, but they still take the inputs, such as the custom-styled checkboxes
+    opaqueVisible () {
+      return rod.visible.apply(this) && window.getComputedStyle(this).opacity !== '0'
+    },
+
     invisible () {
       return !rod.visible.apply(this)
     },
@@ -258,11 +347,12 @@ var Helper = `
         } else {
           s.type = 'text/javascript'
           s.text = content
-          resolve()
+          // the violation of an inline script is reported asynchronously
+          setTimeout(resolve)
         }
 
         s.id = id
-        s.onerror = reject
+        rod.onTagError(s, reject)
         document.head.appendChild(s)
       })
     },
@@ -281,14 +371,26 @@ var Helper = `
           el = document.createElement('style')
           el.type = 'text/css'
           el.appendChild(document.createTextNode(content))
-          resolve()
+          setTimeout(resolve)
         }
 
         el.id = id
         el.onload = resolve
-        el.onerror = reject
+        rod.onTagError(el, reject)
         document.head.appendChild(el)
       })
+    },
+
+    // rejects with a CSPBlocked error if the Content-Security-Policy blocks the element,
+    // the error event is delayed so that the violation event takes precedence
+    onTagError (el, reject) {
+      el.onerror = (e) => setTimeout(() => reject(e))
+      el.addEventListener('securitypolicyviolation', (e) => {
+        const err = new Error(e.violatedDirective)
+        err.name = 'CSPBlocked'
+        el.remove()
+        reject(err)
+      })
     }
   }
 
@@ -408,6 +510,20 @@ var MonitorPage = `<html>
         .rate {
             flex: 1;
         }
+        .main {
+            display: flex;
+            flex-direction: row;
+        }
+        .events {
+            flex: 1;
+            margin: 0;
+            padding: 10px;
+            font-size: 12px;
+            white-space: pre-wrap;
+            overflow: auto;
+            max-height: 90vh;
+            border-left: 1px solid #1413158c;
+        }
     </style>
 </head>
 <body>
@@ -417,7 +533,10 @@ var MonitorPage = `<html>
         <input type="number" class="rate" value="0.5" min="0" step="0.1" title="refresh rate (second)">
     </div>
     <pre class="error"></pre>
-    <img class="screen">
+    <div class="main">
+        <img class="screen">
+        <pre class="events"></pre>
+    </div>
 </body>
 <script>
     let elImg = document.querySelector('.screen')
@@ -425,6 +544,7 @@ var MonitorPage = `<html>
     let elUrl = document.querySelector('.url')
     let elRate = document.querySelector('.rate')
     let elErr = document.querySelector('.error')
+    let elEvents = document.querySelector('.events')
 
     async function update() {
         let res = await fetch('/api/page/{{.id}}')
@@ -432,10 +552,16 @@ var MonitorPage = `<html>
         elTitle.value = info.title
         elUrl.value = info.url 
 
+        res = await fetch('/page/{{.id}}/events')
+        let events = await res.json()
+        elEvents.textContent = events.map(e =>
+            ` + "`" + `${new Date(e.time).toLocaleTimeString()} [${e.type}] ${e.msg}` + "`" + `
+        ).reverse().join('\n')
+
         await new Promise((resolve, reject) => {
             let now = new Date()
-            elImg.src = '/screenshot/{{.id}}?t=' + now.getTime()
-            elImg.style.maxWidth = innerWidth + 'px'
+            elImg.src = '/page/{{.id}}/screenshot?t=' + now.getTime()
+            elImg.style.maxWidth = (innerWidth * 0.7) + 'px'
             elImg.onload = resolve
             elImg.onerror = () => reject('error loading screenshots')
         })
@@ -30,6 +30,21 @@
       return el || null
     },
 
+    textMatches (pattern, isRegexp, normalize) {
+      let text = rod.text.call(this)
+      if (normalize) text = text.replace(/\s+/g, ' ').trim()
+      const matched = isRegexp ? new RegExp(pattern).test(text) : text.includes(pattern)
+      return { matched, text }
+    },
+
+    // returns the first element whose text matches, or the text of the first element if none matches
+    elementText (selector, pattern, isRegexp, normalize) {
+      const list = Array.from((this.document || this).querySelectorAll(selector))
+      if (list.length === 0) return null
+      const el = list.find(el => rod.textMatches.call(el, pattern, isRegexp, normalize).matched)
+      return el || rod.textMatches.call(list[0], pattern, isRegexp, normalize).text
+    },
+
     parents (selector) {
       let p = this.parentElement
       const list = []
@@ -141,6 +156,31 @@
       })
     },
 
+    // resolves when no mutation except the ones inside the ignored selectors happens for d milliseconds
+    waitDOMStable (d, ignore) {
+      return new Promise((resolve) => {
+        const ignored = (node) => {
+          const el = node.nodeType === Node.ELEMENT_NODE ? node : node.parentElement
+          return !!el && ignore.some(s => el.closest(s))
+        }
+
+        let timer
+        const observer = new MutationObserver((list) => {
+          if (list.every(m => ignored(m.target))) return
+          clearTimeout(timer)
+          timer = setTimeout(done, d)
+        })
+        const done = () => {
+          observer.disconnect()
+          resolve()
+        }
+
+        // observe the document itself, so that it works before the documentElement is created
+        observer.observe(document, { childList: true, subtree: true, attributes: true, characterData: true })
+        timer = setTimeout(done, d)
+      })
+    },
+
     waitLoad () {
       return new Promise((resolve) => {
         if (document.readyState === 'complete') return resolve()
@@ -148,7 +188,27 @@
       })
     },
 
-    async scrollIntoViewIfNeeded () {
+    // the values of the dom are in css pixels no matter what the device scale factor is
+    layout () {
+      const root = document.scrollingElement || document.documentElement
+      const vv = window.visualViewport
+      return {
+        contentWidth: Math.max(root.scrollWidth, root.clientWidth),
+        contentHeight: Math.max(root.scrollHeight, root.clientHeight),
+        viewportWidth: vv ? vv.width : root.clientWidth,
+        viewportHeight: vv ? vv.height : root.clientHeight,
+        scrollX: window.scrollX,
+        scrollY: window.scrollY,
+        devicePixelRatio: window.devicePixelRatio
+      }
+    },
+
+    // ignores the "scroll-behavior: smooth" of the page, so that the layout can be read right after it
+    scrollTo (x, y) {
+      window.scrollTo({ left: x, top: y, behavior: 'instant' })
+    },
+
+    async scrollIntoViewIfNeeded (smooth) {
       if (!this.isConnected) { throw new Error('Node is detached from document') }
       if (this.nodeType !== Node.ELEMENT_NODE) { throw new Error('Node is not of type HTMLElement') }
 
@@ -159,7 +219,9 @@
         })
         observer.observe(this)
       })
-      if (visibleRatio !== 1.0) { this.scrollIntoView({ block: 'center', inline: 'center', behavior: 'instant' }) }
+      if (visibleRatio !== 1.0) {
+        this.scrollIntoView({ block: 'center', inline: 'center', behavior: smooth ? 'smooth' : 'instant' })
+      }
     },
 
     inputEvent () {
@@ -167,6 +229,21 @@
       this.dispatchEvent(new Event('change', { bubbles: true }))
     },
 
+    inputValue (value) {
+      const prev = this.value
+      this.value = value
+
+      // the browser doesn't clamp the value, it only marks the value as invalid
+      if (this.validity.rangeUnderflow || this.validity.rangeOverflow || this.value !== value) {
+        const msg = this.validationMessage || 'invalid value'
+        this.value = prev
+        return msg
+      }
+
+      rod.inputEvent.call(this)
+      return null
+    },
+
     selectText (pattern) {
       const m = this.value.match(new RegExp(pattern))
       if (m) {
@@ -178,19 +255,26 @@
       this.select()
     },
 
-    select (selectors) {
-      selectors.forEach(s => {
-        Array.from(this.options).find(el => {
-          try {
-            if (el.innerText.includes(s) || el.matches(s)) {
-              el.selected = true
-              return true
-            }
-          } catch (e) { }
-        })
-      })
+    select (selectors, mode) {
+      const options = Array.from(this.options)
+      const matches = selectors.map(s => mode === 'index' ? options[s] : options.find(el => {
+        if (mode === 'text') return el.innerText.includes(s)
+        if (mode === 'value' && el.value === s) return true
+        if (mode === 'textOrCSS' && el.innerText.includes(s)) return true
+        try { return el.matches(s) } catch (e) { return false }
+      }))
+
+      const notFound = selectors.filter((_, i) => !matches[i])
+      if (notFound.length) {
+        return { notFound, options: options.map(el => ({ text: el.innerText, value: el.value })) }
+      }
+
+      if (this.multiple) options.forEach(el => { el.selected = false })
+      matches.forEach(el => { el.selected = true })
+
       this.dispatchEvent(new Event('input', { bubbles: true }))
       this.dispatchEvent(new Event('change', { bubbles: true }))
+      return null
     },
 
     visible () {
@@ -201,6 +285,11 @@
         !!(box.top || box.bottom || box.width || box.height)
     },
 
+    // the transparent elements can't be seen, but they still take the inputs, such as the custom-styled checkboxes
+    opaqueVisible () {
+      return rod.visible.apply(this) && window.getComputedStyle(this).opacity !== '0'
+    },
+
     invisible () {
       return !rod.visible.apply(this)
     },
@@ -253,11 +342,12 @@
         } else {
           s.type = 'text/javascript'
           s.text = content
-          resolve()
+          // the violation of an inline script is reported asynchronously
+          setTimeout(resolve)
         }
 
         s.id = id
-        s.onerror = reject
+        rod.onTagError(s, reject)
         document.head.appendChild(s)
       })
     },
@@ -276,14 +366,26 @@
           el = document.createElement('style')
           el.type = 'text/css'
           el.appendChild(document.createTextNode(content))
-          resolve()
+          setTimeout(resolve)
         }
 
         el.id = id
         el.onload = resolve
-        el.onerror = reject
+        rod.onTagError(el, reject)
         document.head.appendChild(el)
       })
+    },
+
+    // rejects with a CSPBlocked error if the Content-Security-Policy blocks the element,
+    // the error event is delayed so that the violation event takes precedence
+    onTagError (el, reject) {
+      el.onerror = (e) => setTimeout(() => reject(e))
+      el.addEventListener('securitypolicyviolation', (e) => {
+        const err = new Error(e.violatedDirective)
+        err.name = 'CSPBlocked'
+        el.remove()
+        reject(err)
+      })
     }
   }
 
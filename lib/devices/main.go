@@ -0,0 +1,143 @@
+// Package devices holds the presets of the devices for page emulation
+package devices
+
+import "github.com/ysmood/rod/lib/proto"
+
+// Device represents a emulated device
+type Device struct {
+	Title string
+
+	// Width and Height of the viewport in portrait orientation
+	Width  int64
+	Height int64
+
+	DeviceScaleFactor float64
+	UserAgent         string
+	Mobile            bool
+	Touch             bool
+	Landscape         bool
+}
+
+// Clear is used to clear the device emulation
+var Clear = Device{}
+
+// IsClear tells if it's the device to clear the emulation
+func (d Device) IsClear() bool {
+	return d == Clear
+}
+
+// Rotate returns a copy of the device with the orientation switched
+func (d Device) Rotate() Device {
+	d.Landscape = !d.Landscape
+	return d
+}
+
+// MetricsEmulation returns the proto request to emulate the screen of the device
+func (d Device) MetricsEmulation() *proto.EmulationSetDeviceMetricsOverride {
+	if d.IsClear() {
+		return &proto.EmulationSetDeviceMetricsOverride{}
+	}
+
+	m := &proto.EmulationSetDeviceMetricsOverride{
+		Width:             d.Width,
+		Height:            d.Height,
+		DeviceScaleFactor: d.DeviceScaleFactor,
+		Mobile:            d.Mobile,
+		ScreenOrientation: &proto.EmulationScreenOrientation{
+			Type:  proto.EmulationScreenOrientationTypePortraitPrimary,
+			Angle: 0,
+		},
+	}
+
+	if d.Landscape {
+		m.Width, m.Height = d.Height, d.Width
+		m.ScreenOrientation = &proto.EmulationScreenOrientation{
+			Type:  proto.EmulationScreenOrientationTypeLandscapePrimary,
+			Angle: 90,
+		}
+	}
+
+	return m
+}
+
+// TouchEmulation returns the proto request to emulate the touch support of the device
+func (d Device) TouchEmulation() *proto.EmulationSetTouchEmulationEnabled {
+	if !d.Touch {
+		return &proto.EmulationSetTouchEmulationEnabled{Enabled: false}
+	}
+	return &proto.EmulationSetTouchEmulationEnabled{Enabled: true, MaxTouchPoints: 5}
+}
+
+// UserAgentEmulation returns the proto request to emulate the user agent of the device.
+// An empty user agent disables the override.
+func (d Device) UserAgentEmulation() *proto.NetworkSetUserAgentOverride {
+	return &proto.NetworkSetUserAgentOverride{UserAgent: d.UserAgent}
+}
+
+var (
+	// IPhoneX device
+	IPhoneX = Device{
+		Title:             "iPhone X",
+		Width:             375,
+		Height:            812,
+		DeviceScaleFactor: 3,
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 11_0 like Mac OS X) AppleWebKit/604.1.38 (KHTML, like Gecko) Version/11.0 Mobile/15A372 Safari/604.1",
+		Mobile:            true,
+		Touch:             true,
+	}
+
+	// IPhone8 device
+	IPhone8 = Device{
+		Title:             "iPhone 6/7/8",
+		Width:             375,
+		Height:            667,
+		DeviceScaleFactor: 2,
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 11_0 like Mac OS X) AppleWebKit/604.1.38 (KHTML, like Gecko) Version/11.0 Mobile/15A372 Safari/604.1",
+		Mobile:            true,
+		Touch:             true,
+	}
+
+	// Pixel2 device
+	Pixel2 = Device{
+		Title:             "Pixel 2",
+		Width:             411,
+		Height:            731,
+		DeviceScaleFactor: 2.625,
+		UserAgent:         "Mozilla/5.0 (Linux; Android 8.0; Pixel 2 Build/OPD3.170816.012) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/81.0.4044.138 Mobile Safari/537.36",
+		Mobile:            true,
+		Touch:             true,
+	}
+
+	// GalaxyS5 device
+	GalaxyS5 = Device{
+		Title:             "Galaxy S5",
+		Width:             360,
+		Height:            640,
+		DeviceScaleFactor: 3,
+		UserAgent:         "Mozilla/5.0 (Linux; Android 5.0; SM-G900P Build/LRX21T) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/81.0.4044.138 Mobile Safari/537.36",
+		Mobile:            true,
+		Touch:             true,
+	}
+
+	// IPad device
+	IPad = Device{
+		Title:             "iPad",
+		Width:             768,
+		Height:            1024,
+		DeviceScaleFactor: 2,
+		UserAgent:         "Mozilla/5.0 (iPad; CPU OS 11_0 like Mac OS X) AppleWebKit/604.1.34 (KHTML, like Gecko) Version/11.0 Mobile/15A5341f Safari/604.1",
+		Mobile:            true,
+		Touch:             true,
+	}
+
+	// IPadPro device
+	IPadPro = Device{
+		Title:             "iPad Pro",
+		Width:             1024,
+		Height:            1366,
+		DeviceScaleFactor: 2,
+		UserAgent:         "Mozilla/5.0 (iPad; CPU OS 11_0 like Mac OS X) AppleWebKit/604.1.34 (KHTML, like Gecko) Version/11.0 Mobile/15A5341f Safari/604.1",
+		Mobile:            true,
+		Touch:             true,
+	}
+)
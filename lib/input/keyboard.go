@@ -2,6 +2,7 @@ package input
 
 import (
 	"runtime"
+	"unicode"
 
 	"github.com/ysmood/rod/lib/proto"
 )
@@ -47,6 +48,24 @@ type Key struct {
 	Print bool
 }
 
+// Lookup the key of the rune from the Keys, then the IntlKeys.
+// If the rune is not in them, such as the CJK characters or emojis, the key will only have
+// the text fields, the code and the key codes will be zero.
+func Lookup(r rune) *Key {
+	if k, has := Keys[r]; has {
+		return k
+	}
+	if k, has := IntlKeys[r]; has {
+		return k
+	}
+	return &Key{
+		Key:        string(r),
+		Text:       string(r),
+		Unmodified: string(r),
+		Print:      unicode.IsPrint(r),
+	}
+}
+
 // Encode encodes a keyDown, char, and keyUp sequence for the specified rune.
 func Encode(r rune) []*proto.InputDispatchKeyEvent {
 	// force \n -> \r
@@ -54,8 +73,7 @@ func Encode(r rune) []*proto.InputDispatchKeyEvent {
 		r = '\r'
 	}
 
-	// if not known key, encode as unidentified
-	v := Keys[r]
+	v := Lookup(r)
 
 	// create
 	keyDown := proto.InputDispatchKeyEvent{
@@ -95,3 +113,23 @@ func Encode(r rune) []*proto.InputDispatchKeyEvent {
 
 	return []*proto.InputDispatchKeyEvent{&keyDown, &keyUp}
 }
+
+// CommandOrControl is a virtual key for the shortcuts, it will be pressed as the Meta on mac
+// and the Control on the other platforms, such as the CommandOrControl+A to select all.
+const CommandOrControl rune = '\ue000'
+
+// Modifier returns the bit of the modifier key for the Modifiers of the InputDispatchKeyEvent,
+// such as 2 for the Control. It returns 0 if the key is not a modifier.
+func Modifier(r rune) int64 {
+	switch r {
+	case Alt:
+		return 1
+	case Control:
+		return 2
+	case Meta:
+		return 4
+	case Shift:
+		return 8
+	}
+	return 0
+}
@@ -0,0 +1,33 @@
+package input
+
+// IntlKeys are the printable keys of the common non-US keyboard layouts.
+// Each rune is mapped to the physical key of the layout it's native to, such as 'ä' to the German layout.
+var IntlKeys = map[rune]*Key{
+	// German
+	'ä': {"Quote", "ä", "ä", "ä", 222, 222, false, true},
+	'Ä': {"Quote", "Ä", "Ä", "ä", 222, 222, true, true},
+	'ö': {"Semicolon", "ö", "ö", "ö", 192, 192, false, true},
+	'Ö': {"Semicolon", "Ö", "Ö", "ö", 192, 192, true, true},
+	'ü': {"BracketLeft", "ü", "ü", "ü", 186, 186, false, true},
+	'Ü': {"BracketLeft", "Ü", "Ü", "ü", 186, 186, true, true},
+	'ß': {"Minus", "ß", "ß", "ß", 219, 219, false, true},
+
+	// French
+	'é': {"Digit2", "é", "é", "é", 50, 50, false, true},
+	'è': {"Digit7", "è", "è", "è", 55, 55, false, true},
+	'ç': {"Digit9", "ç", "ç", "ç", 57, 57, false, true},
+	'à': {"Digit0", "à", "à", "à", 48, 48, false, true},
+	'ù': {"Quote", "ù", "ù", "ù", 192, 192, false, true},
+
+	// Spanish
+	'ñ': {"Semicolon", "ñ", "ñ", "ñ", 192, 192, false, true},
+	'Ñ': {"Semicolon", "Ñ", "Ñ", "ñ", 192, 192, true, true},
+
+	// Nordic
+	'å': {"BracketLeft", "å", "å", "å", 221, 221, false, true},
+	'Å': {"BracketLeft", "Å", "Å", "å", 221, 221, true, true},
+	'æ': {"Semicolon", "æ", "æ", "æ", 192, 192, false, true},
+	'Æ': {"Semicolon", "Æ", "Æ", "æ", 192, 192, true, true},
+	'ø': {"Quote", "ø", "ø", "ø", 222, 222, false, true},
+	'Ø': {"Quote", "Ø", "Ø", "ø", 222, 222, true, true},
+}
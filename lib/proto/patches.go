@@ -0,0 +1,169 @@
+// This file contains the definitions that the json-schema of the current version doesn't have yet.
+// The style is the same as the generated definitions.go, so that they can be removed once the schema is updated.
+
+package proto
+
+// InputDragDataItem (experimental) ...
+type InputDragDataItem struct {
+
+	// MimeType Mime type of the dragged data.
+	MimeType string `json:"mimeType"`
+
+	// Data Depending of the value of `mimeType`, it contains the dragged link,
+	// text, HTML markup or any other data.
+	Data string `json:"data"`
+
+	// Title (optional) Title associated with a link. Only valid when `mimeType` == "text/uri-list".
+	Title string `json:"title,omitempty"`
+
+	// BaseURL (optional) Stores the base URL for the contained markup. Only valid when `mimeType`
+	// == "text/html".
+	BaseURL string `json:"baseURL,omitempty"`
+}
+
+// InputDragData (experimental) ...
+type InputDragData struct {
+
+	// Items ...
+	Items []*InputDragDataItem `json:"items"`
+
+	// Files (optional) List of filenames that should be included when dropping
+	Files []string `json:"files,omitempty"`
+
+	// DragOperationsMask Bit field representing allowed drag operations. Copy = 1, Link = 2, Move = 16
+	DragOperationsMask int64 `json:"dragOperationsMask"`
+}
+
+// InputDispatchDragEventType enum
+type InputDispatchDragEventType string
+
+const (
+	// InputDispatchDragEventTypeDragEnter enum const
+	InputDispatchDragEventTypeDragEnter InputDispatchDragEventType = "dragEnter"
+
+	// InputDispatchDragEventTypeDragOver enum const
+	InputDispatchDragEventTypeDragOver InputDispatchDragEventType = "dragOver"
+
+	// InputDispatchDragEventTypeDrop enum const
+	InputDispatchDragEventTypeDrop InputDispatchDragEventType = "drop"
+
+	// InputDispatchDragEventTypeDragCancel enum const
+	InputDispatchDragEventTypeDragCancel InputDispatchDragEventType = "dragCancel"
+)
+
+// InputDispatchDragEvent (experimental) Dispatches a drag event into the page.
+type InputDispatchDragEvent struct {
+
+	// Type Type of the drag event.
+	Type InputDispatchDragEventType `json:"type"`
+
+	// X X coordinate of the event relative to the main frame's viewport in CSS pixels.
+	X float64 `json:"x"`
+
+	// Y Y coordinate of the event relative to the main frame's viewport in CSS pixels. 0 refers to
+	// the top of the viewport and Y increases as it proceeds towards the bottom of the viewport.
+	Y float64 `json:"y"`
+
+	// Data ...
+	Data *InputDragData `json:"data"`
+
+	// Modifiers (optional) Bit field representing pressed modifier keys. Alt=1, Ctrl=2, Meta/Command=4, Shift=8
+	// (default: 0).
+	Modifiers int64 `json:"modifiers,omitempty"`
+}
+
+// Call of the command, sessionID is optional.
+func (m InputDispatchDragEvent) Call(caller Caller) error {
+	return call("Input.dispatchDragEvent", m, nil, caller)
+}
+
+// InputSetInterceptDrags (experimental) Prevents default drag and drop behavior and instead emits `Input.dragIntercepted` events.
+// Drag and drop behavior can be directly controlled via `Input.dispatchDragEvent`.
+type InputSetInterceptDrags struct {
+
+	// Enabled ...
+	Enabled bool `json:"enabled"`
+}
+
+// Call of the command, sessionID is optional.
+func (m InputSetInterceptDrags) Call(caller Caller) error {
+	return call("Input.setInterceptDrags", m, nil, caller)
+}
+
+// InputDragIntercepted (experimental) Emitted only when `Input.setInterceptDrags` is enabled. Use this data with `Input.dispatchDragEvent` to
+// restore normal drag and drop behavior.
+type InputDragIntercepted struct {
+
+	// Data ...
+	Data *InputDragData `json:"data"`
+}
+
+// MethodName interface
+func (evt InputDragIntercepted) MethodName() string {
+	return "Input.dragIntercepted"
+}
+
+// TargetTargetInfoTypeIframe enum const, such as the out-of-process iframes
+const TargetTargetInfoTypeIframe TargetTargetInfoType = "iframe"
+
+// InputDispatchKeyEventWithCommands is the InputDispatchKeyEvent with the editing commands to trigger,
+// such as "selectAll". The commands are required to make the editing shortcuts work on mac,
+// because the keyboard events of the protocol won't trigger them.
+type InputDispatchKeyEventWithCommands struct {
+	InputDispatchKeyEvent
+
+	// Commands the editing commands to send with the key event
+	Commands []string `json:"commands,omitempty"`
+}
+
+// Call of the command, sessionID is optional.
+func (m InputDispatchKeyEventWithCommands) Call(caller Caller) error {
+	return call("Input.dispatchKeyEvent", m, nil, caller)
+}
+
+// InputImeSetComposition sets the composition text of the input method, the page will receive the
+// compositionstart and compositionupdate events. It isn't in the definitions of this protocol version,
+// the browsers that don't support it will respond with the method not found error.
+type InputImeSetComposition struct {
+	// Text of the composition
+	Text string `json:"text"`
+
+	// SelectionStart and SelectionEnd of the caret in the composition, in UTF-16 code units
+	SelectionStart int64 `json:"selectionStart"`
+	SelectionEnd   int64 `json:"selectionEnd"`
+}
+
+// Call of the command, sessionID is optional.
+func (m InputImeSetComposition) Call(caller Caller) error {
+	return call("Input.imeSetComposition", m, nil, caller)
+}
+
+// PagePrintToPDFWithMargins is the PagePrintToPDF whose margins are always sent. The zero margins of the
+// PagePrintToPDF are omitted, so the browser will use its default margins instead of them.
+type PagePrintToPDFWithMargins struct {
+	PagePrintToPDF
+
+	MarginTop    float64 `json:"marginTop"`
+	MarginBottom float64 `json:"marginBottom"`
+	MarginLeft   float64 `json:"marginLeft"`
+	MarginRight  float64 `json:"marginRight"`
+}
+
+// Call of the command, sessionID is optional.
+func (m PagePrintToPDFWithMargins) Call(caller Caller) (res *PagePrintToPDFResult, err error) {
+	res = &PagePrintToPDFResult{}
+	return res, call("Page.printToPDF", m, res, caller)
+}
+
+// EmulationSetPageVisibilityState overrides the visibility state of the page, such as "hidden".
+// It isn't in the definitions of this protocol version, the browsers that don't support it will
+// respond with the method not found error.
+type EmulationSetPageVisibilityState struct {
+	// State of the page visibility, "visible" or "hidden"
+	State string `json:"state"`
+}
+
+// Call of the command, sessionID is optional.
+func (m EmulationSetPageVisibilityState) Call(caller Caller) error {
+	return call("Emulation.setPageVisibilityState", m, nil, caller)
+}
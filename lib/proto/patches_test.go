@@ -0,0 +1,56 @@
+package proto_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+func TestInputDispatchKeyEventWithCommands(t *testing.T) {
+	c := &Client{}
+	err := proto.InputDispatchKeyEventWithCommands{
+		InputDispatchKeyEvent: proto.InputDispatchKeyEvent{Type: "keyDown", Key: "a", Modifiers: 4},
+		Commands:              []string{"selectAll"},
+	}.Call(&Caller{c})
+	kit.E(err)
+
+	assert.Equal(t, "Input.dispatchKeyEvent", c.methodName)
+	assert.Equal(t, `{"type":"keyDown","modifiers":4,"key":"a","commands":["selectAll"]}`, string(c.params.(json.RawMessage)))
+}
+
+func TestPagePrintToPDFWithMargins(t *testing.T) {
+	c := &Client{ret: proto.PagePrintToPDFResult{Stream: "1"}}
+	res, err := proto.PagePrintToPDFWithMargins{
+		PagePrintToPDF: proto.PagePrintToPDF{Landscape: true, MarginTop: 1},
+		MarginLeft:     1,
+	}.Call(&Caller{c})
+	kit.E(err)
+
+	assert.Equal(t, proto.IOStreamHandle("1"), res.Stream)
+	assert.Equal(t, "Page.printToPDF", c.methodName)
+	assert.Equal(t,
+		`{"landscape":true,"marginTop":0,"marginBottom":0,"marginLeft":1,"marginRight":0}`,
+		string(c.params.(json.RawMessage)),
+	)
+}
+
+func TestEmulationSetPageVisibilityState(t *testing.T) {
+	c := &Client{}
+	err := proto.EmulationSetPageVisibilityState{State: "hidden"}.Call(&Caller{c})
+	kit.E(err)
+
+	assert.Equal(t, "Emulation.setPageVisibilityState", c.methodName)
+	assert.Equal(t, `{"state":"hidden"}`, string(c.params.(json.RawMessage)))
+}
+
+func TestInputImeSetComposition(t *testing.T) {
+	c := &Client{}
+	err := proto.InputImeSetComposition{Text: "か", SelectionStart: 1, SelectionEnd: 1}.Call(&Caller{c})
+	kit.E(err)
+
+	assert.Equal(t, "Input.imeSetComposition", c.methodName)
+	assert.Equal(t, `{"text":"か","selectionStart":1,"selectionEnd":1}`, string(c.params.(json.RawMessage)))
+}
@@ -3,6 +3,10 @@ package proto
 import (
 	"context"
 	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/tidwall/gjson"
@@ -135,6 +139,35 @@ func (t MonotonicTime) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d)
 }
 
+// PageLifecycleEventName is the name field of the PageLifecycleEvent, the json-schema doesn't define the enum for it
+type PageLifecycleEventName string
+
+const (
+	// PageLifecycleEventNameInit enum const
+	PageLifecycleEventNameInit PageLifecycleEventName = "init"
+
+	// PageLifecycleEventNameDOMContentLoaded enum const
+	PageLifecycleEventNameDOMContentLoaded PageLifecycleEventName = "DOMContentLoaded"
+
+	// PageLifecycleEventNameLoad enum const
+	PageLifecycleEventNameLoad PageLifecycleEventName = "load"
+
+	// PageLifecycleEventNameFirstPaint enum const
+	PageLifecycleEventNameFirstPaint PageLifecycleEventName = "firstPaint"
+
+	// PageLifecycleEventNameFirstContentfulPaint enum const
+	PageLifecycleEventNameFirstContentfulPaint PageLifecycleEventName = "firstContentfulPaint"
+
+	// PageLifecycleEventNameFirstMeaningfulPaint enum const
+	PageLifecycleEventNameFirstMeaningfulPaint PageLifecycleEventName = "firstMeaningfulPaint"
+
+	// PageLifecycleEventNameNetworkAlmostIdle enum const
+	PageLifecycleEventNameNetworkAlmostIdle PageLifecycleEventName = "networkAlmostIdle"
+
+	// PageLifecycleEventNameNetworkIdle enum const
+	PageLifecycleEventNameNetworkIdle PageLifecycleEventName = "networkIdle"
+)
+
 var _ Normalizable = InputDispatchMouseEvent{}
 
 // Normalize interface
@@ -157,3 +190,133 @@ func (e InputDispatchMouseEvent) Normalize() (json.RawMessage, error) {
 
 	return data, nil
 }
+
+var _ Normalizable = EmulationSetGeolocationOverride{}
+
+// Normalize interface
+func (e EmulationSetGeolocationOverride) Normalize() (json.RawMessage, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	// the position is only unavailable when all of them are omitted,
+	// such as the latitude and longitude of 0 shouldn't be omitted
+	if e.Latitude != 0 || e.Longitude != 0 || e.Accuracy != 0 {
+		data, err = sjson.SetBytes(data, "latitude", e.Latitude)
+		if err != nil {
+			return nil, err
+		}
+		data, err = sjson.SetBytes(data, "longitude", e.Longitude)
+		if err != nil {
+			return nil, err
+		}
+		data, err = sjson.SetBytes(data, "accuracy", e.Accuracy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// ToHTTP converts the cookie to the net/http one. The session cookie won't have the Expires field.
+// The host-only cookie won't have the Domain field, so that the http.CookieJar will treat it as host-only too.
+func (c *NetworkCookie) ToHTTP() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+	}
+
+	if c.IsDomain() {
+		cookie.Domain = c.Domain
+	}
+
+	if !c.Session {
+		cookie.Expires = time.Unix(0, int64(c.Expires*float64(time.Second)))
+	}
+
+	switch c.SameSite {
+	case NetworkCookieSameSiteStrict:
+		cookie.SameSite = http.SameSiteStrictMode
+	case NetworkCookieSameSiteLax:
+		cookie.SameSite = http.SameSiteLaxMode
+	case NetworkCookieSameSiteNone:
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+
+	return cookie
+}
+
+// IsDomain returns true if the cookie is available to the subdomains, its domain has a leading dot.
+// It returns false for the host-only cookie.
+func (c *NetworkCookie) IsDomain() bool {
+	return strings.HasPrefix(c.Domain, ".")
+}
+
+// CookiesFromHTTP converts the net/http cookies to the params of the Network.setCookies, the url is the
+// one that the cookies are received from. A cookie without the Domain field will be host-only.
+func CookiesFromHTTP(cookies []*http.Cookie, url string) []*NetworkCookieParam {
+	list := []*NetworkCookieParam{}
+
+	for _, c := range cookies {
+		param := &NetworkCookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			URL:      url,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+
+		// the Max-Age has precedence over the Expires
+		if c.MaxAge > 0 {
+			param.Expires = &TimeSinceEpoch{time.Now().Add(time.Duration(c.MaxAge) * time.Second)}
+		} else if c.MaxAge < 0 {
+			param.Expires = &TimeSinceEpoch{time.Unix(0, 0)}
+		} else if !c.Expires.IsZero() {
+			param.Expires = &TimeSinceEpoch{c.Expires}
+		}
+
+		switch c.SameSite {
+		case http.SameSiteStrictMode:
+			param.SameSite = NetworkCookieSameSiteStrict
+		case http.SameSiteLaxMode:
+			param.SameSite = NetworkCookieSameSiteLax
+		case http.SameSiteNoneMode:
+			param.SameSite = NetworkCookieSameSiteNone
+		}
+
+		list = append(list, param)
+	}
+
+	return list
+}
+
+var regBrowserMajor = regexp.MustCompile(`/(\d+)\.`)
+
+// Major returns the major version of the product, such as 83 for "HeadlessChrome/83.0.4103.0",
+// it returns 0 if the product has no version
+func (r *BrowserGetVersionResult) Major() int {
+	m := regBrowserMajor.FindStringSubmatch(r.Product)
+	if m == nil {
+		return 0
+	}
+	major, _ := strconv.Atoi(m[1])
+	return major
+}
+
+var regWebKitVersion = regexp.MustCompile(`AppleWebKit/([\d.]+)`)
+
+// WebKitVersion returns the WebKit version from the user agent, such as "537.36"
+func (r *BrowserGetVersionResult) WebKitVersion() string {
+	m := regWebKitVersion.FindStringSubmatch(r.UserAgent)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
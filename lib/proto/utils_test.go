@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/ysmood/kit"
@@ -84,3 +86,70 @@ func TestNormalizeInputDispatchMouseEvent(t *testing.T) {
 
 	assert.Equal(t, `{"type":"mouseWheel","x":0,"y":0,"deltaX":0,"deltaY":0}`, string(data))
 }
+
+func TestNormalizeEmulationSetGeolocationOverride(t *testing.T) {
+	data, err := proto.EmulationSetGeolocationOverride{Accuracy: 1}.Normalize()
+	kit.E(err)
+	assert.Equal(t, `{"accuracy":1,"latitude":0,"longitude":0}`, string(data))
+
+	data, err = proto.EmulationSetGeolocationOverride{}.Normalize()
+	kit.E(err)
+	assert.Equal(t, `{}`, string(data))
+}
+
+func TestCookieToHTTP(t *testing.T) {
+	c := (&proto.NetworkCookie{
+		Name:     "a",
+		Value:    "1",
+		Domain:   ".example.com",
+		Path:     "/",
+		Expires:  123,
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: proto.NetworkCookieSameSiteStrict,
+	}).ToHTTP()
+
+	assert.Equal(t, ".example.com", c.Domain)
+	assert.EqualValues(t, 123, c.Expires.Unix())
+	assert.True(t, c.Secure)
+	assert.True(t, c.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, c.SameSite)
+
+	c = (&proto.NetworkCookie{Domain: "example.com", Expires: -1, Session: true}).ToHTTP()
+	assert.Equal(t, "", c.Domain)
+	assert.True(t, c.Expires.IsZero())
+}
+
+func TestCookiesFromHTTP(t *testing.T) {
+	expires := time.Unix(123, 0)
+
+	list := proto.CookiesFromHTTP([]*http.Cookie{
+		{Name: "a", Value: "1", Expires: expires, SameSite: http.SameSiteNoneMode, Secure: true},
+		{Name: "b", Value: "2", Domain: "example.com", MaxAge: -1},
+		{Name: "c", Value: "3"},
+	}, "https://example.com")
+
+	assert.Equal(t, "https://example.com", list[0].URL)
+	assert.Equal(t, expires, list[0].Expires.Time)
+	assert.Equal(t, proto.NetworkCookieSameSiteNone, list[0].SameSite)
+	assert.True(t, list[0].Secure)
+
+	assert.Equal(t, "example.com", list[1].Domain)
+	assert.True(t, list[1].Expires.Before(time.Now()))
+
+	assert.Nil(t, list[2].Expires)
+}
+
+func TestBrowserGetVersionResult(t *testing.T) {
+	v := &proto.BrowserGetVersionResult{
+		Product:   "HeadlessChrome/83.0.4103.0",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/83.0.4103.0 Safari/537.36",
+	}
+
+	assert.Equal(t, 83, v.Major())
+	assert.Equal(t, "537.36", v.WebKitVersion())
+
+	v = &proto.BrowserGetVersionResult{}
+	assert.Equal(t, 0, v.Major())
+	assert.Equal(t, "", v.WebKitVersion())
+}
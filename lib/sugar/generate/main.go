@@ -0,0 +1,367 @@
+// The generator of the sugar methods of the *E methods that don't have a hand-written one in sugar.go,
+// so that the new *E methods will always have them. Run it via "go generate" on the project root.
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ysmood/kit"
+)
+
+// the sugar methods panic via the mustCall of the must.go
+const output = "sugar_generated.go"
+
+// the types to generate for and the names of their receivers, the same as the ones of the sugar.go
+var receivers = map[string]string{
+	"Page":     "p",
+	"Element":  "el",
+	"Mouse":    "m",
+	"Keyboard": "k",
+}
+
+// the types whose sugar methods return the receiver, so that they can be chained
+var chainable = map[string]bool{
+	"Page":    true,
+	"Element": true,
+}
+
+type method struct {
+	typ  string
+	name string // the name of the *E method
+	decl *ast.FuncDecl
+	file *ast.File
+}
+
+func main() {
+	kit.E(kit.OutputFile(filepath.FromSlash(output), generate("."), nil))
+}
+
+// generate returns the source of the sugar methods of the package in the dir
+func generate(dir string) []byte {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != output
+	}, 0)
+	kit.E(err)
+
+	declared := map[string]map[string]bool{}
+	for t := range receivers {
+		declared[t] = map[string]bool{}
+	}
+
+	list := []*method{}
+	for _, f := range pkgs["rod"].Files {
+		for _, d := range f.Decls {
+			switch d := d.(type) {
+			case *ast.GenDecl:
+				addFields(declared, d)
+			case *ast.FuncDecl:
+				t := recvType(d)
+				if _, ok := receivers[t]; !ok {
+					continue
+				}
+				declared[t][d.Name.Name] = true
+				if isSugarable(d) {
+					list = append(list, &method{t, d.Name.Name, d, f})
+				}
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].typ != list[j].typ {
+			return list[i].typ < list[j].typ
+		}
+		return list[i].name < list[j].name
+	})
+
+	imports := map[string]bool{}
+	body := ""
+	for _, m := range list {
+		if declared[m.typ][strings.TrimSuffix(m.name, "E")] {
+			continue
+		}
+		body += m.format(fset, imports)
+	}
+
+	// the standard packages are grouped before the others, the same as the goimports
+	std, others := []string{}, []string{}
+	for p := range imports {
+		if strings.Contains(strings.Split(p, "/")[0], ".") {
+			others = append(others, strconv.Quote(p))
+		} else {
+			std = append(std, strconv.Quote(p))
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	paths := std
+	if len(std) > 0 && len(others) > 0 {
+		paths = append(paths, "")
+	}
+	paths = append(paths, others...)
+
+	code := `// This file is generated by "./lib/sugar/generate"
+
+package rod
+`
+	if len(paths) > 0 {
+		code += "\nimport (\n" + strings.Join(paths, "\n") + "\n)\n"
+	}
+	code += body
+
+	src, err := format.Source([]byte(code))
+	kit.E(err)
+	return src
+}
+
+// addFields marks the fields of the types as declared, a method can't have the same name as a field
+func addFields(declared map[string]map[string]bool, d *ast.GenDecl) {
+	for _, spec := range d.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok || declared[ts.Name.Name] == nil {
+			continue
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				declared[ts.Name.Name][name.Name] = true
+			}
+			if len(field.Names) == 0 {
+				declared[ts.Name.Name][embeddedName(field.Type)] = true
+			}
+		}
+	}
+}
+
+func embeddedName(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// recvType returns the name of T for the receiver *T
+func recvType(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) != 1 {
+		return ""
+	}
+	star, ok := d.Recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	id, ok := star.X.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	return id.Name
+}
+
+// isSugarable returns true for the exported *E methods whose last result is an error,
+// or whose only result is a function whose last result is an error, such as the wait functions
+func isSugarable(d *ast.FuncDecl) bool {
+	name := d.Name.Name
+	if !ast.IsExported(name) || len(name) < 2 || !strings.HasSuffix(name, "E") {
+		return false
+	}
+	if lastIsError(d.Type.Results) {
+		return true
+	}
+	fn := waitFunc(d.Type)
+	return fn != nil && lastIsError(fn.Results)
+}
+
+func lastIsError(results *ast.FieldList) bool {
+	types := fieldTypes(results)
+	if len(types) == 0 {
+		return false
+	}
+	id, ok := types[len(types)-1].(*ast.Ident)
+	return ok && id.Name == "error"
+}
+
+// waitFunc returns the type of the only result if it's a function
+func waitFunc(t *ast.FuncType) *ast.FuncType {
+	types := fieldTypes(t.Results)
+	if len(types) != 1 {
+		return nil
+	}
+	fn, _ := types[0].(*ast.FuncType)
+	return fn
+}
+
+// fieldTypes returns a type for each of the fields, such as two for "x, y float64"
+func fieldTypes(list *ast.FieldList) []ast.Expr {
+	types := []ast.Expr{}
+	if list == nil {
+		return types
+	}
+	for _, f := range list.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			types = append(types, f.Type)
+		}
+	}
+	return types
+}
+
+func (m *method) format(fset *token.FileSet, imports map[string]bool) string {
+	m.addImports(imports)
+
+	recv := receivers[m.typ]
+	name := strings.TrimSuffix(m.name, "E")
+	full := strconv.Quote(m.typ + "." + m.name)
+
+	params := []string{}
+	args := []string{}
+	values := []string{}
+	for i, f := range fieldTypes(m.decl.Type.Params) {
+		arg := paramName(m.decl.Type.Params, i)
+		params = append(params, arg+" "+node(fset, f))
+		values = append(values, arg)
+		if _, ok := f.(*ast.Ellipsis); ok {
+			arg += "..."
+		}
+		args = append(args, arg)
+	}
+	call := recv + "." + m.name + "(" + strings.Join(args, ", ") + ")"
+	arr := "Array{" + strings.Join(values, ", ") + "}"
+
+	head := kit.S(`
+		// {{.name}} is the sugar of the {{.method}}
+		func ({{.recv}} *{{.typ}}) {{.name}}({{.params}})`,
+		"name", name, "method", m.name, "recv", recv, "typ", m.typ, "params", strings.Join(params, ", "))
+
+	if fn := waitFunc(m.decl.Type); fn != nil && !lastIsError(m.decl.Type.Results) {
+		results := fieldTypes(fn.Results)
+		sig := "func()" + resultList(fset, results[:len(results)-1])
+		vars, ret := resultVars(len(results) - 1)
+		return head + " " + sig + " {\n" +
+			"wait := " + call + "\n" +
+			"return " + sig + " {\n" +
+			vars + "wait()\n" +
+			"mustCall(" + full + ", " + arr + ", err)\n" +
+			ret + "}\n}\n"
+	}
+
+	results := fieldTypes(m.decl.Type.Results)
+	results = results[:len(results)-1]
+
+	if len(results) == 0 {
+		if chainable[m.typ] {
+			return head + " *" + m.typ + " {\n" +
+				"mustCall(" + full + ", " + arr + ", " + call + ")\n" +
+				"return " + recv + "\n}\n"
+		}
+		return head + " {\n" +
+			"mustCall(" + full + ", " + arr + ", " + call + ")\n}\n"
+	}
+
+	vars, ret := resultVars(len(results))
+	return head + resultList(fset, results) + " {\n" +
+		vars + call + "\n" +
+		"mustCall(" + full + ", " + arr + ", err)\n" +
+		ret + "}\n"
+}
+
+// paramName returns the name of the ith param, the unnamed ones are named by the index
+func paramName(list *ast.FieldList, i int) string {
+	j := 0
+	for _, f := range list.List {
+		if len(f.Names) == 0 {
+			if j == i {
+				return "arg" + strconv.Itoa(i)
+			}
+			j++
+			continue
+		}
+		for _, n := range f.Names {
+			if j == i {
+				if n.Name == "_" {
+					return "arg" + strconv.Itoa(i)
+				}
+				return n.Name
+			}
+			j++
+		}
+	}
+	return ""
+}
+
+func resultList(fset *token.FileSet, types []ast.Expr) string {
+	list := []string{}
+	for _, t := range types {
+		list = append(list, node(fset, t))
+	}
+	switch len(list) {
+	case 0:
+		return ""
+	case 1:
+		return " " + list[0]
+	}
+	return " (" + strings.Join(list, ", ") + ")"
+}
+
+// resultVars returns the assignment of the results and the return statement
+func resultVars(n int) (vars, ret string) {
+	if n == 0 {
+		return "err := ", ""
+	}
+	names := []string{}
+	for i := 0; i < n; i++ {
+		names = append(names, "r"+strconv.Itoa(i))
+	}
+	list := strings.Join(names, ", ")
+	return list + ", err := ", "return " + list + "\n"
+}
+
+// addImports adds the packages that the signature of the method uses
+func (m *method) addImports(imports map[string]bool) {
+	paths := map[string]string{}
+	for _, spec := range m.file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(p)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		paths[name] = p
+	}
+
+	ast.Inspect(m.decl.Type, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); ok && paths[id.Name] != "" {
+			imports[paths[id.Name]] = true
+		}
+		return false
+	})
+}
+
+func node(fset *token.FileSet, n ast.Node) string {
+	buf := &bytes.Buffer{}
+	kit.E(printer.Fprint(buf, fset, n))
+	return buf.String()
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerated(t *testing.T) {
+	root := filepath.FromSlash("../../..")
+
+	generated, err := ioutil.ReadFile(filepath.Join(root, output))
+	assert.NoError(t, err)
+
+	assert.Equal(t, string(generated), string(generate(root)), `run "go generate" on the project root`)
+}
@@ -0,0 +1,259 @@
+// This file contains the log of the cdp traffic, it's written at the boundary between the browser and the cdp client,
+// so the calls of all the pages and the events of all the sessions are covered.
+
+package rod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// CDPLogRecord is a line of the json lines written by the CDPLog
+type CDPLogRecord struct {
+	Time time.Time `json:"time"`
+
+	// Type is "call", "response", or "event"
+	Type string `json:"type"`
+
+	// ID pairs the call with its response, it's 0 for the events
+	ID uint64 `json:"id,omitempty"`
+
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params,omitempty"` // the result for the response
+	Latency   time.Duration   `json:"latency,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// CDPLogOptions of the CDPLog
+type CDPLogOptions struct {
+	// Methods are the prefixes of the methods to log, such as "Network.", empty means all
+	Methods []string
+
+	// Sessions are the sessions to log, such as the page.SessionID, empty means all
+	Sessions []proto.TargetSessionID
+
+	// ParamsLimit truncates the string values of the params that are longer than it, 0 means no limit
+	ParamsLimit int
+
+	// KeepSecrets disables the redaction of the secrets, such as the Authorization and Cookie headers,
+	// the body of the Fetch.fulfillRequest, and the credentials of the Fetch.continueWithAuth
+	KeepSecrets bool
+}
+
+// the secret fields of the params of the methods
+var cdpSecretFields = map[string][]string{
+	"Fetch.fulfillRequest":    {"body"},
+	"Fetch.continueRequest":   {"postData"},
+	"Fetch.continueWithAuth":  {"authChallengeResponse"},
+	"Network.getResponseBody": {"body"},
+	"Fetch.getResponseBody":   {"body"},
+}
+
+// the headers to redact, in lower case
+var cdpSecretHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+const cdpRedacted = "[redacted]"
+
+// cdpLogger is shared by all the clones of a browser
+type cdpLogger struct {
+	lock sync.Mutex
+	w    io.Writer
+	opts CDPLogOptions
+	seq  uint64
+}
+
+// CDPLog writes the cdp traffic of the browser to w as json lines of CDPLogRecord, such as os.Stderr.
+// The calls are paired with the responses by the ID, the responses have the latency of the calls.
+// If opts is nil the string values longer than 200 bytes will be truncated and the secrets will be redacted.
+// Use nil w to stop it. It works for all the pages of the browser, including the ones created before it.
+func (b *Browser) CDPLog(w io.Writer, opts *CDPLogOptions) *Browser {
+	if w == nil {
+		b.cdpLog.disable()
+		return b
+	}
+
+	if opts == nil {
+		opts = &CDPLogOptions{ParamsLimit: 200}
+	}
+	b.cdpLog.enable(&cdpLogger{w: w, opts: *opts})
+	return b
+}
+
+// cdpLogState holds the logger, it's nil when the log is disabled, so that the traffic won't be encoded
+type cdpLogState struct {
+	v atomic.Value
+}
+
+func (s *cdpLogState) get() *cdpLogger {
+	if s == nil {
+		return nil
+	}
+	l, _ := s.v.Load().(*cdpLogger)
+	return l
+}
+
+func (s *cdpLogState) enable(l *cdpLogger) { s.v.Store(l) }
+
+func (s *cdpLogState) disable() { s.v.Store((*cdpLogger)(nil)) }
+
+// match returns true if the traffic of the method and session should be logged
+func (l *cdpLogger) match(sessionID, method string) bool {
+	if len(l.opts.Sessions) > 0 {
+		has := false
+		for _, id := range l.opts.Sessions {
+			has = has || string(id) == sessionID
+		}
+		if !has {
+			return false
+		}
+	}
+
+	if len(l.opts.Methods) == 0 {
+		return true
+	}
+	for _, prefix := range l.opts.Methods {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *cdpLogger) write(r *CDPLogRecord) {
+	r.Time = time.Now()
+	line := kit.MustToJSONBytes(r)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, _ = l.w.Write(append(line, '\n'))
+}
+
+// params returns the params that are truncated and redacted
+func (l *cdpLogger) params(method string, params json.RawMessage) json.RawMessage {
+	if len(params) == 0 {
+		return nil
+	}
+
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(params))
+	d.UseNumber()
+	if d.Decode(&v) != nil {
+		return params
+	}
+
+	if !l.opts.KeepSecrets {
+		v = redactSecrets(method, v)
+	}
+	if l.opts.ParamsLimit > 0 {
+		v = truncateStrings(v, l.opts.ParamsLimit)
+	}
+	return kit.MustToJSONBytes(v)
+}
+
+func (l *cdpLogger) event(sessionID, method string, params json.RawMessage) {
+	if !l.match(sessionID, method) {
+		return
+	}
+	l.write(&CDPLogRecord{Type: "event", SessionID: sessionID, Method: method, Params: l.params(method, params)})
+}
+
+// redactSecrets replaces the secret values of the headers and the fields of the method
+func redactSecrets(method string, v interface{}) interface{} {
+	if obj, ok := v.(map[string]interface{}); ok {
+		for _, field := range cdpSecretFields[method] {
+			if _, has := obj[field]; has {
+				obj[field] = cdpRedacted
+			}
+		}
+	}
+	return redactHeaders(v)
+}
+
+func redactHeaders(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		// the header entries of the Fetch domain, such as {"name": "Cookie", "value": "a=1"}
+		if name, ok := val["name"].(string); ok && cdpSecretHeaders[strings.ToLower(name)] {
+			if _, has := val["value"]; has {
+				val["value"] = cdpRedacted
+			}
+		}
+
+		for k, item := range val {
+			if cdpSecretHeaders[strings.ToLower(k)] {
+				val[k] = cdpRedacted
+				continue
+			}
+			val[k] = redactHeaders(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactHeaders(item)
+		}
+	}
+	return v
+}
+
+// loggingClient logs the calls and the responses of the client
+type loggingClient struct {
+	client proto.Client
+	logger *cdpLogger
+}
+
+func (c *loggingClient) Call(ctx context.Context, sessionID, method string, params interface{}) ([]byte, error) {
+	l := c.logger
+	if !l.match(sessionID, method) {
+		return c.client.Call(ctx, sessionID, method, params)
+	}
+
+	id := atomic.AddUint64(&l.seq, 1)
+	l.write(&CDPLogRecord{
+		Type:      "call",
+		ID:        id,
+		SessionID: sessionID,
+		Method:    method,
+		Params:    l.params(method, kit.MustToJSONBytes(params)),
+	})
+
+	start := time.Now()
+	res, err := c.client.Call(ctx, sessionID, method, params)
+
+	r := &CDPLogRecord{
+		Type:      "response",
+		ID:        id,
+		SessionID: sessionID,
+		Method:    method,
+		Params:    l.params(method, res),
+		Latency:   time.Since(start),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	l.write(r)
+
+	return res, err
+}
+
+// cdpClient returns the client to call, it logs the traffic if the CDPLog is enabled
+func (b *Browser) cdpClient() proto.Client {
+	l := b.cdpLog.get()
+	if l == nil {
+		return b.client
+	}
+	return &loggingClient{b.client, l}
+}
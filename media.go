@@ -0,0 +1,211 @@
+// This file contains the helpers of the canvas and the media elements, such as to scrape the charts that are
+// rendered to canvas, or to verify the playback of a video.
+
+package rod
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+const canvasToImageJS = `function (format, quality) {
+	if (this.tagName !== 'CANVAS') return { notCanvas: true }
+
+	try {
+		return { url: this.toDataURL(format, quality) }
+	} catch (e) {
+		return { error: e.name + ': ' + e.message, tainted: e.name === 'SecurityError' }
+	}
+}`
+
+// the this is the list of the webgl contexts of the page, the rows of the webgl are from bottom to top
+const webglToImageJS = `function (canvas, format, quality) {
+	const gl = this.find(c => c.canvas === canvas)
+	if (!gl) return null
+
+	const { width, height } = canvas
+	const pixels = new Uint8Array(width * height * 4)
+	gl.readPixels(0, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, pixels)
+
+	const img = document.createElement('canvas')
+	img.width = width
+	img.height = height
+	const ctx = img.getContext('2d')
+	const data = ctx.createImageData(width, height)
+	for (let y = 0; y < height; y++) {
+		const row = pixels.subarray((height - y - 1) * width * 4, (height - y) * width * 4)
+		data.data.set(row, y * width * 4)
+	}
+	ctx.putImageData(data, 0, 0)
+	return img.toDataURL(format, quality)
+}`
+
+// CanvasToImageE returns the image of the canvas element, the format is the mime type such as "image/png" or
+// "image/jpeg", the quality is between 0 and 1 for the lossy formats. If the canvas is tainted by the cross-origin
+// images an ErrCanvasTainted error will be returned. For a webgl canvas, the page should create the context with
+// the preserveDrawingBuffer, otherwise the image may be blank after the canvas is composited, use the
+// WebGLToImageE to read the drawing buffer instead. The reading never creates a context for the canvas.
+func (el *Element) CanvasToImageE(format string, quality float64) ([]byte, error) {
+	res, err := el.evalE(true, canvasToImageJS, Array{format, quality})
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Value.Get("notCanvas").Bool() {
+		return nil, &Error{nil, ErrNotCanvas, nil}
+	}
+	if msg := res.Value.Get("error"); msg.Exists() {
+		if res.Value.Get("tainted").Bool() {
+			return nil, &Error{nil, ErrCanvasTainted, msg.String()}
+		}
+		return nil, &Error{nil, ErrEval, msg.String()}
+	}
+
+	return decodeDataURL(res.Value.Get("url").String())
+}
+
+// WebGLToImageE is similar to the CanvasToImageE, but it reads the pixels from the drawing buffer of the webgl
+// context that the page has created for the canvas, such as the canvas that looks blank because the context isn't
+// created with the preserveDrawingBuffer. If the canvas has no webgl context, an ErrNotWebGL error will be returned.
+// The contexts are found by scanning the js heap of the page, which is slow and forces a garbage collection.
+func (el *Element) WebGLToImageE(format string, quality float64) ([]byte, error) {
+	res, err := el.evalE(true, `() => this.tagName === 'CANVAS'`, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Value.Bool() {
+		return nil, &Error{nil, ErrNotCanvas, nil}
+	}
+
+	url, err := el.webglImage(format, quality)
+	if err != nil {
+		return nil, err
+	}
+	if url == "" {
+		return nil, &Error{nil, ErrNotWebGL, nil}
+	}
+	return decodeDataURL(url)
+}
+
+func decodeDataURL(url string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(url[strings.Index(url, ",")+1:])
+}
+
+// webglImage returns the data url of the drawing buffer of the webgl context that the page has created for the canvas,
+// it's empty if there's no such context. The contexts are queried from the heap, because calling the getContext on
+// a canvas without any context would bind a new one to it.
+func (el *Element) webglImage(format string, quality float64) (string, error) {
+	for _, name := range []string{"WebGL2RenderingContext", "WebGLRenderingContext"} {
+		prototype, err := el.evalE(false, `n => {
+			const w = this.ownerDocument.defaultView
+			return w[n] ? w[n].prototype : null
+		}`, Array{name})
+		if err != nil {
+			return "", err
+		}
+		if prototype.ObjectID == "" {
+			continue
+		}
+
+		list, err := proto.RuntimeQueryObjects{PrototypeObjectID: prototype.ObjectID}.Call(el)
+		_ = proto.RuntimeReleaseObject{ObjectID: prototype.ObjectID}.Call(el)
+		if err != nil {
+			return "", err
+		}
+
+		call := callFunctionOn(list.Objects.ObjectID, true, webglToImageJS, Array{format, quality})
+		call.Arguments = append([]*proto.RuntimeCallArgument{{ObjectID: el.ObjectID}}, call.Arguments...)
+		res, err := call.Call(el)
+		_ = proto.RuntimeReleaseObject{ObjectID: list.Objects.ObjectID}.Call(el)
+		if err != nil {
+			return "", err
+		}
+		url, err := evalResult(res)
+		if err != nil {
+			return "", err
+		}
+		if url.Value.String() != "" {
+			return url.Value.String(), nil
+		}
+	}
+	return "", nil
+}
+
+// MediaState is the playback state of a video or audio element
+type MediaState struct {
+	CurrentTime float64 `json:"currentTime"`
+	Duration    float64 `json:"duration"` // NaN is reported as 0 before the metadata is loaded
+	Paused      bool    `json:"paused"`
+	Ended       bool    `json:"ended"`
+
+	// ReadyState is the same as the HTMLMediaElement.readyState, such as 4 means enough data to play through
+	ReadyState int `json:"readyState"`
+
+	// VideoWidth and VideoHeight are 0 for the audio elements
+	VideoWidth  int `json:"videoWidth"`
+	VideoHeight int `json:"videoHeight"`
+}
+
+// MediaStateE returns the playback state of the video or audio element
+func (el *Element) MediaStateE() (*MediaState, error) {
+	state := &MediaState{}
+	err := el.mediaEval(state, `() => ({
+		currentTime: this.currentTime,
+		duration: isNaN(this.duration) ? 0 : this.duration,
+		paused: this.paused,
+		ended: this.ended,
+		readyState: this.readyState,
+		videoWidth: this.videoWidth || 0,
+		videoHeight: this.videoHeight || 0,
+	})`)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// PlayE plays the video or audio element and waits until it starts. If the autoplay policy of the browser blocks it,
+// an ErrMediaNotAllowed error will be returned, such as the page hasn't been interacted by the user.
+func (el *Element) PlayE() error {
+	var msg *string
+	err := el.mediaEval(&msg, `async () => {
+		try {
+			await this.play()
+			return null
+		} catch (e) {
+			return e.name + ': ' + e.message
+		}
+	}`)
+	if err != nil {
+		return err
+	}
+
+	if msg == nil {
+		return nil
+	}
+	if strings.HasPrefix(*msg, "NotAllowedError") {
+		return &Error{nil, ErrMediaNotAllowed, *msg}
+	}
+	return &Error{nil, ErrEval, *msg}
+}
+
+// PauseE pauses the video or audio element
+func (el *Element) PauseE() error {
+	var nothing *struct{}
+	return el.mediaEval(&nothing, `() => { this.pause(); return null }`)
+}
+
+// mediaEval evals the js into dst if the element is a media element, otherwise returns an ErrNotMedia error
+func (el *Element) mediaEval(dst interface{}, js string) error {
+	res, err := el.evalE(true, `() => this instanceof HTMLMediaElement`, nil)
+	if err != nil {
+		return err
+	}
+	if !res.Value.Bool() {
+		return &Error{nil, ErrNotMedia, nil}
+	}
+
+	return el.page.Context(el.ctx).evalInto(dst, el.ObjectID, js, nil)
+}
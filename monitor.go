@@ -0,0 +1,278 @@
+// This file contains the monitor server of the browser.
+// The reason why not to use "chrome://inspect/#devices" is one target cannot be driven by multiple controllers.
+
+package rod
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/assets"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the max number of the events to keep for each page of the monitor, the older ones will be dropped
+var monitorEvents = 100
+
+// MonitorEventType is the type of the MonitorEvent
+type MonitorEventType string
+
+const (
+	// MonitorEventTrace is the trace record of an action, such as a click
+	MonitorEventTrace MonitorEventType = "trace"
+
+	// MonitorEventConsole is the console message of the page, such as the console.log
+	MonitorEventConsole MonitorEventType = "console"
+)
+
+// MonitorEvent is an event of a page that is served by the monitor server
+type MonitorEvent struct {
+	Time time.Time        `json:"time"`
+	Type MonitorEventType `json:"type"`
+	Msg  string           `json:"msg"`
+}
+
+// monitor is shared by all the clones of a browser
+type monitor struct {
+	browser *Browser
+
+	lock   sync.Mutex
+	pages  map[proto.TargetTargetID]*Page // the clones owned by the monitor, canceling them won't affect the user's pages
+	events map[proto.TargetTargetID][]*MonitorEvent
+}
+
+// monitorState is shared by all the clones of a browser
+type monitorState struct {
+	lock sync.Mutex
+	m    *monitor // nil means the monitor server isn't started
+}
+
+// get returns the monitor that is started the last
+func (s *monitorState) get() *monitor {
+	if s == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.m
+}
+
+func (s *monitorState) start(m *monitor) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.m = m
+}
+
+func (m *monitor) add(id proto.TargetTargetID, typ MonitorEventType, msg string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	list := append(m.events[id], &MonitorEvent{Time: time.Now(), Type: typ, Msg: msg})
+	if len(list) > monitorEvents {
+		list = list[len(list)-monitorEvents:]
+	}
+	m.events[id] = list
+}
+
+func (m *monitor) list(id proto.TargetTargetID) []*MonitorEvent {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	list := append([]*MonitorEvent{}, m.events[id]...)
+	return list
+}
+
+// page returns the session of the monitor for the target, it's created when a client polls the page for the
+// first time, so that the monitor won't interfere with the pages nobody watches
+func (m *monitor) page(id proto.TargetTargetID) (*Page, error) {
+	m.lock.Lock()
+	p, has := m.pages[id]
+	m.lock.Unlock()
+
+	if has {
+		return p, nil
+	}
+
+	shared, err := m.browser.PageFromTargetIDE(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the page from the registry may be the one the user holds
+	p = shared.Context(m.browser.ctx)
+
+	_, err = p.EachConsoleE(func(e *proto.RuntimeConsoleAPICalled) {
+		m.add(id, MonitorEventConsole, ConsoleText(e))
+	}, true)
+	if err != nil {
+		p.ctxCancel()
+		return nil, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if prev, has := m.pages[id]; has {
+		p.ctxCancel()
+		return prev, nil
+	}
+	m.pages[id] = p
+	return p, nil
+}
+
+// drop the session of the target, such as the target is closed
+func (m *monitor) drop(id proto.TargetTargetID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if p, has := m.pages[id]; has {
+		p.ctxCancel()
+	}
+	delete(m.pages, id)
+	delete(m.events, id)
+}
+
+// ServeMonitorE starts the monitor server on the host, such as "127.0.0.1:0", and returns the url of it.
+// Besides the html viewer, it serves the json endpoints for programmatic use:
+//
+//	GET /pages                   the target infos of the pages
+//	GET /page/:id/screenshot     the png screenshot of the page, the "/screenshot/:id" is the same
+//	GET /page/:id/events         the last trace records and console messages of the page
+//
+// The screenshots are only captured when a client requests them.
+func (b *Browser) ServeMonitorE(host string) (string, error) {
+	srv, err := b.serveMonitor(host)
+	if err != nil {
+		return "", err
+	}
+	return "http://" + srv.Listener.Addr().String(), nil
+}
+
+// ServeMonitor starts the monitor server, check ServeMonitorE for the details
+func (b *Browser) ServeMonitor(host string) *kit.ServerContext {
+	if host == "" {
+		return nil
+	}
+
+	srv, err := b.serveMonitor(host)
+	kit.E(err)
+
+	kit.Log("[rod] monitor server on", "http://"+srv.Listener.Addr().String(), "(open it with your browser)")
+
+	return srv
+}
+
+func (b *Browser) serveMonitor(host string) (*kit.ServerContext, error) {
+	srv, err := kit.Server(host)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &monitor{
+		browser: b,
+		pages:   map[proto.TargetTargetID]*Page{},
+		events:  map[proto.TargetTargetID][]*MonitorEvent{},
+	}
+	b.monitor.start(m)
+
+	jsonErr := func(ctx kit.GinContext, err error) {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	pages := func() ([]*proto.TargetTargetInfo, error) {
+		res, err := proto.TargetGetTargets{}.Call(b)
+		if err != nil {
+			return nil, err
+		}
+
+		list := []*proto.TargetTargetInfo{}
+		for _, info := range res.TargetInfos {
+			if info.Type == "page" {
+				list = append(list, info)
+			}
+		}
+		return list, nil
+	}
+
+	srv.Engine.GET("/", func(ctx kit.GinContext) {
+		list, err := pages()
+		kit.E(err)
+
+		ginHTML(ctx, kit.S(assets.Monitor, "list", list))
+	})
+	srv.Engine.GET("/pages", func(ctx kit.GinContext) {
+		list, err := pages()
+		if err != nil {
+			jsonErr(ctx, err)
+			return
+		}
+		ctx.PureJSON(http.StatusOK, list)
+	})
+	srv.Engine.GET("/page/:id", func(ctx kit.GinContext) {
+		ginHTML(ctx, kit.S(
+			assets.MonitorPage,
+			"id", ctx.Param("id"),
+		))
+	})
+	srv.Engine.GET("/api/page/:id", func(ctx kit.GinContext) {
+		info, err := proto.TargetGetTargetInfo{
+			TargetID: proto.TargetTargetID(ctx.Param("id")),
+		}.Call(b)
+		if err != nil {
+			jsonErr(ctx, err)
+			return
+		}
+		ctx.PureJSON(http.StatusOK, info.TargetInfo)
+	})
+	screenshot := func(ctx kit.GinContext) {
+		id := proto.TargetTargetID(ctx.Param("id"))
+
+		p, err := m.page(id)
+		if err != nil {
+			jsonErr(ctx, err)
+			return
+		}
+
+		bin, err := p.ScreenshotE(false, &proto.PageCaptureScreenshot{})
+		if err != nil {
+			m.drop(id)
+			jsonErr(ctx, err)
+			return
+		}
+
+		ctx.Header("Content-Type", "image/png;")
+		_, _ = ctx.Writer.Write(bin)
+	}
+	srv.Engine.GET("/page/:id/screenshot", screenshot)
+	srv.Engine.GET("/screenshot/:id", screenshot) // the old route
+	srv.Engine.GET("/page/:id/events", func(ctx kit.GinContext) {
+		id := proto.TargetTargetID(ctx.Param("id"))
+
+		_, err := m.page(id)
+		if err != nil {
+			jsonErr(ctx, err)
+			return
+		}
+
+		ctx.PureJSON(http.StatusOK, m.list(id))
+	})
+
+	// the closed targets won't be polled anymore
+	go goob.Each(b.event.Subscribe(b.ctx), func(msg *cdp.Event) {
+		e := &proto.TargetTargetDestroyed{}
+		if Event(msg, e) {
+			m.drop(e.TargetID)
+		}
+	})
+
+	go func() { _ = srv.Do() }()
+	go func() {
+		<-b.ctx.Done()
+		_ = srv.Listener.Close()
+	}()
+
+	return srv, nil
+}
@@ -1,13 +1,20 @@
 package rod
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"sync"
+	"time"
 
+	"github.com/ysmood/rod/lib/cdp"
 	"github.com/ysmood/rod/lib/input"
 	"github.com/ysmood/rod/lib/proto"
 )
 
+// the interval between the moves of a drag, so that the dragover handlers of the page can fire
+var dragInterval = 10 * time.Millisecond
+
 // Mouse represents the mouse on a page, it's always related the main frame
 type Mouse struct {
 	page *Page
@@ -48,7 +55,7 @@ func (m *Mouse) MoveE(x, y float64, steps int) error {
 			Y:         toY,
 			Button:    button,
 			Buttons:   buttons,
-			Modifiers: m.page.Keyboard.modifiers,
+			Modifiers: m.page.Keyboard.getModifiers(),
 		}.Call(m.page)
 		if err != nil {
 			return err
@@ -71,9 +78,7 @@ func (m *Mouse) MoveE(x, y float64, steps int) error {
 
 // ScrollE the relative offset with specified steps
 func (m *Mouse) ScrollE(offsetX, offsetY float64, steps int) error {
-	if m.page.browser.trace {
-		defer m.page.Overlay(0, 0, 200, 0, fmt.Sprintf("scroll (%.2f, %.2f)", offsetX, offsetY))()
-	}
+	defer m.page.tryTrace(0, 0, 200, 0, fmt.Sprintf("scroll (%.2f, %.2f)", offsetX, offsetY))()
 	m.page.browser.trySlowmotion()
 
 	if steps < 1 {
@@ -92,7 +97,7 @@ func (m *Mouse) ScrollE(offsetX, offsetY float64, steps int) error {
 			Y:         m.y,
 			Button:    button,
 			Buttons:   buttons,
-			Modifiers: m.page.Keyboard.modifiers,
+			Modifiers: m.page.Keyboard.getModifiers(),
 			DeltaX:    stepX,
 			DeltaY:    stepY,
 		}.Call(m.page)
@@ -118,7 +123,7 @@ func (m *Mouse) DownE(button proto.InputMouseButton, clicks int64) error {
 		Button:     button,
 		Buttons:    buttons,
 		ClickCount: clicks,
-		Modifiers:  m.page.Keyboard.modifiers,
+		Modifiers:  m.page.Keyboard.getModifiers(),
 		X:          m.x,
 		Y:          m.y,
 	}.Call(m.page)
@@ -161,9 +166,7 @@ func (m *Mouse) UpE(button proto.InputMouseButton, clicks int64) error {
 
 // ClickE doc is similar to the method Click
 func (m *Mouse) ClickE(button proto.InputMouseButton) error {
-	if m.page.browser.trace {
-		defer m.page.Overlay(0, 0, 200, 0, "click "+string(button))()
-	}
+	defer m.page.tryTrace(0, 0, 200, 0, "click "+string(button))()
 	m.page.browser.trySlowmotion()
 
 	err := m.DownE(button, 1)
@@ -173,3 +176,112 @@ func (m *Mouse) ClickE(button proto.InputMouseButton) error {
 
 	return m.UpE(button, 1)
 }
+
+// DragE holds the left button and moves from the start point to the end point with specified steps.
+// If the page starts a native drag and drop, such as the draggable elements, the drag events
+// will be dispatched with the data of the drag, because the mouse events can't simulate them.
+func (m *Mouse) DragE(fromX, fromY, toX, toY float64, steps int) error {
+	defer m.page.tryTrace(
+		math.Min(fromX, toX), math.Min(fromY, toY),
+		math.Max(math.Abs(toX-fromX), 1), math.Max(math.Abs(toY-fromY), 1),
+		fmt.Sprintf("drag (%.2f, %.2f) to (%.2f, %.2f)", fromX, fromY, toX, toY),
+	)()
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	err := m.MoveE(fromX, fromY, 1)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(m.page.ctx)
+	defer cancel()
+	s := m.page.event.Subscribe(ctx)
+
+	err = proto.InputSetInterceptDrags{Enabled: true}.Call(m.page)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = proto.InputSetInterceptDrags{Enabled: false}.Call(m.page) }()
+
+	// returns the data of the native drag if it starts within the interval
+	intercepted := func() *proto.InputDragData {
+		t := time.NewTimer(dragInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				return nil
+			case msg, ok := <-s:
+				if !ok {
+					return nil
+				}
+				e := &proto.InputDragIntercepted{}
+				if Event(msg.(*cdp.Event), e) {
+					return e.Data
+				}
+			}
+		}
+	}
+
+	dispatch := func(t proto.InputDispatchDragEventType, x, y float64, data *proto.InputDragData) error {
+		return proto.InputDispatchDragEvent{
+			Type:      t,
+			X:         x,
+			Y:         y,
+			Data:      data,
+			Modifiers: m.page.Keyboard.getModifiers(),
+		}.Call(m.page)
+	}
+
+	err = m.DownE(proto.InputMouseButtonLeft, 1)
+	if err != nil {
+		return err
+	}
+
+	var data *proto.InputDragData
+
+	stepX := (toX - fromX) / float64(steps)
+	stepY := (toY - fromY) / float64(steps)
+
+	for i := 1; i <= steps; i++ {
+		x := fromX + stepX*float64(i)
+		y := fromY + stepY*float64(i)
+
+		err = m.MoveE(x, y, 1)
+		if err != nil {
+			return err
+		}
+
+		if data == nil {
+			data = intercepted()
+			if data == nil {
+				continue
+			}
+
+			err = dispatch(proto.InputDispatchDragEventTypeDragEnter, x, y, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		// the drop is only allowed after a dragover
+		err = dispatch(proto.InputDispatchDragEventTypeDragOver, x, y, data)
+		if err != nil {
+			return err
+		}
+		time.Sleep(dragInterval)
+	}
+
+	if data != nil {
+		err = dispatch(proto.InputDispatchDragEventTypeDrop, toX, toY, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.UpE(proto.InputMouseButtonLeft, 1)
+}
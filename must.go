@@ -0,0 +1,133 @@
+// This file contains the helpers of the sugar methods that are generated by "./lib/sugar/generate",
+// such as the error they panic with, and the Recover that turns the panic into the failure of a test.
+
+package rod
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// CallDetails is the details of the ErrCallFailed error
+type CallDetails struct {
+	// Method is the name of the method that fails, such as "Page.WaitRequestIdleE"
+	Method string
+
+	// Args are the arguments of the method, a variadic argument is a single slice
+	Args Array
+
+	Err error
+}
+
+// the methods whose arguments carry the credentials, they are redacted from the details
+var secretArgsMethods = map[string]bool{
+	"Browser.HandleAuthE": true,
+	"Page.HandleAuthE":    true,
+	"Page.SetProxyE":      true,
+	"Page.SetCookiesE":    true,
+	"Page.RestoreStateE":  true,
+}
+
+// the index of the argument that is the dict of the headers, the values of the secret headers are redacted
+var headerDictArgs = map[string]int{
+	"Page.SetExtraHeadersE":         0,
+	"Page.SetExtraHeadersForHostsE": 1,
+}
+
+// the max length of an argument in the details, the longer ones such as the bodies are truncated
+var callDetailsArgLimit = 200
+
+// String such as `Page.GetDownloadFileE("*.csv"): context deadline exceeded`
+func (d *CallDetails) String() string {
+	args := []string{}
+	for i, a := range d.Args {
+		args = append(args, d.formatArg(i, a))
+	}
+	return fmt.Sprintf("%s(%s): %v", d.Method, strings.Join(args, ", "), d.Err)
+}
+
+func (d *CallDetails) formatArg(i int, a interface{}) string {
+	if secretArgsMethods[d.Method] {
+		return cdpRedacted
+	}
+
+	if j, has := headerDictArgs[d.Method]; has && j == i {
+		if dict, ok := a.([]string); ok {
+			list := append([]string{}, dict...)
+			for k := 0; k+1 < len(list); k += 2 {
+				if cdpSecretHeaders[strings.ToLower(list[k])] {
+					list[k+1] = cdpRedacted
+				}
+			}
+			a = list
+		}
+	}
+
+	str := fmt.Sprintf("%#v", a)
+	if len(str) > callDetailsArgLimit {
+		str = str[:callDetailsArgLimit] + "..."
+	}
+	return str
+}
+
+// mustCall panics with an ErrCallFailed error if err isn't nil, the err can be unwrapped from it
+func mustCall(method string, args Array, err error) {
+	if err != nil {
+		panic(&Error{err, ErrCallFailed, &CallDetails{method, args, err}})
+	}
+}
+
+// TestingT is the subset of the testing.TB that the Recover uses
+type TestingT interface {
+	Helper()
+	Fatal(args ...interface{})
+}
+
+// Recover turns the panic of an error, such as the one of the sugar methods, into the t.Fatal with the stack of
+// where the error is thrown, the frames of the runtime and the sugar methods are removed. The other panics are
+// thrown again. Call it via defer at the top of a test:
+//
+//	defer rod.Recover(t)
+func Recover(t TestingT) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	err, ok := v.(error)
+	if !ok {
+		panic(v)
+	}
+
+	t.Helper()
+	t.Fatal(fmt.Sprintf("%v\n\n%s", err, cleanStack()))
+}
+
+// cleanStack returns the stack of the panic that is being recovered
+func cleanStack() string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	list := []string{}
+	for {
+		f, more := frames.Next()
+		if !hiddenFrame(f) {
+			list = append(list, fmt.Sprintf("%s\n\t%s:%d", f.Function, f.File, f.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(list, "\n")
+}
+
+func hiddenFrame(f runtime.Frame) bool {
+	return strings.HasPrefix(f.Function, "runtime.") ||
+		strings.HasPrefix(f.Function, "testing.") ||
+		strings.HasPrefix(f.Function, "github.com/ysmood/kit.") ||
+		strings.HasSuffix(f.File, "/sugar.go") ||
+		strings.HasSuffix(f.File, "/sugar_generated.go") ||
+		strings.HasSuffix(f.File, "/must.go")
+}
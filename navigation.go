@@ -0,0 +1,189 @@
+// This file contains the interception and the responses of the navigations. The decision of the interception is made
+// when the request of the document is paused by the Fetch domain, so the request never leaves the browser if it's canceled.
+
+package rod
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// NavigationDecision is the decision for a navigation of the EachNavigationRequestE
+type NavigationDecision struct {
+	cancel   bool
+	redirect string
+}
+
+var (
+	// NavigationContinue lets the navigation continue
+	NavigationContinue = NavigationDecision{}
+
+	// NavigationCancel cancels the navigation, the current document of the page will be kept
+	NavigationCancel = NavigationDecision{cancel: true}
+)
+
+// NavigationRedirectTo redirects the navigation to the url, the page will see it as a normal redirect
+func NavigationRedirectTo(url string) NavigationDecision {
+	return NavigationDecision{redirect: url}
+}
+
+// EachNavigationRequestE calls the handler with the url of each navigation of the page's frame until stop is called,
+// such as the clicks on the links, the form submissions, and the NavigateE. The navigations of the child iframes
+// are not included. The redirected navigation will be passed to the handler again with the new url.
+// The navigation canceled by the handler fails with net::ERR_ABORTED, so the NavigateE will return an error for it.
+func (p *Page) EachNavigationRequestE(handler func(url string) NavigationDecision) (stop func() error, err error) {
+	fp := newFetchPattern("*")
+	fp.pattern.ResourceType = proto.NetworkResourceTypeDocument
+
+	return p.hijackRequests(fp, func(h *HijackContext) error {
+		if h.Event.FrameID != p.FrameID {
+			return h.ContinueRequest(nil)
+		}
+
+		d := handler(h.URL())
+
+		switch {
+		case d.cancel:
+			// the aborted navigation doesn't commit an error page, so the current document stays intact
+			return h.FailRequest(proto.NetworkErrorReasonAborted)
+
+		case d.redirect != "":
+			return h.FulfillRequest(&proto.FetchFulfillRequest{
+				ResponseCode: 302,
+				ResponseHeaders: []*proto.FetchHeaderEntry{
+					{Name: "Location", Value: d.redirect},
+				},
+			})
+		}
+
+		return h.ContinueRequest(nil)
+	})
+}
+
+// RestrictNavigationE cancels the navigations of the page whose hosts are not in the allowedHosts until stop is called,
+// such as to prevent a crawler from leaving the site. The hosts are compared without the ports, the navigations
+// that are not http or https, such as the data urls, are not restricted.
+func (p *Page) RestrictNavigationE(allowedHosts []string) (stop func() error, err error) {
+	return p.EachNavigationRequestE(func(u string) NavigationDecision {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return NavigationCancel
+		}
+
+		if parsed.Scheme != "http" && parsed.Scheme != "https" {
+			return NavigationContinue
+		}
+
+		for _, host := range allowedHosts {
+			if strings.EqualFold(parsed.Hostname(), host) {
+				return NavigationContinue
+			}
+		}
+		return NavigationCancel
+	})
+}
+
+// NavigationResult is the result of the NavigateWithResponseE
+type NavigationResult struct {
+	// URL of the final document after the redirects
+	URL string
+
+	// Status and Headers of the response of the final document, the Status is 0 if there's no response
+	Status  int64
+	Headers proto.NetworkHeaders
+
+	// Response of the final document, nil if there's no response
+	Response *proto.NetworkResponse
+
+	// Redirects are the responses of the redirects in order, the first one is the response of the original url
+	Redirects []*proto.NetworkResponse
+
+	// NoResponse is true if the document isn't loaded from the network, such as the back/forward cache,
+	// the same document navigations, and the "about:blank"
+	NoResponse bool
+}
+
+// OK returns true if the status is 2xx or there's no response
+func (r *NavigationResult) OK() bool {
+	return r.NoResponse || (r.Status >= 200 && r.Status < 300)
+}
+
+// NavigateWithResponseE navigates like the NavigateE, and returns the response of the document of the navigation,
+// the responses are matched with the loader id of the navigation. A non-2xx status isn't an error, use the
+// NavigationResult.OK or the NavigateOKE to check it.
+func (p *Page) NavigateWithResponseE(u string) (*NavigationResult, error) {
+	// subscribe before the navigation, so that we won't miss the responses that arrive before it returns
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	s := p.event.Subscribe(ctx)
+
+	nav, err := p.navigate(u)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &NavigationResult{URL: u}
+
+	// the same document navigation has no loader
+	if nav.LoaderID == "" {
+		result.NoResponse = true
+		return result, nil
+	}
+
+	// the StopLoadingE before the navigation may stop the previous loading, skip the events of it
+	started := false
+
+	for msg := range s {
+		e := msg.(*cdp.Event)
+		sent := &proto.NetworkRequestWillBeSent{}
+		received := &proto.NetworkResponseReceived{}
+		start := &proto.PageFrameStartedLoading{}
+		stopped := &proto.PageFrameStoppedLoading{}
+
+		switch {
+		case Event(e, start) && start.FrameID == nav.FrameID:
+			started = true
+
+		case Event(e, sent) && sent.LoaderID == nav.LoaderID && sent.Type == proto.NetworkResourceTypeDocument:
+			if sent.RedirectResponse != nil {
+				result.Redirects = append(result.Redirects, sent.RedirectResponse)
+			}
+			result.URL = sent.Request.URL
+
+		case Event(e, received) && received.LoaderID == nav.LoaderID && received.Type == proto.NetworkResourceTypeDocument:
+			result.URL = received.Response.URL
+			result.Status = received.Response.Status
+			result.Headers = received.Response.Headers
+			result.Response = received.Response
+			return result, nil
+
+		// such as the back/forward cache, the frame stops loading without any response
+		case Event(e, stopped) && stopped.FrameID == nav.FrameID && started:
+			result.NoResponse = true
+			return result, nil
+		}
+	}
+
+	if err := p.crash.err(); err != nil {
+		return nil, err
+	}
+	return nil, p.ctx.Err()
+}
+
+// NavigateOKE is similar to the NavigateWithResponseE, but returns an ErrNavigationStatus error if the status
+// of the response is 400 or above
+func (p *Page) NavigateOKE(u string) (*NavigationResult, error) {
+	result, err := p.NavigateWithResponseE(u)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Status >= 400 {
+		return result, &Error{nil, ErrNavigationStatus, result.Status}
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+// This file contains the code to serialize the remote objects of the page.
+
+package rod
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// jsonShim stringifies the object, it throws when the object has members that JSON.stringify
+// can't serialize correctly, such as the dom nodes, maps and sets, so that we can walk them instead
+const jsonShim = `function() {
+	return JSON.stringify(this, function(k, v) {
+		if (v instanceof Node || v instanceof Map || v instanceof Set) throw new Error('not serializable')
+		return v
+	})
+}`
+
+// ObjectToJSONE serializes the remote object, such as the one returned by EvalE with byValue set to false.
+// The undefined values and the functions become null, the dom nodes become their descriptions, such as "div#id.class",
+// the maps and sets become arrays, and the circular references become "[Circular]".
+// The remote objects created during the serialization will be released.
+func (p *Page) ObjectToJSONE(obj *proto.RuntimeRemoteObject) (proto.JSON, error) {
+	w := &objectWalker{page: p}
+	defer w.release()
+
+	v, _, err := w.walk(obj, nil)
+	if err != nil {
+		return proto.JSON{}, err
+	}
+	return proto.NewJSON(v), nil
+}
+
+type objectWalker struct {
+	page *Page
+
+	// the remote objects created by the walker
+	created []proto.RuntimeRemoteObjectID
+}
+
+func (w *objectWalker) release() {
+	for _, id := range w.created {
+		_ = w.page.ReleaseE(id)
+	}
+}
+
+func (w *objectWalker) track(obj *proto.RuntimeRemoteObject) {
+	if obj != nil && obj.ObjectID != "" {
+		w.created = append(w.created, obj.ObjectID)
+	}
+}
+
+// walk returns the value of the obj, ok is false if the obj should be omitted as a member,
+// the ancestors are used to detect the circular references
+func (w *objectWalker) walk(obj *proto.RuntimeRemoteObject, ancestors []proto.RuntimeRemoteObjectID) (
+	v interface{}, ok bool, err error,
+) {
+	switch {
+	case obj == nil, obj.Type == proto.RuntimeRemoteObjectTypeUndefined, obj.Type == proto.RuntimeRemoteObjectTypeFunction,
+		obj.Type == proto.RuntimeRemoteObjectTypeSymbol:
+		return nil, false, nil
+	case obj.UnserializableValue != "":
+		return string(obj.UnserializableValue), true, nil
+	case obj.ObjectID == "":
+		if obj.Value.Raw == "" {
+			return nil, true, nil
+		}
+		return json.RawMessage(obj.Value.Raw), true, nil
+	case obj.Subtype == proto.RuntimeRemoteObjectSubtypeNode:
+		return obj.Description, true, nil
+	}
+
+	circular, err := w.call(obj.ObjectID, true, `function() { return Array.prototype.includes.call(arguments, this) }`, ancestors)
+	if err != nil {
+		return nil, false, err
+	}
+	if circular.Value.Bool() {
+		return "[Circular]", true, nil
+	}
+
+	if obj.Subtype == proto.RuntimeRemoteObjectSubtypeMap || obj.Subtype == proto.RuntimeRemoteObjectSubtypeSet {
+		list, err := w.call(obj.ObjectID, false, `function() { return Array.from(this) }`, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		w.track(list)
+		return w.walk(list, append(ancestors, obj.ObjectID))
+	}
+
+	str, err := w.call(obj.ObjectID, true, jsonShim, nil)
+	if err == nil {
+		if str.Type != proto.RuntimeRemoteObjectTypeString {
+			return nil, true, nil
+		}
+		return json.RawMessage(str.Value.String()), true, nil
+	}
+	if !IsError(err, ErrEval) {
+		return nil, false, err
+	}
+
+	res, err := proto.RuntimeGetProperties{ObjectID: obj.ObjectID, OwnProperties: true}.Call(w.page)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ancestors = append(ancestors, obj.ObjectID)
+	list := []interface{}{}
+	dict := map[string]interface{}{}
+
+	for _, prop := range res.Result {
+		w.track(prop.Value)
+
+		if !prop.Enumerable || prop.Value == nil || prop.Symbol != nil {
+			continue
+		}
+
+		val, ok, err := w.walk(prop.Value, ancestors)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if obj.Subtype == proto.RuntimeRemoteObjectSubtypeArray {
+			i, err := strconv.Atoi(prop.Name)
+			if err != nil {
+				continue
+			}
+			for len(list) <= i {
+				list = append(list, nil)
+			}
+			list[i] = val
+		} else if ok {
+			dict[prop.Name] = val
+		}
+	}
+
+	if obj.Subtype == proto.RuntimeRemoteObjectSubtypeArray {
+		return list, true, nil
+	}
+	return dict, true, nil
+}
+
+func (w *objectWalker) call(objectID proto.RuntimeRemoteObjectID, byValue bool, js string, ids []proto.RuntimeRemoteObjectID) (
+	*proto.RuntimeRemoteObject, error,
+) {
+	args := []*proto.RuntimeCallArgument{}
+	for _, id := range ids {
+		args = append(args, &proto.RuntimeCallArgument{ObjectID: id})
+	}
+
+	res, err := proto.RuntimeCallFunctionOn{
+		ObjectID:            objectID,
+		FunctionDeclaration: js,
+		Arguments:           args,
+		ReturnByValue:       byValue,
+	}.Call(w.page)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.ExceptionDetails != nil {
+		return nil, &Error{nil, ErrEval, exceptionText(res.ExceptionDetails)}
+	}
+	return res.Result, nil
+}
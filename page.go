@@ -1,19 +1,36 @@
 package rod
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/tidwall/gjson"
 	"github.com/ysmood/goob"
 	"github.com/ysmood/kit"
 	"github.com/ysmood/rod/lib/assets"
 	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/devices"
 	"github.com/ysmood/rod/lib/proto"
 )
 
@@ -26,6 +43,7 @@ type Page struct {
 	ctx           context.Context
 	ctxCancel     func()
 	timeoutCancel func()
+	timeoutParent *Page // the object that the Timeout is called on
 
 	browser *Browser
 
@@ -36,13 +54,45 @@ type Page struct {
 	// devices
 	Mouse    *Mouse
 	Keyboard *Keyboard
+	Touch    *Touch
 
-	element             *Element                    // iframe only
-	windowObjectID      proto.RuntimeRemoteObjectID // used as the thisObject when eval js
+	element             *Element                        // iframe only
+	windowObjectID      proto.RuntimeRemoteObjectID     // used as the thisObject when eval js
+	jsContextID         proto.RuntimeExecutionContextID // the isolated world of the iframe, 0 means the main world
 	getDownloadFileLock *sync.Mutex
-	viewport            *proto.EmulationSetDeviceMetricsOverride
-
-	event *goob.Observable
+	fetch               *fetchState
+	blocking            *blockingState
+	exposed             *exposedFunctions
+	tracing             *tracingState
+	coverage            *coverageState
+	proxy               *proxyState
+	hostHeaders         *hostHeadersState
+	fileChooser         *fileChooserState
+	objects             *objectTracker
+	frames              *frameStates
+	frameState          *frameState                              // iframe only, the lifecycle of the frame
+	frameGen            int                                      // the generation of the frame when the windowObjectID is created
+	viewport            *proto.EmulationSetDeviceMetricsOverride // nil means the viewport isn't emulated
+	network             *proto.NetworkEmulateNetworkConditions   // nil means no network emulation
+	userAgent           *proto.NetworkSetUserAgentOverride       // nil means the user agent isn't overridden
+	headers             *proto.NetworkSetExtraHTTPHeaders        // nil means no extra headers
+	cache               *cacheState                              // the http cache disabled by the DisableCacheE
+	media               *proto.EmulationSetEmulatedMedia         // nil means the media isn't emulated
+	skipInteractable    bool                                     // skip the interactable checks of the ClickE and InputE
+	inputKeystrokes     bool                                     // the InputE presses the keys instead of the InsertText
+	sleeper             func() kit.Sleeper                       // nil means the backoff of the browser is used
+
+	dialog   *dialogState   // the dialog of the page session
+	crash    *crashState    // the exceptions and the crash of the page session
+	security *securityState // the security state of the page session
+	attach   *attachState   // the detach of the page session, it's replaced by the ReattachE
+
+	sessionLock *sessionLock // guards the SessionID, attach, and windowObjectID that the ReattachE replaces
+
+	event  *goob.Observable // kept by the ReattachE, so that the subscriptions of the clones get the new session's events
+	events *eventQueue      // the queue of the events of the page session
+
+	lazy *lazySession // nil if the page is attached
 }
 
 // IsIframe tells if it's iframe
@@ -91,6 +141,71 @@ func (p *Page) SetCookiesE(cookies []*proto.NetworkCookieParam) error {
 	return err
 }
 
+// SaveCookiesE writes the cookies of the current page to w as json
+func (p *Page) SaveCookiesE(w io.Writer) error {
+	cookies, err := p.CookiesE(nil)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(cookies)
+}
+
+// LoadCookiesE reads the cookies that are saved by SaveCookiesE from r and sets them to the browser.
+// The expired cookies will be skipped, the number of them will be returned.
+func (p *Page) LoadCookiesE(r io.Reader) (skipped int, err error) {
+	var cookies []*proto.NetworkCookie
+	err = json.NewDecoder(r).Decode(&cookies)
+	if err != nil {
+		return 0, err
+	}
+
+	params, skipped := cookieParams(cookies)
+	return skipped, p.SetCookiesE(params)
+}
+
+// cookieParams converts the cookies to the params of the SetCookiesE, the expired ones are skipped
+func cookieParams(cookies []*proto.NetworkCookie) (params []*proto.NetworkCookieParam, skipped int) {
+	now := time.Now()
+	params = []*proto.NetworkCookieParam{}
+
+	for _, c := range cookies {
+		param := &proto.NetworkCookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: c.SameSite,
+			Priority: c.Priority,
+		}
+
+		if !c.Session {
+			expires := time.Unix(0, int64(c.Expires*float64(time.Second)))
+			if expires.Before(now) {
+				skipped++
+				continue
+			}
+			param.Expires = &proto.TimeSinceEpoch{Time: expires}
+		}
+
+		// a cookie that is set with the url but without the domain will be host-only
+		if c.IsDomain() {
+			param.Domain = c.Domain
+		} else {
+			scheme := "http"
+			if c.Secure {
+				scheme = "https"
+			}
+			param.URL = scheme + "://" + c.Domain + c.Path
+		}
+
+		params = append(params, param)
+	}
+
+	return params, skipped
+}
+
 // SetExtraHeadersE whether to always send extra HTTP headers with the requests from this page.
 func (p *Page) SetExtraHeadersE(dict []string) error {
 	headers := proto.NetworkHeaders{}
@@ -99,7 +214,14 @@ func (p *Page) SetExtraHeadersE(dict []string) error {
 		headers[dict[i]] = proto.NewJSON(dict[i+1])
 	}
 
-	return proto.NetworkSetExtraHTTPHeaders{Headers: headers}.Call(p)
+	req := &proto.NetworkSetExtraHTTPHeaders{Headers: headers}
+	err := req.Call(p)
+	if err != nil {
+		return err
+	}
+
+	p.headers = req
+	return nil
 }
 
 // SetUserAgentE Allows overriding user agent with the given string.
@@ -111,23 +233,172 @@ func (p *Page) SetUserAgentE(req *proto.NetworkSetUserAgentOverride) error {
 			Platform:       "MacIntel",
 		}
 	}
-	return req.Call(p)
+	p.stealthUserAgent(req)
+	err := req.Call(p)
+	if err != nil {
+		return err
+	}
+
+	p.userAgent = req
+	return nil
 }
 
 // NavigateE doc is similar to the method Navigate
 func (p *Page) NavigateE(url string) error {
+	_, err := p.navigate(url)
+	return err
+}
+
+func (p *Page) navigate(url string) (*proto.PageNavigateResult, error) {
+	defer p.tryTrace(0, 0, 300, 0, "navigate "+html.EscapeString(url))()
+
 	err := p.StopLoadingE()
+	if err != nil {
+		return nil, err
+	}
+
+	var res *proto.PageNavigateResult
+	err = p.unlessDialog(func(p *Page) (err error) {
+		res, err = proto.PageNavigate{URL: url}.Call(p)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.ErrorText != "" {
+		return nil, &Error{Code: ErrNavigation, Details: res.ErrorText}
+	}
+	return res, nil
+}
+
+// NavigateBlankE navigates to "about:blank", so that the page always has a document to set content on
+func (p *Page) NavigateBlankE() error {
+	return p.NavigateE("about:blank")
+}
+
+// SetDocumentContentE replaces the document of the frame with the content, such as rendering a snippet of html
+// in tests. It works on the iframes too. Unlike NavigateE the url and origin of the frame are kept, and the
+// browser won't fire a new load event for it, so it returns after the content and its resources are loaded.
+func (p *Page) SetDocumentContentE(content string) error {
+	defer p.tryTrace(0, 0, 300, 0, "set document content")()
+
+	err := proto.PageSetDocumentContent{FrameID: p.FrameID, HTML: content}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	// the js context may be replaced, let the next EvalE create a new one
+	p.windowObjectID = ""
+	p.jsContextID = 0
+
+	return p.WaitLoadE()
+}
+
+// ReloadE reloads the page, the scriptToEvaluateOnLoad will be injected into all the frames after the reload.
+// For an iframe only the frame will be reloaded by navigating it to its current url,
+// the ignoreCache and scriptToEvaluateOnLoad are ignored in that case.
+func (p *Page) ReloadE(ignoreCache bool, scriptToEvaluateOnLoad string) error {
+	// the js context will be destroyed, let the next EvalE create a new one
+	p.windowObjectID = ""
+	p.jsContextID = 0
+
+	if p.IsIframe() {
+		frame, err := p.frame()
+		if err != nil {
+			return err
+		}
+		if frame == nil {
+			return &Error{nil, ErrNavigation, "frame not found: " + p.FrameID}
+		}
+
+		res, err := proto.PageNavigate{URL: frame.URL, FrameID: p.FrameID}.Call(p)
+		if err != nil {
+			return err
+		}
+		if res.ErrorText != "" {
+			return &Error{Code: ErrNavigation, Details: res.ErrorText}
+		}
+		return nil
+	}
+
+	return proto.PageReload{
+		IgnoreCache:            ignoreCache,
+		ScriptToEvaluateOnLoad: scriptToEvaluateOnLoad,
+	}.Call(p)
+}
+
+// ReloadAndWaitLoadE reloads the page and waits until the load event of the new document is fired
+func (p *Page) ReloadAndWaitLoadE() error {
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+
+	wait := p.Context(ctx).WaitNavigationE(proto.PageLifecycleEventNameLoad)
+
+	err := p.ReloadE(false, "")
 	if err != nil {
 		return err
 	}
-	res, err := proto.PageNavigate{URL: url}.Call(p)
+
+	return wait()
+}
+
+// HistoryE returns the index of the current entry and the entry list of the navigation history
+func (p *Page) HistoryE() (int64, []*proto.PageNavigationEntry, error) {
+	res, err := proto.PageGetNavigationHistory{}.Call(p)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.CurrentIndex, res.Entries, nil
+}
+
+// NavigateBackE doc is similar to the method NavigateBack
+func (p *Page) NavigateBackE() error {
+	return p.navigateHistory(-1)
+}
+
+// NavigateForwardE doc is similar to the method NavigateForward
+func (p *Page) NavigateForwardE() error {
+	return p.navigateHistory(1)
+}
+
+// navigateHistory navigates to the entry that is offset away from the current one,
+// it returns after the main frame is navigated, so that it's safe to call WaitLoadE after it.
+func (p *Page) navigateHistory(offset int64) error {
+	index, entries, err := p.HistoryE()
 	if err != nil {
 		return err
 	}
-	if res.ErrorText != "" {
-		return &Error{Code: ErrNavigation, Details: res.ErrorText}
+
+	i := index + offset
+	if i < 0 || i >= int64(len(entries)) {
+		return &Error{nil, ErrNoHistoryEntry, i}
 	}
-	return nil
+
+	frameID := p.Root().FrameID
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	s := p.event.Subscribe(ctx)
+
+	err = proto.PageNavigateToHistoryEntry{EntryID: entries[i].ID}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	for msg := range s {
+		e := msg.(*cdp.Event)
+		navigated := &proto.PageFrameNavigated{}
+		within := &proto.PageNavigatedWithinDocument{}
+
+		if Event(e, navigated) && navigated.Frame.ID == frameID {
+			return nil
+		}
+		if Event(e, within) && within.FrameID == frameID {
+			return nil
+		}
+	}
+
+	return p.ctx.Err()
 }
 
 func (p *Page) getWindowID() (proto.BrowserWindowID, error) {
@@ -164,195 +435,1075 @@ func (p *Page) WindowE(bounds *proto.BrowserBounds) error {
 	return err
 }
 
-// ViewportE doc is similar to the method Viewport
-func (p *Page) ViewportE(params *proto.EmulationSetDeviceMetricsOverride) error {
-	p.viewport = params
-	err := params.Call(p)
-	return err
+// WindowMaximizeE doc is similar to the method WindowMaximize
+func (p *Page) WindowMaximizeE() error {
+	return p.windowStateE(proto.BrowserWindowStateMaximized)
 }
 
-// StopLoadingE forces the page stop all navigations and pending resource fetches.
-func (p *Page) StopLoadingE() error {
-	return proto.PageStopLoading{}.Call(p)
+// WindowMinimizeE doc is similar to the method WindowMinimize
+func (p *Page) WindowMinimizeE() error {
+	return p.windowStateE(proto.BrowserWindowStateMinimized)
 }
 
-// CloseE page
-func (p *Page) CloseE() error {
-	err := p.StopLoadingE()
+// WindowFullscreenE doc is similar to the method WindowFullscreen
+func (p *Page) WindowFullscreenE() error {
+	return p.windowStateE(proto.BrowserWindowStateFullscreen)
+}
+
+// WindowNormalE doc is similar to the method WindowNormal
+func (p *Page) WindowNormalE() error {
+	return p.windowStateE(proto.BrowserWindowStateNormal)
+}
+
+// windowStateE switches the window to the state. The browser can't switch between
+// the minimized, maximized and fullscreen states directly, the window has to be normal first.
+func (p *Page) windowStateE(state proto.BrowserWindowState) error {
+	id, err := p.getWindowID()
 	if err != nil {
 		return err
 	}
-	err = proto.PageClose{}.Call(p)
+
+	res, err := proto.BrowserGetWindowBounds{WindowID: id}.Call(p)
 	if err != nil {
 		return err
 	}
 
-	p.ctxCancel()
-	return nil
-}
-
-// HandleDialogE doc is similar to the method HandleDialog
-func (p *Page) HandleDialogE(accept bool, promptText string) func() error {
-	wait := p.WaitEvent()
+	current := res.Bounds.WindowState
+	if current == state {
+		return nil
+	}
 
-	return func() error {
-		wait(&proto.PageJavascriptDialogOpening{})
-		return proto.PageHandleJavaScriptDialog{
-			Accept:     accept,
-			PromptText: promptText,
+	if current != proto.BrowserWindowStateNormal && state != proto.BrowserWindowStateNormal {
+		err = proto.BrowserSetWindowBounds{
+			WindowID: id,
+			Bounds:   &proto.BrowserBounds{WindowState: proto.BrowserWindowStateNormal},
 		}.Call(p)
+		if err != nil {
+			return err
+		}
 	}
+
+	return proto.BrowserSetWindowBounds{
+		WindowID: id,
+		Bounds:   &proto.BrowserBounds{WindowState: state},
+	}.Call(p)
 }
 
-// GetDownloadFileE how it works is to proxy the request, the dir is the dir to save the file.
-func (p *Page) GetDownloadFileE(dir, pattern string) (func() (http.Header, []byte, error), error) {
-	var fetchEnable *proto.FetchEnable
-	if pattern != "" {
-		fetchEnable = &proto.FetchEnable{
-			Patterns: []*proto.FetchRequestPattern{
-				{URLPattern: pattern},
-			},
-		}
+// ActivateE doc is similar to the method Activate
+func (p *Page) ActivateE() error {
+	err := proto.TargetActivateTarget{TargetID: p.TargetID}.Call(p)
+	if err != nil {
+		return err
 	}
+	return proto.PageBringToFront{}.Call(p)
+}
 
-	// both Page.setDownloadBehavior and Fetch.enable will pollute the global status,
-	// we have to prevent race condition here
-	p.getDownloadFileLock.Lock()
+// ViewportE doc is similar to the method Viewport
+func (p *Page) ViewportE(params *proto.EmulationSetDeviceMetricsOverride) error {
+	p.viewport = params
+	err := params.Call(p)
+	return err
+}
 
-	err := proto.PageSetDownloadBehavior{
-		Behavior:     proto.PageSetDownloadBehaviorBehaviorAllow,
-		DownloadPath: dir,
-	}.Call(p)
+// EmulateDeviceE emulates the viewport, user agent, and touch support of the device.
+// Use devices.Clear to clear the emulation.
+func (p *Page) EmulateDeviceE(device devices.Device) error {
+	err := p.ViewportE(device.MetricsEmulation())
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	err = fetchEnable.Call(p)
+	ua := device.UserAgentEmulation()
+	err = ua.Call(p)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	p.userAgent = ua
 
-	wait := p.WaitEvent()
-
-	return func() (http.Header, []byte, error) {
-		defer p.getDownloadFileLock.Unlock()
-
-		var err error
-		defer func() {
-			e := proto.FetchDisable{}.Call(p)
-			if err == nil {
-				err = e
-			}
-		}()
-
-		msgReq := &proto.FetchRequestPaused{}
-		wait(msgReq)
-
-		req := kit.Req(msgReq.Request.URL).Context(p.ctx)
-
-		for k, v := range msgReq.Request.Headers {
-			req.Header(k, v.String())
-		}
-
-		res, err := req.Response()
-		if err != nil {
-			return nil, nil, err
-		}
+	return device.TouchEmulation().Call(p)
+}
 
-		body, err := req.Bytes()
+// EmulateGeolocationE overrides the geolocation of the page, if grant is true the geolocation permission will be
+// granted to the origin of the page, so that the page won't be blocked by the permission prompt.
+func (p *Page) EmulateGeolocationE(lat, lon, accuracy float64, grant bool) error {
+	if grant {
+		origin, err := p.origin()
 		if err != nil {
-			return nil, nil, err
+			return err
 		}
 
-		headers := []*proto.FetchHeaderEntry{}
-		for k, vs := range res.Header {
-			for _, v := range vs {
-				headers = append(headers, &proto.FetchHeaderEntry{Name: k, Value: v})
+		if origin != "" {
+			err = p.browser.GrantPermissionsE(origin, []proto.BrowserPermissionType{proto.BrowserPermissionTypeGeolocation})
+			if err != nil {
+				return err
 			}
 		}
+	}
 
-		err = proto.FetchFulfillRequest{
-			RequestID:       msgReq.RequestID,
-			ResponseCode:    int64(res.StatusCode),
-			ResponseHeaders: headers,
-			Body:            body,
-		}.Call(p)
-		if err != nil {
-			return nil, nil, err
-		}
-
-		return res.Header, body, nil
-	}, err
+	return proto.EmulationSetGeolocationOverride{
+		Latitude:  lat,
+		Longitude: lon,
+		Accuracy:  accuracy,
+	}.Call(p)
 }
 
-// ScreenshotE options: https://chromedevtools.github.io/devtools-protocol/tot/Page#method-captureScreenshot
-func (p *Page) ScreenshotE(fullpage bool, req *proto.PageCaptureScreenshot) ([]byte, error) {
-	if fullpage {
-		metrics, err := proto.PageGetLayoutMetrics{}.Call(p)
-		if err != nil {
-			return nil, err
-		}
-
-		oldView := p.viewport
-		view := *oldView
-		view.Width = int64(metrics.ContentSize.Width)
-		view.Height = int64(metrics.ContentSize.Height)
-
-		err = p.ViewportE(&view)
-		if err != nil {
-			return nil, err
-		}
-		defer func() {
-			e := p.ViewportE(oldView)
-			if err == nil {
-				err = e
-			}
-		}()
+// ClearGeolocationE clears the geolocation override of the page, and resets the geolocation permission of the
+// origin of the page to prompt
+func (p *Page) ClearGeolocationE() error {
+	err := proto.EmulationClearGeolocationOverride{}.Call(p)
+	if err != nil {
+		return err
 	}
 
-	shot, err := req.Call(p)
-	if err != nil {
-		return nil, err
+	origin, err := p.origin()
+	if err != nil || origin == "" {
+		return err
 	}
-	return shot.Data, nil
+
+	return proto.BrowserSetPermission{
+		Origin:           origin,
+		Permission:       &proto.BrowserPermissionDescriptor{Name: "geolocation"},
+		Setting:          proto.BrowserPermissionSettingPrompt,
+		BrowserContextID: p.browser.BrowserContextID,
+	}.Call(p.browser)
 }
 
-// PDFE prints page as PDF
-func (p *Page) PDFE(req *proto.PagePrintToPDF) ([]byte, error) {
-	res, err := req.Call(p)
+// origin returns the origin of the page, it's empty for the opaque origins, such as the "about:blank" or the data urls
+func (p *Page) origin() (string, error) {
+	res, err := p.evalE(true, "", `() => location.origin`, nil)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	return res.Data, nil
+	if origin := res.Value.String(); origin != "null" {
+		return origin, nil
+	}
+	return "", nil
 }
 
-// WaitOpenE doc is similar to the method WaitPage
-func (p *Page) WaitOpenE() func() (*Page, error) {
-	b := p.browser.Context(p.ctx)
-	wait := b.EachEvent()
+// EmulateTimezoneE overrides the timezone of the page, such as "Europe/Berlin".
+// If the tzID is empty, the default timezone of the host system will be restored.
+func (p *Page) EmulateTimezoneE(tzID string) error {
+	return proto.EmulationSetTimezoneOverride{TimezoneID: tzID}.Call(p)
+}
 
-	return func() (*Page, error) {
-		var targetID proto.TargetTargetID
+// EmulateLocaleE overrides the locale of the page, such as "de_DE".
+// If the locale is empty, the default locale of the host system will be restored.
+func (p *Page) EmulateLocaleE(locale string) error {
+	return proto.EmulationSetLocaleOverride{Locale: locale}.Call(p)
+}
 
-		wait(func(e *proto.TargetTargetCreated) bool {
-			if e.TargetInfo.OpenerID == p.TargetID {
-				targetID = e.TargetInfo.TargetID
-				return true
-			}
-			return false
-		})
-		return b.PageFromTargetIDE(targetID)
+// ClipboardTextE reads the text from the clipboard via the navigator.clipboard of the page
+func (p *Page) ClipboardTextE() (string, error) {
+	err := p.prepareClipboardE()
+	if err != nil {
+		return "", err
 	}
+
+	res, err := p.evalE(true, "", `() => navigator.clipboard.readText()`, nil)
+	if err != nil {
+		return "", err
+	}
+	return res.Value.String(), nil
 }
 
-// PauseE doc is similar to the method Pause
-func (p *Page) PauseE() error {
-	_, err := proto.DebuggerEnable{}.Call(p)
+// SetClipboardTextE writes the text to the clipboard via the navigator.clipboard of the page
+func (p *Page) SetClipboardTextE(text string) error {
+	err := p.prepareClipboardE()
 	if err != nil {
 		return err
 	}
-	wait := p.WaitEvent()
-	err = proto.DebuggerPause{}.Call(p)
+
+	_, err = p.evalE(true, "", `text => navigator.clipboard.writeText(text)`, Array{text})
+	return err
+}
+
+// prepareClipboardE grants the clipboard permissions and focuses the page,
+// the clipboard api rejects the calls when the document isn't focused.
+func (p *Page) prepareClipboardE() error {
+	res, err := p.evalE(true, "", `() => location.origin`, nil)
+	if err != nil {
+		return err
+	}
+
+	// such as the "about:blank" or the data urls
+	origin := res.Value.String()
+	if origin == "null" {
+		origin = ""
+	}
+
+	err = p.browser.GrantPermissionsE(origin, []proto.BrowserPermissionType{
+		proto.BrowserPermissionTypeClipboardReadWrite,
+		proto.BrowserPermissionTypeClipboardSanitizedWrite,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.ActivateE()
+	if err != nil {
+		return err
+	}
+
+	focused, err := p.evalE(true, "", `() => document.hasFocus()`, nil)
+	if err != nil {
+		return err
+	}
+	if !focused.Value.Bool() {
+		return &Error{nil, ErrNotFocused, p.FrameID}
+	}
+	return nil
+}
+
+// NetworkConditions to emulate
+type NetworkConditions struct {
+	// Offline emulates internet disconnection
+	Offline bool
+
+	// Latency from request sent to response headers received
+	Latency time.Duration
+
+	// DownloadThroughput in bytes per second, 0 means no throttling
+	DownloadThroughput float64
+
+	// UploadThroughput in bytes per second, 0 means no throttling
+	UploadThroughput float64
+}
+
+// the presets are the same as the ones of the devtools
+var (
+	// Slow3G network conditions
+	Slow3G = &NetworkConditions{
+		Latency:            2000 * time.Millisecond,
+		DownloadThroughput: 500 * 1000 / 8 * 0.8,
+		UploadThroughput:   500 * 1000 / 8 * 0.8,
+	}
+
+	// Fast3G network conditions
+	Fast3G = &NetworkConditions{
+		Latency:            562500 * time.Microsecond,
+		DownloadThroughput: 1.6 * 1000 * 1000 / 8 * 0.9,
+		UploadThroughput:   750 * 1000 / 8 * 0.9,
+	}
+
+	// Offline network conditions
+	Offline = &NetworkConditions{Offline: true}
+)
+
+// EmulateNetworkE throttles the network of the page, use nil to reset to no throttling.
+// The conditions are remembered by the page, they apply to the navigations and reloads after the call.
+func (p *Page) EmulateNetworkE(conditions *NetworkConditions) error {
+	var req *proto.NetworkEmulateNetworkConditions
+
+	if conditions != nil {
+		req = &proto.NetworkEmulateNetworkConditions{
+			Offline:            conditions.Offline,
+			Latency:            float64(conditions.Latency) / float64(time.Millisecond),
+			DownloadThroughput: conditions.DownloadThroughput,
+			UploadThroughput:   conditions.UploadThroughput,
+		}
+		if req.DownloadThroughput == 0 {
+			req.DownloadThroughput = -1
+		}
+		if req.UploadThroughput == 0 {
+			req.UploadThroughput = -1
+		}
+	}
+
+	err := networkConditions(req).Call(p)
+	if err != nil {
+		return err
+	}
+
+	p.network = req
+	return nil
+}
+
+// SetOfflineE doc is similar to the method SetOffline
+func (p *Page) SetOfflineE(offline bool) error {
+	if !offline {
+		return p.EmulateNetworkE(nil)
+	}
+	return p.EmulateNetworkE(Offline)
+}
+
+// networkConditions returns the request to reset the network emulation if req is nil
+func networkConditions(req *proto.NetworkEmulateNetworkConditions) *proto.NetworkEmulateNetworkConditions {
+	if req == nil {
+		return &proto.NetworkEmulateNetworkConditions{DownloadThroughput: -1, UploadThroughput: -1}
+	}
+	return req
+}
+
+// cacheState is shared by all the clones of a page target
+type cacheState struct {
+	lock     sync.Mutex
+	disabled bool
+}
+
+func (s *cacheState) get() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.disabled
+}
+
+func (s *cacheState) set(disabled bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.disabled = disabled
+}
+
+// DisableCacheE disables/enables the http cache of the page, such as to measure the cold loads. The flag is kept
+// by the page target and shared by its clones, the new sessions of it will be disabled too, such as the
+// out-of-process iframes and the ReattachE.
+func (p *Page) DisableCacheE(disabled bool) error {
+	err := proto.NetworkSetCacheDisabled{CacheDisabled: disabled}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	p.cache.set(disabled)
+	return nil
+}
+
+// ClearBrowserCacheE clears the http cache of the browser, it's shared by all the pages of the browser
+func (p *Page) ClearBrowserCacheE() error {
+	return proto.NetworkClearBrowserCache{}.Call(p)
+}
+
+// ClearBrowserCookiesE clears the cookies of the browser, it's shared by all the pages of the browser context
+func (p *Page) ClearBrowserCookiesE() error {
+	return proto.NetworkClearBrowserCookies{}.Call(p)
+}
+
+// StopLoadingE forces the page stop all navigations and pending resource fetches.
+func (p *Page) StopLoadingE() error {
+	return proto.PageStopLoading{}.Call(p)
+}
+
+// CloseE page
+func (p *Page) CloseE() error {
+	// the crashed renderer won't respond to the session, close the target via the browser
+	if p.crash.err() != nil {
+		defer p.ctxCancel()
+		_, err := proto.TargetCloseTarget{TargetID: p.TargetID}.Call(p.browser)
+		return err
+	}
+
+	err := p.StopLoadingE()
+	if err != nil {
+		return err
+	}
+
+	if p.windowObjectID != "" {
+		_ = p.ReleaseE(p.windowObjectID)
+		p.windowObjectID = ""
+	}
+
+	err = p.unlessDialog(func(p *Page) error {
+		// subscribe before the Page.close, so that we won't miss the destroyed event
+		wait := p.browser.waitDestroyed(p.ctx, p.TargetID)
+
+		err := proto.PageClose{}.Call(p)
+		if err != nil {
+			wait.cancel()
+			return err
+		}
+
+		return wait.wait()
+	})
+	if IsError(err, ErrDialogPending) {
+		// the page is still open
+		return err
+	}
+
+	p.objects.reportLeaks()
+
+	// stop the event filter and all the helpers that depend on the page context
+	p.ctxCancel()
+	return err
+}
+
+// WaitCloseE waits until the target of the page is destroyed, such as the page is closed by the window.close().
+// It returns immediately if the target is already gone.
+func (p *Page) WaitCloseE() error {
+	wait := p.browser.waitDestroyed(p.ctx, p.TargetID)
+
+	res, err := proto.TargetGetTargets{}.Call(p.browser)
+	if err != nil {
+		wait.cancel()
+		return err
+	}
+
+	for _, info := range res.TargetInfos {
+		if info.TargetID == p.TargetID {
+			return wait.wait()
+		}
+	}
+
+	wait.cancel()
+	return nil
+}
+
+// WaitDownloadE returns a wait function that waits for the next download of the page to finish, and returns the path
+// of the downloaded file. Unlike GetDownloadFileE, the download is done by the browser itself, so it works with the
+// cookies and the POST requests of the page. The browser saves the file into the dir with the download GUID as
+// its name, then it's moved to "<dir>/<GUID>/<suggested filename>" if the browser reports the suggested filename,
+// so the concurrent downloads won't overwrite each other's files. The download behavior of the browser context is
+// shared, while other waits of the same browser context are pending the file will be saved into the dir of the
+// earliest one, it's reset to the default after the last wait returns. If the download is canceled,
+// an ErrDownloadCanceled error will be returned.
+func (p *Page) WaitDownloadE(dir string) func() (path string, err error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	dir, err := p.browser.downloads.acquire(p.browser, dir)
+
+	return func() (string, error) {
+		defer cancel()
+
+		if err != nil {
+			return "", err
+		}
+		defer p.browser.downloads.release(p.browser)
+
+		var begin *proto.PageDownloadWillBegin
+		var progress *proto.PageDownloadProgress
+		name := ""
+
+		goob.Each(s, func(msg *cdp.Event) bool {
+			b := &proto.PageDownloadWillBegin{}
+			pg := &proto.PageDownloadProgress{}
+
+			switch {
+			case begin == nil && Event(msg, b):
+				begin = b
+				// the suggestedFilename is only sent by the newer browsers
+				name = gjson.GetBytes(msg.Params, "suggestedFilename").String()
+			case begin != nil && Event(msg, pg) && pg.GUID == begin.GUID:
+				if pg.State != proto.PageDownloadProgressStateInProgress {
+					progress = pg
+					return true
+				}
+			}
+			return false
+		})
+
+		if progress == nil {
+			return "", p.ctx.Err()
+		}
+
+		if progress.State == proto.PageDownloadProgressStateCanceled {
+			return "", &Error{nil, ErrDownloadCanceled, begin.URL}
+		}
+
+		path := filepath.Join(dir, begin.GUID)
+		if name == "" {
+			return path, nil
+		}
+
+		named := filepath.Join(dir, begin.GUID+".d", filepath.Base(name))
+		err := os.MkdirAll(filepath.Dir(named), 0755)
+		if err != nil {
+			return "", err
+		}
+		return named, os.Rename(path, named)
+	}
+}
+
+// downloadState is the download behavior of the browser contexts that is shared by the clones of the browser
+type downloadState struct {
+	lock sync.Mutex
+	list map[proto.BrowserBrowserContextID]*downloadBehavior
+}
+
+type downloadBehavior struct {
+	dir   string
+	count int // the number of the pending waits
+}
+
+// acquire allows the downloads of the browser context of b, it returns the dir that the files will be saved into
+func (s *downloadState) acquire(b *Browser, dir string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if d, has := s.list[b.BrowserContextID]; has {
+		d.count++
+		return d.dir, nil
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = proto.BrowserSetDownloadBehavior{
+		Behavior:         proto.BrowserSetDownloadBehaviorBehaviorAllowAndName,
+		BrowserContextID: b.BrowserContextID,
+		DownloadPath:     dir,
+	}.Call(b)
+	if err != nil {
+		return "", err
+	}
+
+	s.list[b.BrowserContextID] = &downloadBehavior{dir: dir, count: 1}
+	return dir, nil
+}
+
+// release resets the download behavior of the browser context of b after the last wait of it returns
+func (s *downloadState) release(b *Browser) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	d := s.list[b.BrowserContextID]
+	d.count--
+	if d.count > 0 {
+		return
+	}
+
+	delete(s.list, b.BrowserContextID)
+	_ = proto.BrowserSetDownloadBehavior{
+		Behavior:         proto.BrowserSetDownloadBehaviorBehaviorDefault,
+		BrowserContextID: b.BrowserContextID,
+	}.Call(b)
+}
+
+// ScrollToBottomE keeps scrolling the page to the bottom until the height of the page stops growing,
+// such as the infinite scrolling pages. After each scrolling, it waits for the idle duration for the page to
+// load more content. Use a context with a timeout for the pages that never end.
+func (p *Page) ScrollToBottomE(idle time.Duration) error {
+	height := int64(-1)
+
+	for {
+		res, err := p.evalE(true, "", `() => {
+			const h = document.documentElement.scrollHeight
+			window.scrollTo(0, h)
+			return h
+		}`, nil)
+		if err != nil {
+			return err
+		}
+
+		h := res.Value.Int()
+		if h == height {
+			return nil
+		}
+		height = h
+
+		t := time.NewTimer(idle)
+		select {
+		case <-p.ctx.Done():
+			t.Stop()
+			return p.ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Layout of the page, all the values are in css pixels, even if the device metrics are emulated with a scale factor
+type Layout struct {
+	// ContentWidth and ContentHeight are the size of the scrollable area of the document
+	ContentWidth  float64 `json:"contentWidth"`
+	ContentHeight float64 `json:"contentHeight"`
+
+	// ViewportWidth and ViewportHeight are the size of the visible area, the scrollbars are excluded
+	ViewportWidth  float64 `json:"viewportWidth"`
+	ViewportHeight float64 `json:"viewportHeight"`
+
+	ScrollX float64 `json:"scrollX"`
+	ScrollY float64 `json:"scrollY"`
+
+	// DevicePixelRatio is the number of device pixels of a css pixel, use it to convert the values to device pixels
+	DevicePixelRatio float64 `json:"devicePixelRatio"`
+}
+
+// LayoutE returns the layout of the page. Unlike the PageGetLayoutMetrics, whose values may be device pixels
+// under the emulation, the values are read from the document, so they are always css pixels.
+func (p *Page) LayoutE() (*Layout, error) {
+	layout := &Layout{}
+	err := p.evalInto(layout, "", p.jsFn("layout"), nil)
+	if err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// ScrollToE scrolls the document to the position in css pixels, the position is clamped to the scrollable area.
+// It ignores the smooth scroll behavior of the page. If the page isn't at the position after the scrolling, such as
+// a script of the page scrolls it back, an ErrScrollPosition error will be returned with the layout.
+func (p *Page) ScrollToE(x, y float64) error {
+	_, err := p.EvalE(true, "", p.jsFn("scrollTo"), Array{x, y})
+	if err != nil {
+		return err
+	}
+
+	// re-read the layout, the scroll event handlers of the page run after the scrolling
+	layout, err := p.LayoutE()
+	if err != nil {
+		return err
+	}
+
+	clamp := func(v, max float64) float64 {
+		return math.Max(0, math.Min(v, math.Max(0, max)))
+	}
+
+	// the scroll offset is rounded to the device pixels
+	tolerance := 1 / math.Max(1, layout.DevicePixelRatio)
+	if math.Abs(layout.ScrollX-clamp(x, layout.ContentWidth-layout.ViewportWidth)) > tolerance ||
+		math.Abs(layout.ScrollY-clamp(y, layout.ContentHeight-layout.ViewportHeight)) > tolerance {
+		return &Error{nil, ErrScrollPosition, layout}
+	}
+	return nil
+}
+
+// ScrollByE scrolls the document by the offset in css pixels from the current position, see the ScrollToE
+func (p *Page) ScrollByE(dx, dy float64) error {
+	layout, err := p.LayoutE()
+	if err != nil {
+		return err
+	}
+	return p.ScrollToE(layout.ScrollX+dx, layout.ScrollY+dy)
+}
+
+// GetDownloadFileE how it works is to proxy the request, the dir is the dir to save the file.
+// The request is sent with the same method, body, headers, and cookies of the page, such as the download
+// of a form submission. The page stays on the current document after the download.
+func (p *Page) GetDownloadFileE(dir, pattern string) (func() (http.Header, []byte, error), error) {
+	fp := newFetchPattern(pattern)
+
+	// the first owned request is the download, the later ones are continued
+	paused := make(chan *proto.FetchRequestPaused, 1)
+	fp.handle = func(e *proto.FetchRequestPaused) {
+		select {
+		case paused <- e:
+		default:
+			_ = proto.FetchContinueRequest{RequestID: e.RequestID}.Call(p)
+		}
+	}
+
+	// both Page.setDownloadBehavior and Fetch.enable will pollute the global status,
+	// we have to prevent race condition here
+	p.getDownloadFileLock.Lock()
+
+	err := proto.PageSetDownloadBehavior{
+		Behavior:     proto.PageSetDownloadBehaviorBehaviorAllow,
+		DownloadPath: dir,
+	}.Call(p)
+	if err != nil {
+		p.getDownloadFileLock.Unlock()
+		return nil, err
+	}
+
+	err = p.fetch.add(p, fp)
+	if err != nil {
+		p.getDownloadFileLock.Unlock()
+		return nil, err
+	}
+
+	return func() (http.Header, []byte, error) {
+		defer p.getDownloadFileLock.Unlock()
+
+		var err error
+		defer func() {
+			e := p.fetch.remove(p, fp)
+			if err == nil {
+				err = e
+			}
+		}()
+
+		var msgReq *proto.FetchRequestPaused
+		select {
+		case msgReq = <-paused:
+		case <-p.ctx.Done():
+			return nil, nil, p.ctx.Err()
+		}
+
+		// the redirects are followed the same as the browser, such as the 303 after a POST changes it to GET
+		req, err := p.pausedRequest(msgReq)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() { _ = res.Body.Close() }()
+
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// a document that isn't an attachment will be rendered, the page would navigate away from the current one
+		header := res.Header
+		disposition := header.Get("Content-Disposition")
+		if msgReq.ResourceType == proto.NetworkResourceTypeDocument &&
+			!strings.HasPrefix(strings.ToLower(disposition), "attachment") {
+			header = header.Clone()
+			value := "attachment"
+			if i := strings.Index(disposition, ";"); i >= 0 {
+				value += disposition[i:] // keep the filename
+			}
+			header.Set("Content-Disposition", value)
+		}
+		headers := fetchHeaders(header)
+
+		err = proto.FetchFulfillRequest{
+			RequestID:       msgReq.RequestID,
+			ResponseCode:    int64(res.StatusCode),
+			ResponseHeaders: headers,
+			Body:            body,
+		}.Call(p)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return res.Header, body, nil
+	}, err
+}
+
+// ScreenshotE options: https://chromedevtools.github.io/devtools-protocol/tot/Page#method-captureScreenshot
+func (p *Page) ScreenshotE(fullpage bool, req *proto.PageCaptureScreenshot) (bin []byte, err error) {
+	if fullpage {
+		metrics, err := proto.PageGetLayoutMetrics{}.Call(p)
+		if err != nil {
+			return nil, err
+		}
+
+		width, height := metrics.ContentSize.Width, metrics.ContentSize.Height
+
+		if req.Clip == nil && height > maxTextureSize {
+			return p.screenshotSlicesE(metrics, req)
+		}
+
+		restore, err := p.resizeViewport(int64(width), int64(height))
+		if err != nil {
+			return nil, err
+		}
+
+		bin, err = p.captureE(req)
+		e := restore()
+		if err != nil {
+			return nil, err
+		}
+		return bin, e
+	}
+
+	return p.captureE(req)
+}
+
+func (p *Page) captureE(req *proto.PageCaptureScreenshot) ([]byte, error) {
+	shot, err := req.Call(p)
+	if err != nil {
+		return nil, err
+	}
+	return shot.Data, nil
+}
+
+// the max size of the texture of the browser, the fullpage screenshots taller than it will be captured in slices
+var maxTextureSize = 16384.0
+
+// the height of each slice of a tall fullpage screenshot
+var screenshotSliceHeight = 4096.0
+
+// screenshotSlicesE scrolls through the page, captures it slice by slice, then stitches the slices into one image
+func (p *Page) screenshotSlicesE(metrics *proto.PageGetLayoutMetricsResult, req *proto.PageCaptureScreenshot) (bin []byte, err error) {
+	width, height := metrics.ContentSize.Width, metrics.ContentSize.Height
+
+	restore, err := p.resizeViewport(int64(width), int64(screenshotSliceHeight))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_, e := p.evalE(true, "", `(x, y) => window.scrollTo(x, y)`, Array{
+			metrics.LayoutViewport.PageX, metrics.LayoutViewport.PageY,
+		})
+		if err == nil {
+			err = e
+		}
+		e = restore()
+		if err == nil {
+			err = e
+		}
+	}()
+
+	slices := []image.Image{}
+	bounds := image.Rect(0, 0, 0, 0)
+
+	for y := 0.0; y < height; y += screenshotSliceHeight {
+		_, err = p.evalE(true, "", `y => window.scrollTo(0, y)`, Array{y})
+		if err != nil {
+			return nil, err
+		}
+
+		opts := *req
+		opts.Clip = &proto.PageViewport{
+			X:      0,
+			Y:      y,
+			Width:  width,
+			Height: math.Min(screenshotSliceHeight, height-y),
+			Scale:  1,
+		}
+
+		data, err := p.captureE(&opts)
+		if err != nil {
+			return nil, err
+		}
+
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		slices = append(slices, img)
+		size := img.Bounds().Size()
+		if size.X > bounds.Max.X {
+			bounds.Max.X = size.X
+		}
+		bounds.Max.Y += size.Y
+	}
+
+	canvas := image.NewRGBA(bounds)
+	top := 0
+	for _, img := range slices {
+		size := img.Bounds().Size()
+		draw.Draw(canvas, image.Rect(0, top, size.X, top+size.Y), img, img.Bounds().Min, draw.Src)
+		top += size.Y
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if req.Format == proto.PageCaptureScreenshotFormatJpeg {
+		quality := jpeg.DefaultQuality
+		if req.Quality > 0 {
+			quality = int(req.Quality)
+		}
+		err = jpeg.Encode(buf, canvas, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(buf, canvas)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ScreenshotAreaE captures the area of the page, x and y are the CSS pixels relative to the document.
+// The fields of req except the Clip will be used for the capture. If the area isn't inside the layout viewport,
+// the viewport will be temporarily expanded to the size of the content, just like the fullpage screenshot.
+func (p *Page) ScreenshotAreaE(x, y, width, height float64, req *proto.PageCaptureScreenshot) ([]byte, error) {
+	metrics, err := proto.PageGetLayoutMetrics{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := proto.PageCaptureScreenshot{}
+	if req != nil {
+		opts = *req
+	}
+	opts.Clip = &proto.PageViewport{
+		X:      x,
+		Y:      y,
+		Width:  width,
+		Height: height,
+		// the clip is in CSS pixels, the device scale factor of the emulation will be applied by the browser
+		Scale: 1,
+	}
+
+	view := metrics.LayoutViewport
+	inside := x >= float64(view.PageX) && y >= float64(view.PageY) &&
+		x+width <= float64(view.PageX+view.ClientWidth) &&
+		y+height <= float64(view.PageY+view.ClientHeight)
+
+	return p.ScreenshotE(!inside, &opts)
+}
+
+// resizeViewport temporarily, the restore function sets the viewport back to the one of the viewportOverride,
+// if there's none, the override of the device metrics will be cleared.
+func (p *Page) resizeViewport(width, height int64) (restore func() error, err error) {
+	view := proto.EmulationSetDeviceMetricsOverride{}
+	if v := p.viewportOverride(); v != nil {
+		view = *v
+	}
+	view.Width = width
+	view.Height = height
+
+	err = view.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		v := p.viewportOverride()
+		if v == nil {
+			return proto.EmulationClearDeviceMetricsOverride{}.Call(p)
+		}
+		return v.Call(p)
+	}, nil
+}
+
+// viewportOverride returns the viewport of the page, or the default viewport of the browser if the page's isn't set
+func (p *Page) viewportOverride() *proto.EmulationSetDeviceMetricsOverride {
+	if p.viewport != nil {
+		return p.viewport
+	}
+	return p.browser.defaultViewport
+}
+
+// PDFE prints page as PDF
+func (p *Page) PDFE(req *proto.PagePrintToPDF) ([]byte, error) {
+	res, err := req.Call(p)
+	if err != nil {
+		return nil, err
+	}
+	return res.Data, nil
+}
+
+// PDFStreamE prints page as PDF, the data will be read from the browser chunk by chunk when the reader is read.
+// The TransferMode of the req will be set to ReturnAsStream. Remember to close the reader after use,
+// it will also be closed when the page context is done.
+func (p *Page) PDFStreamE(req *proto.PagePrintToPDF) (io.ReadCloser, error) {
+	req.TransferMode = proto.PagePrintToPDFTransferModeReturnAsStream
+
+	res, err := req.Call(p)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamReader(p, res.Stream), nil
+}
+
+// ScreenshotStreamE is similar to ScreenshotE, but returns a reader.
+// The protocol doesn't support the stream transfer mode for screenshots yet, so the image will still be
+// captured at once, it only provides the same interface as the PDFStreamE.
+func (p *Page) ScreenshotStreamE(fullpage bool, req *proto.PageCaptureScreenshot) (io.ReadCloser, error) {
+	bin, err := p.ScreenshotE(fullpage, req)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(bin)), nil
+}
+
+// streamReader reads the IO stream of the browser lazily
+type streamReader struct {
+	page   *Page
+	handle proto.IOStreamHandle
+
+	// base64 chars that haven't been decoded
+	pending string
+	buf     []byte
+	eof     bool
+
+	closeOnce sync.Once
+	closeErr  error
+	done      chan kit.Nil
+}
+
+func newStreamReader(p *Page, handle proto.IOStreamHandle) *streamReader {
+	r := &streamReader{page: p, handle: handle, done: make(chan kit.Nil)}
+
+	// prevent the handle from leaking when the page context is done
+	go func() {
+		select {
+		case <-p.ctx.Done():
+			_ = r.Close()
+		case <-r.done:
+		}
+	}()
+
+	return r
+}
+
+// Read interface
+func (r *streamReader) Read(b []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		err := r.fill()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *streamReader) fill() error {
+	res, err := proto.IORead{Handle: r.handle}.Call(r.page)
+	if err != nil {
+		return err
+	}
+	r.eof = res.EOF
+
+	if !res.Base64Encoded {
+		r.buf = append(r.buf, res.Data...)
+		return nil
+	}
+
+	// a chunk may end in the middle of a base64 quantum, only decode the complete ones
+	r.pending += res.Data
+	n := len(r.pending) / 4 * 4
+	if r.eof {
+		n = len(r.pending)
+	}
+
+	bin, err := base64.StdEncoding.DecodeString(r.pending[:n])
+	if err != nil {
+		return err
+	}
+	r.pending = r.pending[n:]
+	r.buf = append(r.buf, bin...)
+	return nil
+}
+
+// Close interface
+func (r *streamReader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.closeErr = proto.IOClose{Handle: r.handle}.Call(r)
+	})
+	return r.closeErr
+}
+
+// CallContext uses the browser context, so that the handle can be closed after the page context is done
+func (r *streamReader) CallContext() (context.Context, proto.Client, string) {
+	client, id := r.page.sessionClient()
+	return r.page.browser.ctx, client, id
+}
+
+// WaitOpenE doc is similar to the method WaitPage
+func (p *Page) WaitOpenE() func() (*Page, error) {
+	b := p.browser.Context(p.ctx)
+	wait := b.EachEvent()
+
+	return func() (*Page, error) {
+		var targetID proto.TargetTargetID
+
+		wait(func(e *proto.TargetTargetCreated) bool {
+			if e.TargetInfo.OpenerID == p.TargetID {
+				targetID = e.TargetInfo.TargetID
+				return true
+			}
+			return false
+		})
+		return b.PageFromTargetIDE(targetID)
+	}
+}
+
+// PauseE doc is similar to the method Pause
+func (p *Page) PauseE() error {
+	_, err := proto.DebuggerEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+	wait := p.WaitEvent()
+	err = proto.DebuggerPause{}.Call(p)
 	if err != nil {
 		return err
 	}
@@ -363,20 +1514,67 @@ func (p *Page) PauseE() error {
 // WaitRequestIdleE returns a wait function that waits until no request for d duration.
 // Use the includes and excludes regexp list to filter the requests by their url.
 // Such as set n to 1 if there's a polling request.
+// The wait function can only be called once, the later calls return an ErrWaitFunctionUsed error.
+// The pending requests are rescanned periodically in the background, at most 10 of them each time, the ones whose
+// response bodies are available are treated as finished, so that a lost Network.loadingFinished event won't make
+// it wait forever.
 func (p *Page) WaitRequestIdleE(d time.Duration, includes, excludes []string) func() error {
+	return p.WaitRequestsIdleE(d, &RequestIdleOptions{Includes: includes, Excludes: excludes})
+}
+
+// RequestIdleOptions of the WaitRequestsIdleE
+type RequestIdleOptions struct {
+	// Includes and Excludes are the regexp lists to filter the requests by their url
+	Includes []string
+	Excludes []string
+
+	// ExcludeTypes are the resource types of the requests to ignore, such as the images and the media
+	ExcludeTypes []proto.NetworkResourceType
+
+	// MaxInflight is the number of the requests that are allowed to be pending when it's idle,
+	// such as 1 for a page with a long-polling request
+	MaxInflight int
+}
+
+// WaitRequestsIdleE is similar to the WaitRequestIdleE, but it's idle when there are at most opts.MaxInflight
+// pending requests for d duration. Only the requests that are sent after the call are tracked, the ones that
+// are sent before it won't affect the idleness. If opts is nil the defaults will be used.
+func (p *Page) WaitRequestsIdleE(d time.Duration, opts *RequestIdleOptions) func() error {
+	if opts == nil {
+		opts = &RequestIdleOptions{}
+	}
+
 	ctx, cancel := context.WithCancel(p.ctx)
 	s := p.event.Subscribe(ctx)
 	done := make(chan error)
 
+	excluded := func(t proto.NetworkResourceType) bool {
+		for _, e := range opts.ExcludeTypes {
+			if e == t {
+				return true
+			}
+		}
+		return false
+	}
+
 	go func() {
 		defer cancel()
 
 		reqList := map[proto.NetworkRequestID]kit.Nil{}
 		timeout := time.NewTimer(d)
+		rescan := time.NewTicker(requestIdleRescan)
+		defer rescan.Stop()
+
+		// the rescan runs in the background, so that it won't block the consuming of the events
+		scanned := make(chan []proto.NetworkRequestID, 1)
+		scanning := false
 
 		reset := func(id proto.NetworkRequestID) {
+			if _, has := reqList[id]; !has {
+				return
+			}
 			delete(reqList, id)
-			if len(reqList) == 0 {
+			if len(reqList) == opts.MaxInflight {
 				timeout.Reset(d)
 			}
 		}
@@ -386,9 +1584,41 @@ func (p *Page) WaitRequestIdleE(d time.Duration, includes, excludes []string) fu
 			case <-p.ctx.Done():
 				done <- p.ctx.Err()
 				return
+			case <-p.crash.crashed:
+				done <- p.crash.err()
+				return
 			case <-timeout.C:
 				done <- nil
 				return
+			case <-rescan.C:
+				if scanning {
+					continue
+				}
+				ids := []proto.NetworkRequestID{}
+				for id := range reqList {
+					if len(ids) == requestIdleRescanMax {
+						break
+					}
+					ids = append(ids, id)
+				}
+				scanning = true
+				go func() {
+					list := []proto.NetworkRequestID{}
+					for _, id := range ids {
+						if ctx.Err() != nil {
+							break
+						}
+						if p.responseAvailable(id) {
+							list = append(list, id)
+						}
+					}
+					scanned <- list
+				}()
+			case list := <-scanned:
+				scanning = false
+				for _, id := range list {
+					reset(id)
+				}
 			case msg, ok := <-s:
 				if !ok {
 					done <- nil
@@ -401,95 +1631,572 @@ func (p *Page) WaitRequestIdleE(d time.Duration, includes, excludes []string) fu
 				failed := &proto.NetworkLoadingFailed{}
 
 				if Event(e, sent) {
-					timeout.Stop()
-					kit.E(json.Unmarshal(e.Params, sent))
 					url := sent.Request.URL
 					id := sent.RequestID
-					if matchWithFilter(url, includes, excludes) {
+
+					// the redirect reuses the id of the request
+					if _, has := reqList[id]; has {
+						continue
+					}
+
+					// the blocked requests will never be sent to the server
+					if matchWithFilter(url, opts.Includes, opts.Excludes) && !excluded(sent.Type) &&
+						!p.blocking.blocked(url, sent.Type) {
 						reqList[id] = kit.Nil{}
+						if len(reqList) == opts.MaxInflight+1 {
+							timeout.Stop()
+						}
 					}
 				} else if Event(e, finished) {
-					kit.E(json.Unmarshal(e.Params, finished))
 					reset(finished.RequestID)
 				} else if Event(e, failed) {
-					kit.E(json.Unmarshal(e.Params, failed))
 					reset(failed.RequestID)
 				}
 			}
 		}
 	}()
 
+	var used int32
+
 	return func() (err error) {
-		defer func() { done = nil }()
-		if done == nil {
-			panic("can't use wait function twice")
+		if !atomic.CompareAndSwapInt32(&used, 0, 1) {
+			return &Error{nil, ErrWaitFunctionUsed, nil}
+		}
+
+		defer p.tryTrace(0, 0, 300, 0, "waiting for request idle "+strings.Join(opts.Includes, " "))()
+
+		return <-done
+	}
+}
+
+// the interval to rescan the pending requests of the WaitRequestsIdleE
+var requestIdleRescan = 3 * time.Second
+
+// the max number of the pending requests to check in each rescan
+const requestIdleRescanMax = 10
+
+// responseAvailable returns true if the body of the request can be read, which means the loading is finished
+func (p *Page) responseAvailable(id proto.NetworkRequestID) bool {
+	// the body of a streaming response, such as the event source, may never be available
+	t := p.Timeout(time.Second)
+	defer t.CancelTimeout()
+
+	_, err := proto.NetworkGetResponseBody{RequestID: id}.Call(t)
+	return err == nil
+}
+
+// WaitResponseE returns a wait function that waits for the first response whose url matches the regex,
+// then returns the response and its body. The body is decoded if the browser sends it as base64.
+// If the matched request fails before it finishes, an ErrRequestFailed error will be returned.
+func (p *Page) WaitResponseE(urlPattern string) func() (*proto.NetworkResponse, []byte, error) {
+	reg := regexp.MustCompile(urlPattern)
+
+	// subscribe before the action, so that we won't miss any event
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	return func() (*proto.NetworkResponse, []byte, error) {
+		defer cancel()
+
+		// the requests that match the pattern, the response will be nil until it's received
+		list := map[proto.NetworkRequestID]*proto.NetworkResponse{}
+
+		var id proto.NetworkRequestID
+		var err error
+
+		goob.Each(s, func(e *cdp.Event) bool {
+			sent := &proto.NetworkRequestWillBeSent{}
+			received := &proto.NetworkResponseReceived{}
+			finished := &proto.NetworkLoadingFinished{}
+			failed := &proto.NetworkLoadingFailed{}
+
+			switch {
+			case Event(e, sent):
+				if reg.MatchString(sent.Request.URL) {
+					list[sent.RequestID] = nil
+				}
+			case Event(e, received):
+				if _, has := list[received.RequestID]; has || reg.MatchString(received.Response.URL) {
+					list[received.RequestID] = received.Response
+				}
+			case Event(e, finished):
+				if list[finished.RequestID] != nil {
+					id = finished.RequestID
+					return true
+				}
+			case Event(e, failed):
+				if _, has := list[failed.RequestID]; has {
+					err = &Error{nil, ErrRequestFailed, failed.ErrorText}
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if id == "" {
+			return nil, nil, p.ctx.Err()
+		}
+
+		res, err := proto.NetworkGetResponseBody{RequestID: id}.Call(p)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body := []byte(res.Body)
+		if res.Base64Encoded {
+			body, err = base64.StdEncoding.DecodeString(res.Body)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return list[id], body, nil
+	}
+}
+
+// WaitIdleE doc is similar to the method WaitIdle
+func (p *Page) WaitIdleE(timeout time.Duration) (err error) {
+	_, err = p.EvalE(true, "", p.jsFn("waitIdle"), Array{timeout.Seconds()})
+	return err
+}
+
+// WaitDOMStableE waits until no DOM mutation happens for the duration d, the mutations inside the elements that
+// match the ignore selectors are excluded, such as the ads or the animations that never stop mutating.
+// It can be called right after the NavigateE, the wait will start once the document is ready for the js.
+func (p *Page) WaitDOMStableE(d time.Duration, ignore []string) error {
+	if ignore == nil {
+		ignore = []string{}
+	}
+	_, err := p.EvalE(true, "", p.jsFn("waitDOMStable"), Array{float64(d) / float64(time.Millisecond), ignore})
+	return err
+}
+
+// WaitLoadE doc is similar to the method WaitLoad
+func (p *Page) WaitLoadE() error {
+	_, err := p.EvalE(true, "", p.jsFn("waitLoad"), nil)
+	return err
+}
+
+// WaitNavigationE returns a wait function that waits until the next navigation of the frame reaches the lifecycle event,
+// such as "load", "DOMContentLoaded", "networkAlmostIdle", or "networkIdle".
+// Lifecycle events of other frames, such as iframes, are ignored.
+func (p *Page) WaitNavigationE(name proto.PageLifecycleEventName) func() error {
+	wait := p.EachEvent()
+
+	// the events of the current document will be emitted again when the lifecycle events get enabled,
+	// use the loader id to skip them
+	loaderID, err := p.frameLoaderID()
+	if err == nil {
+		err = proto.PageSetLifecycleEventsEnabled{Enabled: true}.Call(p)
+	}
+
+	return func() error {
+		if err != nil {
+			return err
+		}
+
+		wait(func(e *proto.PageLifecycleEvent) bool {
+			return e.FrameID == p.FrameID && e.LoaderID != loaderID && e.Name == string(name)
+		})
+		return p.ctx.Err()
+	}
+}
+
+// frameLoaderID returns the loader id of the current document of the frame
+func (p *Page) frameLoaderID() (proto.NetworkLoaderID, error) {
+	frame, err := p.frame()
+	if err != nil || frame == nil {
+		return "", err
+	}
+	return frame.LoaderID, nil
+}
+
+// frame returns the info of the frame of the page from the frame tree, returns nil if not found
+func (p *Page) frame() (*proto.PageFrame, error) {
+	res, err := proto.PageGetFrameTree{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := findFrameTree(res.FrameTree, p.FrameID)
+	if tree == nil {
+		return nil, nil
+	}
+	return tree.Frame, nil
+}
+
+// FramesE returns all the iframes of the page recursively, such as the iframes inside the iframes,
+// the out-of-process iframes will have their own sessions. The helper js of each frame will be injected
+// when the frame is evaluated against for the first time.
+func (p *Page) FramesE() ([]*Page, error) {
+	list := []*Page{}
+	err := p.eachFrameE(func(_ *proto.PageFrame, f *Page) (bool, bool) {
+		list = append(list, f)
+		return true, false
+	})
+	return list, err
+}
+
+// FrameByURLE returns the first iframe whose url matches the regexp pattern
+func (p *Page) FrameByURLE(pattern string) (*Page, error) {
+	reg, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.findFrameE(pattern, func(frame *proto.PageFrame) bool {
+		return reg.MatchString(frame.URL)
+	})
+}
+
+// FrameByNameE returns the first iframe whose name, such as the name attribute of the iframe element, equals the name
+func (p *Page) FrameByNameE(name string) (*Page, error) {
+	return p.findFrameE(name, func(frame *proto.PageFrame) bool {
+		return frame.Name == name
+	})
+}
+
+func (p *Page) findFrameE(query string, match func(*proto.PageFrame) bool) (*Page, error) {
+	var found *Page
+	err := p.eachFrameE(func(frame *proto.PageFrame, f *Page) (bool, bool) {
+		if match(frame) {
+			found = f
+			return true, true
+		}
+		return false, false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, &Error{nil, ErrFrameNotFound, query}
+	}
+	return found, nil
+}
+
+// eachFrameE walks the frame tree of the page in depth-first order until stop is true, the iframe elements of the
+// frames that fn doesn't keep are released after fn returns, unless a frame inside them is kept
+func (p *Page) eachFrameE(fn func(*proto.PageFrame, *Page) (keep, stop bool)) error {
+	_, _, err := p.eachFrame(fn)
+	return err
+}
+
+func (p *Page) eachFrame(fn func(*proto.PageFrame, *Page) (keep, stop bool)) (kept, stop bool, err error) {
+	res, err := proto.PageGetFrameTree{}.Call(p)
+	if err != nil {
+		return false, false, err
+	}
+
+	tree := findFrameTree(res.FrameTree, p.FrameID)
+	if tree == nil {
+		return false, false, &Error{nil, ErrFrameDetached, p.FrameID}
+	}
+
+	var walk func(parent *Page, tree *proto.PageFrameTree) (bool, error)
+	walk = func(parent *Page, tree *proto.PageFrameTree) (kept bool, err error) {
+		for _, child := range tree.ChildFrames {
+			owner, err := proto.DOMGetFrameOwner{FrameID: child.Frame.ID}.Call(parent)
+			if err != nil {
+				return kept, err
+			}
+
+			el, err := parent.resolveNodeE(owner.BackendNodeID)
+			if err != nil {
+				return kept, err
+			}
+
+			f, err := el.FrameE()
+			if err != nil {
+				_ = el.ReleaseE()
+				return kept, err
+			}
+
+			keep, s := fn(child.Frame, f)
+			stop = s
+
+			// the frame tree of the parent session doesn't have the children of the out-of-process iframes
+			subKept := false
+			if !stop {
+				if f.SessionID != parent.SessionID {
+					subKept, stop, err = f.eachFrame(fn)
+				} else {
+					subKept, err = walk(f, child)
+				}
+			}
+
+			// the kept frames use their iframe elements, such as to get the offset of them
+			if keep || subKept {
+				kept = true
+			} else {
+				_ = el.ReleaseE()
+			}
+
+			if err != nil || stop {
+				return kept, err
+			}
+		}
+		return kept, nil
+	}
+
+	kept, err = walk(p, tree)
+	return kept, stop, err
+}
+
+func findFrameTree(tree *proto.PageFrameTree, id proto.PageFrameID) *proto.PageFrameTree {
+	list := []*proto.PageFrameTree{tree}
+	for len(list) > 0 {
+		t := list[0]
+		list = append(list[1:], t.ChildFrames...)
+
+		if t.Frame.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// detachedFrameErr returns an ErrFrameDetached error if the page is an iframe that has been removed
+func (p *Page) detachedFrameErr() error {
+	if !p.IsIframe() {
+		return nil
+	}
+
+	frame, err := p.frame()
+	if err == nil && frame == nil {
+		return &Error{nil, ErrFrameDetached, p.FrameID}
+	}
+	return nil
+}
+
+// OnEvent calls the handler for each occurrence of the event type of e, the payload is decoded into e before
+// each call. Each subscriber gets all the events independently, the handler is called in order in its own goroutine.
+// The subscription stops when cancel is called or the context is done.
+func (p *Page) OnEvent(e proto.Event, handler func()) (cancel func()) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+	go onEvent(s, e, handler)
+	return cancel
+}
+
+// WaitEvent waits for the next event for one time. It will also load the data into the event object.
+func (p *Page) WaitEvent() (wait func(proto.Event)) {
+	ctx, cancel := p.crash.context(p.ctx)
+	s := p.event.Subscribe(ctx)
+	return func(e proto.Event) {
+		defer cancel()
+		for msg := range s {
+			if Event(msg.(*cdp.Event), e) {
+				return
+			}
 		}
+	}
+}
+
+// AddScriptTagE to page. If url is empty, content will be used.
+func (p *Page) AddScriptTagE(url, content string) error {
+	hash := md5.Sum([]byte(url + content))
+	id := hex.EncodeToString(hash[:])
+	_, err := p.EvalE(true, "", p.jsFn("addScriptTag"), Array{id, url, content})
+	return cspErr(err)
+}
+
+// AddStyleTagE to page. If url is empty, content will be used.
+func (p *Page) AddStyleTagE(url, content string) error {
+	hash := md5.Sum([]byte(url + content))
+	id := hex.EncodeToString(hash[:])
+	_, err := p.EvalE(true, "", p.jsFn("addStyleTag"), Array{id, url, content})
+	return cspErr(err)
+}
+
+// AddScriptE evaluates the js source in the page without adding any tag to the DOM, so the
+// Content-Security-Policy of the page won't block it. If isolated is true, the js will run in a new
+// isolated world of the frame, it shares the DOM with the page but not the js globals.
+func (p *Page) AddScriptE(js string, isolated bool) error {
+	req := proto.RuntimeEvaluate{Expression: js}
 
-		if p.browser.trace {
-			defer p.Overlay(0, 0, 300, 0, "waiting for request idle "+strings.Join(includes, " "))()
+	if isolated {
+		res, err := proto.PageCreateIsolatedWorld{FrameID: p.FrameID}.Call(p)
+		if err != nil {
+			return err
 		}
+		req.ContextID = res.ExecutionContextID
+	} else if p.IsIframe() {
+		req.ContextID = p.jsContextID
+	}
+
+	res, err := req.Call(p)
+	if err != nil {
+		return err
+	}
+	if res.ExceptionDetails != nil {
+		return &Error{nil, ErrEval, exceptionText(res.ExceptionDetails)}
+	}
+	return nil
+}
+
+// the fallback of the SetVisibilityE, the iframes that are cross-origin or out-of-process are skipped
+const syntheticVisibility = `function (state) {
+	const apply = (win) => {
+		try {
+			const doc = win.document
+			Object.defineProperty(doc, 'visibilityState', { get: () => state, configurable: true })
+			Object.defineProperty(doc, 'hidden', { get: () => state === 'hidden', configurable: true })
+			doc.dispatchEvent(new Event('visibilitychange', { bubbles: true }))
+		} catch (e) {}
+		for (let i = 0; i < win.frames.length; i++) apply(win.frames[i])
+	}
+	apply(window)
+}`
+
+// DispatchEventE dispatches a synthetic event on the window of the page, see the Element.DispatchEventE
+func (p *Page) DispatchEventE(name string, detail interface{}, opts *EventOptions) (canceled bool, err error) {
+	res, err := p.evalE(true, "", dispatchEventJS, dispatchEventArgs(name, detail, opts))
+	if err != nil {
+		return false, err
+	}
+	return res.Value.Bool(), nil
+}
+
+// SetVisibilityE emulates the visibility state of the page, "visible" or "hidden", the iframes inherit it.
+// If the browser doesn't support the emulation, the document.visibilityState and document.hidden of the current
+// documents will be overridden in the main world and the visibilitychange events will be dispatched instead, only
+// the same-origin iframes get the override, and it won't survive the navigations.
+func (p *Page) SetVisibilityE(state string) error {
+	err := proto.EmulationSetPageVisibilityState{State: state}.Call(p)
+	if !isMethodNotFound(err) {
+		return err
+	}
+
+	// the override must be in the main world of the root page, so that the scripts of the page can see it
+	_, err = p.Root().evalE(true, "", syntheticVisibility, Array{state})
+	return err
+}
+
+// SetFocusEmulationE makes the page behave like it's focused even if it isn't, such as the document.hasFocus()
+// returns true in the headless browser, which is required by the clipboard and some input events
+func (p *Page) SetFocusEmulationE(enabled bool) error {
+	return proto.EmulationSetFocusEmulationEnabled{Enabled: enabled}.Call(p)
+}
+
+// EmulateMediaE emulates the css media type, such as "print" or "screen", and the media features, such as
+// {Name: "prefers-color-scheme", Value: "dark"} or {Name: "prefers-reduced-motion", Value: "reduce"}.
+// An empty media keeps the media type of the page. Each call replaces the emulation of the previous one.
+func (p *Page) EmulateMediaE(media string, features []proto.EmulationMediaFeature) error {
+	req := &proto.EmulationSetEmulatedMedia{Media: media}
+	for i := range features {
+		f := features[i]
+		req.Features = append(req.Features, &f)
+	}
 
-		return <-done
+	err := req.Call(p)
+	if err != nil {
+		return err
 	}
-}
 
-// WaitIdleE doc is similar to the method WaitIdle
-func (p *Page) WaitIdleE(timeout time.Duration) (err error) {
-	_, err = p.EvalE(true, "", p.jsFn("waitIdle"), Array{timeout.Seconds()})
-	return err
+	p.media = req
+	return nil
 }
 
-// WaitLoadE doc is similar to the method WaitLoad
-func (p *Page) WaitLoadE() error {
-	_, err := p.EvalE(true, "", p.jsFn("waitLoad"), nil)
-	return err
+// ClearEmulatedMediaE clears the media type and the media features that are emulated by the EmulateMediaE
+func (p *Page) ClearEmulatedMediaE() error {
+	err := proto.EmulationSetEmulatedMedia{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	p.media = nil
+	return nil
 }
 
-// WaitEvent waits for the next event for one time. It will also load the data into the event object.
-func (p *Page) WaitEvent() (wait func(proto.Event)) {
-	ctx, cancel := context.WithCancel(p.ctx)
-	s := p.event.Subscribe(ctx)
-	return func(e proto.Event) {
-		defer cancel()
-		for msg := range s {
-			if Event(msg.(*cdp.Event), e) {
-				return
+// DarkModeE emulates the "prefers-color-scheme" media feature to be dark or light,
+// the other emulated media features and the media type are kept
+func (p *Page) DarkModeE(dark bool) error {
+	scheme := "light"
+	if dark {
+		scheme = "dark"
+	}
+
+	media := ""
+	features := []proto.EmulationMediaFeature{}
+	if p.media != nil {
+		media = p.media.Media
+		for _, f := range p.media.Features {
+			if f.Name != "prefers-color-scheme" {
+				features = append(features, *f)
 			}
 		}
 	}
+	features = append(features, proto.EmulationMediaFeature{Name: "prefers-color-scheme", Value: scheme})
+
+	return p.EmulateMediaE(media, features)
 }
 
-// AddScriptTagE to page. If url is empty, content will be used.
-func (p *Page) AddScriptTagE(url, content string) error {
-	hash := md5.Sum([]byte(url + content))
-	id := hex.EncodeToString(hash[:])
-	_, err := p.EvalE(true, "", p.jsFn("addScriptTag"), Array{id, url, content})
-	return err
+// SetBypassCSPE enables/disables bypassing the Content-Security-Policy of the page,
+// it takes effect after the next navigation or reload of the page.
+func (p *Page) SetBypassCSPE(enabled bool) error {
+	return proto.PageSetBypassCSP{Enabled: enabled}.Call(p)
 }
 
-// AddStyleTagE to page. If url is empty, content will be used.
-func (p *Page) AddStyleTagE(url, content string) error {
-	hash := md5.Sum([]byte(url + content))
-	id := hex.EncodeToString(hash[:])
-	_, err := p.EvalE(true, "", p.jsFn("addStyleTag"), Array{id, url, content})
-	return err
+// EvalOnNewDocumentE evaluates the script in every frame of the page upon creation, before any script of the frame runs.
+// The js is the source of the script, not a function definition. It won't affect the rod helper of the frames.
+func (p *Page) EvalOnNewDocumentE(js string) (proto.PageScriptIdentifier, error) {
+	res, err := proto.PageAddScriptToEvaluateOnNewDocument{Source: js}.Call(p)
+	if err != nil {
+		return "", err
+	}
+	return res.Identifier, nil
+}
+
+// RemoveScriptE removes the script added by EvalOnNewDocumentE
+func (p *Page) RemoveScriptE(id proto.PageScriptIdentifier) error {
+	return proto.PageRemoveScriptToEvaluateOnNewDocument{Identifier: id}.Call(p)
 }
 
 // EvalE thisID is the remote objectID that will be the this of the js function, if it's empty "window" will be used.
 // Set the byValue to true to reduce memory occupation.
 func (p *Page) EvalE(byValue bool, thisID proto.RuntimeRemoteObjectID, js string, jsArgs Array) (*proto.RuntimeRemoteObject, error) {
-	backoff := kit.BackoffSleeper(30*time.Millisecond, 3*time.Second, nil)
+	// the rod helpers, such as the overlays, are not traced
+	if !strings.HasPrefix(js, p.jsFnPrefix()) {
+		defer p.tryTrace(0, 0, 300, 0, "eval "+html.EscapeString(js))()
+	}
+
+	res, err := p.evalE(byValue, thisID, js, jsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	p.objects.add(res, js)
+	return res, nil
+}
+
+// evalE is the same as EvalE without the tracing, it's used by the internal js calls
+func (p *Page) evalE(byValue bool, thisID proto.RuntimeRemoteObjectID, js string, jsArgs Array) (*proto.RuntimeRemoteObject, error) {
+	// the helper of the page is bound to the frame id, which is unknown before the lazy page is attached
+	err := p.ensureSession()
+	if err != nil {
+		return nil, err
+	}
+
+	backoff := p.evalSleeper()
 	objectID := thisID
-	var err error
 	var res *proto.RuntimeCallFunctionOnResult
 
 	// js context will be invalid if a frame is reloaded
 	err = kit.Retry(p.ctx, backoff, func() (bool, error) {
+		if e := p.checkFrame(); e != nil {
+			return true, e
+		}
+
 		if thisID == "" {
 			if p.windowObjectID == "" {
 				err := p.initJS()
 				if err != nil {
-					if isNilContextErr(err) {
+					if IsNilContextError(err) {
+						if e := p.detachedFrameErr(); e != nil {
+							return true, e
+						}
 						return false, nil
 					}
 					return true, err
@@ -498,21 +2205,10 @@ func (p *Page) EvalE(byValue bool, thisID proto.RuntimeRemoteObjectID, js string
 			objectID = p.windowObjectID
 		}
 
-		args := []*proto.RuntimeCallArgument{}
-		for _, p := range jsArgs {
-			args = append(args, &proto.RuntimeCallArgument{Value: proto.NewJSON(p)})
-		}
-
-		res, err = proto.RuntimeCallFunctionOn{
-			ObjectID:            objectID,
-			AwaitPromise:        true,
-			ReturnByValue:       byValue,
-			FunctionDeclaration: SprintFnThis(js),
-			Arguments:           args,
-		}.Call(p)
+		res, err = callFunctionOn(objectID, byValue, js, jsArgs).Call(p)
 
 		if thisID == "" {
-			if isNilContextErr(err) {
+			if IsNilContextError(err) {
 				_ = p.initJS()
 				return false, nil
 			}
@@ -525,6 +2221,25 @@ func (p *Page) EvalE(byValue bool, thisID proto.RuntimeRemoteObjectID, js string
 		return nil, err
 	}
 
+	return evalResult(res)
+}
+
+func callFunctionOn(objectID proto.RuntimeRemoteObjectID, byValue bool, js string, jsArgs Array) proto.RuntimeCallFunctionOn {
+	args := []*proto.RuntimeCallArgument{}
+	for _, p := range jsArgs {
+		args = append(args, &proto.RuntimeCallArgument{Value: proto.NewJSON(p)})
+	}
+
+	return proto.RuntimeCallFunctionOn{
+		ObjectID:            objectID,
+		AwaitPromise:        true,
+		ReturnByValue:       byValue,
+		FunctionDeclaration: SprintFnThis(js),
+		Arguments:           args,
+	}
+}
+
+func evalResult(res *proto.RuntimeCallFunctionOnResult) (*proto.RuntimeRemoteObject, error) {
 	if res.ExceptionDetails != nil {
 		return nil, &Error{nil, ErrEval, res.ExceptionDetails.Exception.Description}
 	}
@@ -532,13 +2247,299 @@ func (p *Page) EvalE(byValue bool, thisID proto.RuntimeRemoteObjectID, js string
 	return res.Result, nil
 }
 
-// Sleeper returns the default sleeper for retry, it uses backoff and requestIdleCallback to wait
+// WaitE retries the js function until it returns a truthy value, thisID is the same as the one of EvalE.
+// If sleeper is nil, the p.Sleeper() will be used. If the js throws, the ErrEval error will be returned immediately.
+// If the context is done before the js returns a truthy value, an ErrWaitTimeout error that wraps the
+// context error will be returned.
+func (p *Page) WaitE(sleeper kit.Sleeper, thisID proto.RuntimeRemoteObjectID, js string, params Array) error {
+	if sleeper == nil {
+		sleeper = p.Sleeper()
+	}
+	sleeper, stats := countSleeper(sleeper)
+
+	truthy := fmt.Sprintf(`async function() { return !!(await (%s).apply(this, arguments)) }`, js)
+
+	err := kit.Retry(p.ctx, sleeper, func() (bool, error) {
+		res, err := p.evalE(true, thisID, truthy, params)
+		if err != nil && p.ctx.Err() == nil {
+			return true, err
+		}
+
+		return res != nil && res.Value.Bool(), nil
+	})
+
+	if err == nil {
+		return nil
+	}
+	if IsError(err, ErrEval) {
+		return err
+	}
+	if p.ctx.Err() != nil {
+		return &Error{p.ctx.Err(), ErrWaitTimeout, fmt.Sprintf("%s, %s", js, stats)}
+	}
+	return err
+}
+
+// WaitTextE waits until one of the elements that match the selector contains the substr, and returns the first
+// one of them. The text is the same as the one of the Element.WaitTextE. If the context is done before any of them
+// matches, an ErrWaitTimeout error with the current text of the first element will be returned.
+func (p *Page) WaitTextE(selector, substr string, opts *WaitTextOptions) (*Element, error) {
+	if opts == nil {
+		opts = &WaitTextOptions{}
+	}
+
+	text := ""
+	var el *Element
+	sleeper, stats := countSleeper(p.Sleeper())
+	err := kit.Retry(p.ctx, sleeper, func() (bool, error) {
+		res, err := p.evalE(false, "", p.jsFn("elementText"), Array{selector, substr, opts.Regexp, opts.NormalizeSpace})
+		if err != nil {
+			return true, err
+		}
+
+		if res.Subtype == proto.RuntimeRemoteObjectSubtypeNode {
+			el = p.ElementFromObjectID(res.ObjectID)
+			return true, nil
+		}
+
+		text = res.Value.String()
+		return false, nil
+	})
+
+	if err != nil && p.ctx.Err() != nil {
+		return nil, waitTextErr(p.ctx.Err(), text, fmt.Sprintf("wait for text %q of %s, %s", substr, selector, stats))
+	}
+	return el, err
+}
+
+// the js shim that wraps the binding into a function returns promise
+const exposeFunctionShim = `(name, bindingName) => {
+	const binding = window[bindingName]
+	if (!binding) return
+
+	const callbacks = new Map()
+	let lastID = 0
+
+	window[bindingName + '_resolve'] = (id, result, error) => {
+		const cb = callbacks.get(id)
+		callbacks.delete(id)
+		if (error) cb.reject(new Error(error))
+		else cb.resolve(result)
+	}
+
+	window[name] = (...args) => new Promise((resolve, reject) => {
+		const id = ++lastID
+		callbacks.set(id, { resolve, reject })
+		binding(JSON.stringify({ id, args }))
+	})
+}`
+
+// exposedFunctions is shared by all the clones of a page
+type exposedFunctions struct {
+	lock sync.Mutex
+	list map[string]func() error // name -> remove function
+}
+
+// ExposeFunctionE exposes fn as window[name] to the page, the js function returns a promise that resolves
+// to the return value of fn. The args are the JSON encoded arguments of the js function.
+// The function will survive navigations, each call will be handled in its own goroutine.
+func (p *Page) ExposeFunctionE(name string, fn func(args []json.RawMessage) (interface{}, error)) error {
+	p.exposed.lock.Lock()
+	defer p.exposed.lock.Unlock()
+
+	if _, has := p.exposed.list[name]; has {
+		return &Error{nil, ErrFunctionExposed, name}
+	}
+
+	bindingName := "__rod_binding_" + name
+	shim := sprintFnApply(exposeFunctionShim, Array{name, bindingName})
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	wait := p.Context(ctx).EachEvent()
+
+	err := proto.RuntimeEnable{}.Call(p)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	err = proto.RuntimeAddBinding{Name: bindingName}.Call(p)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	// undo the binding and the script, so that the function can be exposed again after the failure
+	cleanup := func(scriptID proto.PageScriptIdentifier) {
+		cancel()
+		_ = proto.RuntimeRemoveBinding{Name: bindingName}.Call(p)
+		if scriptID != "" {
+			_ = p.RemoveScriptE(scriptID)
+		}
+	}
+
+	scriptID, err := p.EvalOnNewDocumentE(shim)
+	if err != nil {
+		cleanup("")
+		return err
+	}
+
+	_, err = proto.RuntimeEvaluate{Expression: shim}.Call(p)
+	if err != nil {
+		cleanup(scriptID)
+		return err
+	}
+
+	go wait(func(e *proto.RuntimeBindingCalled) bool {
+		if e.Name == bindingName {
+			go p.callExposedFunction(e, bindingName, fn)
+		}
+		return false
+	})
+
+	p.exposed.list[name] = func() error {
+		cancel()
+
+		err := proto.RuntimeRemoveBinding{Name: bindingName}.Call(p)
+		if err != nil {
+			return err
+		}
+
+		err = p.RemoveScriptE(scriptID)
+		if err != nil {
+			return err
+		}
+
+		_, err = proto.RuntimeEvaluate{
+			Expression: sprintFnApply(`name => { delete window[name] }`, Array{name}),
+		}.Call(p)
+		return err
+	}
+
+	return nil
+}
+
+func (p *Page) callExposedFunction(
+	e *proto.RuntimeBindingCalled,
+	bindingName string,
+	fn func(args []json.RawMessage) (interface{}, error),
+) {
+	var payload struct {
+		ID   int64             `json:"id"`
+		Args []json.RawMessage `json:"args"`
+	}
+	if json.Unmarshal([]byte(e.Payload), &payload) != nil {
+		return
+	}
+
+	errMsg := ""
+	res, err := callExposed(fn, payload.Args)
+	data, e2 := json.Marshal(res)
+	if err == nil {
+		err = e2
+	}
+	if err != nil {
+		data = []byte("null")
+		errMsg = err.Error()
+	}
+
+	_, _ = proto.RuntimeEvaluate{
+		ContextID: e.ExecutionContextID,
+		Expression: sprintFnApply(
+			`(name, id, res, err) => window[name](id, res, err)`,
+			Array{bindingName + "_resolve", payload.ID, json.RawMessage(data), errMsg},
+		),
+	}.Call(p)
+}
+
+// callExposed turns the panic of the fn into an error, so that the js promise is rejected with the panic message
+func callExposed(fn func(args []json.RawMessage) (interface{}, error), args []json.RawMessage) (res interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(args)
+}
+
+// RemoveExposedFunctionE removes the function that is exposed by ExposeFunctionE
+func (p *Page) RemoveExposedFunctionE(name string) error {
+	p.exposed.lock.Lock()
+	defer p.exposed.lock.Unlock()
+
+	remove, has := p.exposed.list[name]
+	if !has {
+		return &Error{nil, ErrFunctionNotExposed, name}
+	}
+	delete(p.exposed.list, name)
+
+	return remove()
+}
+
+// Sleeper returns the default sleeper for retry, it uses backoff and requestIdleCallback to wait.
+// The backoff can be set by the Browser.Backoff or the WithSleeper.
 func (p *Page) Sleeper() kit.Sleeper {
-	return kit.BackoffSleeper(100*time.Millisecond, time.Second, nil)
+	if p.sleeper != nil {
+		return p.sleeper()
+	}
+	if p.browser.backoff != nil {
+		return p.browser.backoff.sleeper()
+	}
+	return defaultBackoff.sleeper()
+}
+
+// SkipInteractableCheck disables/enables the interactable checks of the ClickE and InputE for the elements of the
+// page, such as to save the calls when the page is known to be well-formed. It only affects the page and the
+// clones created after it.
+func (p *Page) SkipInteractableCheck(skip bool) *Page {
+	p.skipInteractable = skip
+	return p
+}
+
+// InputKeystrokes makes the InputE and AppendE of the elements of the page press the key of each character instead
+// of inserting the whole text at once, such as the page listens to the key events. It's slower than the default
+// Keyboard.InsertTextE. It only affects the page and the clones created after it.
+func (p *Page) InputKeystrokes(enabled bool) *Page {
+	p.inputKeystrokes = enabled
+	return p
+}
+
+// resolveNodeE creates the element of the node in the js world where the helper functions of the page live
+func (p *Page) resolveNodeE(id proto.DOMBackendNodeID) (*Element, error) {
+	if p.windowObjectID == "" {
+		_, err := p.evalE(true, "", `() => {}`, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node, err := proto.DOMResolveNode{BackendNodeID: id, ExecutionContextID: p.jsContextID}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.ElementFromObjectID(node.Object.ObjectID), nil
+}
+
+// HTMLE doc is similar to the method HTML
+func (p *Page) HTMLE() (string, error) {
+	doc, err := p.evalE(false, "", `() => document`, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = p.ReleaseE(doc.ObjectID) }()
+
+	res, err := proto.DOMGetOuterHTML{ObjectID: doc.ObjectID}.Call(p)
+	if err != nil {
+		return "", err
+	}
+	return res.OuterHTML, nil
 }
 
 // ElementFromObjectID creates an Element from the remote object id.
 func (p *Page) ElementFromObjectID(id proto.RuntimeRemoteObjectID) *Element {
+	p.objects.add(&proto.RuntimeRemoteObject{ObjectID: id}, "")
+
 	return (&Element{
 		page:     p,
 		ObjectID: id,
@@ -548,15 +2549,62 @@ func (p *Page) ElementFromObjectID(id proto.RuntimeRemoteObjectID) *Element {
 // ReleaseE doc is similar to the method Release
 func (p *Page) ReleaseE(objectID proto.RuntimeRemoteObjectID) error {
 	err := proto.RuntimeReleaseObject{ObjectID: objectID}.Call(p)
+	if err == nil {
+		p.objects.remove(objectID)
+	}
 	return err
 }
 
 // CallContext parameters for proto
 func (p *Page) CallContext() (context.Context, proto.Client, string) {
-	return p.ctx, p.browser.client, string(p.SessionID)
+	if err := p.ensureSession(); err != nil {
+		return p.ctx, failedClient{err}, ""
+	}
+	client, id := p.sessionClient()
+	return p.ctx, client, id
+}
+
+// isFrameTarget checks if the frame is hosted in a separate target
+func (p *Page) isFrameTarget(frameID proto.PageFrameID) (bool, error) {
+	res, err := proto.TargetGetTargets{}.Call(p.browser)
+	if err != nil {
+		return false, err
+	}
+
+	for _, info := range res.TargetInfos {
+		if info.Type == proto.TargetTargetInfoTypeIframe && string(info.TargetID) == string(frameID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// frameOffsetE returns the position of the viewport of the session relative to the viewport of the root page.
+// The positions from the DOM domain are relative to the viewport of the session, for the iframes of the same
+// session they are already relative to the root, only the out-of-process iframes need the offset.
+func (p *Page) frameOffsetE() (x, y float64, err error) {
+	if !p.IsIframe() {
+		return 0, 0, nil
+	}
+
+	parent := p.element.page
+
+	x, y, err = parent.frameOffsetE()
+	if err != nil || parent.SessionID == p.SessionID {
+		return
+	}
+
+	res, err := proto.DOMGetBoxModel{ObjectID: p.element.ObjectID}.Call(p.element)
+	if err != nil {
+		return
+	}
+
+	left, top, _, _ := quadBounds(res.Model.Content)
+	return x + left, y + top, nil
 }
 
-func (p *Page) initSession() error {
+// initSession attaches a new session to the target, the owner is the page that is reattached, nil for a new page
+func (p *Page) initSession(owner *Page) error {
 	obj, err := proto.TargetAttachToTarget{
 		TargetID: p.TargetID,
 		Flatten:  true, // if it's not set no response will return
@@ -566,11 +2614,39 @@ func (p *Page) initSession() error {
 	}
 	p.SessionID = obj.SessionID
 
-	err = p.initEvents()
+	err = p.initEvents(owner)
+	if err != nil {
+		return err
+	}
+
+	err = p.installStealth()
 	if err != nil {
 		return err
 	}
 
+	// the viewport of the iframe follows the size of its element
+	if v := p.viewportOverride(); v != nil && !p.IsIframe() {
+		err = v.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	// such as the session of the out-of-process iframe inherits the network conditions of its parent
+	if p.network != nil {
+		err = p.network.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.cache.get() {
+		err = proto.NetworkSetCacheDisabled{CacheDisabled: true}.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
 	res, err := proto.DOMGetDocument{}.Call(p)
 	if err != nil {
 		return err
@@ -586,16 +2662,40 @@ func (p *Page) initSession() error {
 	return nil
 }
 
-func (p *Page) initEvents() error {
-	p.event = p.browser.event.Filter(p.ctx, func(e *cdp.Event) bool {
-		return e.SessionID == string(p.SessionID)
-	})
+func (p *Page) initEvents(owner *Page) error {
+	id := p.SessionID
+
+	switch l := p.events.lazySession(); {
+	case owner != nil:
+		// the queue forwards the events of the new session to the same observable, only the detach is per session
+		p.events.switchSession(id)
+		p.attach = newAttachState(owner)
+		go p.attach.watch(p.browser.event.Subscribe(owner.ctx), p.TargetID, func() proto.TargetSessionID { return id })
+	case l != nil && !l.used:
+		// the lazy page creates the states before it's attached, they are used by its first session
+		l.use(id)
+	default:
+		p.initStates(func() proto.TargetSessionID { return id })
+	}
 
 	err := proto.PageEnable{}.Call(p)
 	if err != nil {
 		return err
 	}
 
+	err = proto.InspectorEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	// the page can detect the enabled runtime, such as via the getters of the logged objects
+	if len(p.browser.stealth) == 0 {
+		err = proto.RuntimeEnable{}.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = proto.NetworkEnable{}.Call(p)
 	if err != nil {
 		return err
@@ -604,14 +2704,50 @@ func (p *Page) initEvents() error {
 	return nil
 }
 
+// initTargetStates creates the states that are shared by all the clones of the page of a target
+func (p *Page) initTargetStates() {
+	p.sessionLock = &sessionLock{}
+	p.fetch = &fetchState{}
+	p.blocking = &blockingState{}
+	p.exposed = &exposedFunctions{list: map[string]func() error{}}
+	p.tracing = &tracingState{}
+	p.coverage = &coverageState{}
+	p.proxy = &proxyState{}
+	p.hostHeaders = &hostHeadersState{}
+	p.fileChooser = &fileChooserState{}
+	p.objects = &objectTracker{}
+	p.frames = &frameStates{list: map[proto.PageFrameID]*frameState{}}
+	p.cache = &cacheState{}
+}
+
+// initStates creates the event queue and the states of the page session, the sessionID returns the id of the session
+func (p *Page) initStates(sessionID func() proto.TargetSessionID) {
+	// the queue exists before the Page.enable, so that we won't miss the dialog that is already opening
+	p.dialog = &dialogState{}
+	p.initEventQueue(sessionID)
+
+	p.crash = newCrashState()
+	go p.crash.watch(p.event.Subscribe(p.ctx))
+
+	p.security = newSecurityState()
+	go p.security.watch(p.event.Subscribe(p.ctx))
+
+	// the detach is reported on the browser level
+	p.attach = newAttachState(p)
+	go p.attach.watch(p.browser.event.Subscribe(p.ctx), p.TargetID, sessionID)
+}
+
 func (p *Page) initJS() error {
-	scriptURL := "\n//# sourceURL=__rod_helper__"
+	scriptURL := "\n//# sourceURL=" + helperScriptURL
 
 	params := &proto.RuntimeEvaluate{
 		Expression: sprintFnApply(assets.Helper, Array{p.FrameID}) + scriptURL,
 	}
 
 	if p.IsIframe() {
+		// read the generation before the world is created, so that a navigation during the creation won't be missed
+		gen := p.frameGeneration()
+
 		res, err := proto.PageCreateIsolatedWorld{
 			FrameID: p.FrameID,
 		}.Call(p)
@@ -620,6 +2756,8 @@ func (p *Page) initJS() error {
 		}
 
 		params.ContextID = res.ExecutionContextID
+		p.jsContextID = res.ExecutionContextID
+		p.frameContextCreated(res.ExecutionContextID, gen)
 	}
 
 	res, err := params.Call(p)
@@ -627,6 +2765,21 @@ func (p *Page) initJS() error {
 		return err
 	}
 
+	if res.ExceptionDetails != nil {
+		text := exceptionText(res.ExceptionDetails)
+		if !strings.Contains(text, "Content Security Policy") {
+			return &Error{nil, ErrEval, text}
+		}
+
+		if p.browser.bypassCSP {
+			err = p.SetBypassCSPE(true)
+			if err != nil {
+				return err
+			}
+		}
+		return &Error{nil, ErrCSPBlocked, text}
+	}
+
 	p.windowObjectID = res.Result.ObjectID
 
 	if p.browser.trace {
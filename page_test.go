@@ -3,17 +3,25 @@ package rod_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"image/png"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/ysmood/kit"
 	"github.com/ysmood/rod"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/devices"
 	"github.com/ysmood/rod/lib/input"
 	"github.com/ysmood/rod/lib/proto"
 )
@@ -38,6 +46,71 @@ func (s *S) TestSetCookies() {
 	s.Equal("1", cookies[1].Value)
 }
 
+func (s *S) TestSaveLoadCookies() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", func(ctx kit.GinContext) {
+		http.SetCookie(ctx.Writer, &http.Cookie{Name: "session", Value: "1", HttpOnly: true})
+		http.SetCookie(ctx.Writer, &http.Cookie{
+			Name: "persist", Value: "2", MaxAge: 3600, SameSite: http.SameSiteLaxMode,
+		})
+		ctx.Status(200)
+	})
+	engine.GET("/check", func(ctx kit.GinContext) {
+		session, err := ctx.Cookie("session")
+		kit.E(err)
+		persist, err := ctx.Cookie("persist")
+		kit.E(err)
+		kit.E(ctx.Writer.WriteString(session + persist))
+	})
+
+	page := s.browser.Incognito().Page(url)
+	buf := &bytes.Buffer{}
+	page.SaveCookies(buf)
+	page.Close()
+
+	// add an expired cookie
+	var cookies []*proto.NetworkCookie
+	kit.E(json.Unmarshal(buf.Bytes(), &cookies))
+	s.Len(cookies, 2)
+	expired := *cookies[0]
+	expired.Name = "expired"
+	expired.Session = false
+	expired.Expires = 1
+	cookies = append(cookies, &expired)
+
+	page = s.browser.Incognito().Page("")
+	defer page.Close()
+
+	skipped, err := page.LoadCookiesE(bytes.NewBufferString(kit.MustToJSON(cookies)))
+	kit.E(err)
+	s.Equal(1, skipped)
+
+	page.Navigate(url + "/check")
+	s.Equal("12", page.Element("body").Text())
+
+	for _, c := range page.Cookies() {
+		if c.Name == "persist" {
+			s.Equal(proto.NetworkCookieSameSiteLax, c.SameSite)
+			s.False(c.IsDomain())
+		}
+	}
+
+	// replay the session with a plain http client
+	req, err := http.NewRequest(http.MethodGet, url+"/check", nil)
+	kit.E(err)
+	for _, c := range page.Cookies() {
+		req.AddCookie(c.ToHTTP())
+	}
+	res, err := http.DefaultClient.Do(req)
+	kit.E(err)
+	defer func() { kit.E(res.Body.Close()) }()
+	body, err := ioutil.ReadAll(res.Body)
+	kit.E(err)
+	s.Equal("12", string(body))
+}
+
 func (s *S) TestSetExtraHeaders() {
 	url, engine, close := serve()
 	defer close()
@@ -62,6 +135,81 @@ func (s *S) TestSetExtraHeaders() {
 	s.Equal("2", out2)
 }
 
+func (s *S) TestSetExtraHeadersForHosts() {
+	url, engine, close := serve()
+	defer close()
+
+	// the same server as a third-party host
+	thirdParty := strings.Replace(url, "127.0.0.1", "localhost", 1)
+
+	lock := sync.Mutex{}
+	got := map[string]string{}
+	engine.GET("/", ginHTML(`<html><body>ok</body></html>`))
+	engine.GET("/api", func(ctx kit.GinContext) {
+		lock.Lock()
+		defer lock.Unlock()
+		got[ctx.Request.Host+" token"] = ctx.GetHeader("X-Token")
+		got[ctx.Request.Host+" global"] = ctx.GetHeader("X-Global")
+		ctx.Header("Access-Control-Allow-Origin", "*")
+		ctx.Header("Access-Control-Allow-Headers", "*")
+	})
+	engine.OPTIONS("/api", func(ctx kit.GinContext) {
+		ctx.Header("Access-Control-Allow-Origin", "*")
+		ctx.Header("Access-Control-Allow-Headers", "*")
+	})
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	// the hijacks get their requests no matter they are registered before or after the headers
+	hijack := func(name string) func() {
+		return p.HijackRequests("*/"+name, func(ctx *rod.HijackContext) error {
+			return ctx.FulfillRequest(&proto.FetchFulfillRequest{
+				ResponseHeaders: []*proto.FetchHeaderEntry{{Name: "Access-Control-Allow-Origin", Value: "*"}},
+				Body:            []byte(name),
+			})
+		})
+	}
+	defer hijack("before")()
+
+	s.True(rod.IsError(p.SetExtraHeadersForHostsE([]string{"127.0.0.1"}, []string{"X-Token"}), rod.ErrHeaderDict))
+
+	p.SetExtraHeaders("X-Global", "global", "X-Token", "global")
+	p.SetExtraHeadersForHosts([]string{"127.0.0.1"}, "X-Token", "old")
+	p.SetExtraHeadersForHosts([]string{"127.0.0.1"}, "x-token", "secret")
+	p.SetExtraHeadersForHosts([]string{"*.example.com"}, "X-Token", "other")
+	s.Len(p.ExtraHeadersForHosts(), 2)
+
+	defer hijack("after")()
+	s.Equal("secret", p.ExtraHeadersForHosts()[0].Headers["x-token"].String())
+
+	fetch := func(u string) {
+		p.Eval(`u => fetch(u).then(r => r.text())`, u+"/api")
+	}
+
+	p.Navigate(url)
+	fetch(url)
+	fetch(thirdParty)
+	for _, name := range []string{"before", "after"} {
+		s.Equal(name, p.Eval(`u => fetch(u).then(r => r.text())`, url+"/"+name).String())
+	}
+
+	host := url[len("http://"):]
+	thirdHost := thirdParty[len("http://"):]
+	lock.Lock()
+	s.Equal("secret", got[host+" token"])
+	s.Equal("global", got[host+" global"])
+	s.Equal("global", got[thirdHost+" token"])
+	lock.Unlock()
+
+	p.RemoveExtraHeadersForHosts("*.example.com").RemoveExtraHeadersForHosts("127.0.0.1")
+	s.Len(p.ExtraHeadersForHosts(), 0)
+	fetch(url)
+	lock.Lock()
+	s.Equal("global", got[host+" token"])
+	lock.Unlock()
+}
+
 func (s *S) TestSetUserAgent() {
 	url, engine, close := serve()
 	defer close()
@@ -126,6 +274,127 @@ func (s *S) TestWindow() {
 	s.EqualValues(611, page.Eval(`() => window.innerHeight`).Int())
 }
 
+func (s *S) TestPageBlockRequests() {
+	url, engine, close := serve()
+	defer close()
+
+	var lock sync.Mutex
+	hits := map[string]int{}
+	count := func(ctx kit.GinContext) {
+		lock.Lock()
+		defer lock.Unlock()
+		hits[ctx.Request.URL.Path]++
+	}
+
+	engine.GET("/a.png", count)
+	engine.GET("/b.js", count)
+	engine.GET("/c.js", count)
+	engine.GET("/", ginHTML(`<html>
+		<img src="/a.png">
+		<script src="/b.js"></script>
+		<script src="/c.js"></script>
+	</html>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	p.BlockResourceTypes(proto.NetworkResourceTypeImage).BlockRequests("*/b.js")
+
+	wait := p.WaitRequestIdle()
+	p.Navigate(url).WaitLoad()
+	wait()
+
+	lock.Lock()
+	s.Equal(map[string]int{"/c.js": 1}, hits)
+	lock.Unlock()
+
+	// stop the blocking
+	p.BlockResourceTypes().BlockRequests()
+	wait = p.WaitRequestIdle()
+	p.Reload().WaitLoad()
+	wait()
+
+	lock.Lock()
+	s.Equal(1, hits["/a.png"])
+	s.Equal(1, hits["/b.js"])
+	lock.Unlock()
+}
+
+func (s *S) TestPageFrames() {
+	p := s.page.Navigate(srcFile("fixtures/click-iframes.html"))
+	p.Element("iframe").Frame().Element("iframe")
+
+	frames := p.Frames()
+	s.Len(frames, 2)
+
+	frame := p.FrameByURL(`click\.html$`)
+	frame.Element("button").Click()
+	s.True(frame.Has("[a=ok]"))
+
+	p.Element("iframe").Frame().Eval(`() => window.name = 'a'`)
+	s.Contains(p.FrameByName("a").Eval(`() => location.href`).String(), "click-iframe.html")
+
+	_, err := p.FrameByNameE("not-exists")
+	s.True(errors.Is(err, rod.ErrFrameNotFound))
+
+	p.Eval(`() => document.querySelector('iframe').remove()`)
+	_, err = frames[1].EvalE(true, "", `() => 1`, nil)
+	s.True(errors.Is(err, rod.ErrFrameDetached))
+}
+
+func (s *S) TestPageTimeoutClone() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	t := p.Timeout(100 * time.Millisecond)
+	s.Equal(p, t.CancelTimeout())
+
+	t = p.Timeout(100 * time.Millisecond)
+	_, err := proto.RuntimeEvaluate{
+		Expression:   `new Promise(r => setTimeout(r, 1000))`,
+		AwaitPromise: true,
+	}.Call(t)
+	s.True(errors.Is(err, context.DeadlineExceeded))
+
+	callErr := &rod.CallError{}
+	s.True(errors.As(err, &callErr))
+	s.Equal("Runtime.evaluate", callErr.Method)
+
+	// the original page should still work
+	s.Nil(p.GetContext().Err())
+	s.EqualValues(1, p.Eval(`() => 1`).Int())
+	s.Equal(p, t.CancelTimeout())
+
+	// a page without timeout
+	s.Equal(p, p.CancelTimeout())
+}
+
+func (s *S) TestWindowState() {
+	page := s.browser.Page(srcFile("fixtures/click.html"))
+	defer page.Close()
+
+	bounds := page.GetWindow()
+	defer page.Window(
+		bounds.Left,
+		bounds.Top,
+		bounds.Width,
+		bounds.Height,
+	)
+
+	page.Activate()
+
+	page.WindowMaximize()
+	s.Equal(proto.BrowserWindowStateMaximized, page.GetWindow().WindowState)
+
+	kit.E(page.WindowFullscreenE())
+	s.Equal(proto.BrowserWindowStateFullscreen, page.GetWindow().WindowState)
+
+	kit.E(page.WindowMaximizeE())
+	s.Equal(proto.BrowserWindowStateMaximized, page.GetWindow().WindowState)
+
+	page.WindowNormal()
+	s.Equal(proto.BrowserWindowStateNormal, page.GetWindow().WindowState)
+}
+
 func (s *S) TestSetViewport() {
 	page := s.browser.Page(srcFile("fixtures/click.html"))
 	defer page.Close()
@@ -140,83 +409,1322 @@ func (s *S) TestSetViewport() {
 	s.NotEqual(int64(317), res.Get("0").Int())
 }
 
-func (s *S) TestPageAddScriptTag() {
-	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+func (s *S) TestEmulateDevice() {
+	page := s.browser.Page(srcFile("fixtures/click.html"))
+	defer page.Close()
 
-	res := p.AddScriptTag(srcFile("fixtures/add-script-tag.js")).Eval(`() => count()`)
-	s.EqualValues(0, res.Int())
+	page.EmulateDevice(devices.IPhoneX)
+	res := page.Eval(`() => [window.innerWidth, window.devicePixelRatio, navigator.userAgent, 'ontouchstart' in window]`)
+	s.EqualValues(375, res.Get("0").Int())
+	s.EqualValues(3, res.Get("1").Int())
+	s.Equal(devices.IPhoneX.UserAgent, res.Get("2").String())
+	s.True(res.Get("3").Bool())
 
-	res = p.AddScriptTag(srcFile("fixtures/add-script-tag.js")).Eval(`() => count()`)
-	s.EqualValues(1, res.Int())
+	page.EmulateDevice(devices.IPhoneX.Rotate())
+	s.EqualValues(812, page.Eval(`() => window.innerWidth`).Int())
 
-	kit.E(p.AddScriptTagE("", `let ok = 'yes'`))
-	res = p.Eval(`() => ok`)
-	s.Equal("yes", res.String())
+	page.EmulateDevice(devices.Clear)
+	res = page.Eval(`() => [window.innerWidth, navigator.userAgent]`)
+	s.NotEqual(int64(812), res.Get("0").Int())
+	s.NotEqual(devices.IPhoneX.UserAgent, res.Get("1").String())
 }
 
-func (s *S) TestPageAddStyleTag() {
-	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+func (s *S) TestEmulateGeolocation() {
+	url, engine, close := serve()
+	defer close()
 
-	res := p.AddStyleTag(srcFile("fixtures/add-style-tag.css")).
-		Element("h4").Eval(`() => getComputedStyle(this).color`)
-	s.Equal("rgb(255, 0, 0)", res.String())
+	engine.GET("/", ginHTML(`<html></html>`))
 
-	p.AddStyleTag(srcFile("fixtures/add-style-tag.css"))
-	s.Len(p.Elements("link"), 1)
+	page := s.browser.Page(url)
+	defer page.Close()
 
-	kit.E(p.AddStyleTagE("", "h4 { color: green; }"))
-	res = p.Element("h4").Eval(`() => getComputedStyle(this).color`)
-	s.Equal("rgb(0, 128, 0)", res.String())
+	permission := func() string {
+		return page.Eval(`() => navigator.permissions.query({ name: 'geolocation' }).then(r => r.state)`).String()
+	}
+
+	page.EmulateGeolocation(0, 13.4, 10, false)
+	s.Equal("prompt", permission())
+
+	page.EmulateGeolocation(0, 13.4, 10, true)
+	s.Equal("granted", permission())
+	res := page.Eval(`() => new Promise((resolve, reject) => navigator.geolocation.getCurrentPosition(
+		p => resolve([p.coords.latitude, p.coords.longitude]), reject))`)
+	s.EqualValues(0, res.Get("0").Float())
+	s.EqualValues(13.4, res.Get("1").Float())
+
+	page.ClearGeolocation()
+	s.Equal("prompt", permission())
 }
 
-func (s *S) TestUntilPage() {
-	page := s.page.Timeout(3 * time.Second).Navigate(srcFile("fixtures/open-page.html"))
-	defer page.CancelTimeout()
+func (s *S) TestEmulateTimezoneAndLocale() {
+	page := s.browser.Page(srcFile("fixtures/click.html"))
+	defer page.Close()
 
-	wait := page.WaitOpen()
+	page.EmulateTimezone("Asia/Tokyo").EmulateLocale("de_DE")
+	res := page.Eval(`() => [Intl.DateTimeFormat().resolvedOptions().timeZone, (1000.5).toLocaleString()]`)
+	s.Equal("Asia/Tokyo", res.Get("0").String())
+	s.Equal("1.000,5", res.Get("1").String())
 
-	page.Element("a").Click()
+	err := page.EmulateTimezoneE("Invalid/Zone")
+	s.Error(err)
+	s.Contains(err.Error(), "Invalid timezone")
 
-	newPage := wait()
+	page.EmulateTimezone("").EmulateLocale("")
+	s.NotEqual("Asia/Tokyo", page.Eval(`() => Intl.DateTimeFormat().resolvedOptions().timeZone`).String())
+}
 
-	s.Equal("click me", newPage.Element("button").Text())
+func (s *S) TestPageEvalOnNewDocument() {
+	p := s.browser.Page("")
+	defer p.Close()
+
+	id := p.EvalOnNewDocument(`
+		Object.defineProperty(navigator, 'webdriver', { get: () => false })
+		window.mutations = 0
+		new MutationObserver(() => window.mutations++).observe(document, { childList: true, subtree: true })
+	`)
+
+	p.Navigate(srcFile("fixtures/click.html"))
+	s.False(p.Eval(`() => navigator.webdriver`).Bool())
+	s.Greater(p.Eval(`() => mutations`).Int(), int64(0))
+
+	p.RemoveScript(id)
+	p.Navigate(srcFile("fixtures/input.html"))
+	s.Equal("undefined", p.Eval(`() => typeof mutations`).String())
 }
 
-func (s *S) TestPageWaitRequestIdle() {
+func (s *S) TestPageEvalInto() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	var obj struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	p.EvalInto(&obj, `async (b) => ({ a: 1, b })`, "ok")
+	s.Equal(1, obj.A)
+	s.Equal("ok", obj.B)
+
+	list := []int{}
+	p.EvalInto(&list, `() => [1, 2, 3]`)
+	s.Equal([]int{1, 2, 3}, list)
+
+	dict := map[string]bool{}
+	p.Element("button").EvalInto(&dict, `() => ({ [this.tagName]: true })`)
+	s.Equal(map[string]bool{"BUTTON": true}, dict)
+
+	ptr := &obj
+	p.EvalInto(&ptr, `() => null`)
+	s.Nil(ptr)
+
+	err := p.EvalIntoE(&obj, `() => undefined`, nil)
+	s.True(errors.Is(err, rod.ErrEvalValue))
+	s.Contains(err.Error(), "undefined")
+
+	err = p.EvalIntoE(&list, `() => "str"`, nil)
+	s.True(errors.Is(err, rod.ErrEvalValue))
+	s.Contains(err.Error(), `"str"`)
+
+	s.Error(p.EvalIntoE(list, `() => []`, nil))
+}
+
+func (s *S) TestPageEvalBatch() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	el := p.Element("button")
+
+	list, err := p.EvalBatchE([]*rod.EvalCall{
+		{ByValue: true, JS: `n => n + 1`, Args: rod.Array{1}},
+		{ByValue: true, JS: `() => { throw new Error('err') }`},
+		{ByValue: true, ThisID: el.ObjectID, JS: `function() { return this.tagName }`},
+	})
+
+	var batchErr *rod.EvalBatchError
+	s.True(errors.As(err, &batchErr))
+	s.True(errors.Is(err, rod.ErrEval))
+	s.Nil(batchErr.Errors[0])
+	s.Error(batchErr.Errors[1])
+	s.Nil(batchErr.Errors[2])
+
+	s.EqualValues(2, list[0].Value.Int())
+	s.Nil(list[1])
+	s.Equal("BUTTON", list[2].Value.String())
+
+	list = p.EvalBatch(&rod.EvalCall{ByValue: true, JS: `() => 1`})
+	s.EqualValues(1, list[0].Value.Int())
+}
+
+func (s *S) TestPageTrackObjects() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+
+	var leaks []*rod.ObjectLeak
+	p.TrackObjects(func(list []*rod.ObjectLeak) {
+		leaks = list
+	})
+
+	p.Element("button").Release()
+	el := p.Element("button")
+	_, err := p.EvalE(false, "", `() => document.body`, nil)
+	kit.E(err)
+
+	s.Len(p.TrackedObjects(), 2)
+	s.Equal(el.ObjectID, p.TrackedObjects()[0].ObjectID)
+
+	p.ReleaseAll()
+	s.Len(p.TrackedObjects(), 0)
+
+	obj, err := p.EvalE(false, "", `() => document.body`, nil)
+	kit.E(err)
+
+	p.Close()
+	s.Len(leaks, 1)
+	s.Equal(obj.ObjectID, leaks[0].ObjectID)
+	s.Equal(`() => document.body`, leaks[0].JS)
+}
+
+func (s *S) TestPageCrash() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	p.Eval(`() => setTimeout(() => { throw new Error('boom') })`)
+	kit.Sleep(0.1)
+
+	list := p.Exceptions()
+	s.Len(list, 1)
+	s.Contains(list[0].Exception.Description, "boom")
+
+	wait := p.WaitRequestIdleE(time.Minute, nil, nil)
+	go func() { _ = proto.PageCrash{}.Call(p) }()
+	s.True(errors.Is(wait(), rod.ErrPageCrashed))
+
+	// the calls after the crash fail fast
+	_, err := p.Timeout(time.Minute).EvalE(true, "", `() => 1`, nil)
+	s.True(errors.Is(err, rod.ErrPageCrashed))
+
+	_, err = p.ExceptionsE()
+	s.True(errors.Is(err, rod.ErrPageCrashed))
+
+	// the sugar still dumps the exceptions after the crash
+	s.Len(p.Exceptions(), 1)
+}
+
+func (s *S) TestPageReattach() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	p.Viewport(320, 240, 1, false)
+
+	detach := func() {
+		old := p.SessionID
+		wait := s.browser.WaitEvent()
+		kit.E(proto.TargetDetachFromTarget{SessionID: old}.Call(s.browser))
+		wait(&proto.TargetDetachedFromTarget{})
+		kit.Sleep(0.1)
+	}
+
+	detach()
+	_, err := p.EvalE(true, "", `() => 1`, nil)
+	s.True(errors.Is(err, rod.ErrPageDetached))
+
+	// the subscription of a clone that is made before the reattach gets the events of the new session
+	clone := p.Timeout(time.Minute)
+	wait := clone.WaitEvent()
+
+	old := p.SessionID
+	p.Reattach()
+	s.NotEqual(old, p.SessionID)
+	s.EqualValues(320, p.Eval(`() => innerWidth`).Int())
+
+	p.Eval(`() => console.log("reattached")`)
+	wait(&proto.RuntimeConsoleAPICalled{})
+	clone.CancelTimeout()
+
+	s.browser.AutoReattach(true)
+	defer s.browser.AutoReattach(false)
+
+	detach()
+	s.EqualValues(320, p.Eval(`() => innerWidth`).Int())
+
+	closed := s.browser.Page("")
+	closed.Close()
+	s.True(errors.Is(closed.ReattachE(), rod.ErrTargetGone))
+}
+
+func (s *S) TestPageRace() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	p.Eval(`() => setTimeout(() => {
+		const el = document.createElement('div')
+		el.className = 'error'
+		el.innerText = 'failed'
+		document.body.append(el)
+	}, 100)`)
+
+	i, el := p.Race().Element("div.toast").Element("div.error").Event(&proto.PageJavascriptDialogOpening{}).Do()
+	s.Equal(1, i)
+	s.Equal("failed", el.Text())
+
+	e := &proto.PageJavascriptDialogOpening{}
+	wait := p.HandleDialog(true, "")
+	p.Eval(`() => setTimeout(() => alert('ok'), 100)`)
+	i, el = p.Race().Element("div.toast").Event(e).Do()
+	wait()
+	s.Equal(1, i)
+	s.Nil(el)
+	s.Equal("ok", e.Message)
+
+	_, _, err := p.Timeout(100 * time.Millisecond).Race().Element("div.toast").DoE()
+	s.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func (s *S) TestPageNetworkTimeline() {
+	url, engine, close := serve()
+	defer close()
+
+	pause, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	engine.GET("/a", func(ctx kit.GinContext) {
+		ctx.Redirect(http.StatusFound, "/b")
+	})
+	engine.GET("/b", ginHTML(`<html><img src="/c"></html>`))
+	engine.GET("/c", func(ctx kit.GinContext) {
+		<-pause.Done()
+	})
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	stop := p.StartNetworkTimeline()
+	p.Navigate(url + "/a")
+	kit.Sleep(0.3)
+	list := stop()
+	cancel()
+
+	s.Len(list, 3)
+	s.EqualValues(http.StatusFound, list[0].Status)
+	s.Equal(0, list[0].Redirect)
+	s.Equal(list[0].RequestID, list[1].RequestID)
+	s.Equal(1, list[1].Redirect)
+	s.EqualValues(http.StatusOK, list[1].Status)
+	s.False(list[1].Pending)
+	s.Greater(int64(list[1].Total), int64(0))
+	s.Greater(list[1].EncodedDataLength, float64(0))
+	s.Equal(proto.NetworkResourceTypeImage, list[2].Type)
+	s.True(list[2].Pending)
+
+	_, err := json.Marshal(list)
+	s.Nil(err)
+}
+
+func (s *S) TestPageCertErrors() {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer srv.Close()
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	err := p.NavigateE(srv.URL)
+	s.Error(err)
+
+	p.IgnoreCertErrors(true)
+	p.Navigate(srv.URL)
+	s.Equal("ok", p.Element("body").Text())
+	s.NotNil(p.SecurityState())
+	p.IgnoreCertErrors(false)
+
+	// the handler runs in the goroutine of the events
+	var lock sync.Mutex
+	hosts := []string{}
+	stop := p.HandleCertErrors(func(e *proto.SecurityCertificateError) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		hosts = append(hosts, e.RequestURL)
+		return strings.HasPrefix(e.RequestURL, srv.URL)
+	})
+	defer stop()
+
+	p.Navigate(srv.URL + "/a")
+	s.Equal("ok", p.Element("body").Text())
+	lock.Lock()
+	s.NotEmpty(hosts)
+	lock.Unlock()
+}
+
+func (s *S) TestPageFillForm() {
+	p := s.browser.Page(srcFile("fixtures/form.html"))
+	defer p.Close()
+
+	err := p.FillFormE(map[string]interface{}{
+		"user":  true,
+		"agree": "yes",
+		"color": "red",
+	}, &rod.FormOptions{Form: "#signup"})
+
+	var formErr *rod.FormError
+	s.True(errors.As(err, &formErr))
+	s.Len(formErr.Errors, 2)
+	s.True(errors.Is(formErr.Errors["user"], rod.ErrFormValue))
+	s.True(errors.Is(formErr.Errors["agree"], rod.ErrFormValue))
+
+	p.FillForm(map[string]interface{}{
+		"user":          "jack",
+		"bio":           "hi",
+		"agree":         true,
+		"plan":          "pro",
+		"color":         "blue",
+		"tags":          []string{"a", "c"},
+		"birthday":      time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		"[name=avatar]": file("fixtures/click.html"),
+	}, &rod.FormOptions{Form: "#signup", Submit: true, WaitNavigation: proto.PageLifecycleEventNameLoad})
+
+	query := p.Eval(`() => location.search`).String()
+	for _, v := range []string{"user=jack", "bio=hi", "agree=on", "plan=pro", "color=blue", "tags=a&tags=c", "birthday=2020-01-02", "avatar=click.html"} {
+		s.Contains(query, v)
+	}
+}
+
+func (s *S) TestPageNavigationRequest() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<html><a href="/a">a</a></html>`))
+	engine.GET("/a", ginHTML(`<html>a</html>`))
+	engine.GET("/b", ginHTML(`<html>b</html>`))
+
+	p := s.browser.Page(url)
+	defer p.Close()
+
+	stop := p.EachNavigationRequest(func(u string) rod.NavigationDecision {
+		if strings.HasSuffix(u, "/a") {
+			return rod.NavigationRedirectTo(url + "/b")
+		}
+		return rod.NavigationContinue
+	})
+
+	wait := p.WaitNavigation(proto.PageLifecycleEventNameLoad)
+	p.Element("a").Click()
+	wait()
+	s.Equal(url+"/b", p.Eval(`() => location.href`).String())
+	stop()
+
+	p.Navigate(url)
+	stop = p.RestrictNavigation("localhost")
+	defer stop()
+
+	// the server listens on the 127.0.0.1, the localhost is another host of it
+	local := strings.Replace(url, "127.0.0.1", "localhost", 1)
+	p.Navigate(local)
+	s.Equal(local+"/", p.Eval(`() => location.href`).String())
+
+	s.Error(p.NavigateE(url + "/a"))
+
+	// the current document is intact
+	s.Equal(local+"/", p.Eval(`() => location.href`).String())
+	s.True(p.Has("a"))
+}
+
+func (s *S) TestPageStorage() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<html>ok</html>`))
+
+	p := s.browser.Page(url)
+	defer p.Close()
+
+	p.SetLocalStorage("a", "1").SetSessionStorage("b", "2")
+	s.Equal(map[string]string{"a": "1"}, p.LocalStorage())
+	s.Equal(map[string]string{"b": "2"}, p.SessionStorage())
+
+	p.ClearStorage(proto.StorageStorageTypeIndexeddb)
+	s.Len(p.LocalStorage(), 1)
+
+	p.ClearStorage(proto.StorageStorageTypeLocalStorage)
+	s.Len(p.LocalStorage(), 0)
+	s.Len(p.SessionStorage(), 0)
+
+	p.SetLocalStorage("a", "1")
+	p.ClearAllStorage()
+	s.Len(p.LocalStorage(), 0)
+
+	p.Navigate("data:text/html,ok")
+	_, err := p.LocalStorageE()
+	s.True(errors.Is(err, rod.ErrStorageAccess))
+	s.True(errors.Is(p.ClearStorageE(), rod.ErrStorageAccess))
+	s.True(errors.Is(p.ClearAllStorageE(), rod.ErrStorageAccess))
+}
+
+func (s *S) TestPageSnapshotState() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", func(ctx kit.GinContext) {
+		http.SetCookie(ctx.Writer, &http.Cookie{Name: "session", Value: "1", HttpOnly: true, Secure: true})
+		ctx.Status(200)
+	})
+	engine.GET("/app", ginHTML(`<html><script>
+		window.seen = localStorage.getItem('token') + sessionStorage.getItem('tab')
+	</script></html>`))
+
+	p := s.browser.Incognito().Page(url)
+	p.Navigate(url + "/app").WaitLoad()
+	p.SetLocalStorage("token", "a").SetSessionStorage("tab", "b")
+
+	data, err := json.Marshal(p.SnapshotState())
+	kit.E(err)
+	p.Close()
+
+	var state rod.PageState
+	kit.E(json.Unmarshal(data, &state))
+	s.Equal(url+"/app", state.URL)
+	s.Equal(map[string]string{"token": "a"}, state.LocalStorage)
+
+	p = s.browser.Incognito().Page("")
+	defer p.Close()
+
+	p.RestoreState(&state)
+	s.Equal("ab", p.Eval(`() => window.seen`).String())
+
+	cookies := p.Cookies()
+	s.Len(cookies, 1)
+	s.True(cookies[0].HTTPOnly)
+	s.True(cookies[0].Secure)
+
+	// the storages won't be reset by the later navigations
+	p.SetLocalStorage("token", "c").Reload().WaitLoad()
+	s.Equal("cb", p.Eval(`() => window.seen`).String())
+
+	blank := s.browser.Incognito().Page("")
+	defer blank.Close()
+	s.Empty(blank.SnapshotState().Origin)
+}
+
+func (s *S) TestPageNavigateWithResponse() {
 	url, engine, close := serve()
 	defer close()
 
-	sleep := time.Second
+	engine.GET("/a", func(ctx kit.GinContext) {
+		ctx.Redirect(http.StatusFound, "/b")
+	})
+	engine.GET("/b", func(ctx kit.GinContext) {
+		ctx.Header("X-A", "ok")
+		ctx.String(http.StatusNotFound, "not found")
+	})
+	engine.GET("/c", ginHTML(`<html>ok</html>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	res := p.NavigateWithResponse(url + "/a")
+	s.Equal(url+"/b", res.URL)
+	s.EqualValues(http.StatusNotFound, res.Status)
+	s.Equal("ok", res.Headers["X-A"].String())
+	s.Len(res.Redirects, 1)
+	s.EqualValues(http.StatusFound, res.Redirects[0].Status)
+	s.False(res.OK())
+
+	_, err := p.NavigateOKE(url + "/a")
+	s.True(errors.Is(err, rod.ErrNavigationStatus))
+
+	res = p.NavigateOK(url + "/c")
+	s.True(res.OK())
+	s.Nil(res.Redirects)
+
+	res = p.NavigateWithResponse(url + "/c#a")
+	s.True(res.NoResponse)
+}
+
+func (s *S) TestPageVisibilityAndFocus() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<iframe src="/frame"></iframe>`))
+	engine.GET("/frame", ginHTML(`<p>frame</p>`))
+
+	p := s.browser.Page(url)
+	defer p.Close()
+	p.Element("iframe").Frame().Element("p")
+
+	state := `() => document.visibilityState + ' ' + document.hasFocus()`
+
+	// the frame.Eval runs in the isolated world, the scripts of the iframe see the main world of it
+	frameState := `() => document.querySelector('iframe').contentDocument.visibilityState`
+
+	p.Eval(`() => document.addEventListener('visibilitychange', () => window.changed = true)`)
+
+	p.SetVisibility("hidden").SetFocusEmulation(true)
+	s.Equal("hidden true", p.Eval(state).String())
+	s.Equal("hidden", p.Eval(frameState).String())
+	s.True(p.Eval(`() => window.changed`).Bool())
+
+	p.SetVisibility("visible")
+	s.Equal("visible", p.Eval(`() => document.visibilityState`).String())
+	s.Equal("visible", p.Eval(frameState).String())
+}
+
+func (s *S) TestPageDisableCache() {
+	url, engine, close := serve()
+	defer close()
+
+	var lock sync.Mutex
+	count := 0
+	get := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return count
+	}
+	engine.GET("/", ginHTML(`<script src="/a.js"></script>`))
+	engine.GET("/a.js", func(ctx kit.GinContext) {
+		lock.Lock()
+		count++
+		lock.Unlock()
+		ctx.Header("Cache-Control", "max-age=3600")
+		ctx.String(200, "")
+	})
+
+	b := s.browser.NewContext()
+	defer b.CloseContext()
+
+	p := b.Page("")
+	defer p.Close()
+
+	p.ClearBrowserCache()
+	p.Navigate(url).Navigate(url)
+	s.Equal(1, get())
+
+	// the clones share the flag
+	p.Timeout(time.Minute).DisableCache(true)
+	p.Navigate(url)
+	s.Equal(2, get())
+
+	p.Reattach().Navigate(url)
+	s.Equal(3, get())
+}
+
+func (s *S) TestPageEventBuffer() {
+	s.browser.EventBuffer(10, rod.EventOverflowBlock)
+	defer s.browser.EventBuffer(1000, rod.EventOverflowBlock)
+
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := p.Event().Subscribe(ctx)
+
+	p.Eval(`() => { for (let i = 0; i < 500; i++) console.log(i) }`)
+
+	count := 0
+	for msg := range sub {
+		if rod.Event(msg.(*cdp.Event), &proto.RuntimeConsoleAPICalled{}) {
+			// the slow subscriber
+			time.Sleep(time.Millisecond)
+			count++
+			if count == 500 {
+				break
+			}
+		}
+	}
+
+	s.Equal(500, count)
+	s.EqualValues(0, p.DroppedEvents())
+}
+
+func (s *S) TestPageEventBufferDrop() {
+	s.browser.EventBuffer(1, rod.EventOverflowDrop)
+	defer s.browser.EventBuffer(1000, rod.EventOverflowBlock)
+
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	// the burst overflows the 1-slot queue
+	p.Eval(`() => { for (let i = 0; i < 10000; i++) console.log(i) }`)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for p.DroppedEvents() == 0 && time.Now().Before(deadline) {
+		kit.Sleep(0.1)
+	}
+	s.Greater(p.DroppedEvents(), int64(0))
+}
+
+func (s *S) TestPageOverlayAndHighlight() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	count := func() int64 {
+		return p.Eval(`() => document.querySelectorAll('div[id^=rod-]').length`).Int()
+	}
+	before := count()
+
+	remove, err := p.OverlayE(10, 10, 100, 100, "<b>overlay</b>")
+	kit.E(err)
+	s.Equal(before+1, count())
+	remove()
+	remove()
+	s.Equal(before, count())
+
+	removeHighlight := p.Element("button").Highlight("button")
+	s.Equal(before+1, count())
+	removeHighlight()
+	s.Equal(before, count())
+
+	// safe after the overlay is gone with the navigation
+	remove, err = p.OverlayE(0, 0, 10, 10, "")
+	kit.E(err)
+	p.Navigate(srcFile("fixtures/click.html"))
+	remove()
+}
+
+func (s *S) TestPageEmulateMedia() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	matches := func(query string) bool {
+		return p.Eval(`q => window.matchMedia(q).matches`, query).Bool()
+	}
+
+	p.EmulateMedia("print", proto.EmulationMediaFeature{Name: "prefers-reduced-motion", Value: "reduce"})
+	s.True(matches("print"))
+	s.True(matches("(prefers-reduced-motion: reduce)"))
+
+	p.DarkMode(true)
+	s.True(matches("print"))
+	s.True(matches("(prefers-reduced-motion: reduce)"))
+	s.True(matches("(prefers-color-scheme: dark)"))
+
+	p.DarkMode(false)
+	s.True(matches("(prefers-color-scheme: light)"))
+
+	// the emulation survives the reattach
+	p.DarkMode(true)
+	kit.E(proto.TargetDetachFromTarget{SessionID: p.SessionID}.Call(s.browser))
+	p.Reattach()
+	s.True(matches("(prefers-color-scheme: dark)"))
+
+	p.ClearEmulatedMedia()
+	s.True(matches("screen"))
+	s.False(matches("(prefers-reduced-motion: reduce)"))
+}
+
+func (s *S) TestPageBackoff() {
+	s.browser.Backoff(10*time.Millisecond, 20*time.Millisecond, nil)
+	defer s.browser.Backoff(100*time.Millisecond, time.Second, nil)
+
+	p := s.page.Timeout(500 * time.Millisecond)
+	defer p.CancelTimeout()
+
+	err := p.WaitE(nil, "", `() => false`, nil)
+	s.True(errors.Is(err, rod.ErrWaitTimeout))
+
+	// the stats of the retries are reported, the 20ms interval should try many times
+	msg := err.Error()
+	s.Contains(msg, "gave up after ")
+	attempts := 0
+	_, err = fmt.Sscanf(msg[strings.Index(msg, "gave up after "):], "gave up after %d attempts", &attempts)
+	kit.E(err)
+	s.Greater(attempts, 10)
+
+	count := 0
+	err = s.page.WithSleeper(func() kit.Sleeper {
+		count++
+		return kit.CountSleeper(2)
+	}).WaitE(nil, "", `() => false`, nil)
+	s.True(errors.Is(err, kit.ErrMaxSleepCount))
+	s.Greater(count, 1)
+}
+
+func (s *S) TestPageHandleFileDialog() {
+	p := s.page.Navigate(srcFile("fixtures/file-chooser.html"))
+	names := func(selector string) string {
+		return p.Element(selector).Eval(`() => Array.from(this.files).map(f => f.name).join()`).String()
+	}
+
+	wait := p.HandleFileDialog(slash("fixtures/click.html"), slash("fixtures/alert.html"))
+	p.Element("#open-multiple").Click()
+	wait()
+	s.Equal("click.html,alert.html", names("#multiple"))
+
+	waitE := p.HandleFileDialogE([]string{slash("fixtures/click.html"), slash("fixtures/alert.html")})
+	p.Element("#open-single").Click()
+	s.True(errors.Is(waitE(), rod.ErrFileChooserMode))
+
+	wait = p.HandleFileDialog(slash("fixtures/click.html"))
+	p.Element("#open-single").Click()
+	wait()
+	s.Equal("click.html", names("#single"))
+
+	// the interception is disabled when the context is done, even if the wait function isn't called
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Context(ctx).HandleFileDialog(slash("fixtures/click.html"))
+	cancel()
+	kit.Sleep(0.3)
+
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), time.Second)
+	defer cancelTimeout()
+	sub := p.Event().Subscribe(timeout)
+	p.Element("#open-single").Click()
+	opened := false
+	for msg := range sub {
+		if rod.Event(msg.(*cdp.Event), &proto.PageFileChooserOpened{}) {
+			opened = true
+			break
+		}
+	}
+	s.False(opened)
+
+	// a new one still works in the single-file mode
+	wait = p.HandleFileDialog(slash("fixtures/alert.html"))
+	p.Element("#open-single").Click()
+	wait()
+	s.Equal("alert.html", names("#single"))
+
+	s.Error(p.HandleFileDialogE([]string{"not-exists"})())
+}
+
+func (s *S) TestPageExposeFunction() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	p.ExposeFunction("add", func(args []json.RawMessage) (interface{}, error) {
+		var a, b int
+		kit.E(json.Unmarshal(args[0], &a))
+		kit.E(json.Unmarshal(args[1], &b))
+		return a + b, nil
+	})
+	s.EqualValues(3, p.Eval(`() => add(1, 2)`).Int())
+
+	p.ExposeFunction("fail", func(args []json.RawMessage) (interface{}, error) {
+		return nil, errors.New("err")
+	})
+	_, err := p.EvalE(true, "", `() => fail()`, nil)
+	s.Contains(err.Error(), "Error: err")
+
+	p.ExposeFunction("crash", func(args []json.RawMessage) (interface{}, error) {
+		panic("boom")
+	})
+	_, err = p.EvalE(true, "", `() => crash()`, nil)
+	s.Contains(err.Error(), "Error: panic: boom")
+
+	// survive navigations
+	p.Navigate(srcFile("fixtures/input.html")).WaitLoad()
+	s.EqualValues(5, p.Eval(`() => add(2, 3)`).Int())
+
+	err = p.ExposeFunctionE("add", nil)
+	s.True(rod.IsError(err, rod.ErrFunctionExposed))
+
+	p.RemoveExposedFunction("add")
+	s.Equal("undefined", p.Eval(`() => typeof add`).String())
+	s.True(rod.IsError(p.RemoveExposedFunctionE("add"), rod.ErrFunctionNotExposed))
+}
+
+func (s *S) TestPageAddScriptTag() {
+	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+
+	res := p.AddScriptTag(srcFile("fixtures/add-script-tag.js")).Eval(`() => count()`)
+	s.EqualValues(0, res.Int())
+
+	res = p.AddScriptTag(srcFile("fixtures/add-script-tag.js")).Eval(`() => count()`)
+	s.EqualValues(1, res.Int())
+
+	kit.E(p.AddScriptTagE("", `let ok = 'yes'`))
+	res = p.Eval(`() => ok`)
+	s.Equal("yes", res.String())
+}
+
+func (s *S) TestPageAddStyleTag() {
+	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+
+	res := p.AddStyleTag(srcFile("fixtures/add-style-tag.css")).
+		Element("h4").Eval(`() => getComputedStyle(this).color`)
+	s.Equal("rgb(255, 0, 0)", res.String())
+
+	p.AddStyleTag(srcFile("fixtures/add-style-tag.css"))
+	s.Len(p.Elements("link"), 1)
+
+	kit.E(p.AddStyleTagE("", "h4 { color: green; }"))
+	res = p.Element("h4").Eval(`() => getComputedStyle(this).color`)
+	s.Equal("rgb(0, 128, 0)", res.String())
+}
+
+func (s *S) TestPageObjectToJSON() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	res, err := p.EvalE(false, "", `() => {
+		const a = { num: 1, str: 'ok', list: [1, 'a', null], undef: undefined, fn() {} }
+		a.self = a
+		a.node = document.querySelector('button')
+		a.map = new Map([['k', 'v']])
+		a.big = 10n
+		return a
+	}`, nil)
+	kit.E(err)
+
+	j := p.ObjectToJSON(res)
+	s.EqualValues(1, j.Get("num").Int())
+	s.Equal("ok", j.Get("str").String())
+	s.Equal(`[1,"a",null]`, j.Get("list").Raw)
+	s.False(j.Get("undef").Exists())
+	s.False(j.Get("fn").Exists())
+	s.Equal("[Circular]", j.Get("self").String())
+	s.Equal("button", j.Get("node").String())
+	s.Equal(`[["k","v"]]`, j.Get("map").Raw)
+	s.Equal("10n", j.Get("big").String())
+
+	res, err = p.EvalE(false, "", `() => ({ a: [1, { b: 2 }] })`, nil)
+	kit.E(err)
+	s.Equal(`{"a":[1,{"b":2}]}`, p.ObjectToJSON(res).Raw)
+
+	res, err = p.EvalE(false, "", `() => undefined`, nil)
+	kit.E(err)
+	s.Equal("null", p.ObjectToJSON(res).Raw)
+}
+
+func (s *S) TestPageScreencast() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	frames, stop, err := p.ScreencastE(&proto.PageStartScreencast{Format: proto.PageStartScreencastFormatPng})
+	kit.E(err)
+
+	p.Eval(`() => document.body.style.background = 'red'`)
+
+	frame := <-frames
+	s.Equal([]byte("\x89PNG"), frame.Data[:4])
+	s.False(frame.Timestamp.IsZero())
+
+	kit.E(stop())
+	kit.E(stop())
+
+	for range frames {
+	}
+}
+
+func (s *S) TestPageRecord() {
+	dir := filepath.Join("tmp", "record", kit.RandString(8))
+
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	stop := p.Record(dir)
+	for i := 0; i < 3; i++ {
+		p.Eval(`i => document.body.style.background = ['red', 'green', 'blue'][i]`, i)
+		kit.Sleep(0.1)
+	}
+	stop()
+
+	list, err := ioutil.ReadDir(dir)
+	kit.E(err)
+	s.NotEmpty(list)
+	s.Equal("00001.jpg", list[0].Name())
+}
+
+func (s *S) TestPageCSP() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", func(ctx kit.GinContext) {
+		ctx.Header("Content-Security-Policy", "script-src 'self'; style-src 'self'")
+		ginHTML(`<html><body><h4>ok</h4></body></html>`)(ctx)
+	})
+
+	p := s.browser.Page(url)
+	defer p.Close()
+
+	err := p.AddScriptTagE("", `window.a = 1`)
+	s.True(errors.Is(err, rod.ErrCSPBlocked))
+
+	err = p.AddStyleTagE("", `h4 { color: green; }`)
+	s.True(errors.Is(err, rod.ErrCSPBlocked))
+
+	p.AddScript(`window.b = 1`)
+	s.EqualValues(1, p.Eval(`() => window.b`).Int())
+
+	kit.E(p.AddScriptE(`window.c = 1`, true))
+	s.Nil(p.Eval(`() => window.c`).Value())
+
+	p.SetBypassCSP(true).Reload()
+	kit.E(p.AddScriptTagE("", `window.d = 1`))
+	s.EqualValues(1, p.Eval(`() => window.d`).Int())
+}
+
+func (s *S) TestUntilPage() {
+	page := s.page.Timeout(3 * time.Second).Navigate(srcFile("fixtures/open-page.html"))
+	defer page.CancelTimeout()
+
+	wait := page.WaitOpen()
+
+	page.Element("a").Click()
+
+	newPage := wait()
+
+	s.Equal("click me", newPage.Element("button").Text())
+}
+
+func (s *S) TestPageWaitRequestIdle() {
+	url, engine, close := serve()
+	defer close()
+
+	sleep := time.Second
+
+	engine.GET("/r1", func(ctx kit.GinContext) {})
+	engine.GET("/r2", func(ctx kit.GinContext) { time.Sleep(sleep) })
+	engine.GET("/", ginHTML(`<html>
+		<button>click</button>
+		<script>
+			document.querySelector("button").onclick = () => {
+				fetch('/r1')
+				fetch('/r2').then(r => r.text())
+			}
+		</script>
+	</html>`))
+
+	page := s.page.Navigate(url)
+
+	wait := page.WaitRequestIdle("/r1")
+	page.Element("button").Click()
+	start := time.Now()
+	wait()
+	s.Greater(int64(time.Since(start)), int64(sleep))
+
+	wait = page.WaitRequestIdle("/r2")
+	page.Element("button").Click()
+	start = time.Now()
+	wait()
+	s.Less(int64(time.Since(start)), int64(sleep))
+
+	s.Panics(func() {
+		wait()
+	})
+
+	waitE := page.WaitRequestIdleE(100*time.Millisecond, nil, nil)
+	kit.E(waitE())
+	s.True(rod.IsError(waitE(), rod.ErrWaitFunctionUsed))
+}
+
+func (s *S) TestPageWaitRequestsIdle() {
+	url, engine, close := serve()
+	defer close()
+
+	sleep := 3 * time.Second
+
+	engine.GET("/poll", func(ctx kit.GinContext) { time.Sleep(sleep) })
+	engine.GET("/img", func(ctx kit.GinContext) { time.Sleep(sleep) })
+	engine.GET("/", ginHTML(`<html>
+		<button id="poll" onclick="fetch('/poll')">poll</button>
+		<button id="img" onclick="document.body.appendChild(new Image()).src = '/img'">img</button>
+	</html>`))
+
+	page := s.page.Navigate(url)
+
+	wait := page.WaitRequestsIdleE(300*time.Millisecond, &rod.RequestIdleOptions{MaxInflight: 1})
+	page.Element("#poll").Click()
+	start := time.Now()
+	kit.E(wait())
+	s.Less(int64(time.Since(start)), int64(sleep))
+
+	wait = page.WaitRequestsIdleE(300*time.Millisecond, &rod.RequestIdleOptions{
+		ExcludeTypes: []proto.NetworkResourceType{proto.NetworkResourceTypeImage},
+	})
+	page.Element("#img").Click()
+	start = time.Now()
+	kit.E(wait())
+	s.Less(int64(time.Since(start)), int64(sleep))
+}
+
+func (s *S) TestPageOnEvent() {
+	p := s.browser.Page("")
+	defer p.Close()
+
+	var lock sync.Mutex
+	urls1, urls2 := []string{}, []string{}
+
+	e1 := &proto.PageFrameNavigated{}
+	cancel1 := p.OnEvent(e1, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		urls1 = append(urls1, e1.Frame.URL)
+	})
+	defer cancel1()
+
+	e2 := &proto.PageFrameNavigated{}
+	cancel2 := p.OnEvent(e2, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		urls2 = append(urls2, e2.Frame.URL)
+	})
+
+	p.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+	cancel2()
+	p.Navigate(srcFile("fixtures/input.html")).WaitLoad()
+	kit.Sleep(0.1)
+
+	lock.Lock()
+	defer lock.Unlock()
+	s.Len(urls1, 2)
+	s.Len(urls2, 1)
+	s.Contains(urls2[0], "click.html")
+}
+
+func (s *S) TestPageWaitResponse() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/api", func(ctx kit.GinContext) {
+		ctx.Status(201)
+		kit.E(ctx.Writer.WriteString(`{"a": 1}`))
+	})
+	engine.GET("/png", func(ctx kit.GinContext) {
+		ctx.Header("Content-Type", "image/png")
+		kit.E(ctx.Writer.Write([]byte{0x89, 0x50, 0x4e, 0x47}))
+	})
+	engine.GET("/", ginHTML(`<html>
+		<button id="api" onclick="fetch('/api')">api</button>
+		<button id="png" onclick="fetch('/png')">png</button>
+		<button id="fail" onclick="fetch('http://not-exists.localhost/fail')">fail</button>
+	</html>`))
+
+	p := s.page.Navigate(url)
+
+	wait := p.WaitResponse("/api$")
+	p.Element("#api").Click()
+	res, body := wait()
+	s.EqualValues(201, res.Status)
+	s.Equal(`{"a": 1}`, string(body))
+
+	wait = p.WaitResponse("/png$")
+	p.Element("#png").Click()
+	_, body = wait()
+	s.Equal([]byte{0x89, 0x50, 0x4e, 0x47}, body)
+
+	waitE := p.WaitResponseE("/fail$")
+	p.Element("#fail").Click()
+	_, _, err := waitE()
+	s.True(rod.IsError(err, rod.ErrRequestFailed))
+}
+
+func (s *S) TestPageConsole() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	msgs := make(chan *proto.RuntimeConsoleAPICalled, 10)
+	stop := p.EachConsole(func(e *proto.RuntimeConsoleAPICalled) {
+		msgs <- e
+	}, true)
+	defer stop()
+
+	wait := p.WaitConsole("done")
+	p.Eval(`() => {
+		console.log('a', 1, {b: [2]}, document.body)
+		setTimeout(() => { throw new Error('err') })
+		setTimeout(() => console.warn('done'), 100)
+	}`)
+	s.Equal("done", wait())
+
+	e := <-msgs
+	s.Equal(proto.RuntimeConsoleAPICalledTypeLog, e.Type)
+	s.Equal(`a 1 {"b":[2]} body`, rod.ConsoleText(e))
+
+	e = <-msgs
+	s.Equal(proto.RuntimeConsoleAPICalledTypeError, e.Type)
+	s.Contains(rod.ConsoleText(e), "Error: err")
+
+	e = <-msgs
+	s.Equal(proto.RuntimeConsoleAPICalledTypeWarning, e.Type)
+}
+
+func (s *S) TestPageHAR() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", func(ctx kit.GinContext) { ctx.Redirect(http.StatusFound, "/page?a=1") })
+	engine.GET("/page", ginHTML(`<p>ok</p><img src="/big">`))
+	engine.GET("/big", func(ctx kit.GinContext) {
+		ctx.Data(http.StatusOK, "application/octet-stream", bytes.Repeat([]byte("a"), 1000))
+	})
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	stop, err := p.StartHARLimitE(100)
+	kit.E(err)
+	p.Navigate(url + "/").WaitLoad()
+	har, err := stop()
+	kit.E(err)
+
+	data, err := json.Marshal(har)
+	kit.E(err)
+	s.Contains(string(data), `"version":"1.2"`)
+
+	entries := map[string]*rod.HAREntry{}
+	for _, e := range har.Log.Entries {
+		entries[e.Request.URL] = e
+	}
+
+	redirect := entries[url+"/"]
+	s.EqualValues(http.StatusFound, redirect.Response.Status)
+	s.Equal("/page?a=1", redirect.Response.RedirectURL[len(url):])
+
+	page := entries[url+"/page?a=1"]
+	s.EqualValues(http.StatusOK, page.Response.Status)
+	s.Equal("a", page.Request.QueryString[0].Name)
+	s.Contains(page.Response.Content.Text, "<p>ok</p>")
+	s.GreaterOrEqual(page.Time, float64(0))
+
+	big := entries[url+"/big"]
+	s.EqualValues(1000, big.Response.Content.Size)
+	s.Len(big.Response.Content.Text, 100)
+	s.Equal("truncated", big.Response.Content.Comment)
+}
+
+func (s *S) TestPageClipboard() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<button onclick="navigator.clipboard.writeText('複製 ok')">copy</button>`))
+
+	p := s.browser.Page(url)
+	defer p.Close()
+
+	p.SetClipboardText("äÖ雲")
+	s.Equal("äÖ雲", p.ClipboardText())
+
+	p.Element("button").Click()
+	kit.Sleep(0.1)
+	s.Equal("複製 ok", p.ClipboardText())
+}
+
+func (s *S) TestPageReload() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	p.Element("button").Click()
+	s.True(p.Has("[a=ok]"))
+
+	p.Reload()
+	s.False(p.Has("[a=ok]"))
+
+	wait := p.WaitNavigation(proto.PageLifecycleEventNameLoad)
+	kit.E(p.ReloadE(true, `window.reloaded = true`))
+	wait()
+	s.True(p.Eval(`() => window.reloaded`).Bool())
+}
+
+func (s *S) TestPageReloadIframe() {
+	p := s.page.Navigate(srcFile("fixtures/click-iframe.html"))
+	frame := p.Element("iframe").Frame()
+
+	p.Eval(`() => window.top_flag = true`)
+	frame.Element("button").Click()
+	s.True(frame.Has("[a=ok]"))
+
+	kit.E(frame.ReloadAndWaitLoadE())
+	s.False(frame.Has("[a=ok]"))
+	s.True(p.Eval(`() => window.top_flag`).Bool())
+}
+
+func (s *S) TestPageEmulateNetwork() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<p>ok</p>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	p.SetOffline(true)
+	s.Error(p.NavigateE(url))
+
+	p.SetOffline(false)
+	p.EmulateNetwork(&rod.NetworkConditions{Latency: 300 * time.Millisecond})
+
+	start := time.Now()
+	p.Navigate(url)
+	s.Greater(int64(time.Since(start)), int64(300*time.Millisecond))
+
+	p.EmulateNetwork(nil).Navigate(url)
+	s.Equal("ok", p.Element("p").Text())
+}
+
+func (s *S) TestPageErrorTypes() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	err := p.NavigateE("http://not-exists.not-exists")
+	s.True(errors.Is(err, rod.ErrNavigation))
+	s.True(rod.IsError(err, rod.ErrNavigation))
+
+	_, err = p.EvalE(true, "", `() => { throw new Error("err") }`, nil)
+	s.True(errors.Is(err, rod.ErrEval))
+	s.False(errors.Is(err, rod.ErrNavigation))
+
+	_, err = proto.DOMDescribeNode{ObjectID: "not-exists"}.Call(p)
+	var callErr *rod.CallError
+	s.True(errors.As(err, &callErr))
+	s.Equal("DOM.describeNode", callErr.Method)
+	s.Equal(p.TargetID, callErr.TargetID)
+
+	var cdpErr *cdp.Error
+	s.True(errors.As(err, &cdpErr))
+}
+
+func (s *S) TestPageWait() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	go func() {
+		kit.Sleep(0.1)
+		p.Eval(`() => window.waitFlag = "done"`)
+	}()
+	p.Wait(`() => window.waitFlag`)
+
+	err := p.WaitE(nil, "", `() => { throw new Error("err") }`, nil)
+	s.True(rod.IsError(err, rod.ErrEval))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err = p.Context(ctx).WaitE(nil, "", `() => false`, nil)
+	s.True(rod.IsError(err, rod.ErrWaitTimeout))
+	s.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func (s *S) TestPageTracing() {
+	p := s.browser.Page("")
+	defer p.Close()
+
+	p.StartTracing("devtools.timeline")
+	s.True(rod.IsError(p.StartTracingE(nil), rod.ErrTracingStarted))
+
+	p.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+
+	var trace struct {
+		TraceEvents []map[string]interface{} `json:"traceEvents"`
+	}
+	kit.E(json.Unmarshal(p.StopTracing(), &trace))
+	s.NotEmpty(trace.TraceEvents)
+
+	_, err := p.StopTracingE()
+	s.True(rod.IsError(err, rod.ErrTracingNotStarted))
+}
+
+func (s *S) TestPageMetrics() {
+	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
 
-	engine.GET("/r1", func(ctx kit.GinContext) {})
-	engine.GET("/r2", func(ctx kit.GinContext) { time.Sleep(sleep) })
-	engine.GET("/", ginHTML(`<html>
-		<button>click</button>
-		<script>
-			document.querySelector("button").onclick = () => {
-				fetch('/r1')
-				fetch('/r2').then(r => r.text())
-			}
-		</script>
-	</html>`))
+	m := p.Metrics()
+	s.Greater(m["JSHeapUsedSize"], float64(0))
+	s.Contains(m, "LayoutCount")
+}
 
-	page := s.page.Navigate(url)
+func (s *S) TestPageWaitNavigation() {
+	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
 
-	wait := page.WaitRequestIdle("/r1")
-	page.Element("button").Click()
-	start := time.Now()
+	wait := p.WaitNavigation(proto.PageLifecycleEventNameNetworkAlmostIdle)
+	p.Eval(`url => location.href = url`, srcFile("fixtures/input.html"))
 	wait()
-	s.Greater(int64(time.Since(start)), int64(sleep))
 
-	wait = page.WaitRequestIdle("/r2")
-	page.Element("button").Click()
-	start = time.Now()
-	wait()
-	s.Less(int64(time.Since(start)), int64(sleep))
+	s.True(p.Has("textarea"))
 
-	s.Panics(func() {
-		wait()
-	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Error(p.Context(ctx).WaitNavigationE(proto.PageLifecycleEventNameLoad)())
 }
 
 func (s *S) TestPageWaitIdle() {
@@ -227,6 +1735,11 @@ func (s *S) TestPageWaitIdle() {
 	s.True(p.Has("[a=ok]"))
 }
 
+func (s *S) TestPageWaitDOMStable() {
+	p := s.page.Navigate(srcFile("fixtures/dom-stable.html")).WaitDOMStable(200*time.Millisecond, "#ad")
+	s.Len(p.Elements("li"), 10)
+}
+
 func (s *S) TestPageWaitEvent() {
 	wait := s.page.WaitEvent()
 	s.page.Navigate(srcFile("fixtures/click.html"))
@@ -243,6 +1756,44 @@ func (s *S) TestAlert() {
 	wait()
 }
 
+func (s *S) TestPageEachDialog() {
+	page := s.page.Navigate(srcFile("fixtures/alert.html"))
+
+	var lock sync.Mutex
+	types := []proto.PageDialogType{}
+	stop := page.EachDialog(func(e *proto.PageJavascriptDialogOpening) (bool, string) {
+		lock.Lock()
+		defer lock.Unlock()
+		types = append(types, e.Type)
+		return true, ""
+	})
+
+	page.Element("button").Click()
+	page.Element("button").Click()
+	stop()
+
+	lock.Lock()
+	defer lock.Unlock()
+	s.Equal([]proto.PageDialogType{proto.PageDialogTypeAlert, proto.PageDialogTypeAlert}, types)
+}
+
+func (s *S) TestPageDialogPending() {
+	page := s.browser.Page(srcFile("fixtures/alert.html"))
+
+	wait := page.WaitEvent()
+	go page.Element("button").Click()
+	wait(&proto.PageJavascriptDialogOpening{})
+
+	err := page.NavigateE(srcFile("fixtures/click.html"))
+	s.True(errors.Is(err, rod.ErrDialogPending))
+	s.True(errors.Is(page.CloseE(), rod.ErrDialogPending))
+
+	// the pending dialog will be answered
+	page.AutoHandleDialogs(true, "")
+	page.Navigate(srcFile("fixtures/click.html"))
+	page.Close()
+}
+
 func (s *S) TestDownloadFile() {
 	url, engine, close := serve()
 	defer close()
@@ -265,6 +1816,218 @@ func (s *S) TestDownloadFile() {
 	s.Equal(content, string(data))
 }
 
+func (s *S) TestDownloadFilePost() {
+	url, engine, close := serve()
+	defer close()
+
+	content := "id,name\r\n1,\"a, b\"\r\n2,\xe4\xb8\xad\r\n"
+
+	engine.POST("/d", func(ctx kit.GinContext) {
+		if c, _ := ctx.Cookie("a"); c != "b" || ctx.PostForm("k") != "v" {
+			ctx.Status(403)
+			return
+		}
+		ctx.Redirect(303, "/file.csv?k="+ctx.PostForm("k"))
+	})
+	engine.GET("/file.csv", func(ctx kit.GinContext) {
+		if c, _ := ctx.Cookie("a"); c != "b" || ctx.Query("k") != "v" {
+			ctx.Status(403)
+			return
+		}
+		ctx.Header("Content-Type", "text/csv")
+		kit.E(ctx.Writer.Write([]byte(content)))
+	})
+	engine.GET("/", ginHTML(`<html>
+		<form method="post" action="/d"><input name="k" value="v"><button>download</button></form>
+	</html>`))
+
+	page := s.page.Navigate(url)
+	page.SetCookies(&proto.NetworkCookieParam{Name: "a", Value: "b", URL: url})
+
+	wait := page.GetDownloadFile(url + "/d")
+	page.Element("button").Click()
+	header, data := wait()
+
+	s.Equal(content, string(data))
+	s.Equal("text/csv", header.Get("Content-Type"))
+
+	// the page isn't navigated to the csv
+	s.Equal(url+"/", page.Eval(`() => location.href`).String())
+}
+
+func (s *S) TestPageWaitDownload() {
+	url, engine, close := serve()
+	defer close()
+
+	content := "test content"
+
+	engine.POST("/d", func(ctx kit.GinContext) {
+		if c, _ := ctx.Cookie("a"); c != "b" || ctx.PostForm("k") != "v" {
+			ctx.Status(403)
+			return
+		}
+		ctx.Header("Content-Disposition", "attachment; filename=file.txt")
+		kit.E(ctx.Writer.Write([]byte(content)))
+	})
+	engine.GET("/", ginHTML(`<html>
+		<form method="post" action="/d"><input name="k" value="v"><button>download</button></form>
+	</html>`))
+
+	p := s.browser.Page(url)
+	defer p.Close()
+	p.Eval(`() => document.cookie = 'a=b'`)
+
+	// the downloads of the same filename won't overwrite each other
+	paths := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		wait := p.WaitDownload()
+		p.Element("button").Click()
+		path := wait()
+		paths[path] = true
+
+		data, err := ioutil.ReadFile(path)
+		kit.E(err)
+		s.Equal(content, string(data))
+	}
+	s.Len(paths, 2)
+}
+
+func (s *S) TestPageHandleAuth() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", gin.BasicAuth(gin.Accounts{"a": "b"}), ginHTML(`<p>ok</p>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	// the helpers registered after it still get the requests
+	hijacked := make(chan string, 1)
+	wait := p.HandleAuth("a", "b")
+	stop := p.HijackRequests("*", func(ctx *rod.HijackContext) error {
+		select {
+		case hijacked <- ctx.URL():
+		default:
+		}
+		return nil
+	})
+	p.Navigate(url)
+	wait()
+	stop()
+	s.Equal("ok", p.Element("p").Text())
+	s.Equal(url+"/", <-hijacked)
+
+	// use another context, so that the credentials above won't be reused
+	b := s.browser.NewContext()
+	defer b.CloseContext()
+	p = b.Page("")
+
+	waitErr := p.HandleAuthE("a", "wrong")
+	_ = p.NavigateE(url)
+	s.True(rod.IsError(waitErr(), rod.ErrAuthFailed))
+}
+
+func (s *S) TestPageSetProxy() {
+	proxy, engine, close := serve()
+	defer close()
+
+	engine.GET("/", func(ctx kit.GinContext) {
+		// base64 of "a:b"
+		if !ctx.Request.URL.IsAbs() || ctx.GetHeader("Proxy-Authorization") != "Basic YTpi" {
+			ctx.Writer.WriteHeader(407)
+			return
+		}
+		ginHTML(`<html><body>` + ctx.Request.Host + `</body></html>`)(ctx)
+	})
+
+	url, origin, closeOrigin := serve()
+	defer closeOrigin()
+	origin.GET("/", ginHTML(`<html><body>origin</body></html>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	p.SetProxy(proxy, &rod.ProxyAuth{Username: "a", Password: "b"})
+	p.Navigate("http://rod.test/")
+	s.Equal("rod.test", p.Element("body").Text())
+
+	// the hijack registered after the proxy still gets its requests
+	stop := p.HijackRequests("*/hijacked", func(ctx *rod.HijackContext) error {
+		return ctx.FulfillRequest(&proto.FetchFulfillRequest{
+			ResponseHeaders: []*proto.FetchHeaderEntry{{Name: "Content-Type", Value: "text/html"}},
+			Body:            []byte(`<html><body>hijacked</body></html>`),
+		})
+	})
+	p.Navigate("http://rod.test/hijacked")
+	s.Equal("hijacked", p.Element("body").Text())
+	stop()
+
+	p.SetProxy("", nil)
+	p.Navigate(url)
+	s.Equal("origin", p.Element("body").Text())
+}
+
+func (s *S) TestHijackRequests() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/api", func(ctx kit.GinContext) {
+		kit.E(ctx.Writer.WriteString(`{"from": "server"}`))
+	})
+	engine.GET("/analytics", func(ctx kit.GinContext) {
+		panic("should be blocked")
+	})
+	engine.GET("/frame", ginHTML(`<html><script>fetch('/analytics')</script></html>`))
+	engine.GET("/", ginHTML(`<html>
+		<iframe src="/frame"></iframe>
+		<script>
+			fetch('/api').then(r => r.text()).then(t => document.body.setAttribute('data', t))
+		</script>
+	</html>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	blocked := make(chan string, 1)
+
+	// the more specific patterns below own their requests, even though the catch-all is registered first
+	var lock sync.Mutex
+	others := []string{}
+	stopAll := p.HijackRequests("*", func(ctx *rod.HijackContext) error {
+		lock.Lock()
+		defer lock.Unlock()
+		others = append(others, ctx.URL())
+		return nil
+	})
+	defer stopAll()
+
+	stopAPI := p.HijackRequests("*/api", func(ctx *rod.HijackContext) error {
+		return ctx.FulfillRequest(&proto.FetchFulfillRequest{
+			ResponseHeaders: []*proto.FetchHeaderEntry{
+				{Name: "Content-Type", Value: "application/json"},
+			},
+			Body: []byte(`{"from": "rod"}`),
+		})
+	})
+	defer stopAPI()
+
+	stopAnalytics := p.HijackRequests("*/analytics", func(ctx *rod.HijackContext) error {
+		blocked <- ctx.Method() + " " + ctx.URL()
+		return ctx.FailRequest(proto.NetworkErrorReasonBlockedByClient)
+	})
+	defer stopAnalytics()
+
+	p.Navigate(url)
+
+	s.Equal("GET "+url+"/analytics", <-blocked)
+	s.Equal(`{"from": "rod"}`, p.Element(`[data]`).Eval(`() => this.getAttribute('data')`).String())
+
+	lock.Lock()
+	defer lock.Unlock()
+	s.Contains(others, url+"/")
+	s.NotContains(others, url+"/api")
+}
+
 func (s *S) TestMouse() {
 	page := s.page.Navigate(srcFile("fixtures/click.html"))
 	page.Element("button")
@@ -316,26 +2079,49 @@ func (s *S) TestMouseDrag() {
 }
 
 func (s *S) TestNativeDrag() {
-	s.T().Skip("not able to use mouse event to simulate it for now")
-
 	page := s.page.Navigate(srcFile("fixtures/drag.html"))
-	mouse := page.Mouse
 
-	box := page.Element("#draggable").Box()
-	x := box.Left + 3
-	y := box.Top + 3
-	toY := page.Element(".dropzone:nth-child(2)").Box().Top + 3
+	page.Element("#draggable").DragTo(page.Element(".dropzone:nth-child(2)"), 5)
 
-	page.Overlay(x, y, 10, 10, "from")
-	page.Overlay(x, toY, 10, 10, "to")
+	page.Element(".dropzone:nth-child(2) #draggable")
+}
 
-	mouse.Move(x, y)
-	mouse.Down("left")
-	kit.E(mouse.MoveE(x, toY, 5))
-	page.Screenshot("")
-	mouse.Up("left")
+func (s *S) TestMouseDragNoNativeDrag() {
+	page := s.page.Navigate(srcFile("fixtures/click.html"))
 
-	page.Element(".dropzone:nth-child(2) #draggable")
+	page.Eval(`() => document.onmouseup = (e) => document.body.dataset.up = e.clientX + ',' + e.clientY`)
+	page.Mouse.Drag(3, 3, 60, 80, 3)
+
+	s.Equal("60,80", page.Eval(`() => document.body.dataset.up`).String())
+}
+
+func (s *S) TestTouch() {
+	page := s.browser.Page(srcFile("fixtures/touch.html"))
+	defer page.Close()
+
+	logs := func() []string {
+		list := []string{}
+		for _, l := range page.Eval(`() => window.touchLogs.splice(0)`).Array() {
+			list = append(list, l.String())
+		}
+		return list
+	}
+
+	page.Element("button").Tap()
+	s.True(page.Has("[a=ok]"))
+	logs()
+
+	page.Touch.Tap(10, 20)
+	s.Equal([]string{"touchstart 1:10,20", "touchend "}, logs())
+
+	page.Touch.Swipe(10, 10, 30, 50, 2)
+	s.Equal([]string{"touchstart 1:10,10", "touchmove 1:20,30", "touchmove 1:30,50", "touchend "}, logs())
+
+	page.Touch.Pinch(200, 200, 2)
+	list := logs()
+	s.Contains(list, "touchstart 1:150,200 2:250,200")
+	s.Contains(list, "touchmove 1:100,200 2:300,200")
+	s.Equal("touchend ", list[len(list)-1])
 }
 
 func (s *S) TestPagePause() {
@@ -383,8 +2169,51 @@ func (s *S) TestScreenshotFullPageInit() {
 	p := s.browser.Page(srcFile("fixtures/scroll.html"))
 	defer p.Close()
 
+	size := p.Eval(`() => ({w: innerWidth, h: innerHeight})`)
+
 	// should not panic
 	p.ScreenshotFullPage()
+
+	// the override should be cleared
+	s.Equal(size.Raw, p.Eval(`() => ({w: innerWidth, h: innerHeight})`).Raw)
+}
+
+func (s *S) TestScreenshotFullPageTall() {
+	p := s.browser.Page(srcFile("fixtures/click.html"))
+	defer p.Close()
+
+	p.Eval(`() => {
+		document.body.style.margin = 0
+		document.body.innerHTML = '<div style="height: 20000px; background: linear-gradient(red, blue)"></div>'
+	}`)
+
+	img, err := png.Decode(bytes.NewBuffer(p.ScreenshotFullPage()))
+	kit.E(err)
+	s.Equal(20000, img.Bounds().Dy())
+
+	s.EqualValues(0, p.Eval(`() => scrollY`).Int())
+}
+
+func (s *S) TestScreenshotArea() {
+	p := s.page.Navigate(srcFile("fixtures/scroll.html"))
+	p.Element("button")
+
+	data := p.ScreenshotArea(10, 20, 100, 50)
+	img, err := png.Decode(bytes.NewBuffer(data))
+	kit.E(err)
+	s.Equal(100, img.Bounds().Dx())
+	s.Equal(50, img.Bounds().Dy())
+
+	// the area outside of the viewport
+	data = p.ScreenshotArea(0, 1000, 100, 50)
+	img, err = png.Decode(bytes.NewBuffer(data))
+	kit.E(err)
+	s.Equal(100, img.Bounds().Dx())
+	s.Equal(50, img.Bounds().Dy())
+
+	res := p.Eval(`() => ({w: innerWidth, h: innerHeight})`)
+	s.EqualValues(800, res.Get("w").Int())
+	s.EqualValues(600, res.Get("h").Int())
 }
 
 func (s *S) TestPageInput() {
@@ -452,6 +2281,71 @@ func (s *S) TestFonts() {
 	kit.E(kit.OutputFile("tmp/fonts.pdf", p.PDF(), nil))
 }
 
+func (s *S) TestPagePDFStream() {
+	p := s.page.Navigate(srcFile("fixtures/click.html")).WaitLoad()
+
+	r := p.PDFStream()
+	defer func() { kit.E(r.Close()) }()
+
+	// read with a tiny buffer to cross the boundaries of the chunks
+	buf := &bytes.Buffer{}
+	_, err := io.CopyBuffer(buf, struct{ io.Reader }{r}, make([]byte, 3))
+	kit.E(err)
+	s.True(bytes.HasPrefix(buf.Bytes(), []byte("%PDF")))
+
+	r = p.PDFStream()
+	kit.E(r.Close())
+	kit.E(r.Close())
+
+	img, err := p.ScreenshotStreamE(false, &proto.PageCaptureScreenshot{})
+	kit.E(err)
+	_, err = png.Decode(img)
+	kit.E(err)
+	kit.E(img.Close())
+}
+
+func (s *S) TestPagePDFToWriter() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+
+	buf := bytes.NewBuffer(nil)
+	p.PDFToWriter(buf, &rod.PDFOptions{
+		PageRanges:     "1",
+		Landscape:      true,
+		Margins:        &rod.PDFMargins{},
+		FooterTemplate: `<span class="pageNumber"></span>/<span class="totalPages"></span>`,
+	})
+	s.True(bytes.HasPrefix(buf.Bytes(), []byte("%PDF")))
+
+	for _, ranges := range []string{"3-1", "0", "a", "1-2-3", "1,,2"} {
+		err := p.PDFToWriterE(buf, &rod.PDFOptions{PageRanges: ranges})
+		s.True(errors.Is(err, rod.ErrInvalidPageRanges), ranges)
+	}
+}
+
+func (s *S) TestPageHistory() {
+	p := s.browser.Page(srcFile("fixtures/click.html")).WaitLoad()
+	defer p.Close()
+
+	p.Navigate(srcFile("fixtures/input.html")).WaitLoad()
+
+	index, entries, err := p.HistoryE()
+	kit.E(err)
+	s.Contains(entries[index].URL, "input.html")
+
+	p.NavigateBack().WaitLoad()
+	s.True(p.Has("button"))
+
+	prev, _, err := p.HistoryE()
+	kit.E(err)
+	s.Equal(index-1, prev)
+
+	p.NavigateForward().WaitLoad()
+	s.True(p.Has("textarea"))
+
+	err = p.NavigateForwardE()
+	s.True(rod.IsError(err, rod.ErrNoHistoryEntry))
+}
+
 func (s *S) TestNavigateErr() {
 	// dns error
 	s.Panics(func() {
@@ -494,3 +2388,232 @@ func (s *S) TestPageErrors() {
 	err = p.Context(ctx).PauseE()
 	s.Error(err)
 }
+
+func (s *S) TestPageScrollToBottom() {
+	p := s.page.Navigate(srcFile("fixtures/infinite-scroll.html"))
+
+	p.ScrollToBottom()
+
+	s.Equal("5", p.Eval(`() => document.querySelectorAll('.item').length`).String())
+
+	el := p.Element(".item")
+	el.ScrollIntoViewSmooth()
+	s.True(p.Eval(`() => window.scrollY < 10`).Bool())
+}
+
+func (s *S) TestPageLayoutAndScroll() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	defer p.Viewport(800, 600, 1, false)
+
+	p.Viewport(400, 300, 2, false)
+	p.Eval(`() => {
+		document.body.style.width = '2000px'
+		document.body.style.height = '3000px'
+		document.documentElement.style.scrollBehavior = 'smooth'
+	}`)
+
+	layout := p.Layout()
+	s.EqualValues(400, layout.ViewportWidth)
+	s.EqualValues(300, layout.ViewportHeight)
+	s.EqualValues(2, layout.DevicePixelRatio)
+	s.True(layout.ContentWidth >= 2000)
+	s.True(layout.ContentHeight >= 3000)
+
+	p.ScrollTo(100, 200)
+	layout = p.Layout()
+	s.EqualValues(100, layout.ScrollX)
+	s.EqualValues(200, layout.ScrollY)
+
+	p.ScrollBy(10, -50)
+	layout = p.Layout()
+	s.EqualValues(110, layout.ScrollX)
+	s.EqualValues(150, layout.ScrollY)
+
+	// clamped to the scrollable area
+	p.ScrollTo(0, 1e6)
+	layout = p.Layout()
+	s.InDelta(layout.ContentHeight-layout.ViewportHeight, layout.ScrollY, 1)
+}
+
+func (s *S) TestPageWebSocket() {
+	url, engine, close := serve()
+	defer close()
+
+	upgrader := websocket.Upgrader{}
+	engine.GET("/ws", func(ctx kit.GinContext) {
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		kit.E(err)
+		defer func() { _ = conn.Close() }()
+
+		_, msg, err := conn.ReadMessage()
+		kit.E(err)
+		kit.E(conn.WriteMessage(websocket.BinaryMessage, []byte{0, 1, 2}))
+		kit.E(conn.WriteMessage(websocket.TextMessage, append([]byte("echo "), msg...)))
+		_, _, _ = conn.ReadMessage()
+	})
+	engine.GET("/", ginHTML(`<script>
+		const ws = new WebSocket(location.href.replace('http', 'ws') + 'ws')
+		ws.onopen = () => ws.send('hello')
+		ws.onmessage = (e) => { if (typeof e.data === 'string') ws.close() }
+	</script>`))
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	frames := make(chan rod.WebSocketFrame, 10)
+	stop := p.EachWebSocket(func(f rod.WebSocketFrame) {
+		frames <- f
+	})
+	defer stop()
+
+	wait := p.WaitWebSocketMessage("*/ws", "echo")
+	p.Navigate(url)
+	s.Equal("echo hello", wait())
+
+	f := <-frames
+	s.Equal(rod.WebSocketCreated, f.Direction)
+	s.Equal(strings.Replace(url, "http", "ws", 1)+"/ws", f.URL)
+
+	f = <-frames
+	s.Equal(rod.WebSocketSent, f.Direction)
+	s.Equal(1, f.Opcode)
+	s.Equal("hello", string(f.Payload))
+
+	f = <-frames
+	s.Equal(rod.WebSocketReceived, f.Direction)
+	s.Equal(2, f.Opcode)
+	s.Equal([]byte{0, 1, 2}, f.Payload)
+
+	f = <-frames
+	s.Equal(rod.WebSocketReceived, f.Direction)
+	s.Equal("echo hello", string(f.Payload))
+
+	f = <-frames
+	s.Equal(rod.WebSocketClosed, f.Direction)
+	s.Contains(f.URL, "/ws")
+}
+
+func (s *S) TestPageAccessibility() {
+	p := s.page.Navigate(srcFile("fixtures/accessibility.html"))
+
+	root := p.AccessibilitySnapshot()
+	s.Equal("RootWebArea", root.Role)
+	s.NotEmpty(root.Children)
+
+	var walk func(n *rod.AXNode)
+	walk = func(n *rod.AXNode) {
+		s.False(n.Ignored)
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	s.Equal("btn", p.ElementByRole("button", "Submit").Eval(`() => this.id`).String())
+	s.Equal("ok", p.ElementByRole("checkbox", "Accept").Eval(`() => this.value`).String())
+
+	_, err := p.ElementByRoleE("button", "not exists")
+	s.True(errors.Is(err, rod.ErrAXNodeNotFound))
+
+	_, err = (&rod.AXNode{AccessibilityAXNode: &proto.AccessibilityAXNode{}}).ElementE()
+	s.True(errors.Is(err, rod.ErrAXVirtualNode))
+}
+
+func (s *S) TestPageTraceLog() {
+	buf := &bytes.Buffer{}
+	s.browser.TraceLog(buf)
+	defer s.browser.TraceLog(nil)
+
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	p.Eval(`() => 1`)
+	p.Element("button").Click()
+
+	records := []rod.TraceRecord{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		r := rod.TraceRecord{}
+		kit.E(json.Unmarshal([]byte(line), &r))
+		s.Equal(p.TargetID, r.TargetID)
+		records = append(records, r)
+	}
+
+	msgs := []string{}
+	for _, r := range records {
+		msgs = append(msgs, r.Msg)
+	}
+	s.Contains(msgs[0], "navigate ")
+	s.Contains(msgs, "eval () =&gt; 1")
+	s.Contains(msgs, "left click")
+	s.Contains(msgs, "click left")
+}
+
+func (s *S) TestPageSetDocumentContent() {
+	p := s.page.NavigateBlank()
+
+	p.SetDocumentContent(`<p class="a">it's "quoted"</p>`)
+	s.Equal(`it's "quoted"`, p.Element(".a").Text())
+	s.Equal("about:blank", p.Eval(`() => location.href`).String())
+
+	p.SetDocumentContent(`<iframe srcdoc="<p>frame</p>"></iframe>`)
+	frame := p.Element("iframe").Frame()
+	frame.SetDocumentContent(`<p class="b">replaced</p>`)
+	s.Equal("replaced", frame.Element(".b").Text())
+	s.False(p.Has(".b"))
+}
+
+func (s *S) TestPageCoverage() {
+	url, engine, close := serve()
+	defer close()
+
+	engine.GET("/", ginHTML(`<html>
+		<link rel="stylesheet" href="/a.css">
+		<script src="/a.js"></script>
+		<p class="used">ok</p>
+	</html>`))
+	engine.GET("/a.js", func(ctx kit.GinContext) {
+		ctx.Data(http.StatusOK, "text/javascript", []byte(`function used() {}
+			function unused() {}
+			debugger
+			used()`))
+	})
+	engine.GET("/a.css", func(ctx kit.GinContext) {
+		ctx.Data(http.StatusOK, "text/css", []byte(`.used { color: red } .unused { color: blue }`))
+	})
+
+	p := s.browser.Page("")
+	defer p.Close()
+
+	p.StartJSCoverage(&rod.JSCoverageOptions{CallCount: true, Detailed: true})
+	p.StartCSSCoverage(false)
+
+	s.True(errors.Is(p.StartCSSCoverageE(false), rod.ErrCoverageStarted))
+
+	// the debugger statement won't pause the page
+	p.Navigate(url).WaitLoad()
+	s.Equal("ok", p.Element(".used").Text())
+
+	js := p.StopJSCoverage()
+	s.Len(js, 1)
+	s.Equal(url+"/a.js", js[0].URL)
+	s.Contains(js[0].Source, "function unused")
+
+	counts := map[string]int64{}
+	for _, fn := range js[0].Functions {
+		counts[fn.FunctionName] = fn.Ranges[0].Count
+	}
+	s.EqualValues(1, counts["used"])
+	s.EqualValues(0, counts["unused"])
+
+	css := p.StopCSSCoverage()
+	s.Len(css, 1)
+	s.Equal(url+"/a.css", css[0].URL)
+
+	used := map[string]bool{}
+	for _, r := range css[0].Rules {
+		used[css[0].Source[int(r.StartOffset):int(r.EndOffset)]] = r.Used
+	}
+	s.True(used[".used { color: red }"])
+	s.False(used[".unused { color: blue }"])
+
+	_, err := p.StopCSSCoverageE()
+	s.True(errors.Is(err, rod.ErrCoverageNotStarted))
+}
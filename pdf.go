@@ -0,0 +1,139 @@
+// This file contains the pdf related code of the page.
+
+package rod
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// PDFOptions of the PDFToWriterE, the zero values mean the defaults of the browser
+type PDFOptions struct {
+	// PageRanges to print, such as "1-3,5", empty means all the pages
+	PageRanges string
+
+	Landscape       bool
+	PrintBackground bool
+
+	// Scale of the rendering, the browser requires it to be between 0.1 and 2, 0 means 1
+	Scale float64
+
+	// PaperWidth and PaperHeight in inches, 0 means the letter size
+	PaperWidth  float64
+	PaperHeight float64
+
+	// Margins in inches, nil means the default margins of the browser, which are about 0.4 inches
+	Margins *PDFMargins
+
+	// HeaderTemplate and FooterTemplate are the html of the header and footer, the elements with the classes
+	// "date", "title", "url", "pageNumber", and "totalPages" will be filled with the values, such as
+	// <span class="pageNumber"></span>/<span class="totalPages"></span>.
+	// If either of them is set, the header and footer will be displayed, the empty one will be blank.
+	HeaderTemplate string
+	FooterTemplate string
+
+	// PreferCSSPageSize uses the page size defined by the css @page of the page
+	PreferCSSPageSize bool
+}
+
+// PDFMargins in inches
+type PDFMargins struct {
+	Top    float64
+	Bottom float64
+	Left   float64
+	Right  float64
+}
+
+// PDFToWriterE prints the page as PDF into the w, the data is read from the browser chunk by chunk,
+// so the whole document doesn't need to fit in memory. If opts is nil the defaults of the browser will be used.
+// If the PageRanges is invalid, an ErrInvalidPageRanges error will be returned before the page is printed.
+func (p *Page) PDFToWriterE(w io.Writer, opts *PDFOptions) error {
+	if opts == nil {
+		opts = &PDFOptions{}
+	}
+
+	err := validatePageRanges(opts.PageRanges)
+	if err != nil {
+		return err
+	}
+
+	req := proto.PagePrintToPDF{
+		PageRanges:        opts.PageRanges,
+		Landscape:         opts.Landscape,
+		PrintBackground:   opts.PrintBackground,
+		Scale:             opts.Scale,
+		PaperWidth:        opts.PaperWidth,
+		PaperHeight:       opts.PaperHeight,
+		PreferCSSPageSize: opts.PreferCSSPageSize,
+		TransferMode:      proto.PagePrintToPDFTransferModeReturnAsStream,
+	}
+
+	if opts.HeaderTemplate != "" || opts.FooterTemplate != "" {
+		req.DisplayHeaderFooter = true
+
+		// the browser uses its default template for the empty one
+		req.HeaderTemplate, req.FooterTemplate = "<span></span>", "<span></span>"
+		if opts.HeaderTemplate != "" {
+			req.HeaderTemplate = opts.HeaderTemplate
+		}
+		if opts.FooterTemplate != "" {
+			req.FooterTemplate = opts.FooterTemplate
+		}
+	}
+
+	var res *proto.PagePrintToPDFResult
+	if m := opts.Margins; m != nil {
+		res, err = proto.PagePrintToPDFWithMargins{
+			PagePrintToPDF: req,
+			MarginTop:      m.Top,
+			MarginBottom:   m.Bottom,
+			MarginLeft:     m.Left,
+			MarginRight:    m.Right,
+		}.Call(p)
+	} else {
+		res, err = req.Call(p)
+	}
+	if err != nil {
+		return err
+	}
+
+	r := newStreamReader(p, res.Stream)
+	defer func() { _ = r.Close() }()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// validatePageRanges checks the format of the ranges, such as "1-3, 5", the pages start from 1
+func validatePageRanges(ranges string) error {
+	if strings.TrimSpace(ranges) == "" {
+		return nil
+	}
+
+	invalid := &Error{nil, ErrInvalidPageRanges, ranges}
+
+	for _, part := range strings.Split(ranges, ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return invalid
+		}
+
+		list := []int{}
+		for _, b := range bounds {
+			n, err := strconv.Atoi(strings.TrimSpace(b))
+			if err != nil || n < 1 {
+				return invalid
+			}
+			list = append(list, n)
+		}
+
+		if len(list) == 2 && list[0] > list[1] {
+			return invalid
+		}
+	}
+
+	return nil
+}
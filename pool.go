@@ -0,0 +1,264 @@
+// This file contains the page pool of the browser.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// PagePool reuses the pages of the browser, so that the jobs won't pay the cost of creating and initializing
+// a page each time. The pages are reset when they are put back, the crashed or closed ones will be discarded,
+// the pool will create new pages instead of them.
+type PagePool struct {
+	browser *Browser
+	idle    chan *pooledPage
+	slots   chan kit.Nil // each page created by the pool takes a slot
+
+	lock   sync.Mutex
+	used   map[proto.TargetTargetID]*pooledPage // the pages that are lent out
+	closed chan kit.Nil                         // closed by the CloseE
+}
+
+type pooledPage struct {
+	page   *Page  // the page created by the pool, the callers get the clones of it
+	cancel func() // cancels the clone that is lent out
+	dead   chan kit.Nil
+}
+
+// PagePool creates a pool that holds at most size pages, the pages are created when they are needed.
+// The pages will be created in the browser context of b.
+func (b *Browser) PagePool(size int) *PagePool {
+	return &PagePool{
+		browser: b,
+		idle:    make(chan *pooledPage, size),
+		slots:   make(chan kit.Nil, size),
+		used:    map[proto.TargetTargetID]*pooledPage{},
+		closed:  make(chan kit.Nil),
+	}
+}
+
+// GetE doc is similar to the method Get
+func (pp *PagePool) GetE(ctx context.Context) (*Page, error) {
+	for {
+		var item *pooledPage
+
+		if pp.isClosed() {
+			return nil, &Error{nil, ErrPoolClosed, nil}
+		}
+
+		// prefer the idle pages to creating new ones
+		select {
+		case item = <-pp.idle:
+		default:
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-pp.closed:
+				return nil, &Error{nil, ErrPoolClosed, nil}
+			case item = <-pp.idle:
+			case pp.slots <- kit.Nil{}:
+				var err error
+				item, err = pp.create()
+				if err != nil {
+					<-pp.slots
+					return nil, err
+				}
+			}
+		}
+
+		if item.isDead() {
+			_ = pp.discard(item)
+			continue
+		}
+
+		page := item.page.Context(ctx)
+		item.cancel = page.ctxCancel
+
+		pp.lock.Lock()
+		if pp.isClosed() {
+			pp.lock.Unlock()
+			page.ctxCancel()
+			_ = pp.discard(item)
+			return nil, &Error{nil, ErrPoolClosed, nil}
+		}
+		pp.used[page.TargetID] = item
+		pp.lock.Unlock()
+
+		return page, nil
+	}
+}
+
+// Put the page back to the pool, its clones must not be used after it. The context of the clone will be cancelled,
+// so the waits and the subscriptions that are bound to it will stop, then the page will be navigated to "about:blank"
+// and its cookies, viewport, extra headers, user agent, proxy, and request interceptions will be reset.
+// If the reset fails or the pool is closed, the page will be discarded.
+func (pp *PagePool) Put(page *Page) {
+	pp.lock.Lock()
+	item, has := pp.used[page.TargetID]
+	delete(pp.used, page.TargetID)
+	pp.lock.Unlock()
+
+	if !has {
+		return
+	}
+
+	item.cancel()
+
+	if pp.isClosed() || item.isDead() || pp.reset(item.page) != nil {
+		_ = pp.discard(item)
+		return
+	}
+
+	// check it again under the lock, so that the CloseE won't miss the page
+	pp.lock.Lock()
+	defer pp.lock.Unlock()
+	if pp.isClosed() {
+		_ = pp.discard(item)
+		return
+	}
+	pp.idle <- item
+}
+
+// CloseE closes the idle pages of the pool, the pages that are lent out will be closed when they are put back.
+// The GetE will return an ErrPoolClosed error after it.
+func (pp *PagePool) CloseE() error {
+	pp.lock.Lock()
+	if !pp.isClosed() {
+		close(pp.closed)
+	}
+	pp.lock.Unlock()
+
+	var err error
+	for {
+		select {
+		case item := <-pp.idle:
+			e := pp.discard(item)
+			if err == nil {
+				err = e
+			}
+		default:
+			return err
+		}
+	}
+}
+
+func (pp *PagePool) isClosed() bool {
+	select {
+	case <-pp.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (pp *PagePool) create() (*pooledPage, error) {
+	page, err := pp.browser.PageE("")
+	if err != nil {
+		return nil, err
+	}
+
+	item := &pooledPage{page: page, dead: make(chan kit.Nil)}
+
+	// subscribe before the Inspector.enable, so that we won't miss the crash
+	go item.watch(pp.browser.event.Subscribe(page.ctx))
+
+	err = proto.InspectorEnable{}.Call(page)
+	if err != nil {
+		_ = pp.discard(item)
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (pp *PagePool) reset(p *Page) error {
+	err := p.SetProxyE("", nil)
+	if err != nil {
+		return err
+	}
+
+	p.fetch.lock.Lock()
+	p.fetch.patterns = nil
+	p.fetch.lock.Unlock()
+
+	err = proto.FetchDisable{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	err = p.BlockResourceTypesE()
+	if err != nil {
+		return err
+	}
+
+	err = p.BlockRequestsE(nil)
+	if err != nil {
+		return err
+	}
+
+	err = p.NavigateBlankE()
+	if err != nil {
+		return err
+	}
+
+	err = proto.StorageClearCookies{BrowserContextID: pp.browser.BrowserContextID}.Call(pp.browser)
+	if err != nil {
+		return err
+	}
+
+	err = proto.EmulationClearDeviceMetricsOverride{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	err = proto.NetworkSetExtraHTTPHeaders{Headers: proto.NetworkHeaders{}}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	// the empty user agent clears the override
+	return proto.NetworkSetUserAgentOverride{}.Call(p)
+}
+
+// discard closes the page and releases its slot
+func (pp *PagePool) discard(item *pooledPage) error {
+	defer func() { <-pp.slots }()
+	defer item.page.ctxCancel()
+
+	_, err := proto.TargetCloseTarget{TargetID: item.page.TargetID}.Call(pp.browser)
+	return err
+}
+
+// watch closes the dead channel when the page crashes or its target gets destroyed
+func (item *pooledPage) watch(s chan goob.Event) {
+	goob.Each(s, func(msg *cdp.Event) bool {
+		crashed := &proto.TargetTargetCrashed{}
+		destroyed := &proto.TargetTargetDestroyed{}
+
+		switch {
+		case msg.SessionID == string(item.page.SessionID) && Event(msg, &proto.InspectorTargetCrashed{}):
+		case Event(msg, crashed) && crashed.TargetID == item.page.TargetID:
+		case Event(msg, destroyed) && destroyed.TargetID == item.page.TargetID:
+		default:
+			return false
+		}
+
+		close(item.dead)
+		return true
+	})
+}
+
+func (item *pooledPage) isDead() bool {
+	select {
+	case <-item.dead:
+		return true
+	default:
+		return false
+	}
+}
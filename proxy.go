@@ -0,0 +1,174 @@
+// This file contains the per page proxy that is implemented via the request interception,
+// because the --proxy-server flag of the browser applies to all the pages.
+
+package rod
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// ProxyAuth is the credentials of the proxy
+type ProxyAuth struct {
+	Username string
+	Password string
+}
+
+// proxyState is shared by all the clones of a page
+type proxyState struct {
+	lock sync.Mutex
+
+	// stops the interception of the current proxy, nil means no proxy
+	stop func() error
+}
+
+// SetProxyE sends the requests of the page through the proxy, such as "http://127.0.0.1:8080" or
+// "socks5://127.0.0.1:1080", auth is optional. The https targets are tunneled via the CONNECT method.
+// It works with the other request interception helpers of the page no matter the order they are called, the requests
+// they own, such as the ones of the HijackRequestsE, won't go through the proxy. The redirects are returned to the
+// browser as they are, so the browser will follow them itself. The whole response body is read into the memory before
+// it's sent to the page, because the Fetch.fulfillRequest can't stream it, so it isn't suitable for the large downloads.
+// Each call replaces the proxy of the previous one, use an empty proxyURL to restore the normal networking.
+func (p *Page) SetProxyE(proxyURL string, auth *ProxyAuth) error {
+	s := p.proxy
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stop != nil {
+		err := s.stop()
+		s.stop = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	if proxyURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	if auth != nil {
+		u.User = url.UserPassword(auth.Username, auth.Password)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(u)},
+
+		// let the browser follow the redirects, so that the page will get the right url and cookies
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	fp := newFetchPattern("*")
+	fp.priority = fetchPriorityProxy
+
+	stop, err := p.hijackRequests(fp, func(h *HijackContext) error {
+		// the failures of the requests are reported to the page, such as the proxy is unreachable
+		if p.proxyRequest(client, h) != nil && !h.handled {
+			_ = h.FailRequest(proto.NetworkErrorReasonConnectionFailed)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.stop = func() error {
+		client.CloseIdleConnections()
+		return stop()
+	}
+	return nil
+}
+
+func (p *Page) proxyRequest(client *http.Client, h *HijackContext) error {
+	r := h.Event.Request
+
+	// such as the data urls, they don't need the network
+	if !strings.HasPrefix(r.URL, "http:") && !strings.HasPrefix(r.URL, "https:") {
+		return h.ContinueRequest(nil)
+	}
+
+	req, err := p.pausedRequest(h.Event)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	return h.FulfillRequest(&proto.FetchFulfillRequest{
+		ResponseCode:    int64(res.StatusCode),
+		ResponseHeaders: fetchHeaders(res.Header),
+		Body:            data,
+	})
+}
+
+// pausedRequest creates the http request that is the same as the paused one, including the method, the body,
+// and the cookies of the page for the url
+func (p *Page) pausedRequest(e *proto.FetchRequestPaused) (*http.Request, error) {
+	r := e.Request
+
+	body := r.PostData
+	if r.HasPostData && body == "" && e.NetworkID != "" {
+		res, err := proto.NetworkGetRequestPostData{RequestID: proto.NetworkRequestID(e.NetworkID)}.Call(p)
+		if err != nil {
+			return nil, err
+		}
+		body = res.PostData
+	}
+
+	req, err := http.NewRequest(r.Method, r.URL, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(p.ctx)
+
+	for k, v := range r.Headers {
+		req.Header.Set(k, v.String())
+	}
+
+	// the paused requests don't have the cookie header, it's added by the network stack of the browser
+	if req.Header.Get("Cookie") == "" {
+		cookies, err := p.CookiesE([]string{r.URL})
+		if err != nil {
+			return nil, err
+		}
+		list := []string{}
+		for _, c := range cookies {
+			list = append(list, c.Name+"="+c.Value)
+		}
+		if len(list) > 0 {
+			req.Header.Set("Cookie", strings.Join(list, "; "))
+		}
+	}
+
+	return req, nil
+}
+
+func fetchHeaders(header http.Header) []*proto.FetchHeaderEntry {
+	headers := []*proto.FetchHeaderEntry{}
+	for k, vs := range header {
+		for _, v := range vs {
+			headers = append(headers, &proto.FetchHeaderEntry{Name: k, Value: v})
+		}
+	}
+	return headers
+}
@@ -50,7 +50,7 @@ func (ps Pages) Find(selector string) *Page {
 // FindByURLE returns the page that has the url that matches the regex
 func (ps Pages) FindByURLE(regex string) (*Page, error) {
 	for _, page := range ps {
-		res, err := page.EvalE(true, "", `() => location.href`, nil)
+		res, err := page.evalE(true, "", `() => location.href`, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -89,6 +89,230 @@ func (p *Page) HasMatchesE(selector, regex string) (bool, error) {
 	return err == nil, err
 }
 
+// ElementState is the state of the element to wait for
+type ElementState string
+
+const (
+	// ElementStateExists the element is attached to the DOM
+	ElementStateExists ElementState = "exists"
+
+	// ElementStateVisible the element is attached and visible
+	ElementStateVisible ElementState = "visible"
+
+	// ElementStateHidden the element is invisible or not attached
+	ElementStateHidden ElementState = "hidden"
+
+	// ElementStateRemoved the element is not attached
+	ElementStateRemoved ElementState = "removed"
+)
+
+// WaitElementE waits until the element that matches the css selector reaches the state.
+// It returns the element for ElementStateExists and ElementStateVisible, nil for the other states.
+// When the page context times out, the context error will be returned.
+func (p *Page) WaitElementE(selector string, state ElementState) (*Element, error) {
+	defer p.tryTrace(0, 0, 300, 0, "waiting for element "+string(state)+" "+selector)()
+
+	var result *Element
+
+	err := kit.Retry(p.ctx, p.Sleeper(), func() (bool, error) {
+		el, err := p.ElementE(nil, "", selector)
+		if IsError(err, ErrElementNotFound) {
+			return state == ElementStateHidden || state == ElementStateRemoved, nil
+		}
+		if err != nil {
+			return true, err
+		}
+
+		if state == ElementStateExists {
+			result = el
+			return true, nil
+		}
+
+		reached := false
+		if state != ElementStateRemoved {
+			visible, err := el.displayedE()
+			if err != nil {
+				return true, err
+			}
+
+			if state == ElementStateVisible && visible {
+				result = el
+				return true, nil
+			}
+			reached = state == ElementStateHidden && !visible
+		}
+
+		err = el.ReleaseE()
+		if err != nil {
+			return true, err
+		}
+		return reached, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// the max number of search results to fetch in one request
+var searchBatchSize int64 = 100
+
+// SearchE returns the first element that matches the query, the query can be plain text, css selector, or xpath.
+// It searches through all the same-process iframes and shadow roots. For a plain text query the element
+// that contains the text will be returned. It returns ErrElementNotFound if nothing matches.
+func (p *Page) SearchE(query string) (*Element, error) {
+	list, err := p.SearchWithE(&proto.DOMPerformSearch{Query: query}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, &Error{nil, ErrElementNotFound, query}
+	}
+	return list[0], nil
+}
+
+// SearchAllE doc is similar to the method SearchE, it returns all the elements that match the query
+func (p *Page) SearchAllE(query string) (Elements, error) {
+	return p.SearchWithE(&proto.DOMPerformSearch{Query: query}, 0)
+}
+
+// SearchWithE searches with the options of the req, such as IncludeUserAgentShadowDOM.
+// The results are fetched in batches, if limit is greater than 0 at most limit elements will be returned.
+// Each returned element belongs to the page of the frame that contains it.
+func (p *Page) SearchWithE(req *proto.DOMPerformSearch, limit int) (Elements, error) {
+	// the search only works after the DOM domain is enabled
+	_, err := proto.DOMGetDocument{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	search, err := req.Call(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = proto.DOMDiscardSearchResults{SearchID: search.SearchID}.Call(p) }()
+
+	s := &searcher{root: p.Root(), frames: map[proto.DOMBackendNodeID]*Page{}, found: map[proto.DOMBackendNodeID]bool{}}
+	list := Elements{}
+
+	for from := int64(0); from < search.ResultCount; from += searchBatchSize {
+		to := from + searchBatchSize
+		if to > search.ResultCount {
+			to = search.ResultCount
+		}
+
+		res, err := proto.DOMGetSearchResults{SearchID: search.SearchID, FromIndex: from, ToIndex: to}.Call(p)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range res.NodeIds {
+			el, err := s.element(id)
+			if err != nil {
+				return nil, err
+			}
+			if el == nil {
+				continue
+			}
+
+			list = append(list, el)
+			if limit > 0 && len(list) == limit {
+				return list, nil
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// searcher converts the search results to the elements of the frames they belong to
+type searcher struct {
+	root   *Page
+	frames map[proto.DOMBackendNodeID]*Page // the iframe element to its page
+	found  map[proto.DOMBackendNodeID]bool  // such as the text nodes of the same element
+}
+
+// returns nil if the node isn't an element and doesn't belong to any element, or it's already found
+func (s *searcher) element(id proto.DOMNodeID) (*Element, error) {
+	node, err := proto.DOMResolveNode{NodeID: id}.Call(s.root)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = s.root.ReleaseE(node.Object.ObjectID) }()
+
+	res, err := proto.RuntimeCallFunctionOn{
+		ObjectID:            node.Object.ObjectID,
+		FunctionDeclaration: `function() { return this.nodeType === Node.ELEMENT_NODE ? this : this.parentElement }`,
+	}.Call(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if res.Result.ObjectID == "" {
+		return nil, nil
+	}
+	defer func() { _ = s.root.ReleaseE(res.Result.ObjectID) }()
+
+	info, err := proto.DOMDescribeNode{ObjectID: res.Result.ObjectID}.Call(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if s.found[info.Node.BackendNodeID] {
+		return nil, nil
+	}
+	s.found[info.Node.BackendNodeID] = true
+
+	page, err := s.page(res.Result.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.resolveNodeE(info.Node.BackendNodeID)
+}
+
+// page returns the page of the frame that contains the object
+func (s *searcher) page(objectID proto.RuntimeRemoteObjectID) (*Page, error) {
+	res, err := proto.RuntimeCallFunctionOn{
+		ObjectID:            objectID,
+		FunctionDeclaration: `function() { return this.ownerDocument.defaultView.frameElement }`,
+	}.Call(s.root)
+	if err != nil {
+		return nil, err
+	}
+	if res.Result.ObjectID == "" {
+		return s.root, nil
+	}
+	defer func() { _ = s.root.ReleaseE(res.Result.ObjectID) }()
+
+	info, err := proto.DOMDescribeNode{ObjectID: res.Result.ObjectID}.Call(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	id := info.Node.BackendNodeID
+	if frame, has := s.frames[id]; has {
+		return frame, nil
+	}
+
+	parent, err := s.page(res.Result.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	el, err := parent.resolveNodeE(id)
+	if err != nil {
+		return nil, err
+	}
+
+	frame, err := el.FrameE()
+	if err != nil {
+		return nil, err
+	}
+
+	s.frames[id] = frame
+	return frame, nil
+}
+
 // ElementE finds element by css selector
 func (p *Page) ElementE(sleeper kit.Sleeper, objectID proto.RuntimeRemoteObjectID, selector string) (*Element, error) {
 	return p.ElementByJSE(sleeper, objectID, p.jsFn("element"), Array{selector})
@@ -120,12 +344,10 @@ func (p *Page) ElementByJSE(sleeper kit.Sleeper, thisID proto.RuntimeRemoteObjec
 		}
 	}
 
-	if p.browser.trace {
-		defer p.traceFn(js, params)()
-	}
+	defer p.traceFn(js, params)()
 
 	err = kit.Retry(p.ctx, sleeper, func() (bool, error) {
-		res, err = p.EvalE(false, thisID, js, params)
+		res, err = p.evalE(false, thisID, js, params)
 		if err != nil {
 			return true, err
 		}
@@ -157,6 +379,50 @@ func (p *Page) ElementsXE(objectID proto.RuntimeRemoteObjectID, xpath string) (E
 	return p.ElementsByJSE(objectID, p.jsFn("elementsX"), Array{xpath})
 }
 
+// EachElementE calls fn with each element that matches the css selector in document order, the elements are
+// resolved one at a time and released right after fn returns, so that the memory stays flat for a large number of
+// matches. The iteration stops when fn returns true or an error, the remaining nodes won't be resolved.
+func (p *Page) EachElementE(selector string, fn func(*Element) (stop bool, err error)) error {
+	doc, err := p.evalE(false, "", `() => document`, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = p.ReleaseE(doc.ObjectID) }()
+
+	// the node ids are only available after the document is requested
+	_, err = proto.DOMGetDocument{}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	root, err := proto.DOMRequestNode{ObjectID: doc.ObjectID}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	res, err := proto.DOMQuerySelectorAll{NodeID: root.NodeID, Selector: selector}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range res.NodeIds {
+		node, err := proto.DOMResolveNode{NodeID: id, ExecutionContextID: p.jsContextID}.Call(p)
+		if err != nil {
+			return err
+		}
+
+		el := p.ElementFromObjectID(node.Object.ObjectID)
+		stop, err := fn(el)
+		_ = el.ReleaseE()
+
+		if err != nil || stop {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // ElementsByJSE is different from ElementByJSE, it doesn't do retry
 func (p *Page) ElementsByJSE(thisID proto.RuntimeRemoteObjectID, js string, params Array) (Elements, error) {
 	res, err := p.EvalE(false, thisID, js, params)
@@ -233,6 +499,90 @@ func (el *Element) ElementXE(xpath string) (*Element, error) {
 	return el.page.ElementXE(nil, el.ObjectID, xpath)
 }
 
+// ElementDeepE finds the descendant that matches the css selector, it pierces the open shadow roots recursively.
+// It only uses the DOM domain, so it works on the pages whose CSP blocks the injected scripts.
+// If nothing matches and some closed shadow roots are skipped, ErrShadowRootClosed will be returned,
+// otherwise ErrElementNotFound will be returned.
+func (el *Element) ElementDeepE(selector string) (*Element, error) {
+	// push all the nodes to the client, so that each node has its NodeID
+	doc, err := proto.DOMGetDocument{Depth: -1, Pierce: true}.Call(el)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := proto.DOMRequestNode{ObjectID: el.ObjectID}.Call(el)
+	if err != nil {
+		return nil, err
+	}
+
+	root := findNode(doc.Root, node.NodeID)
+	if root == nil {
+		return nil, &Error{nil, ErrElementDetached, nil}
+	}
+
+	closed := 0
+	scopes := []*proto.DOMNode{root}
+
+	for len(scopes) > 0 {
+		scope := scopes[0]
+		scopes = scopes[1:]
+
+		res, err := proto.DOMQuerySelector{NodeID: scope.NodeID, Selector: selector}.Call(el)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.NodeID != 0 {
+			info, err := proto.DOMDescribeNode{NodeID: res.NodeID}.Call(el)
+			if err != nil {
+				return nil, err
+			}
+			return el.page.resolveNodeE(info.Node.BackendNodeID)
+		}
+
+		// the shadow roots of the scope's tree, the nested ones will be collected when their host tree is queried
+		var walk func(n *proto.DOMNode)
+		walk = func(n *proto.DOMNode) {
+			for _, sr := range n.ShadowRoots {
+				if sr.ShadowRootType == proto.DOMShadowRootTypeClosed {
+					closed++
+				} else {
+					scopes = append(scopes, sr)
+				}
+			}
+			for _, c := range n.Children {
+				walk(c)
+			}
+		}
+		walk(scope)
+	}
+
+	if closed > 0 {
+		return nil, &Error{nil, ErrShadowRootClosed, selector}
+	}
+	return nil, &Error{nil, ErrElementNotFound, selector}
+}
+
+// findNode returns the node that has the id in the tree, the shadow roots and iframes are included
+func findNode(tree *proto.DOMNode, id proto.DOMNodeID) *proto.DOMNode {
+	if tree.NodeID == id {
+		return tree
+	}
+
+	list := append([]*proto.DOMNode{}, tree.Children...)
+	list = append(list, tree.ShadowRoots...)
+	if tree.ContentDocument != nil {
+		list = append(list, tree.ContentDocument)
+	}
+
+	for _, n := range list {
+		if found := findNode(n, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 // ElementByJSE doc is similar to the method ElementByJS
 func (el *Element) ElementByJSE(js string, params Array) (*Element, error) {
 	return el.page.ElementByJSE(nil, el.ObjectID, js, params)
@@ -240,7 +590,25 @@ func (el *Element) ElementByJSE(js string, params Array) (*Element, error) {
 
 // ParentE doc is similar to the method Parent
 func (el *Element) ParentE() (*Element, error) {
-	return el.ElementByJSE(`() => this.parentElement`, nil)
+	parent, err := el.ElementByJSE(`() => this.parentElement`, nil)
+	if IsError(err, ErrElementNotFound) {
+		return nil, &Error{err, ErrNoParent, nil}
+	}
+	return parent, err
+}
+
+// ChildrenE doc is similar to the method Children
+func (el *Element) ChildrenE() (Elements, error) {
+	return el.ElementsByJSE(`() => Array.from(this.children)`, nil)
+}
+
+// MatchesE doc is similar to the method Matches
+func (el *Element) MatchesE(selector string) (bool, error) {
+	res, err := el.page.evalE(true, el.ObjectID, `s => this.matches(s)`, Array{selector})
+	if err != nil {
+		return false, err
+	}
+	return res.Value.Bool(), nil
 }
 
 // ParentsE that match the selector
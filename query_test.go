@@ -1,7 +1,14 @@
 package rod_test
 
 import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ysmood/kit"
 	"github.com/ysmood/rod"
+	"github.com/ysmood/rod/lib/proto"
 )
 
 func (s *S) TestPageElements() {
@@ -22,6 +29,29 @@ func (s *S) TestPages() {
 	s.Nil(s.browser.Pages().FindByURL("____"))
 }
 
+func (s *S) TestPageWaitElement() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	p.Element("button")
+
+	p.Eval(`() => setTimeout(() => {
+		let el = document.createElement('a')
+		el.id = 'wait-element'
+		el.innerText = 'ok'
+		document.body.appendChild(el)
+	}, 100)`)
+	s.Equal("ok", p.WaitElement("#wait-element", rod.ElementStateVisible).Text())
+
+	p.Eval(`() => setTimeout(() => document.querySelector('#wait-element').style.display = 'none', 100)`)
+	s.Nil(p.WaitElement("#wait-element", rod.ElementStateHidden))
+	s.NotNil(p.WaitElement("#wait-element", rod.ElementStateExists))
+
+	p.Eval(`() => setTimeout(() => document.querySelector('#wait-element').remove(), 100)`)
+	s.Nil(p.WaitElement("#wait-element", rod.ElementStateRemoved))
+
+	_, err := p.Timeout(300*time.Millisecond).WaitElementE("#not-exists", rod.ElementStateExists)
+	s.True(errors.Is(err, context.DeadlineExceeded))
+}
+
 func (s *S) TestPageHas() {
 	s.page.Navigate(srcFile("fixtures/selector.html"))
 	s.page.Element("body")
@@ -92,6 +122,33 @@ func (s *S) TestElementParent() {
 	s.Equal("FORM", el.Eval(`() => this.tagName`).String())
 }
 
+func (s *S) TestElementNoParent() {
+	p := s.page.Navigate(srcFile("fixtures/input.html"))
+	_, err := p.Element("html").ParentE()
+	s.True(errors.Is(err, rod.ErrNoParent))
+}
+
+func (s *S) TestElementChildren() {
+	p := s.page.Navigate(srcFile("fixtures/click.html"))
+	p.Eval(`() => document.body.innerHTML = 'text <!-- comment --> <p>a</p> <div>b</div>'`)
+
+	list := p.Element("body").Children()
+	s.Len(list, 2)
+	s.True(list[0].Matches("p"))
+	s.False(list[0].Matches("div"))
+	s.True(list[1].Matches("body > div"))
+	s.Equal("BODY", list[1].Parent().Eval(`() => this.tagName`).String())
+
+	_, err := list[0].MatchesE("[")
+	s.True(errors.Is(err, rod.ErrEval))
+
+	// the traversal stays in the iframe
+	p = s.page.Navigate(srcFile("fixtures/click-iframe.html"))
+	body := p.Element("iframe").Frame().Element("button").Parent()
+	s.True(body.Matches("body"))
+	s.Len(body.Children(), 2)
+}
+
 func (s *S) TestElementParents() {
 	p := s.page.Navigate(srcFile("fixtures/input.html"))
 	s.Len(p.Element("option").Parents("*"), 4)
@@ -146,3 +203,94 @@ func (s *S) TestElementsOthers() {
 	s.Nil(list.First())
 	s.Nil(list.Last())
 }
+
+func (s *S) TestPageSearch() {
+	p := s.page.Navigate(srcFile("fixtures/click-iframes.html"))
+
+	// the button is inside the nested iframes
+	el := p.Search("click me")
+	s.Equal("BUTTON", el.Describe().NodeName)
+	el.Click()
+	s.Equal("ok", *el.Attribute("a"))
+
+	s.Equal("H4", p.Search("//h4").Describe().NodeName)
+	s.Len(p.SearchAll("button"), 1)
+
+	_, err := p.SearchE("not-exists-text")
+	s.True(rod.IsError(err, rod.ErrElementNotFound))
+}
+
+func (s *S) TestPageSearchShadowDOM() {
+	p := s.page.Navigate(srcFile("fixtures/shadow-dom.html"))
+	s.Equal("inside", p.Search("inside").Text())
+}
+
+func (s *S) TestPageEachElement() {
+	p := s.page.Navigate(srcFile("fixtures/selector.html"))
+	p.Eval(`() => {
+		const ul = document.createElement('ul')
+		for (let i = 0; i < 10; i++) ul.innerHTML += '<li>' + i + '</li>'
+		document.body.appendChild(ul)
+	}`)
+
+	list := []string{}
+	p.EachElement("li", func(el *rod.Element) bool {
+		list = append(list, el.Text())
+		return len(list) == 3
+	})
+	s.Equal([]string{"0", "1", "2"}, list)
+
+	err := p.EachElementE("li", func(el *rod.Element) (bool, error) {
+		return false, errors.New("err")
+	})
+	s.EqualError(err, "err")
+}
+
+const rowsJS = `() => {
+	const table = document.createElement('table')
+	table.innerHTML = Array.from({ length: 10000 }, (_, i) => '<tr><td>' + i + '</td></tr>').join('')
+	document.body.appendChild(table)
+}`
+
+func BenchmarkElements(b *testing.B) {
+	browser := rod.New().Connect()
+	defer browser.Close()
+	p := browser.Page(srcFile("fixtures/click.html"))
+	p.Eval(rowsJS)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for _, el := range p.Elements("tr") {
+			el.Release()
+		}
+	}
+
+	reportHeap(b, p)
+}
+
+func BenchmarkEachElement(b *testing.B) {
+	browser := rod.New().Connect()
+	defer browser.Close()
+	p := browser.Page(srcFile("fixtures/click.html"))
+	p.Eval(rowsJS)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		p.EachElement("tr", func(el *rod.Element) bool { return false })
+	}
+
+	reportHeap(b, p)
+}
+
+// reportHeap reports the used js heap of the page, so the memory of the remote objects can be compared
+func reportHeap(b *testing.B, p *rod.Page) {
+	b.StopTimer()
+	_ = proto.HeapProfilerCollectGarbage{}.Call(p)
+	res, err := proto.RuntimeGetHeapUsage{}.Call(p)
+	kit.E(err)
+	b.ReportMetric(res.UsedSize, "heap-bytes")
+}
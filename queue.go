@@ -0,0 +1,120 @@
+// This file contains the fan-out of the browser events to the page sessions. Each page session has a bounded queue
+// between the events of the browser and its own observable, so a slow page can't make the others wait.
+
+package rod
+
+import (
+	"sync/atomic"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// EventOverflow is the policy when the event queue of a page session is full
+type EventOverflow int
+
+const (
+	// EventOverflowBlock makes the events wait until the queue has room, no event will be lost,
+	// the waiting events are kept by the subscription of the browser
+	EventOverflowBlock EventOverflow = iota
+
+	// EventOverflowDrop drops the events when the queue is full, the count of them can be
+	// checked via the Page.DroppedEvents
+	EventOverflowDrop
+)
+
+// the default size of the event queue of each page session
+const defaultEventBuffer = 1000
+
+// eventQueue is shared by all the clones of a page session
+type eventQueue struct {
+	dropped int64
+
+	lazy *lazySession // not nil if the queue is created before the page is attached
+
+	session atomic.Value // the proto.TargetSessionID that replaces the one of the initEventQueue after a reattach
+}
+
+// switchSession makes the queue forward the events of the session instead, such as the new session of a reattach
+func (q *eventQueue) switchSession(id proto.TargetSessionID) {
+	q.session.Store(id)
+}
+
+func (q *eventQueue) sessionID(initial func() proto.TargetSessionID) proto.TargetSessionID {
+	if id, ok := q.session.Load().(proto.TargetSessionID); ok {
+		return id
+	}
+	return initial()
+}
+
+func (q *eventQueue) lazySession() *lazySession {
+	if q == nil {
+		return nil
+	}
+	return q.lazy
+}
+
+// initEventQueue creates the observable of the events of the page session
+func (p *Page) initEventQueue(sessionID func() proto.TargetSessionID) {
+	q := &eventQueue{lazy: p.lazy}
+	ob := goob.New()
+
+	overflow := p.browser.eventOverflow
+	buf := make(chan *cdp.Event, p.browser.eventBuffer)
+	s := p.browser.event.Subscribe(p.ctx)
+
+	go func() {
+		defer close(buf)
+
+		for msg := range s {
+			e := msg.(*cdp.Event)
+			if e.SessionID == "" || e.SessionID != string(q.sessionID(sessionID)) {
+				continue
+			}
+
+			if overflow == EventOverflowDrop {
+				select {
+				case buf <- e:
+				default:
+					atomic.AddInt64(&q.dropped, 1)
+				}
+				continue
+			}
+
+			select {
+			case buf <- e:
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	dialog := p.dialog
+	go func() {
+		for e := range buf {
+			dialog.update(e)
+			ob.Publish(e)
+		}
+	}()
+
+	p.event = ob
+	p.events = q
+}
+
+// DroppedEvents returns the count of the events of the page session that are dropped because the queue is full,
+// it's always 0 unless the EventOverflowDrop is used, such as to assert no event is lost in a test
+func (p *Page) DroppedEvents() int64 {
+	if p.events == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.events.dropped)
+}
+
+// EventBuffer sets the size of the event queue of each page session and the policy when it's full,
+// it only affects the pages created after the call. The default is 1000 with the EventOverflowBlock.
+func (b *Browser) EventBuffer(size int, overflow EventOverflow) *Browser {
+	b.eventBuffer = size
+	b.eventOverflow = overflow
+	return b
+}
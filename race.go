@@ -0,0 +1,144 @@
+// This file contains the combinator to race multiple wait conditions of a page.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// RaceContext stores the conditions of the Race
+type RaceContext struct {
+	page       *Page
+	conditions []raceCondition
+}
+
+// raceCondition is started with a clone of the page whose context will be canceled when the race is over.
+// The subscribe is called before any condition starts to wait, so that the events won't be missed.
+type raceCondition func(p *Page) (wait func() (*Element, error))
+
+// Race creates a context to race the conditions, use the DoE to start them.
+// Use Timeout of the page to limit the time of the race, such as page.Timeout(10*time.Second).Race().
+func (p *Page) Race() *RaceContext {
+	return &RaceContext{page: p}
+}
+
+// Element adds a condition that waits until the selector matches an element
+func (rc *RaceContext) Element(selector string) *RaceContext {
+	return rc.add(func(p *Page) func() (*Element, error) {
+		return func() (*Element, error) {
+			return p.ElementE(nil, "", selector)
+		}
+	})
+}
+
+// ElementX adds a condition that waits until the xpath matches an element
+func (rc *RaceContext) ElementX(xpath string) *RaceContext {
+	return rc.add(func(p *Page) func() (*Element, error) {
+		return func() (*Element, error) {
+			return p.ElementXE(nil, "", xpath)
+		}
+	})
+}
+
+// ElementByJS adds a condition that waits until the js returns an element
+func (rc *RaceContext) ElementByJS(js string, params Array) *RaceContext {
+	return rc.add(func(p *Page) func() (*Element, error) {
+		return func() (*Element, error) {
+			return p.ElementByJSE(nil, "", js, params)
+		}
+	})
+}
+
+// Event adds a condition that waits until the event fires, the e will be filled with the event if it wins
+func (rc *RaceContext) Event(e proto.Event) *RaceContext {
+	return rc.add(func(p *Page) func() (*Element, error) {
+		wait := p.WaitEvent()
+
+		return func() (*Element, error) {
+			wait(e)
+
+			// the wait returns without the event when the race is over or the page is closed
+			err := p.ctx.Err()
+			if err == nil {
+				err = p.crash.err()
+			}
+			return nil, err
+		}
+	})
+}
+
+// Func adds a custom condition, fn should return when the context of the p is done
+func (rc *RaceContext) Func(fn func(p *Page) (*Element, error)) *RaceContext {
+	return rc.add(func(p *Page) func() (*Element, error) {
+		return func() (*Element, error) {
+			return fn(p)
+		}
+	})
+}
+
+func (rc *RaceContext) add(c raceCondition) *RaceContext {
+	rc.conditions = append(rc.conditions, c)
+	return rc
+}
+
+// DoE starts all the conditions concurrently and returns the first one that completes, index is the order of it
+// being added. If the winner is an element condition, el will be the element, otherwise el will be nil.
+// The conditions that lose will be canceled, and DoE won't return until all of them exit, so no goroutine or
+// event subscription will be left behind. If there's no condition, index will be -1.
+func (rc *RaceContext) DoE() (index int, el *Element, err error) {
+	if len(rc.conditions) == 0 {
+		return -1, nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(rc.page.ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		el    *Element
+		err   error
+	}
+
+	// buffered, so that the losers won't block on sending their results
+	results := make(chan result, len(rc.conditions))
+	wg := &sync.WaitGroup{}
+
+	waits := []func() (*Element, error){}
+	for _, c := range rc.conditions {
+		waits = append(waits, c(rc.page.Context(ctx)))
+	}
+
+	for i, wait := range waits {
+		wg.Add(1)
+		go func(i int, wait func() (*Element, error)) {
+			defer wg.Done()
+			el, err := wait()
+			results <- result{i, el, err}
+		}(i, wait)
+	}
+
+	r := <-results
+	cancel()
+	wg.Wait()
+	close(results)
+
+	// such as two elements appear at the same time, release the ones of the losers
+	for loser := range results {
+		if loser.el != nil {
+			_ = rc.page.ReleaseE(loser.el.ObjectID)
+		}
+	}
+
+	if r.err != nil {
+		return r.index, nil, r.err
+	}
+
+	// the element is bound to the canceled clone, rebind it to the page
+	if r.el != nil {
+		r.el = rc.page.ElementFromObjectID(r.el.ObjectID)
+	}
+	return r.index, r.el, nil
+}
@@ -0,0 +1,197 @@
+// This file contains the helpers of the range inputs. The value of a slider can be set directly like the other inputs,
+// or be dragged by the mouse, for the pages that only listen to the pointer events of the thumb.
+
+package rod
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/ysmood/rod/lib/input"
+)
+
+// SliderInfo is the state of a range input
+type SliderInfo struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Step  float64 `json:"step"` // 0 means the step is "any"
+	Value float64 `json:"value"`
+
+	// Vertical is true if the slider is vertical, such as the writing-mode is vertical
+	Vertical bool `json:"vertical"`
+
+	// Reversed is true if the min is at the right of a horizontal slider, or at the bottom of a vertical one
+	Reversed bool `json:"reversed"`
+}
+
+// Snap returns the value that the browser will set for v, the value is clamped to the min and max,
+// and rounded to the nearest step from the min
+func (s *SliderInfo) Snap(v float64) float64 {
+	max := math.Max(s.Min, s.Max)
+	v = math.Max(s.Min, math.Min(v, max))
+	if s.Step <= 0 {
+		return v
+	}
+
+	// the half step is rounded up, and the max may not be on a step boundary
+	v = s.Min + math.Floor((v-s.Min)/s.Step+0.5)*s.Step
+	if v > max {
+		v = s.Min + math.Floor((max-s.Min)/s.Step)*s.Step
+	}
+
+	// such as 0.1 * 3 is 0.30000000000000004
+	return math.Round(v*1e9) / 1e9
+}
+
+// the defaults are the same as the browser's when the attributes are missing or invalid
+const sliderInfoJS = `function () {
+	if (this.tagName !== 'INPUT' || this.type !== 'range') return { type: this.type || this.tagName }
+
+	const num = (s, d) => {
+		const n = parseFloat(s)
+		return isNaN(n) ? d : n
+	}
+	const any = this.step.toLowerCase() === 'any'
+	const step = num(this.step, 1)
+
+	const style = window.getComputedStyle(this)
+	const legacy = style.webkitAppearance === 'slider-vertical' || this.getAttribute('orient') === 'vertical'
+	const vertical = legacy || style.writingMode.startsWith('vertical')
+
+	return {
+		min: num(this.min, 0),
+		max: num(this.max, 100),
+		step: any ? 0 : (step > 0 ? step : 1),
+		value: num(this.value, 0),
+		vertical,
+		reversed: legacy || (style.direction === 'rtl')
+	}
+}`
+
+// SliderInfoE returns the min, max, step, and current value of the range input,
+// if the element isn't a range input an ErrInputType error will be returned
+func (el *Element) SliderInfoE() (*SliderInfo, error) {
+	var res struct {
+		SliderInfo
+		Type string `json:"type"`
+	}
+	err := el.page.Context(el.ctx).evalInto(&res, el.ObjectID, sliderInfoJS, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.Type != "" {
+		return nil, &Error{nil, ErrInputType, res.Type}
+	}
+	return &res.SliderInfo, nil
+}
+
+// SetRangeE sets the value of the range input via the value property, the value is snapped like the browser does,
+// see the SliderInfo.Snap. The input and change events will be dispatched if the value changes.
+func (el *Element) SetRangeE(value float64) error {
+	err := el.WaitVisibleE()
+	if err != nil {
+		return err
+	}
+
+	info, err := el.SliderInfoE()
+	if err != nil {
+		return err
+	}
+	target := info.Snap(value)
+
+	defer el.tryTrace("set range " + formatRange(target))()
+	el.page.browser.trySlowmotion()
+
+	_, err = el.EvalE(true, `function (v) {
+		if (this.value === v) return
+		this.value = v
+		this.dispatchEvent(new Event('input', { bubbles: true }))
+		this.dispatchEvent(new Event('change', { bubbles: true }))
+	}`, Array{formatRange(target)})
+	return err
+}
+
+// the max number of the arrow keys to press to correct the value after the dragging
+const rangeCorrectMax = 20
+
+// DragRangeE sets the value of the range input like a user, it drags the thumb of the slider to the position of
+// the value, then presses the arrow keys to correct the value if the position is off by a few steps, because the
+// size of the thumb depends on the style of the page. The vertical and the right-to-left sliders are supported.
+// If the step is "any" the value is only as accurate as the position of the mouse.
+func (el *Element) DragRangeE(value float64) error {
+	err := el.WaitVisibleE()
+	if err != nil {
+		return err
+	}
+
+	err = el.ScrollIntoViewE()
+	if err != nil {
+		return err
+	}
+
+	info, err := el.SliderInfoE()
+	if err != nil {
+		return err
+	}
+	target := info.Snap(value)
+
+	box, err := el.BoxE()
+	if err != nil {
+		return err
+	}
+
+	fromX, fromY := sliderPoint(box, info, info.Value)
+	toX, toY := sliderPoint(box, info, target)
+	err = el.page.Mouse.DragE(fromX, fromY, toX, toY, 10)
+	if err != nil {
+		return err
+	}
+
+	if info.Step <= 0 {
+		return nil
+	}
+
+	for i := 0; i < rangeCorrectMax; i++ {
+		info, err = el.SliderInfoE()
+		if err != nil {
+			return err
+		}
+		if math.Abs(info.Value-target) < info.Step/2 {
+			return nil
+		}
+
+		// the ArrowUp increases the value for sliders of all directions
+		key := input.ArrowUp
+		if info.Value > target {
+			key = input.ArrowDown
+		}
+		err = el.page.Keyboard.PressE(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return &Error{nil, ErrInputInvalid, formatRange(info.Value)}
+}
+
+// sliderPoint returns the center of the thumb when the slider is at the value, the size of the thumb is
+// assumed to be the thickness of the slider, the default style of the browser
+func sliderPoint(box *Box, info *SliderInfo, value float64) (x, y float64) {
+	ratio := 0.0
+	if info.Max > info.Min {
+		ratio = (value - info.Min) / (info.Max - info.Min)
+	}
+	if info.Reversed {
+		ratio = 1 - ratio
+	}
+
+	thumb := math.Min(box.Width, box.Height)
+	if info.Vertical {
+		return box.Left + box.Width/2, box.Top + thumb/2 + ratio*(box.Height-thumb)
+	}
+	return box.Left + thumb/2 + ratio*(box.Width-thumb), box.Top + box.Height/2
+}
+
+func formatRange(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,226 @@
+// This file contains the registry of the pages that are attached by the browser, and the lazy attach of the pages
+// that are listed from the targets. Listing the tabs of a running browser shouldn't attach all of them up front,
+// and a target shouldn't be attached twice by the same browser.
+
+package rod
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// pageRegistry is shared by all the clones of a browser
+type pageRegistry struct {
+	lock sync.Mutex
+	list map[proto.TargetTargetID]*Page
+}
+
+// get returns the page of the target, nil if the target isn't attached or the page is closed
+func (r *pageRegistry) get(id proto.TargetTargetID) *Page {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lookup(id)
+}
+
+// getOrAdd returns the page of the target, if there's none the page that create returns will be added,
+// the lookup and the insert are atomic, so that a target won't get two pages
+func (r *pageRegistry) getOrAdd(id proto.TargetTargetID, create func() *Page) *Page {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if p := r.lookup(id); p != nil {
+		return p
+	}
+	p := create()
+	r.list[id] = p
+	return p
+}
+
+// lookup must be called with the lock held
+func (r *pageRegistry) lookup(id proto.TargetTargetID) *Page {
+	p, has := r.list[id]
+	if !has {
+		return nil
+	}
+
+	if p.ctx.Err() != nil || IsError(p.attachState().err(), ErrTargetGone) {
+		delete(r.list, id)
+		return nil
+	}
+	return p
+}
+
+func (r *pageRegistry) remove(id proto.TargetTargetID) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.list, id)
+}
+
+// removePage removes the page only if it's the one of its target in the registry
+func (r *pageRegistry) removePage(p *Page) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.list[p.TargetID] == p {
+		delete(r.list, p.TargetID)
+	}
+}
+
+// lazySession is shared by all the clones of a page that is created before it's attached
+type lazySession struct {
+	page *Page // the page that will be attached
+
+	once sync.Once
+	err  error
+
+	lock    sync.Mutex
+	id      proto.TargetSessionID
+	frameID proto.PageFrameID
+	used    bool // the states created with the lazy page are used by a session
+}
+
+func (l *lazySession) sessionID() proto.TargetSessionID {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.id
+}
+
+// use binds the states created with the lazy page to the session
+func (l *lazySession) use(id proto.TargetSessionID) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.id = id
+	l.used = true
+}
+
+// lazyPage returns the page of the target without attaching it, the page will be attached on its first call
+func (b *Browser) lazyPage(targetID proto.TargetTargetID) *Page {
+	return b.pages.getOrAdd(targetID, func() *Page {
+		page := b.newPage(targetID)
+		l := &lazySession{page: page}
+		page.lazy = l
+
+		// create the states before the attach, so that the events can be subscribed before the first call
+		page.initStates(l.sessionID)
+		return page
+	})
+}
+
+// attach attaches the target via a copy of the lazy page that isn't lazy, so that the calls of the attach itself
+// won't wait for the attach. The session is only published after the attach is done.
+func (l *lazySession) attach() {
+	attached := *l.page
+	attached.lazy = nil
+
+	l.err = attached.initSession(nil)
+	if l.err != nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.id = attached.SessionID
+	l.frameID = attached.FrameID
+}
+
+// ensureSession attaches the lazy page, all the clones of it wait for the same attach, then use the session of it
+func (p *Page) ensureSession() error {
+	l := p.lazy
+	if l == nil {
+		return nil
+	}
+
+	l.once.Do(l.attach)
+	if l.err != nil {
+		return l.err
+	}
+
+	l.lock.Lock()
+	id, frameID := l.id, l.frameID
+	l.lock.Unlock()
+
+	// the fields are only written once, the session of a reattached page is kept
+	p.sessionLock.Lock()
+	defer p.sessionLock.Unlock()
+	if p.SessionID == "" {
+		p.SessionID = id
+		p.FrameID = frameID
+	}
+	return nil
+}
+
+// failedClient rejects all the calls with the error of the lazy attach
+type failedClient struct {
+	err error
+}
+
+func (c failedClient) Call(context.Context, string, string, interface{}) ([]byte, error) {
+	return nil, c.err
+}
+
+// AllPagesE is similar to the PagesE, but the devtools pages and the background pages of the extensions
+// are included
+func (b *Browser) AllPagesE() (Pages, error) {
+	return b.listPages(true)
+}
+
+// FindPageE returns the first page whose url or title matches the regexp, the page will be attached lazily.
+// If no page matches, an ErrPageNotFound error will be returned.
+func (b *Browser) FindPageE(urlRegex string) (*Page, error) {
+	reg, err := regexp.Compile(urlRegex)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := b.targets(false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range list {
+		if reg.MatchString(info.URL) || reg.MatchString(info.Title) {
+			return b.lazyPage(info.TargetID), nil
+		}
+	}
+	return nil, &Error{nil, ErrPageNotFound, urlRegex}
+}
+
+func (b *Browser) listPages(all bool) (Pages, error) {
+	list, err := b.targets(all)
+	if err != nil {
+		return nil, err
+	}
+
+	pageList := Pages{}
+	for _, info := range list {
+		pageList = append(pageList, b.lazyPage(info.TargetID))
+	}
+	return pageList, nil
+}
+
+// targets returns the page targets of the browser context
+func (b *Browser) targets(all bool) ([]*proto.TargetTargetInfo, error) {
+	res, err := proto.TargetGetTargets{}.Call(b)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*proto.TargetTargetInfo{}
+	for _, info := range res.TargetInfos {
+		if b.BrowserContextID != "" && info.BrowserContextID != b.BrowserContextID {
+			continue
+		}
+
+		switch {
+		case info.Type == "page" && (all || !strings.HasPrefix(info.URL, "devtools://")):
+		case info.Type == "background_page" && all:
+		default:
+			continue
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
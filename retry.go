@@ -0,0 +1,80 @@
+// This file contains the config of the retries, such as the backoff of the sleepers of the waits,
+// and the stats of the retries that are reported by the timeout errors.
+
+package rod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ysmood/kit"
+)
+
+// backoff is the config of the kit.BackoffSleeper
+type backoff struct {
+	init      time.Duration
+	max       time.Duration
+	algorithm func(time.Duration) time.Duration
+}
+
+func (b *backoff) sleeper() kit.Sleeper {
+	return kit.BackoffSleeper(b.init, b.max, b.algorithm)
+}
+
+var (
+	// the default of the Page.Sleeper
+	defaultBackoff = &backoff{100 * time.Millisecond, time.Second, nil}
+
+	// the default of the retries of the eval when the js context is invalid, such as a frame is reloading
+	defaultEvalBackoff = &backoff{30 * time.Millisecond, 3 * time.Second, nil}
+)
+
+// Backoff sets the backoff of the Page.Sleeper of the pages, it's used by the waits and the queries of the elements,
+// such as the ElementE, WaitE, and WaitVisibleE. The interval starts from the init and grows via the algorithm until
+// the max, if the algorithm is nil the kit.DefaultBackoff will be used. Such as a smaller init for a fast local server,
+// or a larger max for a slow CI machine. The default is 100ms to 1s.
+func (b *Browser) Backoff(init, max time.Duration, algorithm func(time.Duration) time.Duration) *Browser {
+	b.backoff = &backoff{init, max, algorithm}
+	return b
+}
+
+// WithSleeper creates a clone whose retries use the sleepers that the newSleeper creates, including the Page.Sleeper
+// and the retries of the EvalE when the js context is invalid. The newSleeper is called once for each operation,
+// because a sleeper keeps the state of its backoff. It overrides the Backoff of the browser for the clone.
+func (p *Page) WithSleeper(newSleeper func() kit.Sleeper) *Page {
+	newObj := *p
+	newObj.sleeper = newSleeper
+	return &newObj
+}
+
+// evalSleeper returns the sleeper of the retries of the eval
+func (p *Page) evalSleeper() kit.Sleeper {
+	if p.sleeper != nil {
+		return p.sleeper()
+	}
+	return defaultEvalBackoff.sleeper()
+}
+
+// retryStats counts the attempts of a kit.Retry
+type retryStats struct {
+	start  time.Time
+	sleeps int
+}
+
+// countSleeper returns the sleeper that counts the attempts of the retries that use it
+func countSleeper(s kit.Sleeper) (kit.Sleeper, *retryStats) {
+	stats := &retryStats{start: time.Now()}
+	return func(ctx context.Context) error {
+		err := s(ctx)
+		if err == nil {
+			stats.sleeps++
+		}
+		return err
+	}, stats
+}
+
+// String such as "gave up after 14 attempts over 9.8s"
+func (s *retryStats) String() string {
+	return fmt.Sprintf("gave up after %d attempts over %s", s.sleeps+1, time.Since(s.start).Round(100*time.Millisecond))
+}
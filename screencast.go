@@ -0,0 +1,123 @@
+// This file contains the screencast related code of the page.
+
+package rod
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the number of frames that can be buffered for a slow consumer, the later frames will be dropped
+var screencastBuffer = 10
+
+// ScreencastFrame is a frame of the screencast
+type ScreencastFrame struct {
+	// Data is the image of the frame, its format is the Format of the PageStartScreencast
+	Data []byte
+
+	// Timestamp of the frame
+	Timestamp time.Time
+
+	Metadata *proto.PageScreencastFrameMetadata
+}
+
+// ScreencastE starts the screencast of the page, if opts is nil the default options of the browser will be used.
+// Each frame will be acknowledged so that the browser keeps sending new ones, if the consumer of the frames is slow
+// the frames will be dropped rather than blocking. The stop function stops the screencast and closes the frames.
+func (p *Page) ScreencastE(opts *proto.PageStartScreencast) (frames <-chan *ScreencastFrame, stop func() error, err error) {
+	if opts == nil {
+		opts = &proto.PageStartScreencast{}
+	}
+
+	// subscribe before the Page.startScreencast, so that we won't miss any frame
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err = opts.Call(p)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	list := make(chan *ScreencastFrame, screencastBuffer)
+	done := make(chan kit.Nil)
+
+	go func() {
+		defer close(done)
+		defer close(list)
+
+		goob.Each(s, func(msg *cdp.Event) {
+			e := &proto.PageScreencastFrame{}
+			if !Event(msg, e) {
+				return
+			}
+
+			_ = proto.PageScreencastFrameAck{SessionID: e.SessionID}.Call(p)
+
+			frame := &ScreencastFrame{Data: e.Data, Timestamp: time.Now(), Metadata: e.Metadata}
+			if e.Metadata != nil && e.Metadata.Timestamp != nil {
+				frame.Timestamp = e.Metadata.Timestamp.Time
+			}
+
+			select {
+			case list <- frame:
+			default:
+			}
+		})
+	}()
+
+	var once sync.Once
+	var stopErr error
+	return list, func() error {
+		once.Do(func() {
+			stopErr = proto.PageStopScreencast{}.Call(p)
+			cancel()
+			<-done
+		})
+		return stopErr
+	}, nil
+}
+
+// RecordE writes the jpeg screencast frames of the page into the dir, the frames are named by their sequence,
+// such as "00001.jpg". The stop function stops the recording and returns the first error of the writing.
+func (p *Page) RecordE(dir string) (stop func() error, err error) {
+	frames, stopScreencast, err := p.ScreencastE(&proto.PageStartScreencast{
+		Format: proto.PageStartScreencastFormatJpeg,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var writeErr error
+	done := make(chan kit.Nil)
+
+	go func() {
+		defer close(done)
+
+		i := 0
+		for frame := range frames {
+			if writeErr != nil {
+				continue
+			}
+			i++
+			writeErr = kit.OutputFile(filepath.Join(dir, fmt.Sprintf("%05d.jpg", i)), frame.Data, nil)
+		}
+	}()
+
+	return func() error {
+		err := stopScreencast()
+		<-done
+		if writeErr != nil {
+			return writeErr
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,112 @@
+// This file contains the certificate errors and the security state of the page.
+
+package rod
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// securityState is shared by all the clones of a page session
+type securityState struct {
+	lock sync.Mutex
+	last *proto.SecuritySecurityStateChanged
+
+	received chan kit.Nil // closed when the first state is received
+	once     sync.Once
+}
+
+func newSecurityState() *securityState {
+	return &securityState{received: make(chan kit.Nil)}
+}
+
+// watch keeps the last security state until the events are closed, the browser only sends them after
+// the Security domain is enabled
+func (s *securityState) watch(events chan goob.Event) {
+	goob.Each(events, func(msg *cdp.Event) {
+		e := &proto.SecuritySecurityStateChanged{}
+		if Event(msg, e) {
+			s.lock.Lock()
+			s.last = e
+			s.lock.Unlock()
+			s.once.Do(func() { close(s.received) })
+		}
+	})
+}
+
+// IgnoreCertErrorsE makes the page ignore the certificate errors, such as the self-signed certificate of
+// a dev server. Call it before the NavigateE, so that the load of the document will be covered too.
+func (p *Page) IgnoreCertErrorsE(enabled bool) error {
+	err := proto.SecurityEnable{}.Call(p)
+	if err != nil {
+		return err
+	}
+	return proto.SecuritySetIgnoreCertificateErrors{Ignore: enabled}.Call(p)
+}
+
+// HandleCertErrorsE calls the handler for each certificate error of the page until stop is called, if the handler
+// returns true the request will continue, otherwise it will be canceled. Such as to only accept the self-signed
+// certificate of a specific host. Call it before the NavigateE, so that the load of the document will be covered too.
+func (p *Page) HandleCertErrorsE(handler func(e *proto.SecurityCertificateError) (accept bool)) (stop func(), err error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+
+	// subscribe before the override, so that we won't miss any error
+	s := p.event.Subscribe(ctx)
+
+	err = proto.SecurityEnable{}.Call(p)
+	if err == nil {
+		err = proto.SecuritySetOverrideCertificateErrors{Override: true}.Call(p)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		e := &proto.SecurityCertificateError{}
+		if !Event(msg, e) {
+			return
+		}
+
+		action := proto.SecurityCertificateErrorActionCancel
+		if handler(e) {
+			action = proto.SecurityCertificateErrorActionContinue
+		}
+		_ = proto.SecurityHandleCertificateError{EventID: e.EventID, Action: action}.Call(p)
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			_ = proto.SecuritySetOverrideCertificateErrors{Override: false}.Call(p)
+		})
+	}, nil
+}
+
+// SecurityStateE returns the most recent security state of the page, such as whether the page is served over
+// a valid TLS connection, the explanations contain the details of the certificate.
+// It waits for the browser to report the first state if it hasn't been reported yet.
+func (p *Page) SecurityStateE() (*proto.SecuritySecurityStateChanged, error) {
+	err := proto.SecurityEnable{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	s := p.security
+
+	select {
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	case <-s.received:
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.last, nil
+}
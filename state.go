@@ -0,0 +1,150 @@
+// This file contains the snapshot of the state of a page, such as the login state that a long-running scraper
+// persists to restore it into a fresh browser later. The storages are injected before the scripts of the page run,
+// because most apps read them only once when they start.
+
+package rod
+
+import (
+	"fmt"
+
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// PageState is the state of a page that the SnapshotStateE collects, use json.Marshal to persist it
+type PageState struct {
+	URL string `json:"url"`
+
+	// Cookies are the cookies for the urls of the page and its frames, including the httpOnly ones
+	Cookies []*proto.NetworkCookie `json:"cookies"`
+
+	// Origin is the origin of the storages, it's empty if the page has an opaque origin, such as "about:blank"
+	Origin string `json:"origin"`
+
+	LocalStorage   map[string]string `json:"localStorage"`
+	SessionStorage map[string]string `json:"sessionStorage"`
+}
+
+// SnapshotStateE collects the current url, the cookies, and the localStorage and sessionStorage of the current origin
+// of the page. The storages of the other origins, such as the ones of the cross-origin iframes, are not included.
+func (p *Page) SnapshotStateE() (*PageState, error) {
+	info, err := proto.TargetGetTargetInfo{TargetID: p.TargetID}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	urls, err := p.frameURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	cookies, err := p.CookiesE(urls)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &PageState{
+		URL:            info.TargetInfo.URL,
+		Cookies:        cookies,
+		LocalStorage:   map[string]string{},
+		SessionStorage: map[string]string{},
+	}
+
+	res, err := p.evalE(true, "", `() => location.origin`, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.Value.String() == "null" {
+		return state, nil
+	}
+	state.Origin = res.Value.String()
+
+	state.LocalStorage, err = p.LocalStorageE()
+	if err != nil {
+		return nil, err
+	}
+
+	state.SessionStorage, err = p.SessionStorageE()
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// frameURLs returns the urls of the page and its frames, the duplicated ones are removed
+func (p *Page) frameURLs() ([]string, error) {
+	res, err := proto.PageGetFrameTree{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := findFrameTree(res.FrameTree, p.FrameID)
+	if tree == nil {
+		return nil, &Error{nil, ErrFrameDetached, p.FrameID}
+	}
+
+	urls := []string{}
+	has := map[string]bool{}
+	list := []*proto.PageFrameTree{tree}
+	for len(list) > 0 {
+		t := list[0]
+		list = append(list[1:], t.ChildFrames...)
+
+		if !has[t.Frame.URL] {
+			has[t.Frame.URL] = true
+			urls = append(urls, t.Frame.URL)
+		}
+	}
+	return urls, nil
+}
+
+// only the top frame of the same origin gets the items, so that the iframes won't override the changes of the app
+const restoreStorageJS = `(function (origin, local, session) {
+	if (window !== window.top || location.origin !== origin) return
+	for (const k in local) localStorage.setItem(k, local[k])
+	for (const k in session) sessionStorage.setItem(k, session[k])
+})(%s, %s, %s)`
+
+// RestoreStateE restores the state that the SnapshotStateE collects, it works for a page of another browser or
+// an incognito one. It sets the cookies, then navigates to the url of the state and waits for the load event.
+// The storages are set before any script of the new document runs. The expired cookies are skipped.
+func (p *Page) RestoreStateE(s *PageState) error {
+	params, _ := cookieParams(s.Cookies)
+	if len(params) > 0 {
+		err := p.SetCookiesE(params)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.URL == "" {
+		return nil
+	}
+
+	if s.Origin == "" || (len(s.LocalStorage) == 0 && len(s.SessionStorage) == 0) {
+		err := p.NavigateE(s.URL)
+		if err != nil {
+			return err
+		}
+		return p.WaitLoadE()
+	}
+
+	js := fmt.Sprintf(restoreStorageJS,
+		kit.MustToJSON(s.Origin), kit.MustToJSON(s.LocalStorage), kit.MustToJSON(s.SessionStorage))
+	id, err := p.EvalOnNewDocumentE(js)
+	if err != nil {
+		return err
+	}
+
+	err = p.NavigateE(s.URL)
+	if err == nil {
+		err = p.WaitLoadE()
+	}
+
+	// the later navigations of the page, such as a reload, shouldn't reset the storages
+	if e := p.RemoveScriptE(id); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+// This file contains the evasions that hide the traits of the automated headless browser from the pages.
+// Each evasion is installed via the Page.addScriptToEvaluateOnNewDocument, so it runs before any script of
+// the page, and it's installed into the session of each out-of-process iframe too.
+
+package rod
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// StealthEvasion is an evasion of the Stealth
+type StealthEvasion string
+
+const (
+	// StealthWebdriver makes the navigator.webdriver false like a normal browser
+	StealthWebdriver StealthEvasion = "webdriver"
+
+	// StealthPlugins fakes the navigator.plugins and navigator.mimeTypes of the pdf viewer if they are empty
+	StealthPlugins StealthEvasion = "plugins"
+
+	// StealthLanguages makes the navigator.languages ["en-US", "en"] if it's empty
+	StealthLanguages StealthEvasion = "languages"
+
+	// StealthPermissions makes the permissions.query of the notifications consistent with the Notification.permission
+	StealthPermissions StealthEvasion = "permissions"
+
+	// StealthWebGL overrides the unmasked vendor and renderer of the WebGL, headless uses "Google SwiftShader"
+	StealthWebGL StealthEvasion = "webgl"
+
+	// StealthChromeRuntime fakes the window.chrome.runtime that the headless browser doesn't have
+	StealthChromeRuntime StealthEvasion = "chrome.runtime"
+
+	// StealthUserAgent removes the "Headless" from the user agent, and makes the navigator.platform and
+	// the Accept-Language consistent with it, the SetUserAgentE will keep them consistent too
+	StealthUserAgent StealthEvasion = "user-agent"
+)
+
+// StealthEvasions are all the evasions in the order they are installed
+var StealthEvasions = []StealthEvasion{
+	StealthWebdriver,
+	StealthPlugins,
+	StealthLanguages,
+	StealthPermissions,
+	StealthWebGL,
+	StealthChromeRuntime,
+	StealthUserAgent,
+}
+
+// the scripts of the evasions, each one is isolated so that a broken one won't affect the others
+var stealthScripts = map[StealthEvasion]string{
+	StealthWebdriver: `
+		Object.defineProperty(Navigator.prototype, 'webdriver', { get: () => false, configurable: true })
+	`,
+
+	StealthPlugins: `
+		if (navigator.plugins.length > 0) return
+
+		const mimeTypes = Object.create(MimeTypeArray.prototype)
+		const plugins = Object.create(PluginArray.prototype)
+
+		const names = ['Chrome PDF Plugin', 'Chrome PDF Viewer', 'Native Client']
+		names.forEach((name, i) => {
+			const plugin = Object.create(Plugin.prototype)
+			const mimeType = Object.create(MimeType.prototype)
+			const define = (obj, props) => Object.keys(props).forEach(k =>
+				Object.defineProperty(obj, k, { get: () => props[k] }))
+
+			define(mimeType, { type: 'application/pdf', suffixes: 'pdf', description: '', enabledPlugin: plugin })
+			define(plugin, { name, filename: 'internal-pdf-viewer', description: 'Portable Document Format', length: 1 })
+			plugin[0] = mimeType
+			plugin.item = (i) => plugin[i]
+			plugin.namedItem = (n) => n === mimeType.type ? mimeType : null
+
+			plugins[i] = plugin
+			plugins[name] = plugin
+			mimeTypes[i] = mimeType
+		})
+
+		const list = (obj, length) => {
+			Object.defineProperty(obj, 'length', { get: () => length })
+			obj.item = (i) => obj[i] || null
+			obj.namedItem = (n) => obj[n] || null
+			obj.refresh = () => {}
+		}
+		list(plugins, names.length)
+		list(mimeTypes, names.length)
+
+		Object.defineProperty(Navigator.prototype, 'plugins', { get: () => plugins, configurable: true })
+		Object.defineProperty(Navigator.prototype, 'mimeTypes', { get: () => mimeTypes, configurable: true })
+	`,
+
+	StealthLanguages: `
+		if (navigator.languages && navigator.languages.length > 0) return
+		Object.defineProperty(Navigator.prototype, 'languages', { get: () => ['en-US', 'en'], configurable: true })
+	`,
+
+	StealthPermissions: `
+		if (!window.navigator.permissions) return
+		const query = Permissions.prototype.query
+		Permissions.prototype.query = function (desc) {
+			if (desc && desc.name === 'notifications') {
+				return Promise.resolve(Object.setPrototypeOf({ state: Notification.permission }, PermissionStatus.prototype))
+			}
+			return query.call(this, desc)
+		}
+	`,
+
+	StealthWebGL: `
+		const patch = (ctx) => {
+			if (!ctx) return
+			const getParameter = ctx.prototype.getParameter
+			ctx.prototype.getParameter = function (p) {
+				if (p === 37445) return 'Intel Inc.' // UNMASKED_VENDOR_WEBGL
+				if (p === 37446) return 'Intel Iris OpenGL Engine' // UNMASKED_RENDERER_WEBGL
+				return getParameter.call(this, p)
+			}
+		}
+		patch(window.WebGLRenderingContext)
+		patch(window.WebGL2RenderingContext)
+	`,
+
+	StealthChromeRuntime: `
+		if (!window.chrome) {
+			Object.defineProperty(window, 'chrome', { value: {}, writable: true, configurable: true })
+		}
+		if (window.chrome.runtime) return
+		window.chrome.runtime = {
+			connect: () => ({ onMessage: { addListener () {} }, postMessage () {}, disconnect () {} }),
+			sendMessage () {}
+		}
+	`,
+}
+
+// Stealth enables/disables all the StealthEvasions for the pages created after it, use StealthWith to choose the
+// evasions, such as disable a broken one
+func (b *Browser) Stealth(enable bool) *Browser {
+	if enable {
+		return b.StealthWith(StealthEvasions...)
+	}
+	return b.StealthWith()
+}
+
+// StealthWith enables the evasions for the pages created after it, the others will be disabled
+func (b *Browser) StealthWith(evasions ...StealthEvasion) *Browser {
+	b.stealth = map[StealthEvasion]bool{}
+	for _, e := range evasions {
+		b.stealth[e] = true
+	}
+	return b
+}
+
+// installStealth installs the enabled evasions into the session
+func (p *Page) installStealth() error {
+	if len(p.browser.stealth) == 0 {
+		return nil
+	}
+
+	list := []string{}
+	for _, e := range StealthEvasions {
+		if js, has := stealthScripts[e]; has && p.browser.stealth[e] {
+			list = append(list, "try {(() => {"+js+"})()} catch (e) {}")
+		}
+	}
+
+	if len(list) > 0 {
+		_, err := proto.PageAddScriptToEvaluateOnNewDocument{Source: strings.Join(list, "\n")}.Call(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !p.browser.stealth[StealthUserAgent] {
+		return nil
+	}
+
+	v, err := p.browser.VersionE()
+	if err != nil {
+		return err
+	}
+
+	return p.SetUserAgentE(&proto.NetworkSetUserAgentOverride{
+		UserAgent: strings.Replace(v.UserAgent, "HeadlessChrome", "Chrome", 1),
+	})
+}
+
+var regStealthPlatform = regexp.MustCompile(`Windows|Macintosh|Android|Linux`)
+
+// stealthUserAgent fills the platform and the accept language of the req to make them consistent with
+// the user agent, if the StealthUserAgent is enabled
+func (p *Page) stealthUserAgent(req *proto.NetworkSetUserAgentOverride) {
+	if !p.browser.stealth[StealthUserAgent] {
+		return
+	}
+
+	if req.Platform == "" {
+		switch regStealthPlatform.FindString(req.UserAgent) {
+		case "Windows":
+			req.Platform = "Win32"
+		case "Macintosh":
+			req.Platform = "MacIntel"
+		case "Android":
+			req.Platform = "Linux armv8l"
+		case "Linux":
+			req.Platform = "Linux x86_64"
+		}
+	}
+
+	if req.AcceptLanguage == "" && p.browser.stealth[StealthLanguages] {
+		req.AcceptLanguage = "en-US,en"
+	}
+}
@@ -0,0 +1,137 @@
+// This file contains the helpers of the web storages, such as the localStorage and the IndexedDB.
+
+package rod
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the storage access throws for the sandboxed iframes and the opaque origins, such as the data urls,
+// the error is returned as a value, so that it won't be treated as a broken js
+const storageJS = `function (name, op, key, value) {
+	let storage
+	try {
+		storage = window[name]
+	} catch (e) {
+		return { error: e.message }
+	}
+	if (!storage) return { error: name + ' is not available' }
+
+	switch (op) {
+		case 'set':
+			storage.setItem(key, value)
+			return {}
+		case 'clear':
+			storage.clear()
+			return {}
+	}
+
+	const items = {}
+	for (let i = 0; i < storage.length; i++) {
+		const k = storage.key(i)
+		items[k] = storage.getItem(k)
+	}
+	return { items }
+}`
+
+func (p *Page) storage(name, op, key, value string) (map[string]string, error) {
+	res, err := p.evalE(true, "", storageJS, Array{name, op, key, value})
+	if err != nil {
+		return nil, err
+	}
+
+	if msg := res.Value.Get("error"); msg.Exists() {
+		return nil, &Error{nil, ErrStorageAccess, msg.String()}
+	}
+
+	items := map[string]string{}
+	for k, v := range res.Value.Get("items").Map() {
+		items[k] = v.String()
+	}
+	return items, nil
+}
+
+// LocalStorageE returns the items of the localStorage of the page's current origin.
+// If the page can't access the storage, such as a sandboxed iframe, an ErrStorageAccess error will be returned.
+func (p *Page) LocalStorageE() (map[string]string, error) {
+	return p.storage("localStorage", "get", "", "")
+}
+
+// SetLocalStorageE sets the item of the localStorage of the page's current origin
+func (p *Page) SetLocalStorageE(key, value string) error {
+	_, err := p.storage("localStorage", "set", key, value)
+	return err
+}
+
+// SessionStorageE returns the items of the sessionStorage of the page's current origin
+func (p *Page) SessionStorageE() (map[string]string, error) {
+	return p.storage("sessionStorage", "get", "", "")
+}
+
+// SetSessionStorageE sets the item of the sessionStorage of the page's current origin
+func (p *Page) SetSessionStorageE(key, value string) error {
+	_, err := p.storage("sessionStorage", "set", key, value)
+	return err
+}
+
+// ClearStorageE clears the storages of the types for the page's current origin, such as the local_storage and the
+// indexeddb, if types is empty all of them will be cleared. The sessionStorage isn't one of the types, because the
+// browser keeps it per tab, it will be cleared with the local_storage in the current document.
+func (p *Page) ClearStorageE(types ...proto.StorageStorageType) error {
+	res, err := p.evalE(true, "", `() => location.origin`, nil)
+	if err != nil {
+		return err
+	}
+
+	origin := res.Value.String()
+	if origin == "null" {
+		return &Error{nil, ErrStorageAccess, "the page has an opaque origin"}
+	}
+
+	if len(types) == 0 {
+		types = []proto.StorageStorageType{proto.StorageStorageTypeAll}
+	}
+
+	list := []string{}
+	clearSession := false
+	for _, t := range types {
+		list = append(list, string(t))
+		clearSession = clearSession || t == proto.StorageStorageTypeAll || t == proto.StorageStorageTypeLocalStorage
+	}
+
+	err = proto.StorageClearDataForOrigin{
+		Origin:       origin,
+		StorageTypes: strings.Join(list, ","),
+	}.Call(p)
+	if err != nil || !clearSession {
+		return err
+	}
+
+	_, err = p.storage("sessionStorage", "clear", "", "")
+	return err
+}
+
+// ClearAllStorageE clears all the storages of the origin of the page's url, such as the cookies, the storages,
+// and the service workers. Unlike the ClearStorageE it doesn't run js in the page, the origin is from the target info.
+func (p *Page) ClearAllStorageE() error {
+	info, err := proto.TargetGetTargetInfo{TargetID: p.TargetID}.Call(p)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(info.TargetInfo.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &Error{nil, ErrStorageAccess, "the page has an opaque origin: " + info.TargetInfo.URL}
+	}
+
+	return proto.StorageClearDataForOrigin{
+		Origin:       u.Scheme + "://" + u.Host,
+		StorageTypes: string(proto.StorageStorageTypeAll),
+	}.Call(p)
+}
@@ -4,24 +4,27 @@ package rod
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/devices"
 	"github.com/ysmood/rod/lib/proto"
 )
 
 // Connect to the browser and start to control it.
 // If fails to connect, try to run a local browser, if local browser not found try to download one.
 func (b *Browser) Connect() *Browser {
-	kit.E(b.ConnectE())
+	mustCall("Browser.ConnectE", Array{}, b.ConnectE())
 	return b
 }
 
 // Close the browser and release related resources
 func (b *Browser) Close() {
-	kit.E(b.CloseE())
+	mustCall("Browser.CloseE", Array{}, b.CloseE())
 }
 
 // EachEvent of the specified event type, if the fn returns true the event loop will stop.
@@ -37,28 +40,95 @@ func (b *Browser) EachEvent() func(fn interface{}) {
 // Incognito creates a new incognito browser
 func (b *Browser) Incognito() *Browser {
 	b, err := b.IncognitoE()
-	kit.E(err)
+	mustCall("Browser.IncognitoE", Array{}, err)
 	return b
 }
 
+// NewContext creates a new browser context, the cookies and storages are isolated from the other contexts
+func (b *Browser) NewContext() *Browser {
+	b, err := b.NewContextE()
+	mustCall("Browser.NewContextE", Array{}, err)
+	return b
+}
+
+// CloseContext disposes the browser context and closes all the pages of it
+func (b *Browser) CloseContext() {
+	mustCall("Browser.CloseContextE", Array{}, b.CloseContextE())
+}
+
+// GrantPermissions grants the permissions to the origin, use "" for all the origins
+func (b *Browser) GrantPermissions(origin string, permissions ...proto.BrowserPermissionType) *Browser {
+	mustCall("Browser.GrantPermissionsE", Array{origin, permissions}, b.GrantPermissionsE(origin, permissions))
+	return b
+}
+
+// Version returns the version info of the browser
+func (b *Browser) Version() *proto.BrowserGetVersionResult {
+	v, err := b.VersionE()
+	mustCall("Browser.VersionE", Array{}, err)
+	return v
+}
+
+// Supports returns true if the browser supports the capability
+func (b *Browser) Supports(c Capability) bool {
+	ok, err := b.SupportsE(c)
+	mustCall("Browser.SupportsE", Array{c}, err)
+	return ok
+}
+
+// RecordEvents writes every cdp event the browser receives to w as json lines,
+// the string values of the params that are longer than the paramsLimit will be truncated if it's greater than 0
+func (b *Browser) RecordEvents(w io.Writer, paramsLimit int) (stop func()) {
+	s := b.RecordEventsE(w, paramsLimit)
+	return func() {
+		mustCall("Browser.RecordEventsE", Array{w, paramsLimit}, s())
+	}
+}
+
+// CloseAllPages closes all the pages of the browser and waits for their targets to be destroyed
+func (b *Browser) CloseAllPages() {
+	mustCall("Browser.CloseAllPagesE", Array{}, b.CloseAllPagesE())
+}
+
 // Page creates a new tab
 func (b *Browser) Page(url string) *Page {
 	p, err := b.PageE(url)
-	kit.E(err)
+	mustCall("Browser.PageE", Array{url}, err)
 	return p
 }
 
+// EachPage calls the handler with every existing and new page of the browser
+func (b *Browser) EachPage(handler func(*Page)) (cancel func()) {
+	cancel, err := b.EachPageE(handler)
+	mustCall("Browser.EachPageE", Array{handler}, err)
+	return cancel
+}
+
 // Pages returns all visible pages
 func (b *Browser) Pages() Pages {
 	list, err := b.PagesE()
-	kit.E(err)
+	mustCall("Browser.PagesE", Array{}, err)
 	return list
 }
 
+// AllPages returns all the pages, including the devtools pages and the background pages of the extensions
+func (b *Browser) AllPages() Pages {
+	list, err := b.AllPagesE()
+	mustCall("Browser.AllPagesE", Array{}, err)
+	return list
+}
+
+// FindPage returns the first page whose url or title matches the regexp
+func (b *Browser) FindPage(urlRegex string) *Page {
+	p, err := b.FindPageE(urlRegex)
+	mustCall("Browser.FindPageE", Array{urlRegex}, err)
+	return p
+}
+
 // PageFromTargetID creates a Page instance from a targetID
 func (b *Browser) PageFromTargetID(targetID proto.TargetTargetID) *Page {
 	p, err := b.PageFromTargetIDE(targetID)
-	kit.E(err)
+	mustCall("Browser.PageFromTargetIDE", Array{targetID}, err)
 	return p
 }
 
@@ -67,14 +137,27 @@ func (b *Browser) PageFromTargetID(targetID proto.TargetTargetID) *Page {
 // Ref: https://developer.mozilla.org/en-US/docs/Web/HTTP/Authentication
 func (b *Browser) HandleAuth(username, password string) {
 	wait, err := b.HandleAuthE(username, password)
-	kit.E(err)
-	go func() { kit.E(wait()) }()
+	mustCall("Browser.HandleAuthE", Array{username, password}, err)
+	go func() { mustCall("Browser.HandleAuthE", Array{username, password}, wait()) }()
+}
+
+// Get a page from the pool, it blocks until a page is available or the ctx is done.
+// The ctx is also the context of the returned page, use Put to return the page to the pool.
+func (pp *PagePool) Get(ctx context.Context) *Page {
+	p, err := pp.GetE(ctx)
+	mustCall("PagePool.GetE", Array{ctx}, err)
+	return p
+}
+
+// Close the idle pages of the pool
+func (pp *PagePool) Close() {
+	mustCall("PagePool.CloseE", Array{}, pp.CloseE())
 }
 
 // FindByURL returns the page that has the url that matches the regex
 func (ps Pages) FindByURL(regex string) *Page {
 	p, err := ps.FindByURLE(regex)
-	kit.E(err)
+	mustCall("Pages.FindByURLE", Array{regex}, err)
 	return p
 }
 
@@ -82,107 +165,284 @@ func (ps Pages) FindByURL(regex string) *Page {
 // The urls is the list of URLs for which applicable cookies will be fetched.
 func (p *Page) Cookies(urls ...string) []*proto.NetworkCookie {
 	cookies, err := p.CookiesE(urls)
-	kit.E(err)
+	mustCall("Page.CookiesE", Array{urls}, err)
 	return cookies
 }
 
 // SetCookies of the page.
 // Cookie format: https://chromedevtools.github.io/devtools-protocol/tot/Network#method-setCookie
 func (p *Page) SetCookies(cookies ...*proto.NetworkCookieParam) *Page {
-	kit.E(p.SetCookiesE(cookies))
+	mustCall("Page.SetCookiesE", Array{cookies}, p.SetCookiesE(cookies))
+	return p
+}
+
+// SaveCookies writes the cookies of the current page to w as json
+func (p *Page) SaveCookies(w io.Writer) *Page {
+	mustCall("Page.SaveCookiesE", Array{w}, p.SaveCookiesE(w))
+	return p
+}
+
+// LoadCookies reads the cookies that are saved by SaveCookies from r, the expired ones will be skipped
+func (p *Page) LoadCookies(r io.Reader) *Page {
+	_, err := p.LoadCookiesE(r)
+	mustCall("Page.LoadCookiesE", Array{r}, err)
+	return p
+}
+
+// SnapshotState collects the url, the cookies, and the storages of the current origin of the page
+func (p *Page) SnapshotState() *PageState {
+	s, err := p.SnapshotStateE()
+	mustCall("Page.SnapshotStateE", Array{}, err)
+	return s
+}
+
+// RestoreState restores the state that the SnapshotState collects, then navigates to the url of it
+func (p *Page) RestoreState(s *PageState) *Page {
+	mustCall("Page.RestoreStateE", Array{s}, p.RestoreStateE(s))
 	return p
 }
 
 // SetExtraHeaders whether to always send extra HTTP headers with the requests from this page.
 // The arguments are key-value pairs, you can set multiple key-value pairs at the same time.
 func (p *Page) SetExtraHeaders(dict ...string) *Page {
-	kit.E(p.SetExtraHeadersE(dict))
+	mustCall("Page.SetExtraHeadersE", Array{dict}, p.SetExtraHeadersE(dict))
+	return p
+}
+
+// SetExtraHeadersForHosts sends the extra headers only with the requests to the hosts.
+// The arguments are key-value pairs, you can set multiple key-value pairs at the same time.
+func (p *Page) SetExtraHeadersForHosts(hosts []string, dict ...string) *Page {
+	mustCall("Page.SetExtraHeadersForHostsE", Array{hosts, dict}, p.SetExtraHeadersForHostsE(hosts, dict))
+	return p
+}
+
+// RemoveExtraHeadersForHosts removes the extra headers of the hosts
+func (p *Page) RemoveExtraHeadersForHosts(hosts ...string) *Page {
+	mustCall("Page.RemoveExtraHeadersForHostsE", Array{hosts}, p.RemoveExtraHeadersForHostsE(hosts))
+	return p
+}
+
+// SetProxy sends the requests of the page through the proxy, such as "http://127.0.0.1:8080", auth is optional.
+// Use an empty proxyURL to restore the normal networking.
+func (p *Page) SetProxy(proxyURL string, auth *ProxyAuth) *Page {
+	mustCall("Page.SetProxyE", Array{proxyURL, auth}, p.SetProxyE(proxyURL, auth))
 	return p
 }
 
 // SetUserAgent Allows overriding user agent with the given string.
 func (p *Page) SetUserAgent(req *proto.NetworkSetUserAgentOverride) *Page {
-	kit.E(p.SetUserAgentE(req))
+	mustCall("Page.SetUserAgentE", Array{req}, p.SetUserAgentE(req))
 	return p
 }
 
 // Navigate to url
 func (p *Page) Navigate(url string) *Page {
-	kit.E(p.NavigateE(url))
+	mustCall("Page.NavigateE", Array{url}, p.NavigateE(url))
+	return p
+}
+
+// WaitFrameNavigated returns a wait function that waits until the frame of the page navigates,
+// the wait function returns the new url of the frame
+func (p *Page) WaitFrameNavigated() (wait func() string) {
+	w := p.WaitFrameNavigatedE()
+	return func() string {
+		url, err := w()
+		mustCall("Page.WaitFrameNavigatedE", Array{}, err)
+		return url
+	}
+}
+
+// NavigateBlank navigates to "about:blank"
+func (p *Page) NavigateBlank() *Page {
+	mustCall("Page.NavigateBlankE", Array{}, p.NavigateBlankE())
+	return p
+}
+
+// SetDocumentContent replaces the document of the frame with the html, it returns after the content is loaded
+func (p *Page) SetDocumentContent(html string) *Page {
+	mustCall("Page.SetDocumentContentE", Array{html}, p.SetDocumentContentE(html))
+	return p
+}
+
+// NavigateBack to the previous entry of the history
+func (p *Page) NavigateBack() *Page {
+	mustCall("Page.NavigateBackE", Array{}, p.NavigateBackE())
+	return p
+}
+
+// NavigateForward to the next entry of the history
+func (p *Page) NavigateForward() *Page {
+	mustCall("Page.NavigateForwardE", Array{}, p.NavigateForwardE())
 	return p
 }
 
 // GetWindow get window bounds
 func (p *Page) GetWindow() *proto.BrowserBounds {
 	bounds, err := p.GetWindowE()
-	kit.E(err)
+	mustCall("Page.GetWindowE", Array{}, err)
 	return bounds
 }
 
 // Window set the window location and size
 func (p *Page) Window(left, top, width, height int64) *Page {
-	kit.E(p.WindowE(&proto.BrowserBounds{
+	bounds := &proto.BrowserBounds{
 		Left:        left,
 		Top:         top,
 		Width:       width,
 		Height:      height,
 		WindowState: proto.BrowserWindowStateNormal,
-	}))
+	}
+	mustCall("Page.WindowE", Array{bounds}, p.WindowE(bounds))
 	return p
 }
 
 // WindowMinimize the window
 func (p *Page) WindowMinimize() *Page {
-	kit.E(p.WindowE(&proto.BrowserBounds{
-		WindowState: proto.BrowserWindowStateMinimized,
-	}))
+	mustCall("Page.WindowMinimizeE", Array{}, p.WindowMinimizeE())
 	return p
 }
 
 // WindowMaximize the window
 func (p *Page) WindowMaximize() *Page {
-	kit.E(p.WindowE(&proto.BrowserBounds{
-		WindowState: proto.BrowserWindowStateMaximized,
-	}))
+	mustCall("Page.WindowMaximizeE", Array{}, p.WindowMaximizeE())
 	return p
 }
 
 // WindowFullscreen the window
 func (p *Page) WindowFullscreen() *Page {
-	kit.E(p.WindowE(&proto.BrowserBounds{
-		WindowState: proto.BrowserWindowStateFullscreen,
-	}))
+	mustCall("Page.WindowFullscreenE", Array{}, p.WindowFullscreenE())
 	return p
 }
 
 // WindowNormal the window size
 func (p *Page) WindowNormal() *Page {
-	kit.E(p.WindowE(&proto.BrowserBounds{
-		WindowState: proto.BrowserWindowStateNormal,
-	}))
+	mustCall("Page.WindowNormalE", Array{}, p.WindowNormalE())
+	return p
+}
+
+// Activate the page, it will bring the tab to front and focus it
+func (p *Page) Activate() *Page {
+	mustCall("Page.ActivateE", Array{}, p.ActivateE())
 	return p
 }
 
 // Viewport overrides the values of device screen dimensions.
 func (p *Page) Viewport(width, height int64, deviceScaleFactor float64, mobile bool) *Page {
-	kit.E(p.ViewportE(&proto.EmulationSetDeviceMetricsOverride{
+	params := &proto.EmulationSetDeviceMetricsOverride{
 		Width:             width,
 		Height:            height,
 		DeviceScaleFactor: deviceScaleFactor,
 		Mobile:            mobile,
-	}))
+	}
+	mustCall("Page.ViewportE", Array{params}, p.ViewportE(params))
+	return p
+}
+
+// EmulateDevice emulates the viewport, user agent, and touch support of the device.
+// Use devices.Clear to clear the emulation.
+func (p *Page) EmulateDevice(device devices.Device) *Page {
+	mustCall("Page.EmulateDeviceE", Array{device}, p.EmulateDeviceE(device))
+	return p
+}
+
+// EmulateGeolocation overrides the geolocation of the page, and grants the geolocation permission if grant is true
+func (p *Page) EmulateGeolocation(lat, lon, accuracy float64, grant bool) *Page {
+	mustCall("Page.EmulateGeolocationE", Array{lat, lon, accuracy, grant}, p.EmulateGeolocationE(lat, lon, accuracy, grant))
+	return p
+}
+
+// ClearGeolocation clears the geolocation override of the page, and resets the geolocation permission
+func (p *Page) ClearGeolocation() *Page {
+	mustCall("Page.ClearGeolocationE", Array{}, p.ClearGeolocationE())
+	return p
+}
+
+// EmulateTimezone overrides the timezone of the page, use "" to restore the default
+func (p *Page) EmulateTimezone(tzID string) *Page {
+	mustCall("Page.EmulateTimezoneE", Array{tzID}, p.EmulateTimezoneE(tzID))
+	return p
+}
+
+// EmulateLocale overrides the locale of the page, use "" to restore the default
+func (p *Page) EmulateLocale(locale string) *Page {
+	mustCall("Page.EmulateLocaleE", Array{locale}, p.EmulateLocaleE(locale))
+	return p
+}
+
+// ClipboardText reads the text from the clipboard, the page will be focused
+func (p *Page) ClipboardText() string {
+	text, err := p.ClipboardTextE()
+	mustCall("Page.ClipboardTextE", Array{}, err)
+	return text
+}
+
+// SetClipboardText writes the text to the clipboard, the page will be focused
+func (p *Page) SetClipboardText(text string) *Page {
+	mustCall("Page.SetClipboardTextE", Array{text}, p.SetClipboardTextE(text))
+	return p
+}
+
+// StartHAR starts to record the network activities of the page, the stop function returns the recorded HAR
+func (p *Page) StartHAR() (stop func() *HAR) {
+	s, err := p.StartHARE()
+	mustCall("Page.StartHARE", Array{}, err)
+	return func() *HAR {
+		har, err := s()
+		mustCall("Page.StartHARE", Array{}, err)
+		return har
+	}
+}
+
+// EmulateNetwork throttles the network of the page, such as rod.Slow3G, use nil to reset to no throttling
+func (p *Page) EmulateNetwork(conditions *NetworkConditions) *Page {
+	mustCall("Page.EmulateNetworkE", Array{conditions}, p.EmulateNetworkE(conditions))
+	return p
+}
+
+// SetOffline emulates the internet disconnection of the page
+func (p *Page) SetOffline(offline bool) *Page {
+	mustCall("Page.SetOfflineE", Array{offline}, p.SetOfflineE(offline))
+	return p
+}
+
+// Reload the page, use ReloadE to ignore the cache or inject scripts
+func (p *Page) Reload() *Page {
+	mustCall("Page.ReloadAndWaitLoadE", Array{}, p.ReloadAndWaitLoadE())
+	return p
+}
+
+// DisableCache disables/enables the http cache of the page
+func (p *Page) DisableCache(disabled bool) *Page {
+	mustCall("Page.DisableCacheE", Array{disabled}, p.DisableCacheE(disabled))
+	return p
+}
+
+// ClearBrowserCache clears the http cache of the browser
+func (p *Page) ClearBrowserCache() *Page {
+	mustCall("Page.ClearBrowserCacheE", Array{}, p.ClearBrowserCacheE())
+	return p
+}
+
+// ClearBrowserCookies clears the cookies of the browser
+func (p *Page) ClearBrowserCookies() *Page {
+	mustCall("Page.ClearBrowserCookiesE", Array{}, p.ClearBrowserCookiesE())
 	return p
 }
 
 // StopLoading forces the page stop all navigations and pending resource fetches.
 func (p *Page) StopLoading() *Page {
-	kit.E(p.StopLoadingE())
+	mustCall("Page.StopLoadingE", Array{}, p.StopLoadingE())
 	return p
 }
 
 // Close page
 func (p *Page) Close() {
-	kit.E(p.CloseE())
+	mustCall("Page.CloseE", Array{}, p.CloseE())
+}
+
+// WaitClose waits until the target of the page is destroyed
+func (p *Page) WaitClose() {
+	mustCall("Page.WaitCloseE", Array{}, p.WaitCloseE())
 }
 
 // EachEvent of the specified event type, if the fn returns true the event loop will stop.
@@ -199,7 +459,39 @@ func (p *Page) EachEvent() func(fn interface{}) {
 func (p *Page) HandleDialog(accept bool, promptText string) (wait func()) {
 	w := p.HandleDialogE(accept, promptText)
 	return func() {
-		kit.E(w())
+		mustCall("Page.HandleDialogE", Array{accept, promptText}, w())
+	}
+}
+
+// HandleFileDialog sets the files of the next file chooser the page opens, call it before the action that opens it
+func (p *Page) HandleFileDialog(paths ...string) (wait func()) {
+	w := p.HandleFileDialogE(paths)
+	return func() {
+		mustCall("Page.HandleFileDialogE", Array{paths}, w())
+	}
+}
+
+// EachDialog answers every javascript dialog of the page with the result of the handler until stop is called.
+// Such as return e.Type == proto.PageDialogTypeBeforeunload to only allow the navigations.
+func (p *Page) EachDialog(handler func(*proto.PageJavascriptDialogOpening) (accept bool, text string)) (stop func()) {
+	stop, err := p.EachDialogE(handler)
+	mustCall("Page.EachDialogE", Array{handler}, err)
+	return
+}
+
+// AutoHandleDialogs answers every javascript dialog of the page until stop is called,
+// so that an unexpected alert won't block the page forever
+func (p *Page) AutoHandleDialogs(accept bool, promptText string) (stop func()) {
+	stop, err := p.AutoHandleDialogsE(accept, promptText)
+	mustCall("Page.AutoHandleDialogsE", Array{accept, promptText}, err)
+	return
+}
+
+// HandleAuth answers the next http basic or proxy auth challenges with the username and password
+func (p *Page) HandleAuth(username, password string) (wait func()) {
+	w := p.HandleAuthE(username, password)
+	return func() {
+		mustCall("Page.HandleAuthE", Array{username, password}, w())
 	}
 }
 
@@ -207,34 +499,99 @@ func (p *Page) HandleDialog(accept bool, promptText string) (wait func()) {
 // Wildcards ('*' -> zero or more, '?' -> exactly one) are allowed. Escape character is backslash. Omitting is equivalent to "*".
 func (p *Page) GetDownloadFile(pattern string) (wait func() (http.Header, []byte)) {
 	w, err := p.GetDownloadFileE(filepath.FromSlash("tmp/rod-downloads"), pattern)
-	kit.E(err)
+	mustCall("Page.GetDownloadFileE", Array{filepath.FromSlash("tmp/rod-downloads"), pattern}, err)
 	return func() (http.Header, []byte) {
 		header, data, err := w()
-		kit.E(err)
+		mustCall("Page.GetDownloadFileE", Array{filepath.FromSlash("tmp/rod-downloads"), pattern}, err)
 		return header, data
 	}
 }
 
+// ScrollToBottom keeps scrolling the page to the bottom until the height of the page stops growing
+func (p *Page) ScrollToBottom() *Page {
+	mustCall("Page.ScrollToBottomE", Array{300 * time.Millisecond}, p.ScrollToBottomE(300*time.Millisecond))
+	return p
+}
+
+// Layout returns the layout of the page in css pixels
+func (p *Page) Layout() *Layout {
+	layout, err := p.LayoutE()
+	mustCall("Page.LayoutE", Array{}, err)
+	return layout
+}
+
+// ScrollTo scrolls the document to the position in css pixels
+func (p *Page) ScrollTo(x, y float64) *Page {
+	mustCall("Page.ScrollToE", Array{x, y}, p.ScrollToE(x, y))
+	return p
+}
+
+// ScrollBy scrolls the document by the offset in css pixels
+func (p *Page) ScrollBy(dx, dy float64) *Page {
+	mustCall("Page.ScrollByE", Array{dx, dy}, p.ScrollByE(dx, dy))
+	return p
+}
+
+// WaitDownload returns a wait function that waits for the next download of the page, and returns the path of the file
+func (p *Page) WaitDownload() (wait func() string) {
+	w := p.WaitDownloadE(filepath.FromSlash("tmp/rod-downloads"))
+	return func() string {
+		path, err := w()
+		mustCall("Page.WaitDownloadE", Array{filepath.FromSlash("tmp/rod-downloads")}, err)
+		return path
+	}
+}
+
+// HijackRequests intercepts the requests whose url matches the pattern, call the stop function to disable it.
+// Wildcards ('*' -> zero or more, '?' -> exactly one) are allowed. Escape character is backslash.
+func (p *Page) HijackRequests(pattern string, handler func(*HijackContext) error) (stop func()) {
+	s, err := p.HijackRequestsE(pattern, handler)
+	mustCall("Page.HijackRequestsE", Array{pattern, handler}, err)
+	return func() { mustCall("Page.HijackRequestsE", Array{pattern, handler}, s()) }
+}
+
 // Screenshot the page and returns the binary of the image
 // If the toFile is "", it will save output to "tmp/screenshots" folder, time as the file name.
 func (p *Page) Screenshot(toFile ...string) []byte {
 	bin, err := p.ScreenshotE(false, &proto.PageCaptureScreenshot{})
-	kit.E(err)
-	kit.E(saveScreenshot(bin, toFile))
+	mustCall("Page.ScreenshotE", Array{false, &proto.PageCaptureScreenshot{}}, err)
+	mustCall("saveScreenshot", Array{toFile}, saveScreenshot(bin, toFile))
 	return bin
 }
 
 // ScreenshotFullPage including all scrollable content and returns the binary of the image.
 func (p *Page) ScreenshotFullPage(toFile ...string) []byte {
 	bin, err := p.ScreenshotE(true, &proto.PageCaptureScreenshot{})
-	kit.E(err)
+	mustCall("Page.ScreenshotE", Array{true, &proto.PageCaptureScreenshot{}}, err)
 	return bin
 }
 
+// ScreenshotArea captures the area of the page and returns the binary of the image,
+// x and y are the CSS pixels relative to the document.
+func (p *Page) ScreenshotArea(x, y, width, height float64) []byte {
+	bin, err := p.ScreenshotAreaE(x, y, width, height, &proto.PageCaptureScreenshot{})
+	mustCall("Page.ScreenshotAreaE", Array{x, y, width, height, &proto.PageCaptureScreenshot{}}, err)
+	return bin
+}
+
+// PDFStream prints page as PDF and returns a reader of it, the data will be read
+// from the browser lazily. Remember to close the reader after use.
+func (p *Page) PDFStream() io.ReadCloser {
+	r, err := p.PDFStreamE(&proto.PagePrintToPDF{})
+	mustCall("Page.PDFStreamE", Array{&proto.PagePrintToPDF{}}, err)
+	return r
+}
+
+// PDFToWriter prints page as PDF into the w, if opts is nil the defaults of the browser will be used
+func (p *Page) PDFToWriter(w io.Writer, opts *PDFOptions) *Page {
+	mustCall("Page.PDFToWriterE", Array{w, opts}, p.PDFToWriterE(w, opts))
+	return p
+}
+
 // PDF prints page as PDF
 func (p *Page) PDF() []byte {
 	pdf, err := p.PDFE(&proto.PagePrintToPDF{})
-	kit.E(err)
+	mustCall("Page.PDFE", Array{&proto.PagePrintToPDF{}}, err)
 	return pdf
 }
 
@@ -243,14 +600,14 @@ func (p *Page) WaitOpen() (wait func() *Page) {
 	w := p.WaitOpenE()
 	return func() *Page {
 		page, err := w()
-		kit.E(err)
+		mustCall("Page.WaitOpenE", Array{}, err)
 		return page
 	}
 }
 
 // Pause stops on the next JavaScript statement
 func (p *Page) Pause() *Page {
-	kit.E(p.PauseE())
+	mustCall("Page.PauseE", Array{}, p.PauseE())
 	return p
 }
 
@@ -258,30 +615,256 @@ func (p *Page) Pause() *Page {
 // You can pass regular expressions to exclude the requests by their url.
 func (p *Page) WaitRequestIdle(excludes ...string) (wait func()) {
 	w := p.WaitRequestIdleE(300*time.Millisecond, []string{""}, excludes)
-	return func() { kit.E(w()) }
+	return func() { mustCall("Page.WaitRequestIdleE", Array{300 * time.Millisecond, []string{""}, excludes}, w()) }
+}
+
+// WaitNavigation returns a wait function that waits until the next navigation of the frame reaches the lifecycle event
+func (p *Page) WaitNavigation(name proto.PageLifecycleEventName) (wait func()) {
+	w := p.WaitNavigationE(name)
+	return func() { mustCall("Page.WaitNavigationE", Array{name}, w()) }
+}
+
+// WaitResponse returns a wait function that waits for the first response whose url matches the regex,
+// the wait function returns the response and its body.
+func (p *Page) WaitResponse(urlPattern string) (wait func() (*proto.NetworkResponse, []byte)) {
+	w := p.WaitResponseE(urlPattern)
+	return func() (*proto.NetworkResponse, []byte) {
+		res, body, err := w()
+		mustCall("Page.WaitResponseE", Array{urlPattern}, err)
+		return res, body
+	}
+}
+
+// EachConsole calls the handler for every console api call of the page in order,
+// if exceptions is true the uncaught exceptions will be included.
+func (p *Page) EachConsole(handler func(*proto.RuntimeConsoleAPICalled), exceptions bool) (stop func()) {
+	stop, err := p.EachConsoleE(handler, exceptions)
+	mustCall("Page.EachConsoleE", Array{handler, exceptions}, err)
+	return stop
+}
+
+// WaitConsole returns a wait function that waits until a console message contains the substr,
+// the wait function returns the text of the message.
+func (p *Page) WaitConsole(substr string) (wait func() string) {
+	w := p.WaitConsoleE(substr)
+	return func() string {
+		text, err := w()
+		mustCall("Page.WaitConsoleE", Array{substr}, err)
+		return text
+	}
+}
+
+// AccessibilitySnapshot returns the root node of the accessibility tree of the page
+func (p *Page) AccessibilitySnapshot() *AXNode {
+	root, err := p.AccessibilitySnapshotE()
+	mustCall("Page.AccessibilitySnapshotE", Array{}, err)
+	return root
+}
+
+// ElementByRole returns the first element whose accessibility node matches the role and the accessible name
+func (p *Page) ElementByRole(role, name string) *Element {
+	el, err := p.ElementByRoleE(role, name)
+	mustCall("Page.ElementByRoleE", Array{role, name}, err)
+	return el
+}
+
+// Element resolves the dom node of the ax node
+func (n *AXNode) Element() *Element {
+	el, err := n.ElementE()
+	mustCall("AXNode.ElementE", Array{}, err)
+	return el
+}
+
+// StartJSCoverage starts to collect the coverage of the scripts, if opts is nil the default options will be used
+func (p *Page) StartJSCoverage(opts *JSCoverageOptions) *Page {
+	mustCall("Page.StartJSCoverageE", Array{opts}, p.StartJSCoverageE(opts))
+	return p
+}
+
+// StopJSCoverage stops the js coverage and returns the coverage of the scripts
+func (p *Page) StopJSCoverage() []*JSCoverage {
+	list, err := p.StopJSCoverageE()
+	mustCall("Page.StopJSCoverageE", Array{}, err)
+	return list
+}
+
+// StartCSSCoverage starts to collect the usage of the css rules
+func (p *Page) StartCSSCoverage(resetOnNavigation bool) *Page {
+	mustCall("Page.StartCSSCoverageE", Array{resetOnNavigation}, p.StartCSSCoverageE(resetOnNavigation))
+	return p
+}
+
+// StopCSSCoverage stops the css coverage and returns the coverage of the style sheets
+func (p *Page) StopCSSCoverage() []*CSSCoverage {
+	list, err := p.StopCSSCoverageE()
+	mustCall("Page.StopCSSCoverageE", Array{}, err)
+	return list
+}
+
+// EachWebSocket calls the handler for every WebSocket event of the page in order,
+// call it before the Navigate to catch the sockets that are created during the page loading
+func (p *Page) EachWebSocket(handler func(WebSocketFrame)) (stop func()) {
+	stop, err := p.EachWebSocketE(handler)
+	mustCall("Page.EachWebSocketE", Array{handler}, err)
+	return stop
+}
+
+// WaitWebSocketMessage returns a wait function that waits until a message of the sockets whose url matches
+// the urlPattern contains the payloadSubstr, the wait function returns the payload of the message.
+func (p *Page) WaitWebSocketMessage(urlPattern, payloadSubstr string) (wait func() string) {
+	w := p.WaitWebSocketMessageE(urlPattern, payloadSubstr)
+	return func() string {
+		payload, err := w()
+		mustCall("Page.WaitWebSocketMessageE", Array{urlPattern, payloadSubstr}, err)
+		return payload
+	}
+}
+
+// Wait until the js returns a truthy value
+func (p *Page) Wait(js string, params ...interface{}) *Page {
+	mustCall("Page.WaitE", Array{nil, "", js, params}, p.WaitE(nil, "", js, params))
+	return p
+}
+
+// Search returns the first element that matches the query through all the iframes and shadow roots,
+// the query can be plain text, css selector, or xpath
+func (p *Page) Search(query string) *Element {
+	el, err := p.SearchE(query)
+	mustCall("Page.SearchE", Array{query}, err)
+	return el
+}
+
+// SearchAll returns all the elements that match the query through all the iframes and shadow roots
+func (p *Page) SearchAll(query string) Elements {
+	list, err := p.SearchAllE(query)
+	mustCall("Page.SearchAllE", Array{query}, err)
+	return list
+}
+
+// HTML of the whole document of the page, including the doctype
+func (p *Page) HTML() string {
+	html, err := p.HTMLE()
+	mustCall("Page.HTMLE", Array{}, err)
+	return html
+}
+
+// StartTracing starts to collect the trace events of the categories
+func (p *Page) StartTracing(categories ...string) *Page {
+	mustCall("Page.StartTracingE", Array{categories}, p.StartTracingE(categories))
+	return p
+}
+
+// StopTracing stops the tracing and returns the trace file in json format, it can be loaded by chrome://tracing
+func (p *Page) StopTracing() []byte {
+	data, err := p.StopTracingE()
+	mustCall("Page.StopTracingE", Array{}, err)
+	return data
+}
+
+// Metrics returns the runtime metrics of the page, such as "JSHeapUsedSize"
+func (p *Page) Metrics() map[string]float64 {
+	m, err := p.MetricsE()
+	mustCall("Page.MetricsE", Array{}, err)
+	return m
 }
 
 // WaitIdle wait until the next window.requestIdleCallback is called.
 func (p *Page) WaitIdle() *Page {
-	kit.E(p.WaitIdleE(time.Minute))
+	mustCall("Page.WaitIdleE", Array{time.Minute}, p.WaitIdleE(time.Minute))
+	return p
+}
+
+// WaitDOMStable waits until no DOM mutation happens for the duration d,
+// the mutations inside the elements that match the ignore selectors are excluded.
+func (p *Page) WaitDOMStable(d time.Duration, ignore ...string) *Page {
+	mustCall("Page.WaitDOMStableE", Array{d, ignore}, p.WaitDOMStableE(d, ignore))
 	return p
 }
 
 // WaitLoad wait until the `window.onload` is complete, resolve immediately if already fired.
 func (p *Page) WaitLoad() *Page {
-	kit.E(p.WaitLoadE())
+	mustCall("Page.WaitLoadE", Array{}, p.WaitLoadE())
 	return p
 }
 
 // AddScriptTag to page. If url is empty, content will be used.
 func (p *Page) AddScriptTag(url string) *Page {
-	kit.E(p.AddScriptTagE(url, ""))
+	mustCall("Page.AddScriptTagE", Array{url, ""}, p.AddScriptTagE(url, ""))
 	return p
 }
 
 // AddStyleTag to page. If url is empty, content will be used.
 func (p *Page) AddStyleTag(url string) *Page {
-	kit.E(p.AddStyleTagE(url, ""))
+	mustCall("Page.AddStyleTagE", Array{url, ""}, p.AddStyleTagE(url, ""))
+	return p
+}
+
+// AddScript evaluates the js source in the page without adding any tag to the DOM
+func (p *Page) AddScript(js string) *Page {
+	mustCall("Page.AddScriptE", Array{js, false}, p.AddScriptE(js, false))
+	return p
+}
+
+// Reattach attaches a new session to the target of the page, and restores the emulations
+func (p *Page) Reattach() *Page {
+	mustCall("Page.ReattachE", Array{}, p.ReattachE())
+	return p
+}
+
+// SetVisibility emulates the visibility state of the page, "visible" or "hidden"
+func (p *Page) SetVisibility(state string) *Page {
+	mustCall("Page.SetVisibilityE", Array{state}, p.SetVisibilityE(state))
+	return p
+}
+
+// SetFocusEmulation makes the page behave like it's focused
+func (p *Page) SetFocusEmulation(enabled bool) *Page {
+	mustCall("Page.SetFocusEmulationE", Array{enabled}, p.SetFocusEmulationE(enabled))
+	return p
+}
+
+// DispatchEvent dispatches a synthetic event on the window of the page, it returns true if the event is canceled
+func (p *Page) DispatchEvent(name string, detail interface{}, opts *EventOptions) bool {
+	canceled, err := p.DispatchEventE(name, detail, opts)
+	mustCall("Page.DispatchEventE", Array{name, detail, opts}, err)
+	return canceled
+}
+
+// EmulateMedia emulates the css media type, such as "print", and the media features
+func (p *Page) EmulateMedia(media string, features ...proto.EmulationMediaFeature) *Page {
+	mustCall("Page.EmulateMediaE", Array{media, features}, p.EmulateMediaE(media, features))
+	return p
+}
+
+// ClearEmulatedMedia clears the emulated media type and media features
+func (p *Page) ClearEmulatedMedia() *Page {
+	mustCall("Page.ClearEmulatedMediaE", Array{}, p.ClearEmulatedMediaE())
+	return p
+}
+
+// DarkMode emulates the "prefers-color-scheme" to be dark or light
+func (p *Page) DarkMode(dark bool) *Page {
+	mustCall("Page.DarkModeE", Array{dark}, p.DarkModeE(dark))
+	return p
+}
+
+// SetBypassCSP enables/disables bypassing the Content-Security-Policy of the page
+func (p *Page) SetBypassCSP(enabled bool) *Page {
+	mustCall("Page.SetBypassCSPE", Array{enabled}, p.SetBypassCSPE(enabled))
+	return p
+}
+
+// EvalOnNewDocument evaluates the script in every frame of the page upon creation, before any script of the frame runs.
+// The js is the source of the script, not a function definition.
+func (p *Page) EvalOnNewDocument(js string) proto.PageScriptIdentifier {
+	id, err := p.EvalOnNewDocumentE(js)
+	mustCall("Page.EvalOnNewDocumentE", Array{js}, err)
+	return id
+}
+
+// RemoveScript removes the script added by EvalOnNewDocument
+func (p *Page) RemoveScript(id proto.PageScriptIdentifier) *Page {
+	mustCall("Page.RemoveScriptE", Array{id}, p.RemoveScriptE(id))
 	return p
 }
 
@@ -289,41 +872,146 @@ func (p *Page) AddStyleTag(url string) *Page {
 // For example page.Eval(`n => n + 1`, 1) will return 2
 func (p *Page) Eval(js string, params ...interface{}) proto.JSON {
 	res, err := p.EvalE(true, "", js, params)
-	kit.E(err)
+	mustCall("Page.EvalE", Array{true, "", js, params}, err)
 	return res.Value
 }
 
+// WaitText waits until one of the elements that match the selector contains the substr, and returns it
+func (p *Page) WaitText(selector, substr string) *Element {
+	el, err := p.WaitTextE(selector, substr, nil)
+	mustCall("Page.WaitTextE", Array{selector, substr, nil}, err)
+	return el
+}
+
+// EvalInto evaluates the js and unmarshals the result into dst
+func (p *Page) EvalInto(dst interface{}, js string, params ...interface{}) {
+	mustCall("Page.EvalIntoE", Array{dst, js, params}, p.EvalIntoE(dst, js, params))
+}
+
+// EvalBatch sends all the calls without waiting for the response of each one, it panics if any of them fails,
+// use EvalBatchE to get the error of each call
+func (p *Page) EvalBatch(calls ...*EvalCall) []*proto.RuntimeRemoteObject {
+	list, err := p.EvalBatchE(calls)
+	mustCall("Page.EvalBatchE", Array{calls}, err)
+	return list
+}
+
+// ExposeFunction exposes fn as window[name] to the page, the js function returns a promise that resolves
+// to the return value of fn. The args are the JSON encoded arguments of the js function.
+func (p *Page) ExposeFunction(name string, fn func(args []json.RawMessage) (interface{}, error)) *Page {
+	mustCall("Page.ExposeFunctionE", Array{name, fn}, p.ExposeFunctionE(name, fn))
+	return p
+}
+
+// RemoveExposedFunction removes the function that is exposed by ExposeFunction
+func (p *Page) RemoveExposedFunction(name string) *Page {
+	mustCall("Page.RemoveExposedFunctionE", Array{name}, p.RemoveExposedFunctionE(name))
+	return p
+}
+
 // Release remote object
 func (p *Page) Release(objectID proto.RuntimeRemoteObjectID) *Page {
-	kit.E(p.ReleaseE(objectID))
+	mustCall("Page.ReleaseE", Array{objectID}, p.ReleaseE(objectID))
+	return p
+}
+
+// Exceptions returns the recent uncaught exceptions of the page, the oldest first.
+// It doesn't panic when the renderer has crashed, so the exceptions can be dumped after the crash,
+// use the ExceptionsE to check the crash.
+func (p *Page) Exceptions() []*proto.RuntimeExceptionDetails {
+	list, err := p.ExceptionsE()
+	if !IsError(err, ErrPageCrashed) {
+		mustCall("Page.ExceptionsE", Array{}, err)
+	}
+	return list
+}
+
+// ReleaseAll releases all the objects recorded by the TrackObjects
+func (p *Page) ReleaseAll() *Page {
+	mustCall("Page.ReleaseAllE", Array{}, p.ReleaseAllE())
+	return p
+}
+
+// Record writes the screencast frames of the page into the dir until stop is called
+func (p *Page) Record(dir string) (stop func()) {
+	s, err := p.RecordE(dir)
+	mustCall("Page.RecordE", Array{dir}, err)
+	return func() { mustCall("Page.RecordE", Array{dir}, s()) }
+}
+
+// Frames returns all the iframes of the page recursively
+func (p *Page) Frames() []*Page {
+	list, err := p.FramesE()
+	mustCall("Page.FramesE", Array{}, err)
+	return list
+}
+
+// FrameByURL returns the first iframe whose url matches the regexp pattern
+func (p *Page) FrameByURL(pattern string) *Page {
+	f, err := p.FrameByURLE(pattern)
+	mustCall("Page.FrameByURLE", Array{pattern}, err)
+	return f
+}
+
+// FrameByName returns the first iframe with the name
+func (p *Page) FrameByName(name string) *Page {
+	f, err := p.FrameByNameE(name)
+	mustCall("Page.FrameByNameE", Array{name}, err)
+	return f
+}
+
+// BlockRequests blocks the requests whose url matches one of the patterns, wildcards ('*') are allowed
+func (p *Page) BlockRequests(patterns ...string) *Page {
+	mustCall("Page.BlockRequestsE", Array{patterns}, p.BlockRequestsE(patterns))
+	return p
+}
+
+// BlockResourceTypes fails the requests of the resource types, such as the images and fonts
+func (p *Page) BlockResourceTypes(types ...proto.NetworkResourceType) *Page {
+	mustCall("Page.BlockResourceTypesE", Array{types}, p.BlockResourceTypesE(types...))
 	return p
 }
 
+// ObjectToJSON serializes the remote object into json
+func (p *Page) ObjectToJSON(obj *proto.RuntimeRemoteObject) proto.JSON {
+	j, err := p.ObjectToJSONE(obj)
+	mustCall("Page.ObjectToJSONE", Array{obj}, err)
+	return j
+}
+
 // Has an element that matches the css selector
 func (p *Page) Has(selector string) bool {
 	has, err := p.HasE(selector)
-	kit.E(err)
+	mustCall("Page.HasE", Array{selector}, err)
 	return has
 }
 
+// WaitElement waits until the element that matches the css selector reaches the state.
+// It returns the element for ElementStateExists and ElementStateVisible, nil for the other states.
+func (p *Page) WaitElement(selector string, state ElementState) *Element {
+	el, err := p.WaitElementE(selector, state)
+	mustCall("Page.WaitElementE", Array{selector, state}, err)
+	return el
+}
+
 // HasX an element that matches the XPath selector
 func (p *Page) HasX(selector string) bool {
 	has, err := p.HasXE(selector)
-	kit.E(err)
+	mustCall("Page.HasXE", Array{selector}, err)
 	return has
 }
 
 // HasMatches an element that matches the css selector and its text matches the regex.
 func (p *Page) HasMatches(selector, regex string) bool {
 	has, err := p.HasMatchesE(selector, regex)
-	kit.E(err)
+	mustCall("Page.HasMatchesE", Array{selector, regex}, err)
 	return has
 }
 
 // Element retries until returns the first element in the page that matches the CSS selector
 func (p *Page) Element(selector string) *Element {
 	el, err := p.ElementE(p.Sleeper(), "", selector)
-	kit.E(err)
+	mustCall("Page.ElementE", Array{p.Sleeper(), "", selector}, err)
 	return el
 }
 
@@ -331,211 +1019,449 @@ func (p *Page) Element(selector string) *Element {
 // The regex is the js regex, not golang's.
 func (p *Page) ElementMatches(selector, regex string) *Element {
 	el, err := p.ElementMatchesE(p.Sleeper(), "", selector, regex)
-	kit.E(err)
+	mustCall("Page.ElementMatchesE", Array{p.Sleeper(), "", selector, regex}, err)
 	return el
 }
 
 // ElementByJS retries until returns the element from the return value of the js function
 func (p *Page) ElementByJS(js string, params ...interface{}) *Element {
 	el, err := p.ElementByJSE(p.Sleeper(), "", js, params)
-	kit.E(err)
+	mustCall("Page.ElementByJSE", Array{p.Sleeper(), "", js, params}, err)
 	return el
 }
 
 // Elements returns all elements that match the css selector
 func (p *Page) Elements(selector string) Elements {
 	list, err := p.ElementsE("", selector)
-	kit.E(err)
+	mustCall("Page.ElementsE", Array{"", selector}, err)
 	return list
 }
 
 // ElementsX returns all elements that match the XPath selector
 func (p *Page) ElementsX(xpath string) Elements {
 	list, err := p.ElementsXE("", xpath)
-	kit.E(err)
+	mustCall("Page.ElementsXE", Array{"", xpath}, err)
 	return list
 }
 
 // ElementX retries until returns the first element in the page that matches the XPath selector
 func (p *Page) ElementX(xpath string) *Element {
 	el, err := p.ElementXE(p.Sleeper(), "", xpath)
-	kit.E(err)
+	mustCall("Page.ElementXE", Array{p.Sleeper(), "", xpath}, err)
 	return el
 }
 
+// EachElement calls fn with each element that matches the css selector until fn returns true
+func (p *Page) EachElement(selector string, fn func(*Element) (stop bool)) {
+	each := func(el *Element) (bool, error) {
+		return fn(el), nil
+	}
+	mustCall("Page.EachElementE", Array{selector, each}, p.EachElementE(selector, each))
+}
+
 // ElementsByJS returns the elements from the return value of the js
 func (p *Page) ElementsByJS(js string, params ...interface{}) Elements {
 	list, err := p.ElementsByJSE("", js, params)
-	kit.E(err)
+	mustCall("Page.ElementsByJSE", Array{"", js, params}, err)
 	return list
 }
 
 // Move to the absolute position
 func (m *Mouse) Move(x, y float64) {
-	kit.E(m.MoveE(x, y, 0))
+	mustCall("Mouse.MoveE", Array{x, y, 0}, m.MoveE(x, y, 0))
 }
 
 // Scroll with the relative offset
 func (m *Mouse) Scroll(x, y float64) {
-	kit.E(m.ScrollE(x, y, 0))
+	mustCall("Mouse.ScrollE", Array{x, y, 0}, m.ScrollE(x, y, 0))
 }
 
 // Down holds the button down
 func (m *Mouse) Down(button proto.InputMouseButton) {
-	kit.E(m.DownE(button, 1))
+	mustCall("Mouse.DownE", Array{button, 1}, m.DownE(button, 1))
 }
 
 // Up release the button
 func (m *Mouse) Up(button proto.InputMouseButton) {
-	kit.E(m.UpE(button, 1))
+	mustCall("Mouse.UpE", Array{button, 1}, m.UpE(button, 1))
 }
 
 // Click will press then release the button
 func (m *Mouse) Click(button proto.InputMouseButton) {
-	kit.E(m.ClickE(button))
+	mustCall("Mouse.ClickE", Array{button}, m.ClickE(button))
+}
+
+// Drag holds the left button and moves from the start point to the end point with specified steps
+func (m *Mouse) Drag(fromX, fromY, toX, toY float64, steps int) {
+	mustCall("Mouse.DragE", Array{fromX, fromY, toX, toY, steps}, m.DragE(fromX, fromY, toX, toY, steps))
+}
+
+// Tap the point, a touchStart then a touchEnd will be dispatched
+func (t *Touch) Tap(x, y float64) {
+	mustCall("Touch.TapE", Array{x, y}, t.TapE(x, y))
+}
+
+// Swipe moves a finger from the start point to the end point with specified steps
+func (t *Touch) Swipe(fromX, fromY, toX, toY float64, steps int) {
+	mustCall("Touch.SwipeE", Array{fromX, fromY, toX, toY, steps}, t.SwipeE(fromX, fromY, toX, toY, steps))
+}
+
+// Pinch moves two fingers around the center in opposite directions,
+// the distance between them will be scaled by the scale, such as 2 to zoom in and 0.5 to zoom out
+func (t *Touch) Pinch(centerX, centerY, scale float64) {
+	mustCall("Touch.PinchE", Array{centerX, centerY, scale}, t.PinchE(centerX, centerY, scale))
 }
 
 // Down holds key down
 func (k *Keyboard) Down(key rune) {
-	kit.E(k.DownE(key))
+	mustCall("Keyboard.DownE", Array{key}, k.DownE(key))
 }
 
 // Up releases the key
 func (k *Keyboard) Up(key rune) {
-	kit.E(k.UpE(key))
+	mustCall("Keyboard.UpE", Array{key}, k.UpE(key))
 }
 
 // Press a key
 func (k *Keyboard) Press(key rune) {
-	kit.E(k.PressE(key))
+	mustCall("Keyboard.PressE", Array{key}, k.PressE(key))
+}
+
+// Type the text by pressing the key of each character, the key events will be triggered.
+// It's much slower than the InsertText for long text.
+func (k *Keyboard) Type(text string) {
+	mustCall("Keyboard.TypeE", Array{text}, k.TypeE(text))
+}
+
+// PressCombination presses the keys in order and releases them in reverse order, such as
+// PressCombination(input.Shift, input.Control, 'p'). The modifiers will be set on the events of the last key.
+// Use input.CommandOrControl for the shortcuts, it's the Meta on mac and the Control on the other platforms,
+// the platform override of the page is respected.
+func (k *Keyboard) PressCombination(keys ...rune) {
+	mustCall("Keyboard.PressCombinationE", Array{keys}, k.PressCombinationE(keys...))
+}
+
+// CanvasToImage returns the image of the canvas element
+func (el *Element) CanvasToImage(format string, quality float64) []byte {
+	bin, err := el.CanvasToImageE(format, quality)
+	mustCall("Element.CanvasToImageE", Array{format, quality}, err)
+	return bin
+}
+
+// MediaState returns the playback state of the video or audio element
+func (el *Element) MediaState() *MediaState {
+	state, err := el.MediaStateE()
+	mustCall("Element.MediaStateE", Array{}, err)
+	return state
+}
+
+// Play plays the video or audio element
+func (el *Element) Play() *Element {
+	mustCall("Element.PlayE", Array{}, el.PlayE())
+	return el
+}
+
+// Pause pauses the video or audio element
+func (el *Element) Pause() *Element {
+	mustCall("Element.PauseE", Array{}, el.PauseE())
+	return el
+}
+
+// SliderInfo returns the min, max, step, and current value of the range input
+func (el *Element) SliderInfo() *SliderInfo {
+	info, err := el.SliderInfoE()
+	mustCall("Element.SliderInfoE", Array{}, err)
+	return info
+}
+
+// SetRange sets the value of the range input, the value is snapped like the browser does
+func (el *Element) SetRange(value float64) *Element {
+	mustCall("Element.SetRangeE", Array{value}, el.SetRangeE(value))
+	return el
+}
+
+// DragRange sets the value of the range input by dragging the thumb of the slider
+func (el *Element) DragRange(value float64) *Element {
+	mustCall("Element.DragRangeE", Array{value}, el.DragRangeE(value))
+	return el
+}
+
+// WaitEnabled waits until the element is enabled
+func (el *Element) WaitEnabled() *Element {
+	mustCall("Element.WaitEnabledE", Array{}, el.WaitEnabledE())
+	return el
+}
+
+// DispatchEvent dispatches a synthetic event on the element, it returns true if the event is canceled
+func (el *Element) DispatchEvent(name string, detail interface{}, opts *EventOptions) bool {
+	canceled, err := el.DispatchEventE(name, detail, opts)
+	mustCall("Element.DispatchEventE", Array{name, detail, opts}, err)
+	return canceled
+}
+
+// Highlight highlights the element with the msg until remove is called
+func (el *Element) Highlight(msg string) (remove func()) {
+	remove, err := el.HighlightE(msg)
+	mustCall("Element.HighlightE", Array{msg}, err)
+	return remove
+}
+
+// IMEInput enters the text like an input method, the composition events will be triggered
+func (k *Keyboard) IMEInput(text string) {
+	mustCall("Keyboard.IMEInputE", Array{text}, k.IMEInputE(text))
+}
+
+// IMECompose sets each of the steps as the composition text in order, then commits the text
+func (k *Keyboard) IMECompose(steps []string, text string) {
+	mustCall("Keyboard.IMEComposeE", Array{steps, text}, k.IMEComposeE(steps, text))
 }
 
 // InsertText like paste text into the page
 func (k *Keyboard) InsertText(text string) {
-	kit.E(k.InsertTextE(text))
+	mustCall("Keyboard.InsertTextE", Array{text}, k.InsertTextE(text))
 }
 
 // Describe returns the element info
 // Returned json: https://chromedevtools.github.io/devtools-protocol/tot/DOM#type-Node
 func (el *Element) Describe() *proto.DOMNode {
 	node, err := el.DescribeE()
-	kit.E(err)
+	mustCall("Element.DescribeE", Array{}, err)
 	return node
 }
 
-// Frame creates a page instance that represents the iframe
+// Frame creates a page instance that represents the iframe, the iframes without src are supported too, such as
+// the srcdoc iframes. If the content frame of the iframe isn't attached yet, it waits for it.
 func (el *Element) Frame() *Page {
 	f, err := el.FrameE()
-	kit.E(err)
+	mustCall("Element.FrameE", Array{}, err)
 	return f
 }
 
 // ShadowRoot returns the shadow root of this element
 func (el *Element) ShadowRoot() *Element {
 	node, err := el.ShadowRootE()
-	kit.E(err)
+	mustCall("Element.ShadowRootE", Array{}, err)
 	return node
 }
 
+// ElementDeep finds the descendant that matches the css selector through the open shadow roots
+func (el *Element) ElementDeep(selector string) *Element {
+	e, err := el.ElementDeepE(selector)
+	mustCall("Element.ElementDeepE", Array{selector}, err)
+	return e
+}
+
 // Focus sets focus on the specified element
 func (el *Element) Focus() *Element {
-	kit.E(el.FocusE())
+	mustCall("Element.FocusE", Array{}, el.FocusE())
 	return el
 }
 
 // ScrollIntoView scrolls the current element into the visible area of the browser
 // window if it's not already within the visible area.
 func (el *Element) ScrollIntoView() *Element {
-	kit.E(el.ScrollIntoViewE())
+	mustCall("Element.ScrollIntoViewE", Array{}, el.ScrollIntoViewE())
+	return el
+}
+
+// ScrollIntoViewSmooth is similar to ScrollIntoView, but the scrolling is animated
+func (el *Element) ScrollIntoViewSmooth() *Element {
+	mustCall("Element.ScrollIntoViewSmoothE", Array{}, el.ScrollIntoViewSmoothE())
 	return el
 }
 
 // Click the element
 func (el *Element) Click() *Element {
-	kit.E(el.ClickE(proto.InputMouseButtonLeft))
+	mustCall("Element.ClickE", Array{proto.InputMouseButtonLeft}, el.ClickE(proto.InputMouseButtonLeft))
+	return el
+}
+
+// Tap the element with the touchscreen
+func (el *Element) Tap() *Element {
+	mustCall("Element.TapE", Array{}, el.TapE())
 	return el
 }
 
 // Press a key
 func (el *Element) Press(key rune) *Element {
-	kit.E(el.PressE(key))
+	mustCall("Element.PressE", Array{key}, el.PressE(key))
 	return el
 }
 
 // SelectText selects the text that matches the regular expression
 func (el *Element) SelectText(regex string) *Element {
-	kit.E(el.SelectTextE(regex))
+	mustCall("Element.SelectTextE", Array{regex}, el.SelectTextE(regex))
 	return el
 }
 
 // SelectAllText selects all text
 func (el *Element) SelectAllText() *Element {
-	kit.E(el.SelectAllTextE())
+	mustCall("Element.SelectAllTextE", Array{}, el.SelectAllTextE())
 	return el
 }
 
-// Input wll click the element and input the text.
-// To empty the input you can use something like el.SelectAllText().Input("")
+// Check clicks the checkbox or radio element if it's not checked, then waits for it to be checked
+func (el *Element) Check() *Element {
+	mustCall("Element.CheckE", Array{}, el.CheckE())
+	return el
+}
+
+// Uncheck clicks the checkbox element if it's checked, then waits for it to be unchecked.
+// A radio element can't be unchecked by clicking it, an ErrUncheckRadio error will be returned for it.
+func (el *Element) Uncheck() *Element {
+	mustCall("Element.UncheckE", Array{}, el.UncheckE())
+	return el
+}
+
+// Checked returns true if the checkbox or radio element is checked
+func (el *Element) Checked() bool {
+	checked, err := el.CheckedE()
+	mustCall("Element.CheckedE", Array{}, err)
+	return checked
+}
+
+// Input will focus the element, clear the existing text with CommandOrControl+A and Delete, then input the text.
+// To empty the input you can use el.Input(""). The text is inserted at once unless the Page.InputKeystrokes is enabled.
 func (el *Element) Input(text string) *Element {
-	kit.E(el.InputE(text))
+	mustCall("Element.InputE", Array{text}, el.InputE(text))
+	return el
+}
+
+// Append will focus the element and input the text at the caret, the selected text will be replaced.
+// Such as el.SelectText("b").Append("c") will change "abc" to "acc".
+func (el *Element) Append(text string) *Element {
+	mustCall("Element.AppendE", Array{text}, el.AppendE(text))
+	return el
+}
+
+// InputTime sets the value of the time or datetime-local input element, the input and change events will be fired
+func (el *Element) InputTime(t time.Time) *Element {
+	mustCall("Element.InputTimeE", Array{t}, el.InputTimeE(t))
+	return el
+}
+
+// InputDate sets the value of the date, month, week, or datetime-local input element, the input and change events
+// will be fired
+func (el *Element) InputDate(t time.Time) *Element {
+	mustCall("Element.InputDateE", Array{t}, el.InputDateE(t))
+	return el
+}
+
+// Type will click the element and type the text by pressing the key of each character.
+// Use it instead of the Input when the page listens to the key events.
+func (el *Element) Type(text string) *Element {
+	mustCall("Element.TypeE", Array{text}, el.TypeE(text))
+	return el
+}
+
+// DragTo drags the center of the element to the center of the target with specified steps
+func (el *Element) DragTo(target *Element, steps int) *Element {
+	mustCall("Element.DragToE", Array{target, steps}, el.DragToE(target, steps))
 	return el
 }
 
 // Select the option elements that match the selectors, the selector can be text content or css selector
 func (el *Element) Select(selectors ...string) *Element {
-	kit.E(el.SelectE(selectors))
+	mustCall("Element.Select", Array{selectors}, el.selectOptions(strings.Join(selectors, "; "), toArray(selectors), "textOrCSS"))
 	return el
 }
 
+// SelectByText selects the option elements whose text content contains the texts
+func (el *Element) SelectByText(texts ...string) *Element {
+	mustCall("Element.SelectE", Array{texts, true}, el.SelectE(texts, true))
+	return el
+}
+
+// SelectIndex selects the options at the indexes, the index starts from 0
+func (el *Element) SelectIndex(indexes ...int) *Element {
+	mustCall("Element.SelectIndexE", Array{indexes}, el.SelectIndexE(indexes))
+	return el
+}
+
+// SelectedOptions returns the values of the selected options
+func (el *Element) SelectedOptions() []string {
+	list, err := el.SelectedOptionsE()
+	mustCall("Element.SelectedOptionsE", Array{}, err)
+	return list
+}
+
 // SetFiles sets files for the given file input element
 func (el *Element) SetFiles(paths ...string) *Element {
-	kit.E(el.SetFilesE(paths))
+	mustCall("Element.SetFilesE", Array{paths}, el.SetFilesE(paths))
 	return el
 }
 
 // Text gets the innerText of the element
 func (el *Element) Text() string {
 	s, err := el.TextE()
-	kit.E(err)
+	mustCall("Element.TextE", Array{}, err)
 	return s
 }
 
 // HTML gets the outerHTML of the element
 func (el *Element) HTML() string {
 	s, err := el.HTMLE()
-	kit.E(err)
+	mustCall("Element.HTMLE", Array{}, err)
 	return s
 }
 
+// Attribute of the DOM object, returns nil if the element doesn't have the attribute.
+// Attribute vs Property: https://stackoverflow.com/questions/6003819/what-is-the-difference-between-properties-and-attributes-in-html
+func (el *Element) Attribute(name string) *string {
+	attr, err := el.AttributeE(name)
+	mustCall("Element.AttributeE", Array{name}, err)
+	return attr
+}
+
+// Property of the DOM object, such as the live "value" of an input
+func (el *Element) Property(name string) proto.JSON {
+	prop, err := el.PropertyE(name)
+	mustCall("Element.PropertyE", Array{name}, err)
+	return prop
+}
+
 // Visible returns true if the element is visible on the page
 func (el *Element) Visible() bool {
 	v, err := el.VisibleE()
-	kit.E(err)
+	mustCall("Element.VisibleE", Array{}, err)
+	return v
+}
+
+// Clickable returns true if the element is visible and not covered by other elements at its center point
+func (el *Element) Clickable() bool {
+	v, err := el.ClickableE()
+	mustCall("Element.ClickableE", Array{}, err)
 	return v
 }
 
 // WaitStable waits until the size and position are stable. Useful when waiting for the animation of modal
 // or button to complete so that we can simulate the mouse to move to it and click on it.
 func (el *Element) WaitStable() *Element {
-	kit.E(el.WaitStableE(100 * time.Millisecond))
+	mustCall("Element.WaitStableE", Array{100 * time.Millisecond}, el.WaitStableE(100*time.Millisecond))
 	return el
 }
 
-// Wait until the js returns true
+// Wait until the js returns a truthy value
 func (el *Element) Wait(js string, params ...interface{}) *Element {
-	kit.E(el.WaitE(js, params))
+	mustCall("Element.WaitE", Array{js, params}, el.WaitE(js, params))
 	return el
 }
 
-// WaitVisible until the element is visible
+// WaitVisible until the element is visible, it panics with ErrElementDetached if the element is removed during the wait
 func (el *Element) WaitVisible() *Element {
-	kit.E(el.WaitVisibleE())
+	mustCall("Element.WaitVisibleE", Array{}, el.WaitVisibleE())
+	return el
+}
+
+// WaitText waits until the text of the element contains the substr
+func (el *Element) WaitText(substr string) *Element {
+	mustCall("Element.WaitTextE", Array{substr, nil}, el.WaitTextE(substr, nil))
 	return el
 }
 
 // WaitInvisible until the element is not visible or removed
 func (el *Element) WaitInvisible() *Element {
-	kit.E(el.WaitInvisibleE())
+	mustCall("Element.WaitInvisibleE", Array{}, el.WaitInvisibleE())
 	return el
 }
 
@@ -544,105 +1470,124 @@ func (el *Element) WaitInvisible() *Element {
 // https://developer.mozilla.org/en-US/docs/Web/API/Element/getBoundingClientRect
 func (el *Element) Box() *Box {
 	box, err := el.BoxE()
-	kit.E(err)
+	mustCall("Element.BoxE", Array{}, err)
 	return box
 }
 
 // Resource returns the binary of the "src" properly, such as the image or audio file.
 func (el *Element) Resource() []byte {
 	bin, err := el.ResourceE()
-	kit.E(err)
+	mustCall("Element.ResourceE", Array{}, err)
 	return bin
 }
 
 // Screenshot of the area of the element
 func (el *Element) Screenshot(toFile ...string) []byte {
 	bin, err := el.ScreenshotE(proto.PageCaptureScreenshotFormatPng, -1)
-	kit.E(err)
-	kit.E(saveScreenshot(bin, toFile))
+	mustCall("Element.ScreenshotE", Array{proto.PageCaptureScreenshotFormatPng, -1}, err)
+	mustCall("saveScreenshot", Array{toFile}, saveScreenshot(bin, toFile))
 	return bin
 }
 
 // Release remote object on browser
 func (el *Element) Release() {
-	kit.E(el.ReleaseE())
+	mustCall("Element.ReleaseE", Array{}, el.ReleaseE())
 }
 
 // Eval evaluates js function on the element, the first param must be a js function definition
 // For example: el.Eval(`name => this.getAttribute(name)`, "value")
 func (el *Element) Eval(js string, params ...interface{}) proto.JSON {
 	res, err := el.EvalE(true, js, params)
-	kit.E(err)
+	mustCall("Element.EvalE", Array{true, js, params}, err)
 	return res.Value
 }
 
+// EvalInto evaluates the js with the element as this and unmarshals the result into dst
+func (el *Element) EvalInto(dst interface{}, js string, params ...interface{}) {
+	mustCall("Element.EvalIntoE", Array{dst, js, params}, el.EvalIntoE(dst, js, params))
+}
+
 // Has an element that matches the css selector
 func (el *Element) Has(selector string) bool {
 	has, err := el.HasE(selector)
-	kit.E(err)
+	mustCall("Element.HasE", Array{selector}, err)
 	return has
 }
 
 // HasX an element that matches the XPath selector
 func (el *Element) HasX(selector string) bool {
 	has, err := el.HasXE(selector)
-	kit.E(err)
+	mustCall("Element.HasXE", Array{selector}, err)
 	return has
 }
 
 // HasMatches an element that matches the css selector and its text matches the regex.
 func (el *Element) HasMatches(selector, regex string) bool {
 	has, err := el.HasMatchesE(selector, regex)
-	kit.E(err)
+	mustCall("Element.HasMatchesE", Array{selector, regex}, err)
 	return has
 }
 
 // Element returns the first child that matches the css selector
 func (el *Element) Element(selector string) *Element {
 	el, err := el.ElementE(selector)
-	kit.E(err)
+	mustCall("Element.ElementE", Array{selector}, err)
 	return el
 }
 
 // ElementX returns the first child that matches the XPath selector
 func (el *Element) ElementX(xpath string) *Element {
 	el, err := el.ElementXE(xpath)
-	kit.E(err)
+	mustCall("Element.ElementXE", Array{xpath}, err)
 	return el
 }
 
 // ElementByJS returns the element from the return value of the js
 func (el *Element) ElementByJS(js string, params ...interface{}) *Element {
 	el, err := el.ElementByJSE(js, params)
-	kit.E(err)
+	mustCall("Element.ElementByJSE", Array{js, params}, err)
 	return el
 }
 
 // Parent returns the parent element
 func (el *Element) Parent() *Element {
 	parent, err := el.ParentE()
-	kit.E(err)
+	mustCall("Element.ParentE", Array{}, err)
 	return parent
 }
 
 // Parents that match the selector
 func (el *Element) Parents(selector string) Elements {
 	list, err := el.ParentsE(selector)
-	kit.E(err)
+	mustCall("Element.ParentsE", Array{selector}, err)
 	return list
 }
 
+// Children returns the child elements, the text and comment nodes are excluded
+func (el *Element) Children() Elements {
+	list, err := el.ChildrenE()
+	mustCall("Element.ChildrenE", Array{}, err)
+	return list
+}
+
+// Matches checks if the element matches the css selector
+func (el *Element) Matches(selector string) bool {
+	ok, err := el.MatchesE(selector)
+	mustCall("Element.MatchesE", Array{selector}, err)
+	return ok
+}
+
 // Next returns the next sibling element
 func (el *Element) Next() *Element {
 	parent, err := el.NextE()
-	kit.E(err)
+	mustCall("Element.NextE", Array{}, err)
 	return parent
 }
 
 // Previous returns the previous sibling element
 func (el *Element) Previous() *Element {
 	parent, err := el.PreviousE()
-	kit.E(err)
+	mustCall("Element.PreviousE", Array{}, err)
 	return parent
 }
 
@@ -650,27 +1595,137 @@ func (el *Element) Previous() *Element {
 // The regex is the js regex, not golang's.
 func (el *Element) ElementMatches(selector, regex string) *Element {
 	el, err := el.ElementMatchesE(selector, regex)
-	kit.E(err)
+	mustCall("Element.ElementMatchesE", Array{selector, regex}, err)
 	return el
 }
 
 // Elements returns all elements that match the css selector
 func (el *Element) Elements(selector string) Elements {
 	list, err := el.ElementsE(selector)
-	kit.E(err)
+	mustCall("Element.ElementsE", Array{selector}, err)
 	return list
 }
 
 // ElementsX returns all elements that match the XPath selector
 func (el *Element) ElementsX(xpath string) Elements {
 	list, err := el.ElementsXE(xpath)
-	kit.E(err)
+	mustCall("Element.ElementsXE", Array{xpath}, err)
 	return list
 }
 
 // ElementsByJS returns the elements from the return value of the js
 func (el *Element) ElementsByJS(js string, params ...interface{}) Elements {
 	list, err := el.ElementsByJSE(js, params)
-	kit.E(err)
+	mustCall("Element.ElementsByJSE", Array{js, params}, err)
 	return list
 }
+
+// Do starts all the conditions concurrently and returns the first one that completes
+func (rc *RaceContext) Do() (index int, el *Element) {
+	index, el, err := rc.DoE()
+	mustCall("RaceContext.DoE", Array{}, err)
+	return index, el
+}
+
+// StartNetworkTimeline records the timing of each request of the page until stop is called
+func (p *Page) StartNetworkTimeline() (stop func() []*ResourceTiming) {
+	stop, err := p.StartNetworkTimelineE()
+	mustCall("Page.StartNetworkTimelineE", Array{}, err)
+	return stop
+}
+
+// IgnoreCertErrors makes the page ignore the certificate errors, call it before the Navigate
+func (p *Page) IgnoreCertErrors(enabled bool) *Page {
+	mustCall("Page.IgnoreCertErrorsE", Array{enabled}, p.IgnoreCertErrorsE(enabled))
+	return p
+}
+
+// HandleCertErrors calls the handler for each certificate error of the page until stop is called,
+// the request continues only if the handler returns true
+func (p *Page) HandleCertErrors(handler func(e *proto.SecurityCertificateError) (accept bool)) (stop func()) {
+	stop, err := p.HandleCertErrorsE(handler)
+	mustCall("Page.HandleCertErrorsE", Array{handler}, err)
+	return stop
+}
+
+// SecurityState returns the most recent security state of the page
+func (p *Page) SecurityState() *proto.SecuritySecurityStateChanged {
+	state, err := p.SecurityStateE()
+	mustCall("Page.SecurityStateE", Array{}, err)
+	return state
+}
+
+// FillForm fills the fields of the form, the keys are the css selectors of the controls, check FillFormE for
+// the value types of the controls
+func (p *Page) FillForm(fields map[string]interface{}, opts *FormOptions) *Page {
+	mustCall("Page.FillFormE", Array{fields, opts}, p.FillFormE(fields, opts))
+	return p
+}
+
+// EachNavigationRequest calls the handler with the url of each navigation of the page to decide whether to continue,
+// cancel, or redirect it, call the stop function to disable it
+func (p *Page) EachNavigationRequest(handler func(url string) NavigationDecision) (stop func()) {
+	s, err := p.EachNavigationRequestE(handler)
+	mustCall("Page.EachNavigationRequestE", Array{handler}, err)
+	return func() { mustCall("Page.EachNavigationRequestE", Array{handler}, s()) }
+}
+
+// RestrictNavigation cancels the navigations of the page whose hosts are not in the allowedHosts,
+// call the stop function to disable it
+func (p *Page) RestrictNavigation(allowedHosts ...string) (stop func()) {
+	s, err := p.RestrictNavigationE(allowedHosts)
+	mustCall("Page.RestrictNavigationE", Array{allowedHosts}, err)
+	return func() { mustCall("Page.RestrictNavigationE", Array{allowedHosts}, s()) }
+}
+
+// LocalStorage returns the items of the localStorage of the page's current origin
+func (p *Page) LocalStorage() map[string]string {
+	items, err := p.LocalStorageE()
+	mustCall("Page.LocalStorageE", Array{}, err)
+	return items
+}
+
+// SetLocalStorage sets the item of the localStorage of the page's current origin
+func (p *Page) SetLocalStorage(key, value string) *Page {
+	mustCall("Page.SetLocalStorageE", Array{key, value}, p.SetLocalStorageE(key, value))
+	return p
+}
+
+// SessionStorage returns the items of the sessionStorage of the page's current origin
+func (p *Page) SessionStorage() map[string]string {
+	items, err := p.SessionStorageE()
+	mustCall("Page.SessionStorageE", Array{}, err)
+	return items
+}
+
+// SetSessionStorage sets the item of the sessionStorage of the page's current origin
+func (p *Page) SetSessionStorage(key, value string) *Page {
+	mustCall("Page.SetSessionStorageE", Array{key, value}, p.SetSessionStorageE(key, value))
+	return p
+}
+
+// ClearStorage clears the storages of the types for the page's current origin, empty types means all of them
+func (p *Page) ClearStorage(types ...proto.StorageStorageType) *Page {
+	mustCall("Page.ClearStorageE", Array{types}, p.ClearStorageE(types...))
+	return p
+}
+
+// ClearAllStorage clears all the storages of the origin of the page's url
+func (p *Page) ClearAllStorage() *Page {
+	mustCall("Page.ClearAllStorageE", Array{}, p.ClearAllStorageE())
+	return p
+}
+
+// NavigateWithResponse navigates to the url and returns the response of the document
+func (p *Page) NavigateWithResponse(url string) *NavigationResult {
+	result, err := p.NavigateWithResponseE(url)
+	mustCall("Page.NavigateWithResponseE", Array{url}, err)
+	return result
+}
+
+// NavigateOK navigates to the url and panics if the status of the response is 400 or above
+func (p *Page) NavigateOK(url string) *NavigationResult {
+	result, err := p.NavigateOKE(url)
+	mustCall("Page.NavigateOKE", Array{url}, err)
+	return result
+}
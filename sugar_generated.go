@@ -0,0 +1,67 @@
+// This file is generated by "./lib/sugar/generate"
+
+package rod
+
+import (
+	"io"
+	"time"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// WebGLToImage is the sugar of the WebGLToImageE
+func (el *Element) WebGLToImage(format string, quality float64) []byte {
+	r0, err := el.WebGLToImageE(format, quality)
+	mustCall("Element.WebGLToImageE", Array{format, quality}, err)
+	return r0
+}
+
+// History is the sugar of the HistoryE
+func (p *Page) History() (int64, []*proto.PageNavigationEntry) {
+	r0, r1, err := p.HistoryE()
+	mustCall("Page.HistoryE", Array{}, err)
+	return r0, r1
+}
+
+// ReloadAndWaitLoad is the sugar of the ReloadAndWaitLoadE
+func (p *Page) ReloadAndWaitLoad() *Page {
+	mustCall("Page.ReloadAndWaitLoadE", Array{}, p.ReloadAndWaitLoadE())
+	return p
+}
+
+// Screencast is the sugar of the ScreencastE
+func (p *Page) Screencast(opts *proto.PageStartScreencast) (<-chan *ScreencastFrame, func() error) {
+	r0, r1, err := p.ScreencastE(opts)
+	mustCall("Page.ScreencastE", Array{opts}, err)
+	return r0, r1
+}
+
+// ScreenshotStream is the sugar of the ScreenshotStreamE
+func (p *Page) ScreenshotStream(fullpage bool, req *proto.PageCaptureScreenshot) io.ReadCloser {
+	r0, err := p.ScreenshotStreamE(fullpage, req)
+	mustCall("Page.ScreenshotStreamE", Array{fullpage, req}, err)
+	return r0
+}
+
+// SearchWith is the sugar of the SearchWithE
+func (p *Page) SearchWith(req *proto.DOMPerformSearch, limit int) Elements {
+	r0, err := p.SearchWithE(req, limit)
+	mustCall("Page.SearchWithE", Array{req, limit}, err)
+	return r0
+}
+
+// StartHARLimit is the sugar of the StartHARLimitE
+func (p *Page) StartHARLimit(maxBodySize int) func() (*HAR, error) {
+	r0, err := p.StartHARLimitE(maxBodySize)
+	mustCall("Page.StartHARLimitE", Array{maxBodySize}, err)
+	return r0
+}
+
+// WaitRequestsIdle is the sugar of the WaitRequestsIdleE
+func (p *Page) WaitRequestsIdle(d time.Duration, opts *RequestIdleOptions) func() {
+	wait := p.WaitRequestsIdleE(d, opts)
+	return func() {
+		err := wait()
+		mustCall("Page.WaitRequestsIdleE", Array{d, opts}, err)
+	}
+}
@@ -0,0 +1,191 @@
+// This file contains the network timeline of the page, it assembles the timing of each request from the network events.
+
+package rod
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// ResourceTiming is the timing of a request, the durations are marshaled as nanoseconds.
+// The durations that the request doesn't have are 0, such as the DNS of a reused connection.
+type ResourceTiming struct {
+	RequestID proto.NetworkRequestID    `json:"requestId"`
+	URL       string                    `json:"url"`
+	Method    string                    `json:"method"`
+	Type      proto.NetworkResourceType `json:"type"`
+	Status    int64                     `json:"status"`
+
+	// Redirect is the position in the redirect chain, 0 means the original request, the entries of a chain share
+	// the same RequestID
+	Redirect int `json:"redirect"`
+
+	// FromCache is true if the response is served from the memory cache or the disk cache
+	FromCache bool `json:"fromCache"`
+
+	// Pending is true if the request is still in flight when the timeline stops
+	Pending bool `json:"pending"`
+
+	// Failed is the error text if the request fails, such as "net::ERR_CONNECTION_REFUSED"
+	Failed string `json:"failed,omitempty"`
+
+	// Start is the wall time when the request is sent
+	Start time.Time `json:"start"`
+
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	SSL      time.Duration `json:"ssl"`
+	TTFB     time.Duration `json:"ttfb"`     // from the request is sent to the response headers are received
+	Download time.Duration `json:"download"` // from the response headers are received to the body is received
+	Total    time.Duration `json:"total"`
+
+	// EncodedDataLength is the bytes received from the network, such as the compressed body and the headers
+	EncodedDataLength float64 `json:"encodedDataLength"`
+
+	sent     time.Duration // the monotonic time when the request is sent
+	received time.Duration // the monotonic time when the response headers are received
+}
+
+// response fills the timing of the response
+func (t *ResourceTiming) response(res *proto.NetworkResponse) {
+	t.Status = res.Status
+	t.FromCache = t.FromCache || res.FromDiskCache
+	t.EncodedDataLength = res.EncodedDataLength
+
+	timing := res.Timing
+	if timing == nil {
+		return
+	}
+
+	span := func(start, end float64) time.Duration {
+		if start < 0 || end < start {
+			return 0
+		}
+		return time.Duration((end - start) * float64(time.Millisecond))
+	}
+
+	t.DNS = span(timing.DNSStart, timing.DNSEnd)
+	t.Connect = span(timing.ConnectStart, timing.ConnectEnd)
+	t.SSL = span(timing.SslStart, timing.SslEnd)
+	t.TTFB = span(timing.SendStart, timing.ReceiveHeadersEnd)
+
+	t.received = time.Duration(timing.RequestTime*float64(time.Second)) +
+		time.Duration(timing.ReceiveHeadersEnd*float64(time.Millisecond))
+}
+
+// end fills the durations that depend on the time when the request ends
+func (t *ResourceTiming) end(at *proto.MonotonicTime) {
+	t.Pending = false
+	if at == nil {
+		return
+	}
+
+	t.Total = at.Duration - t.sent
+	if t.received > 0 && at.Duration > t.received {
+		t.Download = at.Duration - t.received
+	}
+}
+
+// StartNetworkTimelineE records the timing of each request of the page until stop is called,
+// stop returns the entries in the order the requests are sent, the redirects are separate entries.
+func (p *Page) StartNetworkTimelineE() (stop func() []*ResourceTiming, err error) {
+	err = proto.NetworkEnable{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	lock := sync.Mutex{}
+	list := []*ResourceTiming{}
+	current := map[proto.NetworkRequestID]*ResourceTiming{}
+	done := make(chan kit.Nil)
+
+	go func() {
+		defer close(done)
+
+		goob.Each(s, func(msg *cdp.Event) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			sent := &proto.NetworkRequestWillBeSent{}
+			received := &proto.NetworkResponseReceived{}
+			cached := &proto.NetworkRequestServedFromCache{}
+			finished := &proto.NetworkLoadingFinished{}
+			failed := &proto.NetworkLoadingFailed{}
+
+			switch {
+			case Event(msg, sent):
+				redirect := 0
+
+				// the redirect reuses the RequestID, the previous entry ends with the redirect response
+				if prev, has := current[sent.RequestID]; has && sent.RedirectResponse != nil {
+					prev.response(sent.RedirectResponse)
+					prev.end(sent.Timestamp)
+					redirect = prev.Redirect + 1
+				}
+
+				t := &ResourceTiming{
+					RequestID: sent.RequestID,
+					URL:       sent.Request.URL,
+					Method:    sent.Request.Method,
+					Type:      sent.Type,
+					Redirect:  redirect,
+					Pending:   true,
+				}
+				if sent.WallTime != nil {
+					t.Start = sent.WallTime.Time
+				}
+				if sent.Timestamp != nil {
+					t.sent = sent.Timestamp.Duration
+				}
+				current[sent.RequestID] = t
+				list = append(list, t)
+
+			case Event(msg, received):
+				if t, has := current[received.RequestID]; has {
+					t.Type = received.Type
+					t.response(received.Response)
+				}
+
+			case Event(msg, cached):
+				if t, has := current[cached.RequestID]; has {
+					t.FromCache = true
+				}
+
+			case Event(msg, finished):
+				if t, has := current[finished.RequestID]; has {
+					t.EncodedDataLength = finished.EncodedDataLength
+					t.end(finished.Timestamp)
+					delete(current, finished.RequestID)
+				}
+
+			case Event(msg, failed):
+				if t, has := current[failed.RequestID]; has {
+					t.Failed = failed.ErrorText
+					t.end(failed.Timestamp)
+					delete(current, failed.RequestID)
+				}
+			}
+		})
+	}()
+
+	var once sync.Once
+	return func() []*ResourceTiming {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]*ResourceTiming{}, list...)
+	}, nil
+}
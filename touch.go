@@ -0,0 +1,151 @@
+package rod
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the distance from the center to each finger when a pinch starts
+var pinchRadius = 50.0
+
+// the number of moves of a pinch
+var pinchSteps = 10
+
+// Touch represents the touchscreen on a page, it's always related the main frame.
+// The touch emulation of the page will be enabled automatically before the first gesture.
+type Touch struct {
+	page *Page
+	sync.Mutex
+
+	enabled bool
+}
+
+// enableE turns on the touch emulation, two touch points are required by the pinch
+func (t *Touch) enableE() error {
+	if t.enabled {
+		return nil
+	}
+
+	err := proto.EmulationSetTouchEmulationEnabled{
+		Enabled:        true,
+		MaxTouchPoints: 2,
+	}.Call(t.page)
+	if err != nil {
+		return err
+	}
+
+	t.enabled = true
+	return nil
+}
+
+func (t *Touch) dispatch(typ proto.InputDispatchTouchEventType, points ...*proto.InputTouchPoint) error {
+	if points == nil {
+		points = []*proto.InputTouchPoint{}
+	}
+
+	return proto.InputDispatchTouchEvent{
+		Type:        typ,
+		TouchPoints: points,
+		Modifiers:   t.page.Keyboard.getModifiers(),
+	}.Call(t.page)
+}
+
+// TapE doc is similar to the method Tap
+func (t *Touch) TapE(x, y float64) error {
+	defer t.page.tryTrace(x, y, 1, 1, fmt.Sprintf("tap (%.2f, %.2f)", x, y))()
+	t.page.browser.trySlowmotion()
+
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.enableE()
+	if err != nil {
+		return err
+	}
+
+	err = t.dispatch(proto.InputDispatchTouchEventTypeTouchStart, &proto.InputTouchPoint{X: x, Y: y, ID: 1})
+	if err != nil {
+		return err
+	}
+
+	return t.dispatch(proto.InputDispatchTouchEventTypeTouchEnd)
+}
+
+// SwipeE doc is similar to the method Swipe
+func (t *Touch) SwipeE(fromX, fromY, toX, toY float64, steps int) error {
+	defer t.page.tryTrace(fromX, fromY, 1, 1, fmt.Sprintf("swipe (%.2f, %.2f) to (%.2f, %.2f)", fromX, fromY, toX, toY))()
+	t.page.browser.trySlowmotion()
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.enableE()
+	if err != nil {
+		return err
+	}
+
+	err = t.dispatch(proto.InputDispatchTouchEventTypeTouchStart, &proto.InputTouchPoint{X: fromX, Y: fromY, ID: 1})
+	if err != nil {
+		return err
+	}
+
+	stepX := (toX - fromX) / float64(steps)
+	stepY := (toY - fromY) / float64(steps)
+
+	for i := 1; i <= steps; i++ {
+		err = t.dispatch(proto.InputDispatchTouchEventTypeTouchMove, &proto.InputTouchPoint{
+			X:  fromX + stepX*float64(i),
+			Y:  fromY + stepY*float64(i),
+			ID: 1,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.dispatch(proto.InputDispatchTouchEventTypeTouchEnd)
+}
+
+// PinchE doc is similar to the method Pinch
+func (t *Touch) PinchE(centerX, centerY, scale float64) error {
+	defer t.page.tryTrace(centerX, centerY, 1, 1, fmt.Sprintf("pinch %.2f", scale))()
+	t.page.browser.trySlowmotion()
+
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.enableE()
+	if err != nil {
+		return err
+	}
+
+	// the two fingers are on the horizontal line through the center, they move in opposite directions
+	fingers := func(radius float64) []*proto.InputTouchPoint {
+		return []*proto.InputTouchPoint{
+			{X: centerX - radius, Y: centerY, ID: 1},
+			{X: centerX + radius, Y: centerY, ID: 2},
+		}
+	}
+
+	err = t.dispatch(proto.InputDispatchTouchEventTypeTouchStart, fingers(pinchRadius)...)
+	if err != nil {
+		return err
+	}
+
+	step := (pinchRadius*scale - pinchRadius) / float64(pinchSteps)
+
+	for i := 1; i <= pinchSteps; i++ {
+		err = t.dispatch(proto.InputDispatchTouchEventTypeTouchMove, fingers(pinchRadius+step*float64(i))...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.dispatch(proto.InputDispatchTouchEventTypeTouchEnd)
+}
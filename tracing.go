@@ -0,0 +1,165 @@
+// This file contains the tracing and performance related code of the page.
+
+package rod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/ysmood/kit"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// the max time to wait for the Tracing.tracingComplete after the Tracing.end
+var tracingCompleteTimeout = time.Minute
+
+// tracingState is shared by all the clones of a page
+type tracingState struct {
+	lock sync.Mutex
+
+	// not nil when tracing is started
+	complete chan *proto.TracingTracingComplete
+	cancel   func()
+	events   []json.RawMessage
+}
+
+// StartTracingE starts to collect the trace events of the categories, such as "devtools.timeline".
+// If categories is empty, the default categories of the browser will be used.
+func (p *Page) StartTracingE(categories []string) error {
+	t := p.tracing
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.complete != nil {
+		return &Error{nil, ErrTracingStarted, nil}
+	}
+
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+	complete := make(chan *proto.TracingTracingComplete, 1)
+
+	go func() {
+		for msg := range s {
+			e := msg.(*cdp.Event)
+
+			collected := &proto.TracingDataCollected{}
+			done := &proto.TracingTracingComplete{}
+
+			if Event(e, collected) {
+				t.lock.Lock()
+				for _, v := range collected.Value {
+					t.events = append(t.events, json.RawMessage(kit.MustToJSON(v)))
+				}
+				t.lock.Unlock()
+			} else if Event(e, done) {
+				complete <- done
+				return
+			}
+		}
+	}()
+
+	req := proto.TracingStart{
+		TransferMode: proto.TracingStartTransferModeReportEvents,
+	}
+	if len(categories) > 0 {
+		req.TraceConfig = &proto.TracingTraceConfig{IncludedCategories: categories}
+	}
+
+	err := req.Call(p)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	t.complete = complete
+	t.cancel = cancel
+	t.events = nil
+
+	return nil
+}
+
+// StopTracingE stops the tracing and returns the trace file in json format, it can be loaded by chrome://tracing.
+// If the browser doesn't send the tracing complete event in time, an ErrTracingTimeout error will be returned.
+func (p *Page) StopTracingE() ([]byte, error) {
+	t := p.tracing
+
+	t.lock.Lock()
+	complete, cancel := t.complete, t.cancel
+	t.lock.Unlock()
+
+	if complete == nil {
+		return nil, &Error{nil, ErrTracingNotStarted, nil}
+	}
+
+	defer func() {
+		cancel()
+
+		t.lock.Lock()
+		t.complete = nil
+		t.events = nil
+		t.lock.Unlock()
+	}()
+
+	err := proto.TracingEnd{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.NewTimer(tracingCompleteTimeout)
+	defer timeout.Stop()
+
+	var done *proto.TracingTracingComplete
+	select {
+	case <-p.ctx.Done():
+		return nil, p.ctx.Err()
+	case <-timeout.C:
+		return nil, &Error{nil, ErrTracingTimeout, tracingCompleteTimeout}
+	case done = <-complete:
+	}
+
+	// the data is returned as an IO stream, it's already a complete trace file
+	if done.Stream != "" {
+		r := newStreamReader(p, done.Stream)
+		defer func() { _ = r.Close() }()
+		return ioutil.ReadAll(r)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	buf := bytes.NewBufferString(`{"traceEvents":[`)
+	for i, e := range t.events {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(e)
+	}
+	buf.WriteString(`]}`)
+
+	return buf.Bytes(), nil
+}
+
+// MetricsE returns the runtime metrics of the page, such as "JSHeapUsedSize", "LayoutCount", and "TaskDuration".
+func (p *Page) MetricsE() (map[string]float64, error) {
+	err := proto.PerformanceEnable{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := proto.PerformanceGetMetrics{}.Call(p)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := map[string]float64{}
+	for _, m := range res.Metrics {
+		metrics[m.Name] = m.Value
+	}
+	return metrics, nil
+}
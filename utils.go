@@ -3,10 +3,13 @@ package rod
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/ysmood/goob"
@@ -47,12 +50,21 @@ func Event(msg *cdp.Event, evt proto.Event) bool {
 	return false
 }
 
-func isNilContextErr(err error) bool {
-	if err == nil {
-		return false
-	}
-	cdpErr, ok := err.(*cdp.Error)
-	return ok && cdpErr.Code == -32000
+// onEvent calls the handler for each event of the type of evt, the evt is zeroed before each decoding,
+// so that the fields of the previous event won't remain
+func onEvent(s chan goob.Event, evt proto.Event, handler func()) {
+	v := reflect.ValueOf(evt).Elem()
+	zero := reflect.Zero(v.Type())
+
+	goob.Each(s, func(msg *cdp.Event) {
+		if msg.Method != evt.MethodName() {
+			return
+		}
+		v.Set(zero)
+		if Event(msg, evt) {
+			handler()
+		}
+	})
 }
 
 func matchWithFilter(s string, includes, excludes []string) bool {
@@ -69,6 +81,49 @@ func matchWithFilter(s string, includes, excludes []string) bool {
 	return false
 }
 
+// wildcardToRegexp converts the Fetch url pattern to regexp.
+// Wildcards ('*' -> zero or more, '?' -> exactly one) are allowed. Escape character is backslash.
+// Omitting is equivalent to "*".
+func wildcardToRegexp(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	reg := strings.Builder{}
+	escaped := false
+
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			reg.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			reg.WriteString(".*")
+		case r == '?':
+			reg.WriteString(".")
+		default:
+			reg.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	return regexp.MustCompile("^" + reg.String() + "$")
+}
+
+// quadBounds returns the bounding box of the quad
+func quadBounds(quad proto.DOMQuad) (left, top, right, bottom float64) {
+	left, top = math.Inf(1), math.Inf(1)
+	right, bottom = math.Inf(-1), math.Inf(-1)
+	for i := 0; i+1 < len(quad); i += 2 {
+		left = math.Min(left, quad[i])
+		right = math.Max(right, quad[i])
+		top = math.Min(top, quad[i+1])
+		bottom = math.Max(bottom, quad[i+1])
+	}
+	return
+}
+
 func saveScreenshot(bin []byte, toFile []string) error {
 	if len(toFile) == 0 {
 		return nil
@@ -102,3 +157,46 @@ func eachEvent(s chan goob.Event) func(fn interface{}) {
 		})
 	}
 }
+
+// quadEqual returns true if the two quads have the same points
+func quadEqual(a, b proto.DOMQuad) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// exceptionText returns the description of the thrown value, or the text of the exception if nothing is thrown
+func exceptionText(details *proto.RuntimeExceptionDetails) string {
+	if details.Exception != nil && details.Exception.Description != "" {
+		return details.Exception.Description
+	}
+	return details.Text
+}
+
+// cspErr converts the CSPBlocked error thrown by the rod helper into an ErrCSPBlocked error
+func cspErr(err error) error {
+	e, ok := err.(*Error)
+	if !ok || e.Code != ErrEval {
+		return err
+	}
+
+	desc, _ := e.Details.(string)
+	if !strings.HasPrefix(desc, "CSPBlocked: ") {
+		return err
+	}
+
+	directive := strings.SplitN(strings.TrimPrefix(desc, "CSPBlocked: "), "\n", 2)[0]
+	return &Error{err, ErrCSPBlocked, directive}
+}
+
+// isMethodNotFound returns true if the browser doesn't support the method of the call
+func isMethodNotFound(err error) bool {
+	var cdpErr *cdp.Error
+	return errors.As(err, &cdpErr) && cdpErr.Code == -32601
+}
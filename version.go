@@ -0,0 +1,85 @@
+// This file contains the version and capability detection of the browser.
+
+package rod
+
+import (
+	"sync"
+
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// Capability is a feature of the browser that not all the versions support
+type Capability string
+
+const (
+	// CapScrollIntoViewIfNeeded the DOM.scrollIntoViewIfNeeded method
+	CapScrollIntoViewIfNeeded Capability = "DOM.scrollIntoViewIfNeeded"
+
+	// CapDownloadEvents the eventsEnabled option of the Browser.setDownloadBehavior,
+	// and the Browser.downloadWillBegin, Browser.downloadProgress events
+	CapDownloadEvents Capability = "Browser.downloadEvents"
+)
+
+// capabilityVersions are the min major versions of chrome that support the capabilities,
+// the protocol version of chrome is always "1.3", so the major version of the product is used instead
+var capabilityVersions = map[Capability]int{
+	CapScrollIntoViewIfNeeded: 81,
+	CapDownloadEvents:         86,
+}
+
+// versionState is shared by all the clones of a browser, the version is only queried once
+type versionState struct {
+	lock         sync.Mutex
+	version      *proto.BrowserGetVersionResult
+	capabilities map[Capability]bool
+}
+
+// VersionE returns the version info of the browser, such as the product, user agent, and V8 version.
+// Use the WebKitVersion and Major of the result to get the WebKit version and the major version of chrome.
+func (b *Browser) VersionE() (*proto.BrowserGetVersionResult, error) {
+	v := b.version
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.version != nil {
+		return v.version, nil
+	}
+
+	res, err := proto.BrowserGetVersion{}.Call(b)
+	if err != nil {
+		return nil, err
+	}
+
+	major := res.Major()
+	v.capabilities = map[Capability]bool{}
+	for c, min := range capabilityVersions {
+		v.capabilities[c] = major >= min
+	}
+	v.version = res
+
+	return res, nil
+}
+
+// CapabilitiesE returns the capabilities of the browser, the value of a capability is true if it's supported
+func (b *Browser) CapabilitiesE() (map[Capability]bool, error) {
+	_, err := b.VersionE()
+	if err != nil {
+		return nil, err
+	}
+
+	list := map[Capability]bool{}
+	for c, ok := range b.version.capabilities {
+		list[c] = ok
+	}
+	return list, nil
+}
+
+// SupportsE returns true if the browser supports the capability
+func (b *Browser) SupportsE(c Capability) (bool, error) {
+	_, err := b.VersionE()
+	if err != nil {
+		return false, err
+	}
+	return b.version.capabilities[c], nil
+}
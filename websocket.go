@@ -0,0 +1,166 @@
+// This file contains the WebSocket related code of the page.
+
+package rod
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync"
+
+	"github.com/ysmood/goob"
+	"github.com/ysmood/rod/lib/cdp"
+	"github.com/ysmood/rod/lib/proto"
+)
+
+// WebSocketDirection is the kind of a WebSocketFrame
+type WebSocketDirection string
+
+const (
+	// WebSocketCreated the socket is created
+	WebSocketCreated WebSocketDirection = "created"
+
+	// WebSocketSent the message is sent by the page
+	WebSocketSent WebSocketDirection = "sent"
+
+	// WebSocketReceived the message is received by the page
+	WebSocketReceived WebSocketDirection = "received"
+
+	// WebSocketClosed the socket is closed
+	WebSocketClosed WebSocketDirection = "closed"
+)
+
+// WebSocketFrame is a WebSocket event of the page, only the sent and received frames have the opcode and payload
+type WebSocketFrame struct {
+	RequestID proto.NetworkRequestID
+
+	// URL of the socket, it's empty if the socket is created before the subscription
+	URL string
+
+	Direction WebSocketDirection
+
+	// Opcode of the message, 1 is text, 2 is binary
+	Opcode int
+
+	// Payload of the message, the binary messages are base64-decoded
+	Payload []byte
+}
+
+// webSocketURLs remembers the urls of the sockets, because only the Network.webSocketCreated has the url
+type webSocketURLs struct {
+	lock sync.Mutex
+	list map[proto.NetworkRequestID]string
+}
+
+// frame converts the msg to a frame, returns nil if the msg is not a WebSocket event
+func (u *webSocketURLs) frame(msg *cdp.Event) *WebSocketFrame {
+	created := &proto.NetworkWebSocketCreated{}
+	sent := &proto.NetworkWebSocketFrameSent{}
+	received := &proto.NetworkWebSocketFrameReceived{}
+	closed := &proto.NetworkWebSocketClosed{}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	switch {
+	case Event(msg, created):
+		u.list[created.RequestID] = created.URL
+		return &WebSocketFrame{RequestID: created.RequestID, URL: created.URL, Direction: WebSocketCreated}
+	case Event(msg, sent):
+		return newWebSocketFrame(sent.RequestID, u.list[sent.RequestID], WebSocketSent, sent.Response)
+	case Event(msg, received):
+		return newWebSocketFrame(received.RequestID, u.list[received.RequestID], WebSocketReceived, received.Response)
+	case Event(msg, closed):
+		url := u.list[closed.RequestID]
+		delete(u.list, closed.RequestID)
+		return &WebSocketFrame{RequestID: closed.RequestID, URL: url, Direction: WebSocketClosed}
+	}
+	return nil
+}
+
+func newWebSocketFrame(
+	id proto.NetworkRequestID, url string, dir WebSocketDirection, data *proto.NetworkWebSocketFrame,
+) *WebSocketFrame {
+	f := &WebSocketFrame{RequestID: id, URL: url, Direction: dir}
+	if data == nil {
+		return f
+	}
+
+	f.Opcode = int(data.Opcode)
+	f.Payload = []byte(data.PayloadData)
+	if f.Opcode != 1 {
+		bin, err := base64.StdEncoding.DecodeString(data.PayloadData)
+		if err == nil {
+			f.Payload = bin
+		}
+	}
+	return f
+}
+
+// EachWebSocketE calls the handler for every WebSocket event of the page in order.
+// The events are only emitted after the subscription, so call it before the NavigateE to catch the sockets
+// that are created during the page loading. The handler will stop receiving events when stop is called
+// or the page context is done.
+func (p *Page) EachWebSocketE(handler func(WebSocketFrame)) (stop func(), err error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err = proto.NetworkEnable{}.Call(p)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	urls := &webSocketURLs{list: map[proto.NetworkRequestID]string{}}
+
+	go goob.Each(s, func(msg *cdp.Event) {
+		f := urls.frame(msg)
+		if f != nil {
+			handler(*f)
+		}
+	})
+
+	return cancel, nil
+}
+
+// WaitWebSocketMessageE returns a wait function that waits until a message of the sockets whose url matches
+// the urlPattern contains the payloadSubstr, both the sent and received messages are checked.
+// Wildcards ('*' -> zero or more, '?' -> exactly one) are allowed for the urlPattern.
+// The wait function returns the payload of the message.
+func (p *Page) WaitWebSocketMessageE(urlPattern, payloadSubstr string) func() (string, error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	s := p.event.Subscribe(ctx)
+
+	err := proto.NetworkEnable{}.Call(p)
+
+	reg := wildcardToRegexp(urlPattern)
+	urls := &webSocketURLs{list: map[proto.NetworkRequestID]string{}}
+
+	return func() (string, error) {
+		defer cancel()
+
+		if err != nil {
+			return "", err
+		}
+
+		var payload *string
+		goob.Each(s, func(msg *cdp.Event) bool {
+			f := urls.frame(msg)
+			if f == nil || (f.Direction != WebSocketSent && f.Direction != WebSocketReceived) {
+				return false
+			}
+
+			text := string(f.Payload)
+			if reg.MatchString(f.URL) && strings.Contains(text, payloadSubstr) {
+				payload = &text
+				return true
+			}
+			return false
+		})
+
+		if payload == nil {
+			return "", p.ctx.Err()
+		}
+		return *payload, nil
+	}
+}